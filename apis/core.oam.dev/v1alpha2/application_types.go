@@ -59,6 +59,33 @@ type AppStatus struct {
 	// LatestRevision of the application configuration it generates
 	// +optional
 	LatestRevision *Revision `json:"latestRevision,omitempty"`
+
+	// RolloutSourceRevision is the application configuration revision the rollout plan upgrades from
+	// +optional
+	RolloutSourceRevision string `json:"rolloutSourceRevision,omitempty"`
+
+	// RolloutTargetRevision is the application configuration revision the rollout plan upgrades to
+	// +optional
+	RolloutTargetRevision string `json:"rolloutTargetRevision,omitempty"`
+
+	// ResourceTracker records the resources generated from the application so that they can be garbage collected
+	// +optional
+	ResourceTracker []TrackedResource `json:"resourceTracker,omitempty"`
+
+	// Dependency records the inputs of the components that are not satisfied yet
+	// +optional
+	Dependency DependencyStatus `json:"dependency,omitempty"`
+}
+
+// TrackedResource is a resource generated from the application
+type TrackedResource struct {
+	// Component is the name of the application component that generates the resource,
+	// it is empty for the resources that belong to the whole application
+	// +optional
+	Component string `json:"component,omitempty"`
+
+	// Reference to the resource
+	Reference runtimev1alpha1.TypedReference `json:"resourceRef"`
 }
 
 // ApplicationComponentStatus record the health status of App component
@@ -67,6 +94,9 @@ type ApplicationComponentStatus struct {
 	Healthy bool                     `json:"healthy"`
 	Message string                   `json:"message,omitempty"`
 	Traits  []ApplicationTraitStatus `json:"traits,omitempty"`
+
+	// Conditions of the component, they tell if the component is rendered and healthy
+	runtimev1alpha1.ConditionedStatus `json:",inline"`
 }
 
 // ApplicationTraitStatus records the trait health status
@@ -74,8 +104,29 @@ type ApplicationTraitStatus struct {
 	Type    string `json:"type"`
 	Healthy bool   `json:"healthy"`
 	Message string `json:"message,omitempty"`
+
+	// Conditions of the trait, they tell if the trait is rendered and healthy
+	runtimev1alpha1.ConditionedStatus `json:",inline"`
 }
 
+// The condition types of the application components and traits
+const (
+	// TypeRendered indicates whether the component or the trait is rendered from its template
+	TypeRendered runtimev1alpha1.ConditionType = "Rendered"
+	// TypeHealthy indicates whether the component or the trait is healthy
+	TypeHealthy runtimev1alpha1.ConditionType = "Healthy"
+)
+
+// The condition reasons of the application components and traits
+const (
+	ReasonRendered         runtimev1alpha1.ConditionReason = "Rendered"
+	ReasonRenderError      runtimev1alpha1.ConditionReason = "RenderError"
+	ReasonTraitRenderError runtimev1alpha1.ConditionReason = "TraitRenderError"
+	ReasonHealthy          runtimev1alpha1.ConditionReason = "Healthy"
+	ReasonUnhealthy        runtimev1alpha1.ConditionReason = "Unhealthy"
+	ReasonHealthCheckError runtimev1alpha1.ConditionReason = "HealthCheckError"
+)
+
 // ApplicationTrait defines the trait of application
 type ApplicationTrait struct {
 	Name string `json:"name"`
@@ -97,6 +148,16 @@ type ApplicationComponent struct {
 	// scopes in ApplicationComponent defines the component-level scopes
 	// the format is <scope-type:scope-instance-name> pairs, the key represents type of `ScopeDefinition` while the value represent the name of scope instance.
 	Scopes map[string]string `json:"scopes,omitempty"`
+
+	// Outputs are the data the component passes to the other components of the application,
+	// they are read from the workload of the component
+	// +optional
+	Outputs []DataOutput `json:"outputs,omitempty"`
+
+	// Inputs are the data the component takes from the outputs of the other components,
+	// the workload of the component is not created until the inputs are filled into it
+	// +optional
+	Inputs []DataInput `json:"inputs,omitempty"`
 }
 
 // ApplicationSpec is the spec of Application
@@ -0,0 +1,71 @@
+/*
+Copyright 2020 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// ApplicationRevisionSpec is the spec of ApplicationRevision
+type ApplicationRevisionSpec struct {
+	// Application records the snapshot of the application spec of this revision
+	// +kubebuilder:validation:EmbeddedResource
+	Application Application `json:"application"`
+
+	// WorkloadDefinitions records the snapshot of the workloadDefinitions the application is rendered with
+	// the key is the name of the definition
+	// +optional
+	WorkloadDefinitions map[string]WorkloadDefinition `json:"workloadDefinitions,omitempty"`
+
+	// TraitDefinitions records the snapshot of the traitDefinitions the application is rendered with
+	// the key is the name of the definition
+	// +optional
+	TraitDefinitions map[string]TraitDefinition `json:"traitDefinitions,omitempty"`
+
+	// ApplicationConfiguration records the applicationConfiguration rendered from the application
+	// it's the existing applicationConfiguration if the application renders the same one as the previous revision
+	// +kubebuilder:pruning:PreserveUnknownFields
+	ApplicationConfiguration runtime.RawExtension `json:"applicationConfiguration"`
+
+	// Components records the components rendered from the application
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +optional
+	Components []runtime.RawExtension `json:"components,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ApplicationRevision is an immutable snapshot of an application spec, the definitions it is rendered with
+// and the resources rendered from it
+// +kubebuilder:resource:categories={oam},shortName=apprev
+// +kubebuilder:printcolumn:name="AGE",type=date,JSONPath=".metadata.creationTimestamp"
+type ApplicationRevision struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ApplicationRevisionSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ApplicationRevisionList contains a list of ApplicationRevision
+type ApplicationRevisionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ApplicationRevision `json:"items"`
+}
@@ -47,6 +47,12 @@ func (in *AppStatus) DeepCopyInto(out *AppStatus) {
 		*out = new(Revision)
 		**out = **in
 	}
+	if in.ResourceTracker != nil {
+		in, out := &in.ResourceTracker, &out.ResourceTracker
+		*out = make([]TrackedResource, len(*in))
+		copy(*out, *in)
+	}
+	in.Dependency.DeepCopyInto(&out.Dependency)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AppStatus.
@@ -104,6 +110,20 @@ func (in *ApplicationComponent) DeepCopyInto(out *ApplicationComponent) {
 			(*out)[key] = val
 		}
 	}
+	if in.Outputs != nil {
+		in, out := &in.Outputs, &out.Outputs
+		*out = make([]DataOutput, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Inputs != nil {
+		in, out := &in.Inputs, &out.Inputs
+		*out = make([]DataInput, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationComponent.
@@ -122,8 +142,11 @@ func (in *ApplicationComponentStatus) DeepCopyInto(out *ApplicationComponentStat
 	if in.Traits != nil {
 		in, out := &in.Traits, &out.Traits
 		*out = make([]ApplicationTraitStatus, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
+	in.ConditionedStatus.DeepCopyInto(&out.ConditionedStatus)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationComponentStatus.
@@ -425,6 +448,102 @@ func (in *ApplicationList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApplicationRevision) DeepCopyInto(out *ApplicationRevision) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationRevision.
+func (in *ApplicationRevision) DeepCopy() *ApplicationRevision {
+	if in == nil {
+		return nil
+	}
+	out := new(ApplicationRevision)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ApplicationRevision) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApplicationRevisionList) DeepCopyInto(out *ApplicationRevisionList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ApplicationRevision, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationRevisionList.
+func (in *ApplicationRevisionList) DeepCopy() *ApplicationRevisionList {
+	if in == nil {
+		return nil
+	}
+	out := new(ApplicationRevisionList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ApplicationRevisionList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApplicationRevisionSpec) DeepCopyInto(out *ApplicationRevisionSpec) {
+	*out = *in
+	in.Application.DeepCopyInto(&out.Application)
+	if in.WorkloadDefinitions != nil {
+		in, out := &in.WorkloadDefinitions, &out.WorkloadDefinitions
+		*out = make(map[string]WorkloadDefinition, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	if in.TraitDefinitions != nil {
+		in, out := &in.TraitDefinitions, &out.TraitDefinitions
+		*out = make(map[string]TraitDefinition, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	in.ApplicationConfiguration.DeepCopyInto(&out.ApplicationConfiguration)
+	if in.Components != nil {
+		in, out := &in.Components, &out.Components
+		*out = make([]runtime.RawExtension, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationRevisionSpec.
+func (in *ApplicationRevisionSpec) DeepCopy() *ApplicationRevisionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ApplicationRevisionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ApplicationSpec) DeepCopyInto(out *ApplicationSpec) {
 	*out = *in
@@ -471,6 +590,7 @@ func (in *ApplicationTrait) DeepCopy() *ApplicationTrait {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ApplicationTraitStatus) DeepCopyInto(out *ApplicationTraitStatus) {
 	*out = *in
+	in.ConditionedStatus.DeepCopyInto(&out.ConditionedStatus)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationTraitStatus.
@@ -1757,6 +1877,22 @@ func (in *TCPSocketProbe) DeepCopy() *TCPSocketProbe {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TrackedResource) DeepCopyInto(out *TrackedResource) {
+	*out = *in
+	out.Reference = in.Reference
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TrackedResource.
+func (in *TrackedResource) DeepCopy() *TrackedResource {
+	if in == nil {
+		return nil
+	}
+	out := new(TrackedResource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TraitDefinition) DeepCopyInto(out *TraitDefinition) {
 	*out = *in
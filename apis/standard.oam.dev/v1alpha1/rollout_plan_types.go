@@ -2,6 +2,7 @@ package v1alpha1
 
 import (
 	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
@@ -16,6 +17,21 @@ const (
 	DecreaseFirstRolloutStrategyType RolloutStrategyType = "DecreaseFirst"
 )
 
+// RollbackPolicyType defines what to do when a rollout fails
+type RollbackPolicyType string
+
+const (
+	// NoneRollbackPolicyType leaves the resources as they are when the rollout fails
+	NoneRollbackPolicyType RollbackPolicyType = "None"
+
+	// RevertToSourceRollbackPolicyType reverts all the upgraded pods back to the source in reverse batches
+	RevertToSourceRollbackPolicyType RollbackPolicyType = "RevertToSource"
+
+	// PauseForManualRollbackPolicyType leaves the resources as they are and waits for the operator to decide
+	// the operator can change the policy to RevertToSource to start the rollback
+	PauseForManualRollbackPolicyType RollbackPolicyType = "PauseForManual"
+)
+
 // HookType can be pre, post or during rollout
 type HookType string
 
@@ -49,6 +65,10 @@ const (
 	// we can not move forward anymore
 	// we will let the client to decide when or whether to revert
 	RolloutFailedState RollingState = "rolloutFailed"
+	// RollingBackState reverts the upgraded pods back to the source in reverse batches after the rollout failed
+	RollingBackState RollingState = "rollingBack"
+	// RolloutRolledBackState all the upgraded pods are reverted back to the source
+	RolloutRolledBackState RollingState = "rolledBack"
 )
 
 // BatchRollingState is the sub state when the rollout is on the fly
@@ -61,6 +81,8 @@ const (
 	BatchInRollingState BatchRollingState = "batchInRolling"
 	// BatchVerifyingState verifying if the application is ready to roll.
 	BatchVerifyingState BatchRollingState = "batchVerifying"
+	// BatchWaitingApprovalState indicates that the batch requires a manual approval before we roll it
+	BatchWaitingApprovalState BatchRollingState = "batchWaitingApproval"
 	// BatchRolloutFailedState indicates that the batch didn't get the manual or automatic approval
 	BatchRolloutFailedState BatchRollingState = "batchVerifyFailed"
 	// BatchFinalizingState indicates that all the pods in the are available, we can move on to the next batch
@@ -100,10 +122,20 @@ type RolloutPlan struct {
 	// +optional
 	BatchPartition *int32 `json:"lastBatchToRollout,omitempty"`
 
+	// RollbackPolicy defines what to do with the upgraded pods when the rollout fails, default is None
+	// +optional
+	RollbackPolicy RollbackPolicyType `json:"rollbackPolicy,omitempty"`
+
 	// Paused the rollout, default is false
 	// +optional
 	Paused bool `json:"paused,omitempty"`
 
+	// ProgressDeadlineSeconds is the max time in seconds for the whole rollout to finish after it started
+	// the time spent in waiting for approvals counts too. The rollout fails if it doesn't finish in time.
+	// The time the rollout is paused does not count, the deadline is pushed back when the rollout resumes.
+	// +optional
+	ProgressDeadlineSeconds *int32 `json:"progressDeadlineSeconds,omitempty"`
+
 	// RolloutWebhooks provide a way for the rollout to interact with an external process
 	// +optional
 	RolloutWebhooks []RolloutWebhook `json:"rolloutWebhooks,omitempty"`
@@ -112,6 +144,26 @@ type RolloutPlan struct {
 	// before complete the process
 	// +optional
 	CanaryMetric []CanaryMetric `json:"canaryMetric,omitempty"`
+
+	// TrafficRouting shifts the traffic to the target with the Route trait according to the traffic weight
+	// of each batch, the traffic simply follows the pods if it is not set
+	// +optional
+	TrafficRouting *TrafficRouting `json:"trafficRouting,omitempty"`
+}
+
+// TrafficRouting describes how to split the traffic between the source and the target
+type TrafficRouting struct {
+	// RouteName is the name of the Route trait in the same namespace that routes the traffic to the source
+	RouteName string `json:"routeName"`
+
+	// CanaryService is the service in front of the target pods, it becomes the backend of the route
+	// after the rollout succeeds
+	CanaryService BackendServiceRef `json:"canaryService"`
+
+	// SourceServiceName is the service in front of the source pods, only the rules of the route whose backend is
+	// the source service are shifted. It defaults to the rules whose backend service is discovered by the route
+	// +optional
+	SourceServiceName string `json:"sourceServiceName,omitempty"`
 }
 
 // RolloutBatch is used to describe how the each batch rollout should be
@@ -126,6 +178,9 @@ type RolloutBatch struct {
 	// The list of Pods to get upgraded
 	// +optional
 	// it is mutually exclusive with the Replicas field
+	// the source pods of a Deployment are picked with the controller.kubernetes.io/pod-deletion-cost
+	// annotation, which only takes effect on Kubernetes 1.21+
+	// a CloneSet that has its own update priority strategy doesn't support it
 	PodList []string `json:"podList,omitempty"`
 
 	// MaxUnavailable is the max allowed number of pods that is unavailable
@@ -135,6 +190,23 @@ type RolloutBatch struct {
 	// +optional
 	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
 
+	// RequireApproval indicates that the batch waits for a manual approval before any pod is upgraded
+	// The approval is given by annotating the object that owns the rollout plan with the batch number
+	// +optional
+	RequireApproval bool `json:"requireApproval,omitempty"`
+
+	// ProgressDeadlineSeconds is the max time in seconds for the pods in this batch to become ready after the
+	// batch starts to roll. The rollout fails if the batch is not ready in time. The paused time does not count.
+	// +optional
+	ProgressDeadlineSeconds *int32 `json:"progressDeadlineSeconds,omitempty"`
+
+	// TrafficWeight is the percentage of the traffic routed to the target after the pods in this batch are ready
+	// It only takes effect when the rollout plan has traffic routing
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	TrafficWeight *int32 `json:"trafficWeight,omitempty"`
+
 	// The wait time, in seconds, between instances upgrades, default = 0
 	// +optional
 	InstanceInterval *int32 `json:"instanceInterval,omitempty"`
@@ -166,6 +238,66 @@ type RolloutWebhook struct {
 	// Metadata (key-value pairs) for this webhook
 	// +optional
 	Metadata *map[string]string `json:"metadata,omitempty"`
+
+	// SecretRef refers to the key of a secret in the same namespace as the rollout
+	// The payload is signed with the HMAC-SHA256 of the secret value if it is set
+	// +optional
+	SecretRef *WebhookSecretKeySelector `json:"secretRef,omitempty"`
+
+	// TimeoutSeconds is the timeout of each request to the webhook, default is 10
+	// +optional
+	TimeoutSeconds *int32 `json:"timeoutSeconds,omitempty"`
+
+	// MaxRetries is the number of retries when the webhook is unreachable or returns a server error, default is 3
+	// the rollout retries the webhook in the later reconciles, the attempts are recorded in the rollout status
+	// +optional
+	MaxRetries *int32 `json:"maxRetries,omitempty"`
+
+	// RetryBackoffSeconds is the initial wait between two retries, it doubles after each retry, default is 1
+	// +optional
+	RetryBackoffSeconds *int32 `json:"retryBackoffSeconds,omitempty"`
+}
+
+// WebhookSecretKeySelector is a reference to a key of a secret in the same namespace
+type WebhookSecretKeySelector struct {
+	// The name of the secret
+	Name string `json:"name"`
+
+	// The key of the secret to select from
+	Key string `json:"key"`
+}
+
+// WebhookAction is the decision that a webhook makes on the rollout
+type WebhookAction string
+
+const (
+	// ContinueWebhookAction lets the rollout move on, it is the default if the webhook returns no decision
+	ContinueWebhookAction WebhookAction = "continue"
+	// PauseWebhookAction holds the rollout in its current state, the webhook is called again later
+	PauseWebhookAction WebhookAction = "pause"
+	// AbortWebhookAction fails the rollout, the rollback policy is then applied
+	AbortWebhookAction WebhookAction = "abort"
+)
+
+const (
+	// RolloutWebhookSignatureHeader is the http header that carries the signature of a webhook payload, its value
+	// is in the format of "sha256=<hex encoded HMAC-SHA256 of the timestamp header, a dot and the request body>"
+	RolloutWebhookSignatureHeader = "X-Rollout-Signature"
+
+	// RolloutWebhookTimestampHeader is the http header that carries the unix time when a webhook request is signed
+	// the receiver should reject the requests with a stale timestamp
+	RolloutWebhookTimestampHeader = "X-Rollout-Timestamp"
+)
+
+// RolloutWebhookResponse is the optional JSON body that a webhook returns
+type RolloutWebhookResponse struct {
+	// Action is the decision of the webhook on the rollout
+	// +optional
+	Action WebhookAction `json:"action,omitempty"`
+
+	// Message explains the decision
+	// +optional
+	Message string `json:"message,omitempty"`
 }
 
 // RolloutWebhookPayload holds the info and metadata sent to webhooks
@@ -189,15 +321,36 @@ type CanaryMetric struct {
 	Name string `json:"name"`
 
 	// Interval represents the windows size
+	// it is also how often we evaluate the metric, default is 1m
+	// a query of the metric times out after the interval, 30s at most
 	Interval string `json:"interval,omitempty"`
 
 	// Range value accepted for this metric
 	// +optional
 	MetricsRange *MetricsExpectedRange `json:"metricsRange,omitempty"`
 
-	// TemplateRef references a metric template object
+	// TemplateRef references a metric template object, it's not supported yet and is rejected
 	// +optional
 	TemplateRef *runtimev1alpha1.TypedReference `json:"templateRef,omitempty"`
+
+	// Query is the PromQL query that returns a single value for this metric
+	// the `{{interval}}` placeholder in the query is replaced by the interval
+	// +optional
+	Query string `json:"query,omitempty"`
+
+	// MetricsProvider is where we query this metric from
+	// +optional
+	MetricsProvider *MetricsProvider `json:"metricsProvider,omitempty"`
+}
+
+// MetricsProvider describes a metrics server that we can query the canary metrics from
+type MetricsProvider struct {
+	// Type of the metrics server, only prometheus is supported for now
+	// +optional
+	Type string `json:"type,omitempty"`
+
+	// Address of the metrics server, ie. http://prometheus.monitoring:9090
+	Address string `json:"address"`
 }
 
 // MetricsExpectedRange defines the range used for metrics validation
@@ -225,6 +378,11 @@ type RolloutStatus struct {
 	// We update this field only after a successful rollout
 	LastAppliedPodTemplateIdentifier string `json:"lastAppliedPodTemplateIdentifier,omitempty"`
 
+	// RolloutTargetSize is the total number of pods that the rollout ends up with. It is calculated
+	// when the rollout spec is verified and stays the same until the rollout is restarted
+	// +optional
+	RolloutTargetSize int32 `json:"rolloutTargetSize,omitempty"`
+
 	// RollingState is the Rollout State
 	RollingState RollingState `json:"rollingState"`
 
@@ -241,4 +399,113 @@ type RolloutStatus struct {
 
 	// UpgradedReplicas is the number of Pods upgraded by the rollout controller that have a Ready Condition.
 	UpgradedReadyReplicas int32 `json:"upgradedReadyReplicas"`
+
+	// CanaryMetricsStatus records the latest observations of the canary metrics of the current batch
+	// +optional
+	CanaryMetricsStatus []CanaryMetricStatus `json:"canaryMetricsStatus,omitempty"`
+
+	// RollbackSteps records the steps taken to revert a failed rollout
+	// +optional
+	RollbackSteps []RollbackStep `json:"rollbackSteps,omitempty"`
+
+	// TrafficWeight is the actual percentage of the traffic routed to the target as reported by the route
+	// +optional
+	TrafficWeight *int32 `json:"trafficWeight,omitempty"`
+
+	// RolloutStartTime is the time the current rollout started
+	// +optional
+	RolloutStartTime *metav1.Time `json:"rolloutStartTime,omitempty"`
+
+	// BatchStartTime is the time the current batch started to roll
+	// +optional
+	BatchStartTime *metav1.Time `json:"batchStartTime,omitempty"`
+
+	// PausedTime is the time the rollout was paused, the start times are moved forward by the paused
+	// duration when the rollout resumes so that the pause doesn't count towards the progress deadlines
+	// +optional
+	PausedTime *metav1.Time `json:"pausedTime,omitempty"`
+
+	// RolloutHistory records the rollouts that reached a terminal state, the latest one is the last
+	// +optional
+	RolloutHistory []RolloutRecord `json:"rolloutHistory,omitempty"`
+
+	// WebhookAttempt records the failed attempts of the webhook that the rollout is retrying
+	// +optional
+	WebhookAttempt *WebhookAttempt `json:"webhookAttempt,omitempty"`
+}
+
+// WebhookAttempt records the failed attempts of a webhook request
+type WebhookAttempt struct {
+	// Name of the webhook
+	Name string `json:"name"`
+
+	// Type of the webhook
+	Type HookType `json:"type"`
+
+	// Attempts is the number of failed attempts so far
+	Attempts int32 `json:"attempts"`
+
+	// LastAttemptTime is the time of the last failed attempt
+	LastAttemptTime metav1.Time `json:"lastAttemptTime"`
+}
+
+// RollbackStep records one batch of pods reverted back to the source
+type RollbackStep struct {
+	// Batch is the batch that is reverted
+	Batch int32 `json:"batch"`
+
+	// UpgradedReplicas is the number of pods left in the target after this step
+	UpgradedReplicas int32 `json:"upgradedReplicas"`
+
+	// StartTime is the time the step started
+	// +optional
+	StartTime metav1.Time `json:"startTime,omitempty"`
+
+	// CompletionTime is the time the reverted pods became available
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// RolloutRecord records a rollout that reached a terminal state
+type RolloutRecord struct {
+	// Revision is the sequence number of the rollout, it starts from 1
+	Revision int64 `json:"revision"`
+
+	// SourceRevision is the revision the rollout upgraded from, it's empty for the first deployment
+	// +optional
+	SourceRevision string `json:"sourceRevision,omitempty"`
+
+	// TargetRevision is the revision the rollout upgraded to
+	TargetRevision string `json:"targetRevision"`
+
+	// StartTime is the time the rollout started
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// EndTime is the time the rollout reached its final state
+	EndTime metav1.Time `json:"endTime"`
+
+	// FinalState is the terminal state of the rollout
+	FinalState RollingState `json:"finalState"`
+
+	// FailedBatch is the batch the rollout failed at
+	// +optional
+	FailedBatch *int32 `json:"failedBatch,omitempty"`
+}
+
+// CanaryMetricStatus is the observed value of a canary metric
+type CanaryMetricStatus struct {
+	// Name of the metric
+	Name string `json:"name"`
+
+	// Value is the last observed value of the metric
+	// +optional
+	Value string `json:"value,omitempty"`
+
+	// InRange indicates if the last observed value is within the expected range
+	InRange bool `json:"inRange"`
+
+	// LastEvaluationTime is the last time the metric was evaluated
+	// +optional
+	LastEvaluationTime metav1.Time `json:"lastEvaluationTime,omitempty"`
 }
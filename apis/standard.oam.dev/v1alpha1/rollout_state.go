@@ -46,6 +46,9 @@ const (
 	// this events comes after we have examine the pod readiness check and traffic shifting if needed
 	OneBatchAvailableEvent RolloutEvent = "OneBatchAvailable"
 
+	// BatchWaitingApprovalEvent indicates that the batch needs the approval before it can be rolled
+	BatchWaitingApprovalEvent RolloutEvent = "BatchWaitingApprovalEvent"
+
 	// BatchRolloutApprovedEvent indicates that we got the approval manually
 	BatchRolloutApprovedEvent RolloutEvent = "BatchRolloutApprovedEvent"
 
@@ -54,6 +57,12 @@ const (
 
 	// WorkloadModifiedEvent indicates that the res
 	WorkloadModifiedEvent RolloutEvent = "WorkloadModifiedEvent"
+
+	// RollingBackEvent indicates that we start to revert a failed rollout
+	RollingBackEvent RolloutEvent = "RollingBackEvent"
+
+	// OneBatchRolledBackEvent indicates that the reverted pods of one batch are available
+	OneBatchRolledBackEvent RolloutEvent = "OneBatchRolledBackEvent"
 )
 
 // These are valid conditions of the rollout.
@@ -68,6 +77,8 @@ const (
 	RolloutSucceed runtimev1alpha1.ConditionType = "Succeed"
 	// BatchInitialized
 	BatchInitialized runtimev1alpha1.ConditionType = "BatchInitialized"
+	// BatchWaitingApproval
+	BatchWaitingApproval runtimev1alpha1.ConditionType = "BatchWaitingApproval"
 	// BatchInRolled
 	BatchInRolled runtimev1alpha1.ConditionType = "BatchInRolled"
 	// BatchVerified
@@ -78,6 +89,10 @@ const (
 	BatchFinalized runtimev1alpha1.ConditionType = "BatchFinalized"
 	// BatchReady
 	BatchReady runtimev1alpha1.ConditionType = "BatchReady"
+	// RolloutRollingBack means we are reverting a failed rollout
+	RolloutRollingBack runtimev1alpha1.ConditionType = "RollingBack"
+	// RolloutRolledBack means that the failed rollout is reverted
+	RolloutRolledBack runtimev1alpha1.ConditionType = "RolledBack"
 )
 
 // NewPositiveCondition creates a positive condition type
@@ -117,6 +132,9 @@ func (r *RolloutStatus) getRolloutConditionType() runtimev1alpha1.ConditionType
 		case BatchInitializingState:
 			return BatchInitialized
 
+		case BatchWaitingApprovalState:
+			return BatchWaitingApproval
+
 		case BatchVerifyingState:
 			return BatchVerified
 
@@ -133,6 +151,12 @@ func (r *RolloutStatus) getRolloutConditionType() runtimev1alpha1.ConditionType
 	case FinalisingState:
 		return RolloutSucceed
 
+	case RollingBackState:
+		return RolloutRollingBack
+
+	case RolloutRolledBackState:
+		return RolloutRolledBack
+
 	default:
 		return RolloutSucceed
 	}
@@ -198,17 +222,23 @@ func (r *RolloutStatus) StateTransition(event RolloutEvent) {
 		}
 		panic(fmt.Errorf(invalidRollingStateTransition, rollingState, event))
 
-	case RolloutSucceedState:
+	case RolloutSucceedState, RolloutRolledBackState:
 		if event == WorkloadModifiedEvent {
-			r.RollingState = VerifyingState
-			r.SetConditions(NewPositiveCondition(r.getRolloutConditionType()))
+			r.restartRollout()
 			return
 		}
 		panic(fmt.Errorf(invalidRollingStateTransition, rollingState, event))
 
 	case RolloutFailedState:
 		if event == WorkloadModifiedEvent {
-			r.RollingState = VerifyingState
+			r.restartRollout()
+			return
+		}
+		if event == RollingBackEvent {
+			// revert the pods starting from the batch that failed
+			r.RollingState = RollingBackState
+			r.BatchRollingState = BatchInRollingState
+			r.RollbackSteps = nil
 			r.SetConditions(NewPositiveCondition(r.getRolloutConditionType()))
 			return
 		}
@@ -218,6 +248,10 @@ func (r *RolloutStatus) StateTransition(event RolloutEvent) {
 		}
 		panic(fmt.Errorf(invalidRollingStateTransition, rollingState, event))
 
+	case RollingBackState:
+		r.rollbackStateTransition(event)
+		return
+
 	default:
 		panic(fmt.Errorf("invalid rolling state %s", rollingState))
 	}
@@ -238,6 +272,18 @@ func (r *RolloutStatus) batchStateTransition(event RolloutEvent) {
 			r.BatchRollingState = BatchInRollingState
 			return
 		}
+		if event == BatchWaitingApprovalEvent {
+			r.BatchRollingState = BatchWaitingApprovalState
+			r.SetConditions(NewPositiveCondition(r.getRolloutConditionType()))
+			return
+		}
+		panic(fmt.Errorf(invalidBatchRollingStateTransition, batchRollingState, event))
+
+	case BatchWaitingApprovalState:
+		if event == BatchRolloutApprovedEvent {
+			r.BatchRollingState = BatchInRollingState
+			return
+		}
 		panic(fmt.Errorf(invalidBatchRollingStateTransition, batchRollingState, event))
 
 	case BatchInRollingState:
@@ -284,3 +330,45 @@ func (r *RolloutStatus) batchStateTransition(event RolloutEvent) {
 		panic(fmt.Errorf("invalid batch rolling state %s", batchRollingState))
 	}
 }
+
+// rollbackStateTransition handles the state transition when we revert the batches in reverse order
+func (r *RolloutStatus) rollbackStateTransition(event RolloutEvent) {
+	batchRollingState := r.BatchRollingState
+	switch batchRollingState {
+	case BatchInRollingState:
+		if event == BatchRolloutVerifyingEvent {
+			r.BatchRollingState = BatchVerifyingState
+			return
+		}
+		panic(fmt.Errorf(invalidBatchRollingStateTransition, batchRollingState, event))
+
+	case BatchVerifyingState:
+		if event == OneBatchRolledBackEvent {
+			if r.CurrentBatch == 0 {
+				// all the batches are reverted
+				r.RollingState = RolloutRolledBackState
+				r.BatchRollingState = BatchReadyState
+				r.SetConditions(NewPositiveCondition(r.getRolloutConditionType()))
+				return
+			}
+			r.BatchRollingState = BatchInRollingState
+			r.CurrentBatch--
+			return
+		}
+		panic(fmt.Errorf(invalidBatchRollingStateTransition, batchRollingState, event))
+
+	default:
+		panic(fmt.Errorf("invalid batch rolling back state %s", batchRollingState))
+	}
+}
+
+// restartRollout starts over the rollout when the workload is modified
+func (r *RolloutStatus) restartRollout() {
+	r.RollingState = VerifyingState
+	r.CurrentBatch = 0
+	r.UpgradedReplicas = 0
+	r.UpgradedReadyReplicas = 0
+	r.RolloutStartTime = nil
+	r.BatchStartTime = nil
+	r.SetConditions(NewPositiveCondition(r.getRolloutConditionType()))
+}
@@ -68,6 +68,19 @@ type Rule struct {
 	// Backend indicate how to connect backend service
 	// If it's nil, will auto discovery
 	Backend *Backend `json:"backend,omitempty"`
+
+	// CanaryBackend receives part of the traffic of this rule, it is usually set by a rollout
+	CanaryBackend *CanaryBackend `json:"canaryBackend,omitempty"`
+}
+
+// CanaryBackend defines the backend service that receives a percentage of the traffic
+type CanaryBackend struct {
+	// BackendService specifies the canary K8s service and port
+	BackendService BackendServiceRef `json:"backendService"`
+	// Weight is the percentage of the traffic sent to the canary service
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	Weight int32 `json:"weight"`
 }
 
 // TLS defines certificate issuer and type for mTLS configuration
@@ -116,6 +129,9 @@ type RouteStatus struct {
 	Service                           *runtimev1alpha1.TypedReference  `json:"service,omitempty"`
 	Status                            string                           `json:"status,omitempty"`
 	runtimev1alpha1.ConditionedStatus `json:",inline"`
+
+	// CanaryWeight is the weight of the canary backends, it is set once the ingresses are ready
+	CanaryWeight *int32 `json:"canaryWeight,omitempty"`
 }
 
 // Route is the Schema for the routes API
@@ -171,6 +171,22 @@ func (in *BackendServiceRef) DeepCopy() *BackendServiceRef {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CanaryBackend) DeepCopyInto(out *CanaryBackend) {
+	*out = *in
+	out.BackendService = in.BackendService
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CanaryBackend.
+func (in *CanaryBackend) DeepCopy() *CanaryBackend {
+	if in == nil {
+		return nil
+	}
+	out := new(CanaryBackend)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CanaryMetric) DeepCopyInto(out *CanaryMetric) {
 	*out = *in
@@ -184,6 +200,11 @@ func (in *CanaryMetric) DeepCopyInto(out *CanaryMetric) {
 		*out = new(corev1alpha1.TypedReference)
 		**out = **in
 	}
+	if in.MetricsProvider != nil {
+		in, out := &in.MetricsProvider, &out.MetricsProvider
+		*out = new(MetricsProvider)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CanaryMetric.
@@ -196,6 +217,22 @@ func (in *CanaryMetric) DeepCopy() *CanaryMetric {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CanaryMetricStatus) DeepCopyInto(out *CanaryMetricStatus) {
+	*out = *in
+	in.LastEvaluationTime.DeepCopyInto(&out.LastEvaluationTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CanaryMetricStatus.
+func (in *CanaryMetricStatus) DeepCopy() *CanaryMetricStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CanaryMetricStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MetricsExpectedRange) DeepCopyInto(out *MetricsExpectedRange) {
 	*out = *in
@@ -221,6 +258,21 @@ func (in *MetricsExpectedRange) DeepCopy() *MetricsExpectedRange {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MetricsProvider) DeepCopyInto(out *MetricsProvider) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetricsProvider.
+func (in *MetricsProvider) DeepCopy() *MetricsProvider {
+	if in == nil {
+		return nil
+	}
+	out := new(MetricsProvider)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MetricsTrait) DeepCopyInto(out *MetricsTrait) {
 	*out = *in
@@ -422,6 +474,26 @@ func (in *PodSpecWorkloadStatus) DeepCopy() *PodSpecWorkloadStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RollbackStep) DeepCopyInto(out *RollbackStep) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RollbackStep.
+func (in *RollbackStep) DeepCopy() *RollbackStep {
+	if in == nil {
+		return nil
+	}
+	out := new(RollbackStep)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RolloutBatch) DeepCopyInto(out *RolloutBatch) {
 	*out = *in
@@ -436,6 +508,16 @@ func (in *RolloutBatch) DeepCopyInto(out *RolloutBatch) {
 		*out = new(intstr.IntOrString)
 		**out = **in
 	}
+	if in.ProgressDeadlineSeconds != nil {
+		in, out := &in.ProgressDeadlineSeconds, &out.ProgressDeadlineSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.TrafficWeight != nil {
+		in, out := &in.TrafficWeight, &out.TrafficWeight
+		*out = new(int32)
+		**out = **in
+	}
 	if in.InstanceInterval != nil {
 		in, out := &in.InstanceInterval, &out.InstanceInterval
 		*out = new(int32)
@@ -497,6 +579,11 @@ func (in *RolloutPlan) DeepCopyInto(out *RolloutPlan) {
 		*out = new(int32)
 		**out = **in
 	}
+	if in.ProgressDeadlineSeconds != nil {
+		in, out := &in.ProgressDeadlineSeconds, &out.ProgressDeadlineSeconds
+		*out = new(int32)
+		**out = **in
+	}
 	if in.RolloutWebhooks != nil {
 		in, out := &in.RolloutWebhooks, &out.RolloutWebhooks
 		*out = make([]RolloutWebhook, len(*in))
@@ -511,6 +598,11 @@ func (in *RolloutPlan) DeepCopyInto(out *RolloutPlan) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.TrafficRouting != nil {
+		in, out := &in.TrafficRouting, &out.TrafficRouting
+		*out = new(TrafficRouting)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutPlan.
@@ -523,10 +615,78 @@ func (in *RolloutPlan) DeepCopy() *RolloutPlan {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutRecord) DeepCopyInto(out *RolloutRecord) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	in.EndTime.DeepCopyInto(&out.EndTime)
+	if in.FailedBatch != nil {
+		in, out := &in.FailedBatch, &out.FailedBatch
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutRecord.
+func (in *RolloutRecord) DeepCopy() *RolloutRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RolloutStatus) DeepCopyInto(out *RolloutStatus) {
 	*out = *in
 	in.ConditionedStatus.DeepCopyInto(&out.ConditionedStatus)
+	if in.CanaryMetricsStatus != nil {
+		in, out := &in.CanaryMetricsStatus, &out.CanaryMetricsStatus
+		*out = make([]CanaryMetricStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.RollbackSteps != nil {
+		in, out := &in.RollbackSteps, &out.RollbackSteps
+		*out = make([]RollbackStep, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.TrafficWeight != nil {
+		in, out := &in.TrafficWeight, &out.TrafficWeight
+		*out = new(int32)
+		**out = **in
+	}
+	if in.RolloutStartTime != nil {
+		in, out := &in.RolloutStartTime, &out.RolloutStartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.BatchStartTime != nil {
+		in, out := &in.BatchStartTime, &out.BatchStartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.PausedTime != nil {
+		in, out := &in.PausedTime, &out.PausedTime
+		*out = (*in).DeepCopy()
+	}
+	if in.RolloutHistory != nil {
+		in, out := &in.RolloutHistory, &out.RolloutHistory
+		*out = make([]RolloutRecord, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.WebhookAttempt != nil {
+		in, out := &in.WebhookAttempt, &out.WebhookAttempt
+		*out = new(WebhookAttempt)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutStatus.
@@ -639,6 +799,26 @@ func (in *RolloutWebhook) DeepCopyInto(out *RolloutWebhook) {
 			}
 		}
 	}
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(WebhookSecretKeySelector)
+		**out = **in
+	}
+	if in.TimeoutSeconds != nil {
+		in, out := &in.TimeoutSeconds, &out.TimeoutSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxRetries != nil {
+		in, out := &in.MaxRetries, &out.MaxRetries
+		*out = new(int32)
+		**out = **in
+	}
+	if in.RetryBackoffSeconds != nil {
+		in, out := &in.RetryBackoffSeconds, &out.RetryBackoffSeconds
+		*out = new(int32)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutWebhook.
@@ -673,6 +853,21 @@ func (in *RolloutWebhookPayload) DeepCopy() *RolloutWebhookPayload {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutWebhookResponse) DeepCopyInto(out *RolloutWebhookResponse) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutWebhookResponse.
+func (in *RolloutWebhookResponse) DeepCopy() *RolloutWebhookResponse {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutWebhookResponse)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Route) DeepCopyInto(out *Route) {
 	*out = *in
@@ -774,6 +969,11 @@ func (in *RouteStatus) DeepCopyInto(out *RouteStatus) {
 		**out = **in
 	}
 	in.ConditionedStatus.DeepCopyInto(&out.ConditionedStatus)
+	if in.CanaryWeight != nil {
+		in, out := &in.CanaryWeight, &out.CanaryWeight
+		*out = new(int32)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RouteStatus.
@@ -806,6 +1006,11 @@ func (in *Rule) DeepCopyInto(out *Rule) {
 		*out = new(Backend)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.CanaryBackend != nil {
+		in, out := &in.CanaryBackend, &out.CanaryBackend
+		*out = new(CanaryBackend)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Rule.
@@ -876,6 +1081,22 @@ func (in *TargetWorkload) DeepCopy() *TargetWorkload {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TrafficRouting) DeepCopyInto(out *TrafficRouting) {
+	*out = *in
+	out.CanaryService = in.CanaryService
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TrafficRouting.
+func (in *TrafficRouting) DeepCopy() *TrafficRouting {
+	if in == nil {
+		return nil
+	}
+	out := new(TrafficRouting)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Trigger) DeepCopyInto(out *Trigger) {
 	*out = *in
@@ -897,3 +1118,34 @@ func (in *Trigger) DeepCopy() *Trigger {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebhookAttempt) DeepCopyInto(out *WebhookAttempt) {
+	*out = *in
+	in.LastAttemptTime.DeepCopyInto(&out.LastAttemptTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebhookAttempt.
+func (in *WebhookAttempt) DeepCopy() *WebhookAttempt {
+	if in == nil {
+		return nil
+	}
+	out := new(WebhookAttempt)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebhookSecretKeySelector) DeepCopyInto(out *WebhookSecretKeySelector) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebhookSecretKeySelector.
+func (in *WebhookSecretKeySelector) DeepCopy() *WebhookSecretKeySelector {
+	if in == nil {
+		return nil
+	}
+	out := new(WebhookSecretKeySelector)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -20,6 +20,14 @@ const (
 	AnnDescription = "definition.oam.dev/description"
 )
 
+const (
+	// SchemaConfigMapPrefix is the prefix of the ConfigMaps that store the OpenAPI v3 schemas of the parameters of
+	// the WorkloadDefinitions and TraitDefinitions, the ConfigMaps are in the same namespaces as the definitions
+	SchemaConfigMapPrefix = "schema"
+	// OpenapiV3JSONSchema is the key of the OpenAPI v3 json schema in the ConfigMap of a definition
+	OpenapiV3JSONSchema = "openapi-v3-json-schema"
+)
+
 const (
 	// StatusDeployed represents the App was deployed
 	StatusDeployed = "Deployed"
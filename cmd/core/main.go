@@ -33,10 +33,12 @@ import (
 
 	oamcore "github.com/oam-dev/kubevela/apis/core.oam.dev"
 	velacore "github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+	velahttp "github.com/oam-dev/kubevela/pkg/builtin/http"
 	velacontroller "github.com/oam-dev/kubevela/pkg/controller"
 	oamcontroller "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev"
 	oamv1alpha2 "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2"
 	"github.com/oam-dev/kubevela/pkg/controller/utils"
+	"github.com/oam-dev/kubevela/pkg/dsl/task"
 	"github.com/oam-dev/kubevela/pkg/utils/system"
 	oamwebhook "github.com/oam-dev/kubevela/pkg/webhook/core.oam.dev"
 	velawebhook "github.com/oam-dev/kubevela/pkg/webhook/standard.oam.dev"
@@ -80,6 +82,8 @@ func main() {
 	var storageDriver string
 	var syncPeriod time.Duration
 	var applyOnceOnly string
+	var httpTaskOptions velahttp.Options
+	var httpTaskAllowedURLs string
 
 	flag.BoolVar(&useWebhook, "use-webhook", false, "Enable Admission Webhook")
 	flag.BoolVar(&useTraitInjector, "use-trait-injector", false, "Enable TraitInjector")
@@ -104,6 +108,18 @@ func main() {
 	flag.StringVar(&storageDriver, "storage-driver", "Local", "Application file save to the storage driver")
 	flag.DurationVar(&syncPeriod, "informer-re-sync-interval", 5*time.Minute,
 		"controller shared informer lister full re-sync period")
+	flag.DurationVar(&httpTaskOptions.Timeout, "http-task-timeout", velahttp.DefaultOptions.Timeout,
+		"The max time of each http request sent by the processing tasks of the templates.")
+	flag.IntVar(&httpTaskOptions.MaxRetries, "http-task-max-retries", velahttp.DefaultOptions.MaxRetries,
+		"The max number of retries of a failed http request sent by the processing tasks of the templates.")
+	flag.Int64Var(&httpTaskOptions.MaxResponseSize, "http-task-max-response-size", velahttp.DefaultOptions.MaxResponseSize,
+		"The max number of bytes of the http response bodies read by the processing tasks of the templates.")
+	flag.StringVar(&httpTaskAllowedURLs, "http-task-allowed-urls", "",
+		"A comma separated list of the URLs the processing tasks of the templates can send http requests to, "+
+			"all the URLs are allowed if it's empty. The loopback, the link-local and the private addresses are denied "+
+			"unless the exact host of the request is in the list.")
+	flag.DurationVar(&task.Timeout, "processing-task-timeout", task.Timeout,
+		"The max time the processing tasks of the templates of an application take in a render, including the retries.")
 	flag.Parse()
 
 	// setup logging
@@ -168,6 +184,14 @@ func main() {
 		}
 	}
 
+	if len(httpTaskAllowedURLs) != 0 {
+		httpTaskOptions.AllowedURLs = strings.Split(httpTaskAllowedURLs, ",")
+	}
+	if err := velahttp.SetOptions(httpTaskOptions); err != nil {
+		setupLog.Error(err, "unable to set the options of the http tasks")
+		os.Exit(1)
+	}
+
 	switch strings.ToLower(applyOnceOnly) {
 	case "", "false", string(oamcontroller.ApplyOnceOnlyOff):
 		controllerArgs.ApplyMode = oamcontroller.ApplyOnceOnlyOff
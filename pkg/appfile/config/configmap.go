@@ -55,7 +55,7 @@ var _ Store = &Configmap{}
 
 // Configmap is the configmap implementation of config store
 type Configmap struct {
-	Client client.Client
+	Client client.Reader
 }
 
 // GetConfigData will get config data from configmap
@@ -0,0 +1,67 @@
+package appfile
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+)
+
+// definitionReader reads the definitions from a snapshot before it reads them from the cluster
+// it remembers all the definitions it reads so that we know which definitions an application is rendered with
+type definitionReader struct {
+	client.Reader
+
+	snapshotWorkloadDefs map[string]v1alpha2.WorkloadDefinition
+	snapshotTraitDefs    map[string]v1alpha2.TraitDefinition
+
+	workloadDefs map[string]v1alpha2.WorkloadDefinition
+	traitDefs    map[string]v1alpha2.TraitDefinition
+}
+
+func newDefinitionReader(cli client.Reader) *definitionReader {
+	return &definitionReader{
+		Reader:       cli,
+		workloadDefs: make(map[string]v1alpha2.WorkloadDefinition),
+		traitDefs:    make(map[string]v1alpha2.TraitDefinition),
+	}
+}
+
+// Get reads a definition from the snapshot if it's there, other objects are read from the cluster
+func (r *definitionReader) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	switch def := obj.(type) {
+	case *v1alpha2.WorkloadDefinition:
+		if snapshot, exist := r.snapshotWorkloadDefs[key.Name]; exist {
+			snapshot.DeepCopyInto(def)
+		} else if err := r.Reader.Get(ctx, key, def); err != nil {
+			return err
+		}
+		r.workloadDefs[key.Name] = *def.DeepCopy()
+	case *v1alpha2.TraitDefinition:
+		if snapshot, exist := r.snapshotTraitDefs[key.Name]; exist {
+			snapshot.DeepCopyInto(def)
+		} else if err := r.Reader.Get(ctx, key, def); err != nil {
+			return err
+		}
+		r.traitDefs[key.Name] = *def.DeepCopy()
+	default:
+		return r.Reader.Get(ctx, key, obj)
+	}
+	return nil
+}
+
+// WithDefinitions makes the parser render the application with the given definitions
+// instead of the ones in the cluster, the definitions that are not given are still read from the cluster
+func (p *Parser) WithDefinitions(workloadDefs map[string]v1alpha2.WorkloadDefinition,
+	traitDefs map[string]v1alpha2.TraitDefinition) *Parser {
+	p.defs.snapshotWorkloadDefs = workloadDefs
+	p.defs.snapshotTraitDefs = traitDefs
+	return p
+}
+
+// Definitions returns the workloadDefinitions and traitDefinitions the parser has read, the key is their names
+func (p *Parser) Definitions() (map[string]v1alpha2.WorkloadDefinition, map[string]v1alpha2.TraitDefinition) {
+	return p.defs.workloadDefs, p.defs.traitDefs
+}
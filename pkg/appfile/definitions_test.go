@@ -0,0 +1,48 @@
+package appfile
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+)
+
+func TestDefinitionReader(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	assert.NoError(t, v1alpha2.SchemeBuilder.AddToScheme(scheme))
+	cli := fake.NewFakeClientWithScheme(scheme,
+		&v1alpha2.WorkloadDefinition{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "vela-system"},
+			Spec:       v1alpha2.WorkloadDefinitionSpec{Template: "live worker"},
+		},
+		&v1alpha2.TraitDefinition{
+			ObjectMeta: metav1.ObjectMeta{Name: "scaler", Namespace: "vela-system"},
+			Spec:       v1alpha2.TraitDefinitionSpec{Template: "live scaler"},
+		})
+	p := NewApplicationParser(cli, nil).WithDefinitions(map[string]v1alpha2.WorkloadDefinition{
+		"worker": {Spec: v1alpha2.WorkloadDefinitionSpec{Template: "snapshot worker"}},
+	}, nil)
+
+	// the snapshot wins over the cluster
+	var wd v1alpha2.WorkloadDefinition
+	assert.NoError(t, p.defs.Get(ctx, client.ObjectKey{Namespace: "vela-system", Name: "worker"}, &wd))
+	assert.Equal(t, "snapshot worker", wd.Spec.Template)
+	// the definitions that are not in the snapshot come from the cluster
+	var td v1alpha2.TraitDefinition
+	assert.NoError(t, p.defs.Get(ctx, client.ObjectKey{Namespace: "vela-system", Name: "scaler"}, &td))
+	assert.Equal(t, "live scaler", td.Spec.Template)
+	assert.Error(t, p.defs.Get(ctx, client.ObjectKey{Namespace: "vela-system", Name: "missing"},
+		&v1alpha2.TraitDefinition{}))
+
+	wds, tds := p.Definitions()
+	assert.Equal(t, "snapshot worker", wds["worker"].Spec.Template)
+	assert.Equal(t, "live scaler", tds["scaler"].Spec.Template)
+	assert.Len(t, tds, 1)
+}
@@ -0,0 +1,121 @@
+package appfile
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	core "github.com/oam-dev/kubevela/apis/core.oam.dev"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/pkg/oam/discoverymapper"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+// localScheme knows the kinds that the local parser reads from the files
+var localScheme = runtime.NewScheme()
+
+func init() {
+	_ = clientgoscheme.AddToScheme(localScheme)
+	_ = core.AddToScheme(localScheme)
+	_ = apiextensionsv1.AddToScheme(localScheme)
+}
+
+// ReadDefinitionFiles reads the WorkloadDefinitions, TraitDefinitions, ScopeDefinitions and CRDs from the yaml or json
+// files in the path, the path is a file or a directory. A file can have multiple objects, the other objects are ignored.
+func ReadDefinitionFiles(path string) ([]runtime.Object, error) {
+	var objs []runtime.Object
+	err := filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		switch filepath.Ext(file) {
+		case ".yaml", ".yml", ".json":
+		default:
+			return nil
+		}
+		content, err := ioutil.ReadFile(filepath.Clean(file))
+		if err != nil {
+			return err
+		}
+		fileObjs, err := decodeDefinitions(content)
+		if err != nil {
+			return errors.WithMessagef(err, "read definitions from %s", file)
+		}
+		objs = append(objs, fileObjs...)
+		return nil
+	})
+	return objs, err
+}
+
+func decodeDefinitions(content []byte) ([]runtime.Object, error) {
+	var objs []runtime.Object
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(content), 4096)
+	for {
+		u := &unstructured.Unstructured{}
+		if err := decoder.Decode(&u.Object); err != nil {
+			if errors.Is(err, io.EOF) {
+				return objs, nil
+			}
+			return nil, err
+		}
+		var obj runtime.Object
+		switch u.GroupVersionKind() {
+		case v1alpha2.WorkloadDefinitionGroupVersionKind:
+			obj = &v1alpha2.WorkloadDefinition{}
+		case v1alpha2.TraitDefinitionGroupVersionKind:
+			obj = &v1alpha2.TraitDefinition{}
+		case v1alpha2.ScopeDefinitionGroupVersionKind:
+			obj = &v1alpha2.ScopeDefinition{}
+		case apiextensionsv1.SchemeGroupVersion.WithKind("CustomResourceDefinition"):
+			obj = &apiextensionsv1.CustomResourceDefinition{}
+		default:
+			continue
+		}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, obj); err != nil {
+			return nil, errors.WithMessagef(err, "invalid %s %s", u.GetKind(), u.GetName())
+		}
+		objs = append(objs, obj)
+	}
+}
+
+// NewLocalApplicationParser creates a parser that renders the applications with the definitions in the objects,
+// it reads the definitions from a fake client and maps the kinds of the scheme and the CRDs in the objects,
+// so that it works without a cluster
+func NewLocalApplicationParser(objs ...runtime.Object) *Parser {
+	var crds []apiextensionsv1.CustomResourceDefinition
+	var defs []runtime.Object
+	for _, obj := range objs {
+		switch o := obj.(type) {
+		case *apiextensionsv1.CustomResourceDefinition:
+			crds = append(crds, *o)
+		case *v1alpha2.WorkloadDefinition:
+			o = o.DeepCopy()
+			o.Namespace = util.GenNamespacedDefinitionName(o.Name).Namespace
+			defs = append(defs, o)
+		case *v1alpha2.TraitDefinition:
+			o = o.DeepCopy()
+			o.Namespace = util.GenNamespacedDefinitionName(o.Name).Namespace
+			defs = append(defs, o)
+		case *v1alpha2.ScopeDefinition:
+			// the scopeDefinitions are read without the namespace
+			o = o.DeepCopy()
+			o.Namespace = ""
+			defs = append(defs, o)
+		}
+	}
+	cli := fake.NewFakeClientWithScheme(localScheme, defs...)
+	return NewApplicationParser(cli, discoverymapper.NewStatic(localScheme, crds...))
+}
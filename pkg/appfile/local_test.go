@@ -0,0 +1,128 @@
+package appfile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+const localWorkloadDefs = `
+apiVersion: core.oam.dev/v1alpha2
+kind: WorkloadDefinition
+metadata:
+  name: worker
+  namespace: vela-system
+spec:
+  definitionRef:
+    name: deployments.apps
+  extension:
+    template: |
+      output: {
+        apiVersion: "apps/v1"
+        kind:       "Deployment"
+        spec: template: spec: containers: [{name: context.name, image: parameter.image}]
+      }
+      parameter: image: string
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: ignored
+`
+
+const localTraitDefs = `{
+  "apiVersion": "core.oam.dev/v1alpha2",
+  "kind": "TraitDefinition",
+  "metadata": {"name": "scaler"},
+  "spec": {"extension": {"template": "patch: spec: replicas: parameter.replicas\nparameter: replicas: int\n"}}
+}`
+
+const localScopeDefs = `
+apiVersion: core.oam.dev/v1alpha2
+kind: ScopeDefinition
+metadata:
+  name: netscopes.example.com
+  namespace: default
+spec:
+  workloadRefsPath: spec.workloadRefs
+  definitionRef:
+    name: netscopes.example.com
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: netscopes.example.com
+spec:
+  group: example.com
+  names:
+    kind: NetScope
+    plural: netscopes
+    singular: netscope
+  scope: Namespaced
+  versions:
+  - name: v1
+    served: true
+    storage: true
+`
+
+func TestLocalApplicationParser(t *testing.T) {
+	dir, err := ioutil.TempDir("", "definitions")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "scopes"), 0750))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "workloads.yaml"), []byte(localWorkloadDefs), 0600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "traits.json"), []byte(localTraitDefs), 0600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "scopes", "net.yml"), []byte(localScopeDefs), 0600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte("not a definition"), 0600))
+
+	objs, err := ReadDefinitionFiles(dir)
+	assert.NoError(t, err)
+	assert.Len(t, objs, 4)
+
+	app := &v1alpha2.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "myapp", Namespace: "default"},
+		Spec: v1alpha2.ApplicationSpec{Components: []v1alpha2.ApplicationComponent{{
+			Name:         "web",
+			WorkloadType: "worker",
+			Settings:     runtime.RawExtension{Raw: []byte(`{"image":"nginx"}`)},
+			Traits: []v1alpha2.ApplicationTrait{{
+				Name:       "scaler",
+				Properties: runtime.RawExtension{Raw: []byte(`{"replicas":3}`)},
+			}},
+			Scopes: map[string]string{"netscopes.example.com": "mynet"},
+		}}},
+	}
+	p := NewLocalApplicationParser(objs...)
+	af, err := p.GenerateAppFile(app.Name, app)
+	assert.NoError(t, err)
+	ac, comps, err := p.GenerateApplicationConfiguration(af, app.Namespace)
+	assert.NoError(t, err)
+	assert.Len(t, comps, 1)
+	workload, err := util.RawExtension2Map(&comps[0].Spec.Workload)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"replicas": float64(3),
+		"template": map[string]interface{}{"spec": map[string]interface{}{"containers": []interface{}{
+			map[string]interface{}{"name": "web", "image": "nginx"},
+		}}},
+	}, workload["spec"])
+	assert.Equal(t, "example.com/v1", ac.Spec.Components[0].Scopes[0].ScopeReference.APIVersion)
+	assert.Equal(t, "NetScope", ac.Spec.Components[0].Scopes[0].ScopeReference.Kind)
+	assert.Equal(t, "mynet", ac.Spec.Components[0].Scopes[0].ScopeReference.Name)
+
+	// the definitions that are not in the files are not found
+	app.Spec.Components[0].WorkloadType = "webservice"
+	_, err = p.GenerateAppFile(app.Name, app)
+	assert.Error(t, err)
+
+	_, err = ReadDefinitionFiles(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
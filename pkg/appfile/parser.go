@@ -1,6 +1,8 @@
 package appfile
 
 import (
+	"time"
+
 	"github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
 	"github.com/pkg/errors"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
@@ -11,7 +13,9 @@ import (
 	"github.com/oam-dev/kubevela/apis/types"
 	"github.com/oam-dev/kubevela/pkg/appfile/config"
 	"github.com/oam-dev/kubevela/pkg/dsl/definition"
+	"github.com/oam-dev/kubevela/pkg/dsl/model"
 	"github.com/oam-dev/kubevela/pkg/dsl/process"
+	"github.com/oam-dev/kubevela/pkg/dsl/task"
 	"github.com/oam-dev/kubevela/pkg/oam"
 	"github.com/oam-dev/kubevela/pkg/oam/discoverymapper"
 	"github.com/oam-dev/kubevela/pkg/oam/util"
@@ -34,6 +38,16 @@ type Workload struct {
 	Template           string
 	HealthCheckPolicy  string
 	CustomStatusFormat string
+	// DefinitionGeneration is the generation of the definition the template is loaded from
+	DefinitionGeneration int64
+
+	// Outputs are the data the workload passes to the other workloads of the application
+	Outputs []v1alpha2.DataOutput
+	// Inputs are the data the workload takes from the outputs of the other workloads
+	Inputs []v1alpha2.DataInput
+
+	// output is the workload rendered from the component, the components after it can read it from the context
+	output model.Instance
 }
 
 // GetUserConfigName get user config from AppFile, it will contain config file in it.
@@ -54,21 +68,28 @@ func (wl *Workload) GetUserConfigName() string {
 
 // EvalContext eval workload template and set result to context
 func (wl *Workload) EvalContext(ctx process.Context) error {
-	return definition.NewWorkloadAbstractEngine(wl.Name).Params(wl.Params).Complete(ctx, wl.Template)
+	return wl.engine().Params(wl.Params).Complete(ctx, wl.Template)
 }
 
 // EvalStatus eval workload status
 func (wl *Workload) EvalStatus(ctx process.Context, cli client.Client, ns string) (string, error) {
-	return definition.NewWorkloadAbstractEngine(wl.Name).Status(ctx, cli, ns, wl.CustomStatusFormat)
+	return wl.engine().Status(ctx, cli, ns, wl.CustomStatusFormat)
 }
 
 // EvalHealth eval workload health check
 func (wl *Workload) EvalHealth(ctx process.Context, client client.Client, namespace string) (bool, error) {
-	return definition.NewWorkloadAbstractEngine(wl.Name).HealthCheck(ctx, client, namespace, wl.HealthCheckPolicy)
+	return wl.engine().HealthCheck(ctx, client, namespace, wl.HealthCheckPolicy)
+}
+
+// engine returns the engine of the templates of the workload definition
+func (wl *Workload) engine() definition.AbstractEngine {
+	return definition.NewWorkloadAbstractEngine(wl.Name).Definition(wl.Type, wl.DefinitionGeneration)
 }
 
 // Scope defines the scope of workload
 type Scope struct {
+	// Type is the name of the ScopeDefinition
+	Type string
 	Name string
 	GVK  schema.GroupVersionKind
 }
@@ -83,27 +104,42 @@ type Trait struct {
 	Template           string
 	HealthCheckPolicy  string
 	CustomStatusFormat string
+	// DefinitionGeneration is the generation of the definition the template is loaded from
+	DefinitionGeneration int64
 }
 
 // EvalContext eval trait template and set result to context
 func (trait *Trait) EvalContext(ctx process.Context) error {
-	return definition.NewTraitAbstractEngine(trait.Name).Params(trait.Params).Complete(ctx, trait.Template)
+	return trait.engine().Params(trait.Params).Complete(ctx, trait.Template)
 }
 
 // EvalStatus eval trait status
 func (trait *Trait) EvalStatus(ctx process.Context, cli client.Client, ns string) (string, error) {
-	return definition.NewTraitAbstractEngine(trait.Name).Status(ctx, cli, ns, trait.CustomStatusFormat)
+	return trait.engine().Status(ctx, cli, ns, trait.CustomStatusFormat)
 }
 
 // EvalHealth eval trait health check
 func (trait *Trait) EvalHealth(ctx process.Context, client client.Client, namespace string) (bool, error) {
-	return definition.NewTraitAbstractEngine(trait.Name).HealthCheck(ctx, client, namespace, trait.HealthCheckPolicy)
+	return trait.engine().HealthCheck(ctx, client, namespace, trait.HealthCheckPolicy)
+}
+
+// engine returns the engine of the templates of the trait definition
+func (trait *Trait) engine() definition.AbstractEngine {
+	return definition.NewTraitAbstractEngine(trait.Name).Definition(trait.Name, trait.DefinitionGeneration)
 }
 
 // Appfile describes application
 type Appfile struct {
-	Name      string
-	Workloads []*Workload
+	Name string
+	// RevisionName and Revision are the application revision the application is rendered to
+	RevisionName string
+	Revision     int64
+	Labels       map[string]string
+	Annotations  map[string]string
+	Workloads    []*Workload
+
+	// processDeadline is the time the processing tasks of the templates must be done by in a render
+	processDeadline time.Time
 }
 
 // TemplateValidate validate Template format
@@ -114,32 +150,131 @@ func (af *Appfile) TemplateValidate() error {
 // Parser is an application parser
 type Parser struct {
 	client client.Client
-	dm     discoverymapper.DiscoveryMapper
+	// apiReader reads the cluster for the processing tasks of the templates
+	apiReader client.Reader
+	dm        discoverymapper.DiscoveryMapper
+	defs      *definitionReader
 }
 
 // NewApplicationParser create appfile parser
 func NewApplicationParser(cli client.Client, dm discoverymapper.DiscoveryMapper) *Parser {
 	return &Parser{
-		client: cli,
-		dm:     dm,
+		client:    cli,
+		apiReader: cli,
+		dm:        dm,
+		defs:      newDefinitionReader(cli),
+	}
+}
+
+// WithAPIReader sets the reader of the processing tasks, the controllers pass an uncached reader so that reading
+// the secrets and the configmaps in the tasks doesn't cache all of them in the cluster
+func (p *Parser) WithAPIReader(reader client.Reader) *Parser {
+	if reader != nil {
+		p.apiReader = reader
+	}
+	return p
+}
+
+// RenderError is the error of rendering a component of the application
+type RenderError struct {
+	// Component is the name of the component that fails to render
+	Component string
+	// Trait is the type of the trait that fails to render, it's empty if the trait is not the one to blame
+	Trait string
+	Err   error
+}
+
+func (e *RenderError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *RenderError) Unwrap() error {
+	return e.Err
+}
+
+func newRenderError(compName string, err error) *RenderError {
+	var renderErr *RenderError
+	if errors.As(err, &renderErr) {
+		return renderErr
 	}
+	return &RenderError{Component: compName, Err: err}
 }
 
-// GenerateAppFile converts an application to an Appfile
+// GenerateAppFile converts an application to an Appfile, it fails if any of the components fails to parse
 func (p *Parser) GenerateAppFile(name string, app *v1alpha2.Application) (*Appfile, error) {
+	appfile, renderErrs := p.GeneratePartialAppFile(name, app)
+	if len(renderErrs) != 0 {
+		return nil, renderErrs[0]
+	}
+	return appfile, nil
+}
+
+// GeneratePartialAppFile converts an application to an Appfile with the components that parse,
+// it returns the errors of the components that fail to parse
+func (p *Parser) GeneratePartialAppFile(name string, app *v1alpha2.Application) (*Appfile, []*RenderError) {
 	appfile := new(Appfile)
 	appfile.Name = name
+	appfile.Labels = app.GetLabels()
+	appfile.Annotations = app.GetAnnotations()
 	var wds []*Workload
+	renderErrs := checkDependencies(app)
 	for _, comp := range app.Spec.Components {
+		if failedComponent(renderErrs, comp.Name) {
+			continue
+		}
 		wd, err := p.parseWorkload(comp)
 		if err != nil {
-			return nil, err
+			renderErrs = append(renderErrs, newRenderError(comp.Name, err))
+			continue
 		}
 		wds = append(wds, wd)
 	}
 	appfile.Workloads = wds
 
-	return appfile, nil
+	return appfile, renderErrs
+}
+
+// checkDependencies checks the outputs of the components are unique and the inputs of the components come from
+// the outputs, it returns the errors of the components that fail the check
+func checkDependencies(app *v1alpha2.Application) []*RenderError {
+	var renderErrs []*RenderError
+	outputs := map[string]string{}
+	for _, comp := range app.Spec.Components {
+		for _, output := range comp.Outputs {
+			if len(output.Name) == 0 {
+				renderErrs = append(renderErrs, newRenderError(comp.Name,
+					errors.Errorf("an output of component %s has no name", comp.Name)))
+				break
+			}
+			if from, exist := outputs[output.Name]; exist {
+				renderErrs = append(renderErrs, newRenderError(comp.Name,
+					errors.Errorf("output %s of component %s is already defined by component %s", output.Name, comp.Name, from)))
+				break
+			}
+			outputs[output.Name] = comp.Name
+		}
+	}
+	for _, comp := range app.Spec.Components {
+		for _, input := range comp.Inputs {
+			from := input.ValueFrom.DataOutputName
+			if _, exist := outputs[from]; !exist {
+				renderErrs = append(renderErrs, newRenderError(comp.Name,
+					errors.Errorf("input of component %s comes from output %s that is not defined", comp.Name, from)))
+				break
+			}
+		}
+	}
+	return renderErrs
+}
+
+func failedComponent(renderErrs []*RenderError, compName string) bool {
+	for _, renderErr := range renderErrs {
+		if renderErr.Component == compName {
+			return true
+		}
+	}
+	return false
 }
 
 func (p *Parser) parseWorkload(comp v1alpha2.ApplicationComponent) (*Workload, error) {
@@ -147,7 +282,7 @@ func (p *Parser) parseWorkload(comp v1alpha2.ApplicationComponent) (*Workload, e
 	workload.Traits = []*Trait{}
 	workload.Name = comp.Name
 	workload.Type = comp.WorkloadType
-	templ, err := util.LoadTemplate(p.client, workload.Type, types.TypeWorkload)
+	templ, err := util.LoadTemplate(p.defs, workload.Type, types.TypeWorkload)
 	if err != nil && !kerrors.IsNotFound(err) {
 		return nil, errors.WithMessagef(err, "fetch type of %s", comp.Name)
 	}
@@ -155,19 +290,24 @@ func (p *Parser) parseWorkload(comp v1alpha2.ApplicationComponent) (*Workload, e
 	workload.Template = templ.TemplateStr
 	workload.HealthCheckPolicy = templ.Health
 	workload.CustomStatusFormat = templ.CustomStatus
+	workload.DefinitionGeneration = templ.Generation
 	settings, err := util.RawExtension2Map(&comp.Settings)
 	if err != nil {
 		return nil, errors.WithMessagef(err, "fail to parse settings for %s", comp.Name)
 	}
 	workload.Params = settings
+	workload.Outputs = comp.Outputs
+	workload.Inputs = comp.Inputs
 	for _, traitValue := range comp.Traits {
 		properties, err := util.RawExtension2Map(&traitValue.Properties)
 		if err != nil {
-			return nil, errors.Errorf("fail to parse properties of %s for %s", traitValue.Name, comp.Name)
+			return nil, &RenderError{Component: comp.Name, Trait: traitValue.Name,
+				Err: errors.Errorf("fail to parse properties of %s for %s", traitValue.Name, comp.Name)}
 		}
 		trait, err := p.parseTrait(traitValue.Name, properties)
 		if err != nil {
-			return nil, errors.WithMessagef(err, "component(%s) parse trait(%s)", comp.Name, traitValue.Name)
+			return nil, &RenderError{Component: comp.Name, Trait: traitValue.Name,
+				Err: errors.WithMessagef(err, "component(%s) parse trait(%s)", comp.Name, traitValue.Name)}
 		}
 
 		workload.Traits = append(workload.Traits, trait)
@@ -178,6 +318,7 @@ func (p *Parser) parseWorkload(comp v1alpha2.ApplicationComponent) (*Workload, e
 			return nil, err
 		}
 		workload.Scopes = append(workload.Scopes, Scope{
+			Type: scopeType,
 			Name: instanceName,
 			GVK:  gvk,
 		})
@@ -186,7 +327,7 @@ func (p *Parser) parseWorkload(comp v1alpha2.ApplicationComponent) (*Workload, e
 }
 
 func (p *Parser) parseTrait(name string, properties map[string]interface{}) (*Trait, error) {
-	templ, err := util.LoadTemplate(p.client, name, types.TypeTrait)
+	templ, err := util.LoadTemplate(p.defs, name, types.TypeTrait)
 	if kerrors.IsNotFound(err) {
 		return nil, errors.Errorf("trait definition of %s not found", name)
 	}
@@ -195,18 +336,31 @@ func (p *Parser) parseTrait(name string, properties map[string]interface{}) (*Tr
 	}
 
 	return &Trait{
-		Name:               name,
-		CapabilityCategory: templ.CapabilityCategory,
-		Params:             properties,
-		Template:           templ.TemplateStr,
-		HealthCheckPolicy:  templ.Health,
-		CustomStatusFormat: templ.CustomStatus,
+		Name:                 name,
+		CapabilityCategory:   templ.CapabilityCategory,
+		Params:               properties,
+		Template:             templ.TemplateStr,
+		HealthCheckPolicy:    templ.Health,
+		CustomStatusFormat:   templ.CustomStatus,
+		DefinitionGeneration: templ.Generation,
 	}, nil
 }
 
-// GenerateApplicationConfiguration converts an appFile to applicationConfig & Components
+// GenerateApplicationConfiguration converts an appFile to applicationConfig & Components,
+// it fails if any of the components fails to render
 func (p *Parser) GenerateApplicationConfiguration(app *Appfile, ns string) (*v1alpha2.ApplicationConfiguration,
 	[]*v1alpha2.Component, error) {
+	appconfig, components, renderErrs := p.GeneratePartialApplicationConfiguration(app, ns)
+	if len(renderErrs) != 0 {
+		return nil, nil, renderErrs[0]
+	}
+	return appconfig, components, nil
+}
+
+// GeneratePartialApplicationConfiguration converts an appFile to applicationConfig & Components with the components
+// that render, it returns the errors of the components that fail to render
+func (p *Parser) GeneratePartialApplicationConfiguration(app *Appfile, ns string) (*v1alpha2.ApplicationConfiguration,
+	[]*v1alpha2.Component, []*RenderError) {
 	appconfig := &v1alpha2.ApplicationConfiguration{}
 	appconfig.SetGroupVersionKind(v1alpha2.ApplicationConfigurationGroupVersionKind)
 	appconfig.Name = app.Name
@@ -218,42 +372,61 @@ func (p *Parser) GenerateApplicationConfiguration(app *Appfile, ns string) (*v1a
 	appconfig.Labels[oam.LabelAppName] = app.Name
 
 	var components []*v1alpha2.Component
+	var renderErrs []*RenderError
+	app.processDeadline = time.Now().Add(task.Timeout)
+	defer func() { app.processDeadline = time.Time{} }()
 	for _, wl := range app.Workloads {
-		pCtx, err := PrepareProcessContext(p.client, wl, app.Name, ns)
+		comp, acComp, err := p.renderWorkload(app, wl, ns)
 		if err != nil {
-			return nil, nil, err
-		}
-		for _, tr := range wl.Traits {
-			if err := tr.EvalContext(pCtx); err != nil {
-				return nil, nil, errors.Wrapf(err, "evaluate template trait=%s app=%s", tr.Name, wl.Name)
-			}
-		}
-		comp, acComp, err := evalWorkloadWithContext(pCtx, wl, app.Name, wl.Name)
-		if err != nil {
-			return nil, nil, err
-		}
-		comp.Name = wl.Name
-		acComp.ComponentName = comp.Name
-
-		for _, sc := range wl.Scopes {
-			acComp.Scopes = append(acComp.Scopes, v1alpha2.ComponentScope{ScopeReference: v1alpha1.TypedReference{
-				APIVersion: sc.GVK.GroupVersion().String(),
-				Kind:       sc.GVK.Kind,
-				Name:       sc.Name,
-			}})
+			renderErrs = append(renderErrs, newRenderError(wl.Name, err))
+			continue
 		}
+		components = append(components, comp)
+		appconfig.Spec.Components = append(appconfig.Spec.Components, *acComp)
+	}
+	return appconfig, components, renderErrs
+}
 
-		comp.Namespace = ns
-		if comp.Labels == nil {
-			comp.Labels = map[string]string{}
+// renderWorkload renders the component and the ACComponent of the workload
+func (p *Parser) renderWorkload(app *Appfile, wl *Workload, ns string) (*v1alpha2.Component,
+	*v1alpha2.ApplicationConfigurationComponent, error) {
+	appName := app.Name
+	pCtx, err := app.PrepareProcessContext(p.apiReader, wl, ns)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, tr := range wl.Traits {
+		if err := tr.EvalContext(pCtx); err != nil {
+			// the components after it can't read a workload that fails to render
+			wl.output = nil
+			return nil, nil, &RenderError{Component: wl.Name, Trait: tr.Name,
+				Err: errors.Wrapf(err, "evaluate template trait=%s app=%s", tr.Name, wl.Name)}
 		}
-		comp.Labels[oam.LabelAppName] = app.Name
-		comp.SetGroupVersionKind(v1alpha2.ComponentGroupVersionKind)
+	}
+	comp, acComp, err := evalWorkloadWithContext(pCtx, wl, appName, wl.Name)
+	if err != nil {
+		return nil, nil, err
+	}
+	comp.Name = wl.Name
+	acComp.ComponentName = comp.Name
+	acComp.DataOutputs = wl.Outputs
+	acComp.DataInputs = wl.Inputs
+
+	for _, sc := range wl.Scopes {
+		acComp.Scopes = append(acComp.Scopes, v1alpha2.ComponentScope{ScopeReference: v1alpha1.TypedReference{
+			APIVersion: sc.GVK.GroupVersion().String(),
+			Kind:       sc.GVK.Kind,
+			Name:       sc.Name,
+		}})
+	}
 
-		components = append(components, comp)
-		appconfig.Spec.Components = append(appconfig.Spec.Components, *acComp)
+	comp.Namespace = ns
+	if comp.Labels == nil {
+		comp.Labels = map[string]string{}
 	}
-	return appconfig, components, nil
+	comp.Labels[oam.LabelAppName] = appName
+	comp.SetGroupVersionKind(v1alpha2.ComponentGroupVersionKind)
+	return comp, acComp, nil
 }
 
 // evalWorkloadWithContext evaluate the workload's template to generate component and ACComponent
@@ -278,7 +451,12 @@ func evalWorkloadWithContext(pCtx process.Context, wl *Workload, appName, compNa
 	for _, assist := range assists {
 		tr, err := assist.Ins.Unstructured()
 		if err != nil {
-			return nil, nil, errors.Wrapf(err, "evaluate trait=%s template for component=%s app=%s", assist.Name, compName, appName)
+			renderErr := &RenderError{Component: compName,
+				Err: errors.Wrapf(err, "evaluate trait=%s template for component=%s app=%s", assist.Name, compName, appName)}
+			if assist.Type != definition.AuxiliaryWorkload {
+				renderErr.Trait = assist.Type
+			}
+			return nil, nil, renderErr
 		}
 		labels := map[string]string{
 			oam.TraitTypeLabel:    assist.Type,
@@ -298,8 +476,49 @@ func evalWorkloadWithContext(pCtx process.Context, wl *Workload, appName, compNa
 }
 
 // PrepareProcessContext prepares a DSL process Context
-func PrepareProcessContext(k8sClient client.Client, wl *Workload, applicationName string, namespace string) (process.Context, error) {
+func PrepareProcessContext(k8sClient client.Reader, wl *Workload, applicationName string, namespace string) (process.Context, error) {
+	return (&Appfile{Name: applicationName}).PrepareProcessContext(k8sClient, wl, namespace)
+}
+
+// PrepareProcessContext prepares the DSL process Context of a workload of the application and evaluates the workload
+// in it. The context has the information of the application and its components, the outputs of the components before
+// the workload are in the context if they are evaluated.
+func (af *Appfile) PrepareProcessContext(k8sClient client.Reader, wl *Workload, namespace string) (process.Context, error) {
+	return af.prepareProcessContext(k8sClient, wl, namespace, false)
+}
+
+// PrepareHealthCheckContext prepares the DSL process Context to check the health and the status of a workload, the
+// templates that are rendered before don't run their processing tasks again
+func (af *Appfile) PrepareHealthCheckContext(k8sClient client.Reader, wl *Workload, namespace string) (process.Context, error) {
+	return af.prepareProcessContext(k8sClient, wl, namespace, true)
+}
+
+func (af *Appfile) prepareProcessContext(k8sClient client.Reader, wl *Workload, namespace string,
+	healthCheck bool) (process.Context, error) {
+	applicationName := af.Name
 	pCtx := process.NewContext(wl.Name, applicationName)
+	pCtx.SetHealthCheck(healthCheck)
+	pCtx.SetClient(k8sClient, namespace)
+	pCtx.SetDeadline(af.processDeadline)
+	pCtx.SetAppRevision(af.RevisionName, af.Revision)
+	pCtx.SetAppMeta(af.Labels, af.Annotations)
+	scopes := make(map[string]string, len(wl.Scopes))
+	for _, sc := range wl.Scopes {
+		scopes[sc.Type] = sc.Name
+	}
+	pCtx.SetScopes(scopes)
+	before := true
+	for _, sibling := range af.Workloads {
+		if sibling == wl || sibling.Name == wl.Name {
+			before = false
+			continue
+		}
+		comp := process.Component{Name: sibling.Name, Type: sibling.Type}
+		if before {
+			comp.Output = sibling.output
+		}
+		pCtx.AppendComponents(comp)
+	}
 	userConfig := wl.GetUserConfigName()
 	if userConfig != "" {
 		cg := config.Configmap{Client: k8sClient}
@@ -314,5 +533,7 @@ func PrepareProcessContext(k8sClient client.Client, wl *Workload, applicationNam
 	if err := wl.EvalContext(pCtx); err != nil {
 		return nil, errors.Wrapf(err, "evaluate base template app=%s in namespace=%s", applicationName, namespace)
 	}
+	// the traits patch the same instance, so the output has the patches of the traits after they are evaluated
+	wl.output, _ = pCtx.Output()
 	return pCtx, nil
 }
@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"testing"
 
 	"github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
 	"github.com/crossplane/crossplane-runtime/pkg/test"
@@ -34,6 +35,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
 	"github.com/oam-dev/kubevela/pkg/oam"
@@ -489,3 +491,183 @@ var _ = Describe("Test appFile parser", func() {
 	})
 
 })
+
+func TestGeneratePartialApplicationConfiguration(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, v1alpha2.SchemeBuilder.AddToScheme(scheme))
+	cli := fake.NewFakeClientWithScheme(scheme,
+		&v1alpha2.WorkloadDefinition{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker"},
+			Spec: v1alpha2.WorkloadDefinitionSpec{Template: `
+output: {
+	apiVersion: "apps/v1"
+	kind:       "Deployment"
+	spec: replicas: parameter.replicas
+}
+parameter: replicas: int
+`},
+		},
+		&v1alpha2.TraitDefinition{
+			ObjectMeta: metav1.ObjectMeta{Name: "broken"},
+			Spec: v1alpha2.TraitDefinitionSpec{Template: `
+outputs: broken: {
+	apiVersion: "v1"
+	kind:       "Service"
+	spec: port: parameter.port + "x"
+}
+parameter: port: int
+`},
+		})
+	app := &v1alpha2.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "myapp", Namespace: "default"},
+		Spec: v1alpha2.ApplicationSpec{Components: []v1alpha2.ApplicationComponent{
+			{Name: "good", WorkloadType: "worker", Settings: runtime.RawExtension{Raw: []byte(`{"replicas":1}`)}},
+			{Name: "missing-trait", WorkloadType: "worker", Settings: runtime.RawExtension{Raw: []byte(`{"replicas":1}`)},
+				Traits: []v1alpha2.ApplicationTrait{{Name: "missing", Properties: runtime.RawExtension{Raw: []byte(`{}`)}}}},
+			{Name: "bad-trait", WorkloadType: "worker", Settings: runtime.RawExtension{Raw: []byte(`{"replicas":1}`)},
+				Traits: []v1alpha2.ApplicationTrait{{Name: "broken", Properties: runtime.RawExtension{Raw: []byte(`{"port":80}`)}}}},
+			{Name: "bad-settings", WorkloadType: "worker", Settings: runtime.RawExtension{Raw: []byte(`{"replicas":"one"}`)}},
+		}},
+	}
+	p := NewApplicationParser(cli, nil)
+
+	af, renderErrs := p.GeneratePartialAppFile(app.Name, app)
+	assert.Len(t, af.Workloads, 3)
+	assert.Len(t, renderErrs, 1)
+	assert.Equal(t, "missing-trait", renderErrs[0].Component)
+	assert.Equal(t, "missing", renderErrs[0].Trait)
+	_, err := p.GenerateAppFile(app.Name, app)
+	assert.Equal(t, renderErrs[0].Error(), err.Error())
+
+	ac, comps, buildErrs := p.GeneratePartialApplicationConfiguration(af, app.Namespace)
+	assert.Len(t, comps, 1)
+	assert.Equal(t, "good", comps[0].Name)
+	assert.Len(t, ac.Spec.Components, 1)
+	assert.Equal(t, "good", ac.Spec.Components[0].ComponentName)
+	assert.Len(t, buildErrs, 2)
+	assert.Equal(t, "bad-trait", buildErrs[0].Component)
+	assert.Equal(t, "broken", buildErrs[0].Trait)
+	assert.Equal(t, "bad-settings", buildErrs[1].Component)
+	assert.Empty(t, buildErrs[1].Trait)
+}
+
+func TestRenderWithApplicationContext(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, v1alpha2.SchemeBuilder.AddToScheme(scheme))
+	cli := fake.NewFakeClientWithScheme(scheme,
+		&v1alpha2.WorkloadDefinition{
+			ObjectMeta: metav1.ObjectMeta{Name: "db"},
+			Spec: v1alpha2.WorkloadDefinitionSpec{Template: `
+output: {
+	apiVersion: "v1"
+	kind:       "Service"
+	metadata: name: "\(context.name)-\(context.appRevision)"
+	spec: clusterIP: parameter.ip
+}
+parameter: ip: string
+`},
+		},
+		&v1alpha2.WorkloadDefinition{
+			ObjectMeta: metav1.ObjectMeta{Name: "web"},
+			Spec: v1alpha2.WorkloadDefinitionSpec{Template: `
+output: {
+	apiVersion: "v1"
+	kind:       "ConfigMap"
+	metadata: {
+		namespace: context.namespace
+		labels: team: context.appLabels.team
+	}
+	data: {
+		dbHost:  context.components.db.output.spec.clusterIP
+		dbType:  context.components.db.type
+		scope:   context.scopes["healthscopes.core.oam.dev"]
+		if context.components.worker.output == _|_ {
+			later: "not rendered"
+		}
+	}
+}
+`},
+		},
+		&v1alpha2.WorkloadDefinition{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker"},
+			Spec:       v1alpha2.WorkloadDefinitionSpec{Template: `output: {apiVersion: "v1", kind: "ConfigMap"}`},
+		})
+	app := &v1alpha2.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "myapp", Namespace: "prod", Labels: map[string]string{"team": "frontend"}},
+		Spec: v1alpha2.ApplicationSpec{Components: []v1alpha2.ApplicationComponent{
+			{Name: "db", WorkloadType: "db", Settings: runtime.RawExtension{Raw: []byte(`{"ip":"10.0.0.1"}`)}},
+			{Name: "web", WorkloadType: "web", Settings: runtime.RawExtension{Raw: []byte(`{}`)}},
+			{Name: "worker", WorkloadType: "worker", Settings: runtime.RawExtension{Raw: []byte(`{}`)}},
+		}},
+	}
+	p := NewApplicationParser(cli, nil)
+	af, err := p.GenerateAppFile(app.Name, app)
+	assert.NoError(t, err)
+	af.RevisionName, af.Revision = "myapp-v3", 3
+	// the scopes are not looked up in the cluster in this test
+	af.Workloads[1].Scopes = []Scope{{Type: "healthscopes.core.oam.dev", Name: "myscope"}}
+
+	_, comps, err := p.GenerateApplicationConfiguration(af, app.Namespace)
+	assert.NoError(t, err)
+	assert.Len(t, comps, 3)
+	db, err := util.RawExtension2Map(&comps[0].Spec.Workload)
+	assert.NoError(t, err)
+	assert.Equal(t, "db-myapp-v3", db["metadata"].(map[string]interface{})["name"])
+	web, err := util.RawExtension2Map(&comps[1].Spec.Workload)
+	assert.NoError(t, err)
+	assert.Equal(t, "prod", web["metadata"].(map[string]interface{})["namespace"])
+	assert.Equal(t, map[string]interface{}{
+		"dbHost": "10.0.0.1",
+		"dbType": "db",
+		"scope":  "myscope",
+		// the components after this one are not rendered yet
+		"later": "not rendered",
+	}, web["data"])
+}
+
+func TestDataPassing(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, v1alpha2.SchemeBuilder.AddToScheme(scheme))
+	cli := fake.NewFakeClientWithScheme(scheme, &v1alpha2.WorkloadDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker"},
+		Spec:       v1alpha2.WorkloadDefinitionSpec{Template: `output: {apiVersion: "v1", kind: "ConfigMap"}`},
+	})
+	dbSecret := v1alpha2.DataOutput{Name: "db-secret", FieldPath: "status.secretName"}
+	secretInput := v1alpha2.DataInput{
+		ValueFrom:    v1alpha2.DataInputValueFrom{DataOutputName: "db-secret"},
+		ToFieldPaths: []string{"data.secret"},
+	}
+	app := &v1alpha2.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "myapp", Namespace: "default"},
+		Spec: v1alpha2.ApplicationSpec{Components: []v1alpha2.ApplicationComponent{
+			{Name: "db", WorkloadType: "worker", Settings: runtime.RawExtension{Raw: []byte(`{}`)},
+				Outputs: []v1alpha2.DataOutput{dbSecret}},
+			{Name: "web", WorkloadType: "worker", Settings: runtime.RawExtension{Raw: []byte(`{}`)},
+				Inputs: []v1alpha2.DataInput{secretInput}},
+		}},
+	}
+	p := NewApplicationParser(cli, nil)
+	af, err := p.GenerateAppFile(app.Name, app)
+	assert.NoError(t, err)
+	ac, _, err := p.GenerateApplicationConfiguration(af, app.Namespace)
+	assert.NoError(t, err)
+	assert.Equal(t, []v1alpha2.DataOutput{dbSecret}, ac.Spec.Components[0].DataOutputs)
+	assert.Empty(t, ac.Spec.Components[0].DataInputs)
+	assert.Equal(t, []v1alpha2.DataInput{secretInput}, ac.Spec.Components[1].DataInputs)
+
+	// the input comes from an output that is not defined
+	app.Spec.Components[0].Outputs = nil
+	af, renderErrs := p.GeneratePartialAppFile(app.Name, app)
+	assert.Len(t, af.Workloads, 1)
+	assert.Len(t, renderErrs, 1)
+	assert.Equal(t, "web", renderErrs[0].Component)
+	assert.Equal(t, "input of component web comes from output db-secret that is not defined", renderErrs[0].Error())
+
+	// the output is defined twice
+	app.Spec.Components[0].Outputs = []v1alpha2.DataOutput{dbSecret}
+	app.Spec.Components[1].Outputs = []v1alpha2.DataOutput{dbSecret}
+	app.Spec.Components[1].Inputs = nil
+	_, renderErrs = p.GeneratePartialAppFile(app.Name, app)
+	assert.Len(t, renderErrs, 1)
+	assert.Equal(t, "output db-secret of component web is already defined by component db", renderErrs[0].Error())
+}
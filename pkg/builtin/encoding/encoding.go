@@ -0,0 +1,90 @@
+package encoding
+
+import (
+	"crypto/md5"  //nolint:gosec
+	"crypto/sha1" //nolint:gosec
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+
+	"cuelang.org/go/cue"
+
+	"github.com/oam-dev/kubevela/pkg/builtin/registry"
+)
+
+func init() {
+	registry.RegisterRunner("encode", newEncodeCmd)
+}
+
+// The methods of the encode task
+const (
+	MethodBase64       = "base64"
+	MethodBase64Decode = "base64Decode"
+	MethodJSON         = "json"
+	MethodSHA256       = "sha256"
+	MethodSHA1         = "sha1"
+	MethodMD5          = "md5"
+)
+
+// EncodeCmd encodes or hashes the data
+type EncodeCmd struct{}
+
+func newEncodeCmd(_ cue.Value) (registry.Runner, error) {
+	return &EncodeCmd{}, nil
+}
+
+// Run returns the encoded data as a string, the data is encoded as json if it's not a string
+func (c *EncodeCmd) Run(meta *registry.Meta) (interface{}, error) {
+	method := meta.String("method")
+	data := meta.Lookup("data")
+	if meta.Err != nil {
+		return nil, meta.Err
+	}
+	if method == MethodJSON {
+		return marshal(data)
+	}
+	str, err := data.String()
+	if err != nil {
+		if str, err = marshal(data); err != nil {
+			return nil, err
+		}
+	}
+	switch method {
+	case MethodBase64:
+		return base64.StdEncoding.EncodeToString([]byte(str)), nil
+	case MethodBase64Decode:
+		decoded, err := base64.StdEncoding.DecodeString(str)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 data, %w", err)
+		}
+		return string(decoded), nil
+	case MethodSHA256:
+		return hashString(sha256.New(), str), nil
+	case MethodSHA1:
+		return hashString(sha1.New(), str), nil //nolint:gosec
+	case MethodMD5:
+		return hashString(md5.New(), str), nil //nolint:gosec
+	default:
+		return nil, fmt.Errorf("unknown encode method %s", method)
+	}
+}
+
+func marshal(v cue.Value) (string, error) {
+	var data interface{}
+	if err := v.Decode(&data); err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func hashString(h hash.Hash, str string) string {
+	h.Write([]byte(str)) //nolint:errcheck
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,64 @@
+package encoding
+
+import (
+	"testing"
+
+	"cuelang.org/go/cue"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/oam-dev/kubevela/pkg/builtin/registry"
+)
+
+func TestEncodeCmd_Run(t *testing.T) {
+	testCases := map[string]struct {
+		task     string
+		expected interface{}
+		hasErr   bool
+	}{
+		"base64": {
+			task:     `{method: "base64", data: "secret"}`,
+			expected: "c2VjcmV0",
+		},
+		"base64Decode": {
+			task:     `{method: "base64Decode", data: "c2VjcmV0"}`,
+			expected: "secret",
+		},
+		"sha256": {
+			task:     `{method: "sha256", data: "ClusterIP"}`,
+			expected: "d6e79bcb9ebe2da8c86ef1939289fc28e7c5eccd72d5dde3ec3f618e06c13218",
+		},
+		"json": {
+			task:     `{method: "json", data: {port: 80, host: "example.com"}}`,
+			expected: `{"host":"example.com","port":80}`,
+		},
+		"hash a struct as json": {
+			task:     `{method: "md5", data: {port: 80}}`,
+			expected: "0eb17eb3b60aa748b26c7fd78a3c6edf",
+		},
+		"unknown method": {
+			task:   `{method: "rot13", data: "secret"}`,
+			hasErr: true,
+		},
+		"invalid base64": {
+			task:   `{method: "base64Decode", data: "not base64!"}`,
+			hasErr: true,
+		},
+		"no data": {
+			task:   `{method: "base64"}`,
+			hasErr: true,
+		},
+	}
+	for name, tc := range testCases {
+		r := cue.Runtime{}
+		inst, err := r.Compile("", tc.task)
+		assert.NoError(t, err, name)
+		runner, _ := newEncodeCmd(cue.Value{})
+		got, err := runner.Run(&registry.Meta{Obj: inst.Value()})
+		if tc.hasErr {
+			assert.Error(t, err, name)
+			continue
+		}
+		assert.NoError(t, err, name)
+		assert.Equal(t, tc.expected, got, name)
+	}
+}
@@ -1,12 +1,24 @@
 package http
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"cuelang.org/go/cue"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/oam-dev/kubevela/pkg/builtin/registry"
 )
@@ -15,30 +27,129 @@ func init() {
 	registry.RegisterRunner("http", newHTTPCmd)
 }
 
+const (
+	// CACertKey is the key of the CA certificate in the tls secret of a task
+	CACertKey = "ca.crt"
+	// ClientCertKey is the key of the client certificate in the tls secret of a task
+	ClientCertKey = corev1.TLSCertKey
+	// ClientKeyKey is the key of the client key in the tls secret of a task
+	ClientKeyKey = corev1.TLSPrivateKeyKey
+
+	// the max number of redirects a request follows, it's the same as the default client
+	maxRedirects = 10
+	// retryInterval is the interval before the first retry, it's doubled for every retry
+	retryInterval = 100 * time.Millisecond
+)
+
+// Options limits the http requests sent by the processing tasks, they're set by the controller
+type Options struct {
+	// Timeout is the max time of each request, a task can set a shorter one
+	Timeout time.Duration
+	// MaxRetries is the max number of retries of a failed request
+	MaxRetries int
+	// MaxResponseSize is the max number of bytes of a response body
+	MaxResponseSize int64
+	// AllowedURLs are the URLs that the requests can be sent to. A URL is allowed if it has the scheme and
+	// the host of one of them and it's under the path of that one, a host like `*.example.com` allows its subdomains.
+	// All the http and https URLs are allowed if it's empty. Either way the requests can't be sent to the loopback,
+	// the link-local and the private addresses, like the metadata services of the cloud providers and the services
+	// in the cluster, unless the host of an allowed URL is the exact host of the request, a wildcard host doesn't count.
+	AllowedURLs []string
+}
+
+// DefaultOptions are the options the requests are sent with until the controller sets others
+var DefaultOptions = Options{
+	Timeout:         10 * time.Second,
+	MaxRetries:      3,
+	MaxResponseSize: 1 << 20,
+}
+
+// idempotentMethods are the methods whose requests are retried, a task sets `idempotent: true` to retry the others
+var idempotentMethods = map[string]bool{
+	"":                 true,
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+	http.MethodPut:     true,
+	http.MethodDelete:  true,
+}
+
+// internalNets are the loopback, the link-local and the private ranges
+var internalNets = parseCIDRs(
+	"0.0.0.0/8", "127.0.0.0/8", "10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "100.64.0.0/10", "169.254.0.0/16",
+	"::/128", "::1/128", "fe80::/10", "fc00::/7",
+)
+
+// transport sends the requests of all the tasks, it checks the addresses the requests are sent to when it dials them,
+// so it doesn't use the proxies
+var transport = newTransport()
+
+var (
+	optionsMu   sync.RWMutex
+	options     = DefaultOptions
+	allowedURLs []*url.URL
+)
+
+// SetOptions sets the options of all the http tasks
+func SetOptions(opts Options) error {
+	var allowed []*url.URL
+	for _, s := range opts.AllowedURLs {
+		u, err := url.Parse(s)
+		if err != nil {
+			return fmt.Errorf("invalid allowed url %s, %w", s, err)
+		}
+		if (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+			return fmt.Errorf("invalid allowed url %s, it must be an absolute http or https url", s)
+		}
+		allowed = append(allowed, u)
+	}
+	optionsMu.Lock()
+	defer optionsMu.Unlock()
+	options = opts
+	allowedURLs = allowed
+	// the connections are checked when they're dialed, the ones dialed with the old options are not reused
+	transport.CloseIdleConnections()
+	return nil
+}
+
+func getOptions() (Options, []*url.URL) {
+	optionsMu.RLock()
+	defer optionsMu.RUnlock()
+	return options, allowedURLs
+}
+
 // HTTPCmd provides methods for http task
 type HTTPCmd struct {
 	*http.Client
 }
 
 func newHTTPCmd(v cue.Value) (registry.Runner, error) {
-	client := http.DefaultClient
-	return &HTTPCmd{client}, nil
+	return &HTTPCmd{&http.Client{Transport: transport}}, nil
 }
 
-// Run exec the actual http logic, and res represent the result of http task
+// Run exec the actual http logic, and res represent the result of http task.
+// Besides `method`, `url` and `request`, the task can set the `timeout` of each request, the number of `retries` and
+// the `tls_config.secret` that has the CA certificate and the client certificate, they're limited by the Options.
+// Only the requests of the idempotent methods are retried unless the task sets `idempotent: true`, a request isn't
+// retried if the retry can't be done before the deadline of the context, the render fails and is retried later.
 func (c *HTTPCmd) Run(meta *registry.Meta) (res interface{}, err error) {
+	opts, allowed := getOptions()
 	var header, trailer http.Header
 	var (
 		method = meta.String("method")
 		u      = meta.String("url")
 	)
-	var r io.Reader
+	var body []byte
 	if obj := meta.Obj.Lookup("request"); obj.Exists() {
 		if v := obj.Lookup("body"); v.Exists() {
-			r, err = v.Reader()
+			r, err := v.Reader()
 			if err != nil {
 				return nil, err
 			}
+			if body, err = ioutil.ReadAll(r); err != nil {
+				return nil, err
+			}
 		}
 		if header, err = parseHeaders(obj, "header"); err != nil {
 			return nil, err
@@ -48,32 +159,282 @@ func (c *HTTPCmd) Run(meta *registry.Meta) (res interface{}, err error) {
 		}
 	}
 	if header == nil {
+		header = http.Header{}
 		header.Set("Content-Type", "application/json")
 	}
 	if meta.Err != nil {
 		return nil, meta.Err
 	}
-
-	req, err := http.NewRequestWithContext(context.Background(), method, u, r)
+	timeout, retries, err := parseLimits(meta.Obj, method, opts)
+	if err != nil {
+		return nil, err
+	}
+	reqURL, err := url.Parse(u)
 	if err != nil {
 		return nil, err
 	}
+	if err := checkURL(reqURL, allowed); err != nil {
+		return nil, err
+	}
+	cli, err := c.client(meta, allowed)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := meta.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	for i := 0; ; i++ {
+		res, retry, err := do(ctx, cli, method, u, body, header, trailer, timeout, opts.MaxResponseSize)
+		if err == nil || !retry || i >= retries {
+			return res, err
+		}
+		interval := retryInterval << uint(i)
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(interval).After(deadline) {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(interval):
+		}
+	}
+}
+
+// do sends the request once, it tells if the request can be retried when it fails
+func do(ctx context.Context, cli *http.Client, method, u string, body []byte, header, trailer http.Header,
+	timeout time.Duration, maxResponseSize int64) (map[string]interface{}, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, false, err
+	}
 	req.Header = header
 	req.Trailer = trailer
 
-	resp, err := c.Client.Do(req)
+	resp, err := cli.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, true, err
 	}
 	//nolint:errcheck
 	defer resp.Body.Close()
-	b, err := ioutil.ReadAll(resp.Body)
+	b, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxResponseSize+1))
+	if err != nil {
+		return nil, true, err
+	}
+	if int64(len(b)) > maxResponseSize {
+		return nil, false, fmt.Errorf("the response of %s %s is larger than %d bytes", method, u, maxResponseSize)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		retry := resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests
+		return nil, retry, fmt.Errorf("%s %s responds %s", method, u, resp.Status)
+	}
 	// parse response body and headers
 	return map[string]interface{}{
-		"body":    string(b),
-		"header":  resp.Header,
-		"trailer": resp.Trailer,
-	}, err
+		"body":       string(b),
+		"header":     resp.Header,
+		"trailer":    resp.Trailer,
+		"statusCode": resp.StatusCode,
+	}, false, nil
+}
+
+// parseLimits finds the timeout and the number of retries of the task, they can't exceed the options
+func parseLimits(obj cue.Value, method string, opts Options) (time.Duration, int, error) {
+	timeout := opts.Timeout
+	if v := obj.Lookup("timeout"); v.Exists() {
+		s, err := v.String()
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid timeout, %w", err)
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			return 0, 0, fmt.Errorf("invalid timeout %s, it must be a positive duration like 5s", s)
+		}
+		if d < timeout {
+			timeout = d
+		}
+	}
+	var retries int
+	if v := obj.Lookup("retries"); v.Exists() {
+		n, err := v.Int64()
+		if err != nil || n < 0 {
+			return 0, 0, fmt.Errorf("invalid retries, it must be a non-negative int")
+		}
+		retries = int(n)
+	}
+	if retries > opts.MaxRetries {
+		retries = opts.MaxRetries
+	}
+	idempotent := idempotentMethods[strings.ToUpper(method)]
+	if v := obj.Lookup("idempotent"); v.Exists() {
+		b, err := v.Bool()
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid idempotent, it must be a bool")
+		}
+		idempotent = idempotent || b
+	}
+	if !idempotent {
+		retries = 0
+	}
+	return timeout, retries, nil
+}
+
+// checkURL checks if the request can be sent to the url
+func checkURL(u *url.URL, allowed []*url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("the scheme of url %s is not http or https", u)
+	}
+	if len(allowed) == 0 {
+		return nil
+	}
+	for _, a := range allowed {
+		if urlAllowed(u, a) {
+			return nil
+		}
+	}
+	return fmt.Errorf("url %s is not allowed by the controller", u)
+}
+
+func urlAllowed(u, allowed *url.URL) bool {
+	if u.Scheme != allowed.Scheme || u.Port() != allowed.Port() {
+		return false
+	}
+	host, allowedHost := strings.ToLower(u.Hostname()), strings.ToLower(allowed.Hostname())
+	if strings.HasPrefix(allowedHost, "*.") {
+		if !strings.HasSuffix(host, allowedHost[1:]) {
+			return false
+		}
+	} else if host != allowedHost {
+		return false
+	}
+	allowedPath := strings.TrimSuffix(allowed.Path, "/")
+	return u.Path == allowedPath || strings.HasPrefix(u.Path, allowedPath+"/")
+}
+
+// client returns the client that sends the requests of the task, the redirects are checked as the requests
+func (c *HTTPCmd) client(meta *registry.Meta, allowed []*url.URL) (*http.Client, error) {
+	cli := *c.Client
+	cli.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		return checkURL(req.URL, allowed)
+	}
+	tlsConfig := meta.Obj.Lookup("tls_config")
+	if !tlsConfig.Exists() {
+		return &cli, nil
+	}
+	secretName, err := tlsConfig.Lookup("secret").String()
+	if err != nil {
+		return nil, fmt.Errorf("invalid tls_config, %w", err)
+	}
+	config, err := loadTLSConfig(meta, secretName)
+	if err != nil {
+		return nil, err
+	}
+	tlsTransport := transport.Clone()
+	tlsTransport.TLSClientConfig = config
+	cli.Transport = tlsTransport
+	return &cli, nil
+}
+
+func newTransport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = nil
+	t.DialContext = dialContext
+	return t
+}
+
+// dialContext dials the address of a request, it refuses to connect to the internal addresses the host resolves to
+// unless the host is allowed explicitly
+func dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if _, allowed := getOptions(); !hostAllowed(host, allowed) {
+		dialer.Control = func(_, address string, _ syscall.RawConn) error {
+			ip, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if isInternalIP(net.ParseIP(ip)) {
+				return fmt.Errorf("the address %s of %s is internal and the host is not allowed by the controller", ip, host)
+			}
+			return nil
+		}
+	}
+	return dialer.DialContext(ctx, network, addr)
+}
+
+// hostAllowed checks if the host is the exact host of one of the allowed urls
+func hostAllowed(host string, allowed []*url.URL) bool {
+	for _, a := range allowed {
+		if strings.EqualFold(strings.Trim(host, "[]"), a.Hostname()) {
+			return true
+		}
+	}
+	return false
+}
+
+func isInternalIP(ip net.IP) bool {
+	if ip == nil {
+		return true
+	}
+	for _, n := range internalNets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, c := range cidrs {
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			panic(err)
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}
+
+// loadTLSConfig loads the CA certificate and the client certificate from the secret in the namespace of the task
+func loadTLSConfig(meta *registry.Meta, secretName string) (*tls.Config, error) {
+	if meta.Client == nil {
+		return nil, fmt.Errorf("cannot read the tls secret %s without a client", secretName)
+	}
+	ctx := meta.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var secret corev1.Secret
+	if err := meta.Client.Get(ctx, client.ObjectKey{Namespace: meta.Namespace, Name: secretName}, &secret); err != nil {
+		return nil, fmt.Errorf("cannot get the tls secret %s, %w", secretName, err)
+	}
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if ca, ok := secret.Data[CACertKey]; ok {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("invalid %s in the tls secret %s", CACertKey, secretName)
+		}
+		config.RootCAs = pool
+	}
+	cert, hasCert := secret.Data[ClientCertKey]
+	key, hasKey := secret.Data[ClientKeyKey]
+	if hasCert || hasKey {
+		certificate, err := tls.X509KeyPair(cert, key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate in the tls secret %s, %w", secretName, err)
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+	return config, nil
 }
 
 func parseHeaders(obj cue.Value, label string) (http.Header, error) {
@@ -1,15 +1,23 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 
 	"cuelang.org/go/cue"
 	"github.com/bmizerany/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	"github.com/oam-dev/kubevela/pkg/builtin/registry"
 )
@@ -34,6 +42,8 @@ const Req = `
 func TestHTTPCmd_Run(t *testing.T) {
 	s := NewMock()
 	defer s.Close()
+	defer SetOptions(DefaultOptions)
+	allowURLs(t, "http://127.0.0.1:8090")
 
 	r := cue.Runtime{}
 	reqInst, err := r.Compile("", Req)
@@ -74,3 +84,268 @@ func NewMock() *httptest.Server {
 	ts.Start()
 	return ts
 }
+
+func TestHTTPCmd_RunWithoutRequest(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("Content-Type")))
+	}))
+	defer ts.Close()
+	defer SetOptions(DefaultOptions)
+	allowURLs(t, ts.URL)
+
+	got, err := runTask(t, &registry.Meta{}, fmt.Sprintf(`{method: "GET", url: "%s"}`, ts.URL))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "application/json", got["body"])
+	assert.Equal(t, http.StatusOK, got["statusCode"])
+}
+
+func TestHTTPCmd_RunWithLimits(t *testing.T) {
+	var calls int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch strings.TrimPrefix(r.URL.Path, "/api") {
+		case "/flaky":
+			if calls < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte("ok"))
+		case "/notfound":
+			w.WriteHeader(http.StatusNotFound)
+		case "/large":
+			w.Write(make([]byte, 2048))
+		case "/slow":
+			time.Sleep(time.Second)
+		case "/redirect":
+			http.Redirect(w, r, "/internal", http.StatusFound)
+		default:
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer ts.Close()
+	defer SetOptions(DefaultOptions)
+	assert.Equal(t, nil, SetOptions(Options{
+		Timeout:         500 * time.Millisecond,
+		MaxRetries:      2,
+		MaxResponseSize: 1024,
+		AllowedURLs:     []string{ts.URL + "/api", "https://*.example.com"},
+	}))
+
+	testCases := map[string]struct {
+		task      string
+		calls     int
+		errSubstr string
+	}{
+		"retried until it succeeds": {
+			task:  `{method: "GET", url: "%s/api/flaky", retries: 5}`,
+			calls: 3,
+		},
+		"non-idempotent method not retried": {
+			task:      `{method: "POST", url: "%s/api/flaky", retries: 5}`,
+			calls:     1,
+			errSubstr: "503",
+		},
+		"non-idempotent method retried if the task opts in": {
+			task:  `{method: "POST", url: "%s/api/flaky", retries: 5, idempotent: true}`,
+			calls: 3,
+		},
+		"not retried by default": {
+			task:      `{method: "GET", url: "%s/api/flaky"}`,
+			calls:     1,
+			errSubstr: "503",
+		},
+		"client errors are not retried": {
+			task:      `{method: "GET", url: "%s/api/notfound", retries: 2}`,
+			calls:     1,
+			errSubstr: "404",
+		},
+		"response too large": {
+			task:      `{method: "GET", url: "%s/api/large"}`,
+			calls:     1,
+			errSubstr: "larger than 1024 bytes",
+		},
+		"timeout": {
+			task:      `{method: "GET", url: "%s/api/slow", timeout: "100ms"}`,
+			calls:     1,
+			errSubstr: "deadline exceeded",
+		},
+		"invalid timeout": {
+			task:      `{method: "GET", url: "%s/api/slow", timeout: "soon"}`,
+			errSubstr: "invalid timeout",
+		},
+		"url not allowed": {
+			task:      `{method: "GET", url: "%s/internal"}`,
+			errSubstr: "not allowed",
+		},
+		"path not under the allowed one": {
+			task:      `{method: "GET", url: "%s/apis"}`,
+			errSubstr: "not allowed",
+		},
+		"redirect not allowed": {
+			task:      `{method: "GET", url: "%s/api/redirect"}`,
+			calls:     1,
+			errSubstr: "not allowed",
+		},
+		"scheme not allowed": {
+			task:      `{method: "GET", url: "file:///etc/passwd%s"}`,
+			errSubstr: "not http or https",
+		},
+	}
+	for name, tc := range testCases {
+		calls = 0
+		task := tc.task
+		if strings.Contains(task, "file://") {
+			task = fmt.Sprintf(task, "")
+		} else {
+			task = fmt.Sprintf(task, ts.URL)
+		}
+		_, err := runTask(t, &registry.Meta{}, task)
+		if len(tc.errSubstr) == 0 {
+			assert.Equal(t, nil, err, name)
+		} else {
+			assert.T(t, err != nil && strings.Contains(err.Error(), tc.errSubstr), name, err)
+		}
+		assert.Equal(t, tc.calls, calls, name)
+	}
+
+	assert.T(t, checkURL(mustParse(t, "https://api.example.com/token"), allowedURLs) == nil)
+	assert.T(t, checkURL(mustParse(t, "https://example.com.evil.io/token"), allowedURLs) != nil)
+	assert.T(t, SetOptions(Options{AllowedURLs: []string{"example.com"}}) != nil)
+}
+
+func TestHTTPCmd_RunWithInternalAddress(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+	defer SetOptions(DefaultOptions)
+	port := mustParse(t, ts.URL).Port()
+
+	testCases := map[string]struct {
+		allowed []string
+		url     string
+		denied  bool
+	}{
+		"denied by default": {
+			url:    ts.URL,
+			denied: true,
+		},
+		"host resolving to the loopback address": {
+			url:    "http://localhost:" + port,
+			denied: true,
+		},
+		"wildcard host doesn't allow the internal addresses": {
+			allowed: []string{"http://*.0.0.1:" + port},
+			url:     ts.URL,
+			denied:  true,
+		},
+		"allowed explicitly": {
+			allowed: []string{ts.URL},
+			url:     ts.URL,
+		},
+	}
+	for name, tc := range testCases {
+		opts := DefaultOptions
+		opts.AllowedURLs = tc.allowed
+		assert.Equal(t, nil, SetOptions(opts), name)
+		got, err := runTask(t, &registry.Meta{}, fmt.Sprintf(`{method: "GET", url: "%s"}`, tc.url))
+		if tc.denied {
+			assert.T(t, err != nil && strings.Contains(err.Error(), "is internal"), name, err)
+			continue
+		}
+		assert.Equal(t, nil, err, name)
+		assert.Equal(t, "ok", got["body"], name)
+	}
+
+	for ip, internal := range map[string]bool{
+		"169.254.169.254": true,
+		"10.96.0.1":       true,
+		"172.20.0.1":      true,
+		"192.168.1.1":     true,
+		"::1":             true,
+		"fd00::1":         true,
+		"fe80::1":         true,
+		"8.8.8.8":         false,
+		"2001:4860::8888": false,
+	} {
+		assert.Equal(t, internal, isInternalIP(net.ParseIP(ip)), ip)
+	}
+}
+
+func TestHTTPCmd_RunWithDeadline(t *testing.T) {
+	var calls int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+	defer SetOptions(DefaultOptions)
+	allowURLs(t, ts.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := runTask(t, &registry.Meta{Context: ctx}, fmt.Sprintf(`{method: "GET", url: "%s", retries: 3}`, ts.URL))
+	assert.T(t, err != nil && strings.Contains(err.Error(), "503"), err)
+	// the requests are sent after 0, 100ms and 300ms, the last one is not sent as it's after the deadline
+	assert.Equal(t, 2, calls)
+	assert.T(t, time.Since(start) < 250*time.Millisecond, time.Since(start))
+}
+
+func TestHTTPCmd_RunWithTLS(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+	defer SetOptions(DefaultOptions)
+	allowURLs(t, ts.URL)
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	cli := fake.NewFakeClient(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "server-ca", Namespace: "default"},
+		Data:       map[string][]byte{CACertKey: ca},
+	})
+
+	// the certificate of the server is unknown
+	_, err := runTask(t, &registry.Meta{}, fmt.Sprintf(`{method: "GET", url: "%s"}`, ts.URL))
+	assert.NotEqual(t, nil, err)
+
+	task := fmt.Sprintf(`{method: "GET", url: "%s", tls_config: secret: "server-ca"}`, ts.URL)
+	got, err := runTask(t, &registry.Meta{Client: cli, Namespace: "default"}, task)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "ok", got["body"])
+
+	// the secret is read from the namespace of the application only
+	_, err = runTask(t, &registry.Meta{Client: cli, Namespace: "vela-system"}, task)
+	assert.NotEqual(t, nil, err)
+}
+
+func runTask(t *testing.T, meta *registry.Meta, task string) (map[string]interface{}, error) {
+	r := cue.Runtime{}
+	inst, err := r.Compile("", task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta.Obj = inst.Value()
+	runner, _ := newHTTPCmd(cue.Value{})
+	got, err := runner.Run(meta)
+	if err != nil {
+		return nil, err
+	}
+	return got.(map[string]interface{}), nil
+}
+
+func allowURLs(t *testing.T, urls ...string) {
+	opts := DefaultOptions
+	opts.AllowedURLs = urls
+	if err := SetOptions(opts); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func mustParse(t *testing.T, s string) *url.URL {
+	u, err := url.Parse(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
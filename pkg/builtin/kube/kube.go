@@ -0,0 +1,107 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+
+	"cuelang.org/go/cue"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/pkg/builtin/registry"
+)
+
+func init() {
+	registry.RegisterRunner("configmap", newConfigMapCmd)
+	registry.RegisterRunner("secret", newSecretCmd)
+	registry.RegisterRunner("kube", newObjectCmd)
+}
+
+// ConfigMapCmd reads the data of a ConfigMap
+type ConfigMapCmd struct{}
+
+func newConfigMapCmd(_ cue.Value) (registry.Runner, error) {
+	return &ConfigMapCmd{}, nil
+}
+
+// Run returns the data of the ConfigMap
+func (c *ConfigMapCmd) Run(meta *registry.Meta) (interface{}, error) {
+	cm := &corev1.ConfigMap{}
+	if err := get(meta, cm); err != nil {
+		return nil, err
+	}
+	data := make(map[string]interface{}, len(cm.Data))
+	for k, v := range cm.Data {
+		data[k] = v
+	}
+	return map[string]interface{}{"data": data}, nil
+}
+
+// SecretCmd reads the data of a Secret. Any value that a template copies from the
+// result ends up in plain text in the rendered Component and ApplicationRevision,
+// so templates should only use it through a hash such as encode sha256.
+type SecretCmd struct{}
+
+func newSecretCmd(_ cue.Value) (registry.Runner, error) {
+	return &SecretCmd{}, nil
+}
+
+// Run returns the decoded data of the Secret
+func (c *SecretCmd) Run(meta *registry.Meta) (interface{}, error) {
+	secret := &corev1.Secret{}
+	if err := get(meta, secret); err != nil {
+		return nil, err
+	}
+	data := make(map[string]interface{}, len(secret.Data))
+	for k, v := range secret.Data {
+		data[k] = string(v)
+	}
+	return map[string]interface{}{"data": data}, nil
+}
+
+// ObjectCmd looks up a Kubernetes object
+type ObjectCmd struct{}
+
+func newObjectCmd(_ cue.Value) (registry.Runner, error) {
+	return &ObjectCmd{}, nil
+}
+
+// Run returns the object given its apiVersion, kind and name
+func (c *ObjectCmd) Run(meta *registry.Meta) (interface{}, error) {
+	u := &unstructured.Unstructured{}
+	u.SetAPIVersion(meta.String("apiVersion"))
+	u.SetKind(meta.String("kind"))
+	if err := get(meta, u); err != nil {
+		return nil, err
+	}
+	return u.Object, nil
+}
+
+// get reads the object by the name in the task, the objects can only be read from the namespace of the application
+func get(meta *registry.Meta, obj runtime.Object) error {
+	name := meta.String("name")
+	namespace := meta.Namespace
+	if v := meta.Obj.Lookup("namespace"); v.Exists() {
+		ns, err := v.String()
+		if err != nil {
+			return fmt.Errorf("invalid namespace, %w", err)
+		}
+		if len(namespace) != 0 && ns != namespace {
+			return fmt.Errorf("cannot read the objects in namespace %s from namespace %s", ns, namespace)
+		}
+		namespace = ns
+	}
+	if meta.Err != nil {
+		return meta.Err
+	}
+	if meta.Client == nil {
+		return fmt.Errorf("there is no client to read the cluster")
+	}
+	ctx := meta.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return meta.Client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, obj)
+}
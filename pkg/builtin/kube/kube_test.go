@@ -0,0 +1,92 @@
+package kube
+
+import (
+	"testing"
+
+	"cuelang.org/go/cue"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/pkg/builtin/registry"
+)
+
+func TestKubeCmds(t *testing.T) {
+	cli := fake.NewFakeClient(
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "app-config", Namespace: "default"},
+			Data:       map[string]string{"endpoint": "db.example.com"},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+			Data:       map[string][]byte{"password": []byte("secret")},
+		},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+			Spec:       corev1.ServiceSpec{ClusterIP: "10.0.0.1"},
+		})
+	testCases := map[string]struct {
+		newRunner registry.RunnerFunc
+		task      string
+		namespace string
+		expected  interface{}
+		hasErr    bool
+	}{
+		"configmap": {
+			newRunner: newConfigMapCmd,
+			task:      `{name: "app-config"}`,
+			namespace: "default",
+			expected:  map[string]interface{}{"data": map[string]interface{}{"endpoint": "db.example.com"}},
+		},
+		"secret": {
+			newRunner: newSecretCmd,
+			task:      `{name: "db", namespace: "default"}`,
+			namespace: "default",
+			expected:  map[string]interface{}{"data": map[string]interface{}{"password": "secret"}},
+		},
+		"secret in another namespace": {
+			newRunner: newSecretCmd,
+			task:      `{name: "db", namespace: "default"}`,
+			namespace: "vela-system",
+			hasErr:    true,
+		},
+		"secret not found": {
+			newRunner: newSecretCmd,
+			task:      `{name: "cache"}`,
+			namespace: "default",
+			hasErr:    true,
+		},
+		"no name": {
+			newRunner: newConfigMapCmd,
+			task:      `{}`,
+			namespace: "default",
+			hasErr:    true,
+		},
+	}
+	for name, tc := range testCases {
+		r := cue.Runtime{}
+		inst, err := r.Compile("", tc.task)
+		assert.NoError(t, err, name)
+		runner, _ := tc.newRunner(cue.Value{})
+		got, err := runner.Run(&registry.Meta{Obj: inst.Value(), Client: cli, Namespace: tc.namespace})
+		if tc.hasErr {
+			assert.Error(t, err, name)
+			continue
+		}
+		assert.NoError(t, err, name)
+		assert.Equal(t, tc.expected, got, name)
+	}
+
+	r := cue.Runtime{}
+	inst, err := r.Compile("", `{apiVersion: "v1", kind: "Service", name: "db"}`)
+	assert.NoError(t, err)
+	runner, _ := newObjectCmd(cue.Value{})
+	got, err := runner.Run(&registry.Meta{Obj: inst.Value(), Client: cli, Namespace: "default"})
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.0.1", got.(map[string]interface{})["spec"].(map[string]interface{})["clusterIP"])
+
+	// the cluster can't be read without a client
+	_, err = runner.Run(&registry.Meta{Obj: inst.Value(), Namespace: "default"})
+	assert.Error(t, err)
+}
@@ -8,6 +8,7 @@ import (
 
 	"cuelang.org/go/cue"
 	"cuelang.org/go/cue/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 // Meta provides context for running a task.
@@ -18,6 +19,11 @@ type Meta struct {
 	Stderr  io.Writer
 	Obj     cue.Value
 	Err     error
+
+	// Client reads the cluster for the tasks that need it
+	Client client.Reader
+	// Namespace is the default namespace of the resources the task reads
+	Namespace string
 }
 
 // Lookup fetches the value of context by filed
@@ -0,0 +1,102 @@
+package rollout
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+)
+
+// recordBatchStartTime remembers when the current batch starts to roll so that we can check its deadline
+func (r *Controller) recordBatchStartTime() {
+	switch r.rolloutStatus.BatchRollingState {
+	case v1alpha1.BatchInitializingState, v1alpha1.BatchWaitingApprovalState:
+		// the batch has not started yet
+		r.rolloutStatus.BatchStartTime = nil
+	default:
+		if r.rolloutStatus.BatchStartTime == nil {
+			now := metav1.Now()
+			r.rolloutStatus.BatchStartTime = &now
+		}
+	}
+}
+
+// excludePausedTime remembers when the rollout is paused and moves the start times forward by the paused
+// duration when it resumes, so the progress deadlines don't count the time the rollout is paused
+func (r *Controller) excludePausedTime() {
+	status := r.rolloutStatus
+	if r.rolloutSpec.Paused {
+		if status.PausedTime == nil {
+			now := metav1.Now()
+			status.PausedTime = &now
+		}
+		return
+	}
+	if status.PausedTime == nil {
+		return
+	}
+	paused := time.Since(status.PausedTime.Time)
+	for _, startTime := range []*metav1.Time{status.RolloutStartTime, status.BatchStartTime} {
+		if startTime != nil {
+			startTime.Time = startTime.Add(paused)
+		}
+	}
+	klog.InfoS("the rollout resumed", "paused duration", paused)
+	status.PausedTime = nil
+}
+
+// checkProgressDeadline fails the rollout if the rollout or the current batch passes its progress deadline
+// it returns true if the rollout failed
+func (r *Controller) checkProgressDeadline() bool {
+	switch r.rolloutStatus.RollingState {
+	case v1alpha1.VerifyingState, v1alpha1.InitializingState, v1alpha1.RollingInBatchesState,
+		v1alpha1.FinalisingState:
+	default:
+		return false
+	}
+	if deadline := r.rolloutSpec.ProgressDeadlineSeconds; deadline != nil &&
+		deadlinePassed(r.rolloutStatus.RolloutStartTime, *deadline) {
+		r.failOnDeadline(fmt.Sprintf("the rollout did not finish within the progress deadline of %d seconds",
+			*deadline))
+		return true
+	}
+	if r.rolloutStatus.RollingState != v1alpha1.RollingInBatchesState {
+		return false
+	}
+	switch r.rolloutStatus.BatchRollingState {
+	case v1alpha1.BatchInRollingState, v1alpha1.BatchVerifyingState, v1alpha1.BatchFinalizingState:
+	default:
+		return false
+	}
+	currentBatch := r.rolloutStatus.CurrentBatch
+	if int(currentBatch) >= len(r.rolloutSpec.RolloutBatches) {
+		return false
+	}
+	if deadline := r.rolloutSpec.RolloutBatches[currentBatch].ProgressDeadlineSeconds; deadline != nil &&
+		deadlinePassed(r.rolloutStatus.BatchStartTime, *deadline) {
+		r.failOnDeadline(fmt.Sprintf("the batch num = %d did not become ready within the progress deadline "+
+			"of %d seconds, upgraded ready replicas = %d", currentBatch, *deadline,
+			r.rolloutStatus.UpgradedReadyReplicas))
+		return true
+	}
+	return false
+}
+
+func (r *Controller) failOnDeadline(reason string) {
+	klog.InfoS("the rollout passed its progress deadline", "current batch", r.rolloutStatus.CurrentBatch,
+		"reason", reason)
+	r.recorder.Event(r.parentController, event.Warning("Progress deadline exceeded", fmt.Errorf("%s", reason)))
+	r.rolloutStatus.RolloutFailed(reason)
+}
+
+// deadlinePassed checks if the deadline in seconds passed since the start time
+func deadlinePassed(startTime *metav1.Time, deadlineSeconds int32) bool {
+	if startTime == nil {
+		return false
+	}
+	return time.Since(startTime.Time) > time.Duration(deadlineSeconds)*time.Second
+}
@@ -0,0 +1,102 @@
+package rollout
+
+import (
+	"testing"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+)
+
+func newDeadlineTestController(batchState v1alpha1.BatchRollingState, rolloutStarted,
+	batchStarted time.Duration) *Controller {
+	rolloutStartTime := metav1.NewTime(time.Now().Add(-rolloutStarted))
+	batchStartTime := metav1.NewTime(time.Now().Add(-batchStarted))
+	return &Controller{
+		recorder:         event.NewNopRecorder(),
+		parentController: testParent.DeepCopy(),
+		rolloutSpec: &v1alpha1.RolloutPlan{
+			ProgressDeadlineSeconds: pointer.Int32Ptr(600),
+			RolloutBatches: []v1alpha1.RolloutBatch{
+				{ProgressDeadlineSeconds: pointer.Int32Ptr(60)},
+				{},
+			},
+		},
+		rolloutStatus: &v1alpha1.RolloutStatus{
+			RollingState:      v1alpha1.RollingInBatchesState,
+			BatchRollingState: batchState,
+			RolloutStartTime:  &rolloutStartTime,
+			BatchStartTime:    &batchStartTime,
+		},
+	}
+}
+
+func TestCheckProgressDeadline(t *testing.T) {
+	r := newDeadlineTestController(v1alpha1.BatchVerifyingState, time.Minute, 30*time.Second)
+	assert.False(t, r.checkProgressDeadline())
+	assert.Equal(t, v1alpha1.RollingInBatchesState, r.rolloutStatus.RollingState)
+
+	// the batch is not ready in time
+	r = newDeadlineTestController(v1alpha1.BatchVerifyingState, time.Minute, 2*time.Minute)
+	assert.True(t, r.checkProgressDeadline())
+	assert.Equal(t, v1alpha1.RolloutFailedState, r.rolloutStatus.RollingState)
+
+	// the batch deadline doesn't count the time waiting for approval
+	r = newDeadlineTestController(v1alpha1.BatchWaitingApprovalState, time.Minute, 2*time.Minute)
+	assert.False(t, r.checkProgressDeadline())
+
+	// the batch without a deadline
+	r = newDeadlineTestController(v1alpha1.BatchInRollingState, time.Minute, 2*time.Minute)
+	r.rolloutStatus.CurrentBatch = 1
+	assert.False(t, r.checkProgressDeadline())
+
+	// the whole rollout is not finished in time
+	r = newDeadlineTestController(v1alpha1.BatchInRollingState, 11*time.Minute, 0)
+	r.rolloutStatus.CurrentBatch = 1
+	assert.True(t, r.checkProgressDeadline())
+	assert.Equal(t, v1alpha1.RolloutFailedState, r.rolloutStatus.RollingState)
+
+	// no deadline once the rollout finishes
+	r = newDeadlineTestController(v1alpha1.BatchReadyState, 11*time.Minute, 0)
+	r.rolloutStatus.RollingState = v1alpha1.RolloutSucceedState
+	assert.False(t, r.checkProgressDeadline())
+}
+
+func TestExcludePausedTime(t *testing.T) {
+	r := newDeadlineTestController(v1alpha1.BatchVerifyingState, 9*time.Minute, 50*time.Second)
+	r.rolloutSpec.Paused = true
+	r.excludePausedTime()
+	assert.NotNil(t, r.rolloutStatus.PausedTime)
+
+	// the rollout was paused for 5 minutes
+	pausedTime := metav1.NewTime(time.Now().Add(-5 * time.Minute))
+	r.rolloutStatus.PausedTime = &pausedTime
+	r.excludePausedTime()
+	assert.Equal(t, pausedTime, *r.rolloutStatus.PausedTime)
+
+	// the deadlines don't count the paused time after the rollout resumes
+	r.rolloutSpec.Paused = false
+	r.excludePausedTime()
+	assert.Nil(t, r.rolloutStatus.PausedTime)
+	assert.False(t, r.checkProgressDeadline())
+	assert.Equal(t, v1alpha1.RollingInBatchesState, r.rolloutStatus.RollingState)
+	assert.InDelta(t, 4*time.Minute, time.Since(r.rolloutStatus.RolloutStartTime.Time), float64(time.Second))
+}
+
+func TestRecordBatchStartTime(t *testing.T) {
+	r := newDeadlineTestController(v1alpha1.BatchInitializingState, 0, 0)
+	r.recordBatchStartTime()
+	assert.Nil(t, r.rolloutStatus.BatchStartTime)
+
+	r.rolloutStatus.BatchRollingState = v1alpha1.BatchInRollingState
+	r.recordBatchStartTime()
+	startTime := r.rolloutStatus.BatchStartTime
+	assert.NotNil(t, startTime)
+	r.rolloutStatus.BatchRollingState = v1alpha1.BatchVerifyingState
+	r.recordBatchStartTime()
+	assert.Equal(t, startTime, r.rolloutStatus.BatchStartTime)
+}
@@ -0,0 +1,61 @@
+package rollout
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/pointer"
+
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+)
+
+// MaxRolloutHistory is the number of finished rollouts we keep in the status
+const MaxRolloutHistory = 10
+
+// IsRolloutRunning checks if the rollout has not reached a terminal state
+func IsRolloutRunning(state v1alpha1.RollingState) bool {
+	switch state {
+	case "", v1alpha1.RolloutSucceedState, v1alpha1.RolloutFailedState, v1alpha1.RolloutRolledBackState:
+		return false
+	default:
+		return true
+	}
+}
+
+// RecordRolloutHistory records the rollout in the history once it reaches a terminal state from prevState
+// a failed rollout that is rolled back later on updates its own record instead of adding a new one
+func RecordRolloutHistory(prevState v1alpha1.RollingState, status *v1alpha1.RolloutStatus,
+	sourceRevision, targetRevision string) {
+	if prevState == status.RollingState || IsRolloutRunning(status.RollingState) {
+		return
+	}
+	record := v1alpha1.RolloutRecord{
+		SourceRevision: sourceRevision,
+		TargetRevision: targetRevision,
+		StartTime:      status.RolloutStartTime.DeepCopy(),
+		EndTime:        metav1.Now(),
+		FinalState:     status.RollingState,
+	}
+	if status.RollingState == v1alpha1.RolloutFailedState {
+		record.FailedBatch = pointer.Int32Ptr(status.CurrentBatch)
+	}
+	if last := len(status.RolloutHistory) - 1; last >= 0 {
+		lastRecord := status.RolloutHistory[last]
+		record.Revision = lastRecord.Revision + 1
+		if lastRecord.TargetRevision == targetRevision && lastRecord.StartTime.Equal(record.StartTime) {
+			// it's the same rollout
+			record.Revision = lastRecord.Revision
+			if record.FailedBatch == nil {
+				record.FailedBatch = lastRecord.FailedBatch
+			}
+			status.RolloutHistory = status.RolloutHistory[:last]
+		}
+	} else {
+		record.Revision = 1
+	}
+	klog.InfoS("record the finished rollout", "revision", record.Revision, "source revision", sourceRevision,
+		"target revision", targetRevision, "final state", record.FinalState)
+	status.RolloutHistory = append(status.RolloutHistory, record)
+	if len(status.RolloutHistory) > MaxRolloutHistory {
+		status.RolloutHistory = status.RolloutHistory[len(status.RolloutHistory)-MaxRolloutHistory:]
+	}
+}
@@ -0,0 +1,64 @@
+package rollout
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+)
+
+func TestIsRolloutRunning(t *testing.T) {
+	assert.False(t, IsRolloutRunning(""))
+	assert.False(t, IsRolloutRunning(v1alpha1.RolloutSucceedState))
+	assert.False(t, IsRolloutRunning(v1alpha1.RolloutFailedState))
+	assert.True(t, IsRolloutRunning(v1alpha1.RollingInBatchesState))
+	assert.True(t, IsRolloutRunning(v1alpha1.RollingBackState))
+}
+
+func TestRecordRolloutHistory(t *testing.T) {
+	startTime := metav1.Now()
+	status := &v1alpha1.RolloutStatus{
+		RollingState:     v1alpha1.RollingInBatchesState,
+		RolloutStartTime: &startTime,
+	}
+	// nothing to record while the rollout is running
+	RecordRolloutHistory(v1alpha1.InitializingState, status, "app-v1", "app-v2")
+	assert.Empty(t, status.RolloutHistory)
+
+	status.RollingState = v1alpha1.RolloutSucceedState
+	RecordRolloutHistory(v1alpha1.FinalisingState, status, "app-v1", "app-v2")
+	assert.Len(t, status.RolloutHistory, 1)
+	assert.Equal(t, int64(1), status.RolloutHistory[0].Revision)
+	assert.Equal(t, v1alpha1.RolloutSucceedState, status.RolloutHistory[0].FinalState)
+	assert.Equal(t, &startTime, status.RolloutHistory[0].StartTime)
+	assert.Nil(t, status.RolloutHistory[0].FailedBatch)
+	// the rollout is only recorded once
+	RecordRolloutHistory(v1alpha1.RolloutSucceedState, status, "app-v1", "app-v2")
+	assert.Len(t, status.RolloutHistory, 1)
+
+	// a failed rollout that is rolled back later keeps one record
+	startTime = metav1.NewTime(startTime.Add(3600e9))
+	status.RolloutStartTime = &startTime
+	status.RollingState = v1alpha1.RolloutFailedState
+	status.CurrentBatch = 2
+	RecordRolloutHistory(v1alpha1.RollingInBatchesState, status, "app-v2", "app-v3")
+	status.RollingState = v1alpha1.RolloutRolledBackState
+	status.CurrentBatch = 0
+	RecordRolloutHistory(v1alpha1.RollingBackState, status, "app-v2", "app-v3")
+	assert.Len(t, status.RolloutHistory, 2)
+	assert.Equal(t, int64(2), status.RolloutHistory[1].Revision)
+	assert.Equal(t, v1alpha1.RolloutRolledBackState, status.RolloutHistory[1].FinalState)
+	assert.Equal(t, int32(2), *status.RolloutHistory[1].FailedBatch)
+
+	// the history is bounded
+	for i := 0; i < MaxRolloutHistory; i++ {
+		status.RolloutStartTime = nil
+		status.RollingState = v1alpha1.RolloutSucceedState
+		RecordRolloutHistory(v1alpha1.FinalisingState, status, "app-v2", fmt.Sprintf("app-v%d", i+4))
+	}
+	assert.Len(t, status.RolloutHistory, MaxRolloutHistory)
+	assert.Equal(t, int64(MaxRolloutHistory+2), status.RolloutHistory[MaxRolloutHistory-1].Revision)
+}
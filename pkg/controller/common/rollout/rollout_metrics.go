@@ -0,0 +1,222 @@
+package rollout
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/klog/v2"
+
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+	"github.com/oam-dev/kubevela/pkg/controller/common"
+)
+
+const (
+	// the default interval between two evaluations of the same metric
+	defaultMetricInterval = time.Minute
+	// the longest time a metric query can take, a query is also bounded by the interval of its metric
+	maxMetricQueryTimeout = 30 * time.Second
+	// the default interval in the query
+	defaultMetricIntervalWindow = "1m"
+	// the placeholder in the query that is replaced by the metric interval
+	metricIntervalPlaceholder = "{{interval}}"
+	// the only metrics provider type we support for now
+	prometheusProviderType = "prometheus"
+)
+
+// prometheusResponse is the part of the prometheus query API response that we care about
+type prometheusResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	Data   struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+// queryPrometheus issues an instant query to a prometheus compatible server and returns the single value of the result,
+// the query is cancelled after the timeout so that a hanging server does not block the rollout
+func queryPrometheus(ctx context.Context, address, query string, timeout time.Duration) (float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	endpoint, err := url.Parse(address)
+	if err != nil {
+		return 0, err
+	}
+	endpoint.Path = strings.TrimSuffix(endpoint.Path, "/") + "/api/v1/query"
+	endpoint.RawQuery = url.Values{"query": []string{query}}.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint.String(), nil)
+	if err != nil {
+		return 0, err
+	}
+	r, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = r.Body.Close()
+	}()
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return 0, err
+	}
+	klog.V(common.LogDebugWithContent).InfoS("got the metric query response", "query", query, "body", string(body))
+
+	var resp prometheusResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return 0, fmt.Errorf("failed to parse the metric query response, http status = %d: %w", r.StatusCode, err)
+	}
+	if resp.Status != "success" {
+		return 0, fmt.Errorf("the metric query failed, http status = %d, error = %s", r.StatusCode, resp.Error)
+	}
+	return parsePrometheusResult(resp.Data.ResultType, resp.Data.Result)
+}
+
+// parsePrometheusResult extracts the single value from a scalar or a vector result
+func parsePrometheusResult(resultType string, result json.RawMessage) (float64, error) {
+	var sample []interface{}
+	switch resultType {
+	case "scalar":
+		if err := json.Unmarshal(result, &sample); err != nil {
+			return 0, err
+		}
+	case "vector":
+		var vector []struct {
+			Value []interface{} `json:"value"`
+		}
+		if err := json.Unmarshal(result, &vector); err != nil {
+			return 0, err
+		}
+		if len(vector) != 1 {
+			return 0, fmt.Errorf("the metric query has to return exactly one value, got %d", len(vector))
+		}
+		sample = vector[0].Value
+	default:
+		return 0, fmt.Errorf("the metric query result type `%s` is not supported", resultType)
+	}
+	// a sample is a pair of timestamp and the string value
+	if len(sample) != 2 {
+		return 0, fmt.Errorf("the metric query returns a malformed sample %v", sample)
+	}
+	value, ok := sample[1].(string)
+	if !ok {
+		return 0, fmt.Errorf("the metric query returns a malformed value %v", sample[1])
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+// getMetricInterval returns the interval of the metric or the default
+func getMetricInterval(metric v1alpha1.CanaryMetric) (time.Duration, error) {
+	if len(metric.Interval) == 0 {
+		return defaultMetricInterval, nil
+	}
+	return time.ParseDuration(metric.Interval)
+}
+
+// getMetricQueryTimeout returns the timeout of a metric query, it's the interval of the metric
+// as the next evaluation is due by then, but no longer than the max timeout
+func getMetricQueryTimeout(metric v1alpha1.CanaryMetric) time.Duration {
+	interval, err := getMetricInterval(metric)
+	if err != nil || interval <= 0 || interval > maxMetricQueryTimeout {
+		return maxMetricQueryTimeout
+	}
+	return interval
+}
+
+// parseRangeValue parses the bound of a metric range, a string bound can be a float number
+func parseRangeValue(v *intstr.IntOrString) (float64, error) {
+	if v.Type == intstr.Int {
+		return float64(v.IntVal), nil
+	}
+	return strconv.ParseFloat(v.StrVal, 64)
+}
+
+// isMetricInRange checks if the value is within the expected range of the metric
+func isMetricInRange(metric v1alpha1.CanaryMetric, value float64) (bool, error) {
+	// NaN is not comparable, prometheus returns it when there is no sample to compute the metric from
+	if math.IsNaN(value) {
+		return false, fmt.Errorf("the metric %s is not a number", metric.Name)
+	}
+	if metric.MetricsRange == nil {
+		return true, nil
+	}
+	if metric.MetricsRange.Min != nil {
+		min, err := parseRangeValue(metric.MetricsRange.Min)
+		if err != nil {
+			return false, fmt.Errorf("invalid min value of the metric %s: %w", metric.Name, err)
+		}
+		if value < min {
+			return false, nil
+		}
+	}
+	if metric.MetricsRange.Max != nil {
+		max, err := parseRangeValue(metric.MetricsRange.Max)
+		if err != nil {
+			return false, fmt.Errorf("invalid max value of the metric %s: %w", metric.Name, err)
+		}
+		if value > max {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// validateCanaryMetric makes sure that we can evaluate the metric, the rollout can't succeed if we can't
+func validateCanaryMetric(metric v1alpha1.CanaryMetric) error {
+	if metric.TemplateRef != nil {
+		return fmt.Errorf("the metric %s references a metric template which is not supported", metric.Name)
+	}
+	if len(metric.Query) == 0 || metric.MetricsProvider == nil {
+		return fmt.Errorf("the metric %s has no query or metrics provider", metric.Name)
+	}
+	if len(metric.MetricsProvider.Type) != 0 && metric.MetricsProvider.Type != prometheusProviderType {
+		return fmt.Errorf("the metrics provider type `%s` is not supported", metric.MetricsProvider.Type)
+	}
+	if _, err := getMetricInterval(metric); err != nil {
+		return fmt.Errorf("invalid interval of the metric %s: %w", metric.Name, err)
+	}
+	// make sure that the range is valid
+	if metric.MetricsRange != nil {
+		for _, bound := range []*intstr.IntOrString{metric.MetricsRange.Min, metric.MetricsRange.Max} {
+			if bound == nil {
+				continue
+			}
+			if _, err := parseRangeValue(bound); err != nil {
+				return fmt.Errorf("invalid range of the metric %s: %w", metric.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// evaluateCanaryMetric queries the metric and checks if it is in the expected range
+// the metric has to be validated before
+func evaluateCanaryMetric(ctx context.Context, metric v1alpha1.CanaryMetric) (v1alpha1.CanaryMetricStatus, error) {
+	metricStatus := v1alpha1.CanaryMetricStatus{
+		Name:               metric.Name,
+		LastEvaluationTime: metav1.Now(),
+	}
+	// prometheus does not take the go duration format, so we use the interval as it is
+	interval := metric.Interval
+	if len(interval) == 0 {
+		interval = defaultMetricIntervalWindow
+	}
+	query := strings.ReplaceAll(metric.Query, metricIntervalPlaceholder, interval)
+	value, err := queryPrometheus(ctx, metric.MetricsProvider.Address, query, getMetricQueryTimeout(metric))
+	if err != nil {
+		return metricStatus, err
+	}
+	metricStatus.Value = strconv.FormatFloat(value, 'f', -1, 64)
+	metricStatus.InRange, err = isMetricInRange(metric, value)
+	return metricStatus, err
+}
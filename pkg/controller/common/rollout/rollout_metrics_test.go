@@ -0,0 +1,167 @@
+package rollout
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+)
+
+// newFakePrometheus returns a server that answers every instant query with the given value
+func newFakePrometheus(value string, queries *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/query" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		*queries = append(*queries, r.URL.Query().Get("query"))
+		_, _ = fmt.Fprintf(w, `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1609459200,"%s"]}]}}`, value)
+	}))
+}
+
+func newMetricTestController(metric v1alpha1.CanaryMetric) *Controller {
+	min, max := intstr.FromInt(90), intstr.FromString("99.5")
+	metric.MetricsRange = &v1alpha1.MetricsExpectedRange{Min: &min, Max: &max}
+	return &Controller{
+		recorder:         event.NewNopRecorder(),
+		parentController: &v1alpha2.ApplicationDeployment{},
+		rolloutSpec: &v1alpha1.RolloutPlan{
+			RolloutBatches: []v1alpha1.RolloutBatch{{CanaryMetric: []v1alpha1.CanaryMetric{metric}}},
+		},
+		rolloutStatus: &v1alpha1.RolloutStatus{
+			RollingState:      v1alpha1.RollingInBatchesState,
+			BatchRollingState: v1alpha1.BatchVerifyingState,
+		},
+	}
+}
+
+func TestEvaluateCanaryMetrics(t *testing.T) {
+	var queries []string
+	server := newFakePrometheus("95.2", &queries)
+	defer server.Close()
+
+	r := newMetricTestController(v1alpha1.CanaryMetric{
+		Name:            "success-rate",
+		Interval:        "5m",
+		Query:           `sum(rate(requests_total{code="200"}[{{interval}}]))`,
+		MetricsProvider: &v1alpha1.MetricsProvider{Address: server.URL},
+	})
+	assert.True(t, r.evaluateCanaryMetrics(context.Background()))
+	assert.Equal(t, []string{`sum(rate(requests_total{code="200"}[5m]))`}, queries)
+	assert.Len(t, r.rolloutStatus.CanaryMetricsStatus, 1)
+	assert.Equal(t, "95.2", r.rolloutStatus.CanaryMetricsStatus[0].Value)
+	assert.True(t, r.rolloutStatus.CanaryMetricsStatus[0].InRange)
+
+	// the metric is not queried again until the interval is up
+	assert.True(t, r.evaluateCanaryMetrics(context.Background()))
+	assert.Len(t, queries, 1)
+	assert.Equal(t, v1alpha1.BatchVerifyingState, r.rolloutStatus.BatchRollingState)
+}
+
+func TestEvaluateCanaryMetricsOutOfRange(t *testing.T) {
+	var queries []string
+	server := newFakePrometheus("80", &queries)
+	defer server.Close()
+
+	r := newMetricTestController(v1alpha1.CanaryMetric{
+		Name:            "success-rate",
+		Query:           "success_rate",
+		MetricsProvider: &v1alpha1.MetricsProvider{Address: server.URL},
+	})
+	assert.False(t, r.evaluateCanaryMetrics(context.Background()))
+	assert.Equal(t, v1alpha1.RolloutFailedState, r.rolloutStatus.RollingState)
+	assert.Equal(t, v1alpha1.BatchRolloutFailedState, r.rolloutStatus.BatchRollingState)
+	assert.Equal(t, "80", r.rolloutStatus.CanaryMetricsStatus[0].Value)
+	assert.False(t, r.rolloutStatus.CanaryMetricsStatus[0].InRange)
+}
+
+func TestEvaluateCanaryMetricsUnavailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	// the batch waits if the metrics server is not available
+	r := newMetricTestController(v1alpha1.CanaryMetric{
+		Name:            "success-rate",
+		Query:           "success_rate",
+		MetricsProvider: &v1alpha1.MetricsProvider{Address: server.URL},
+	})
+	assert.False(t, r.evaluateCanaryMetrics(context.Background()))
+	assert.Equal(t, v1alpha1.BatchVerifyingState, r.rolloutStatus.BatchRollingState)
+	assert.Empty(t, r.rolloutStatus.CanaryMetricsStatus)
+
+	// the batch fails if the metric can never be evaluated
+	r = newMetricTestController(v1alpha1.CanaryMetric{
+		Name:            "success-rate",
+		Query:           "success_rate",
+		MetricsProvider: &v1alpha1.MetricsProvider{Type: "datadog", Address: server.URL},
+	})
+	assert.False(t, r.evaluateCanaryMetrics(context.Background()))
+	assert.Equal(t, v1alpha1.BatchRolloutFailedState, r.rolloutStatus.BatchRollingState)
+}
+
+func TestEvaluateCanaryMetricsNaN(t *testing.T) {
+	var queries []string
+	server := newFakePrometheus("NaN", &queries)
+	defer server.Close()
+
+	// the batch waits if the metric has no value yet
+	r := newMetricTestController(v1alpha1.CanaryMetric{
+		Name:            "success-rate",
+		Query:           "success_rate",
+		MetricsProvider: &v1alpha1.MetricsProvider{Address: server.URL},
+	})
+	assert.False(t, r.evaluateCanaryMetrics(context.Background()))
+	assert.Equal(t, v1alpha1.BatchVerifyingState, r.rolloutStatus.BatchRollingState)
+	assert.Len(t, queries, 1)
+}
+
+func TestEvaluateCanaryMetricsTemplateRef(t *testing.T) {
+	r := newMetricTestController(v1alpha1.CanaryMetric{
+		Name:        "success-rate",
+		TemplateRef: &runtimev1alpha1.TypedReference{Kind: "MetricTemplate", Name: "success-rate"},
+	})
+	assert.False(t, r.evaluateCanaryMetrics(context.Background()))
+	assert.Equal(t, v1alpha1.BatchRolloutFailedState, r.rolloutStatus.BatchRollingState)
+}
+
+func TestQueryPrometheusTimeout(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer server.Close()
+	defer close(done)
+
+	metric := v1alpha1.CanaryMetric{Name: "success-rate", Interval: "100ms"}
+	assert.Equal(t, 100*time.Millisecond, getMetricQueryTimeout(metric))
+	assert.Equal(t, maxMetricQueryTimeout, getMetricQueryTimeout(v1alpha1.CanaryMetric{}))
+
+	start := time.Now()
+	_, err := queryPrometheus(context.Background(), server.URL, "success_rate", getMetricQueryTimeout(metric))
+	assert.Error(t, err)
+	assert.Less(t, int64(time.Since(start)), int64(5*time.Second))
+}
+
+func TestParsePrometheusResult(t *testing.T) {
+	value, err := parsePrometheusResult("scalar", []byte(`[1609459200, "0.25"]`))
+	assert.NoError(t, err)
+	assert.Equal(t, 0.25, value)
+
+	_, err = parsePrometheusResult("vector", []byte(`[]`))
+	assert.Error(t, err)
+
+	_, err = parsePrometheusResult("matrix", []byte(`[]`))
+	assert.Error(t, err)
+}
@@ -3,12 +3,13 @@ package rollout
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/crossplane/crossplane-runtime/pkg/event"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
-	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -70,7 +71,8 @@ func (r *Controller) Reconcile(ctx context.Context) (res reconcile.Result, statu
 
 	defer func() {
 		if status.RollingState == v1alpha1.RolloutFailedState ||
-			status.RollingState == v1alpha1.RolloutSucceedState {
+			status.RollingState == v1alpha1.RolloutSucceedState ||
+			status.RollingState == v1alpha1.RolloutRolledBackState {
 			// no need to requeue if we reach the terminal states
 			res = reconcile.Result{}
 		} else {
@@ -87,38 +89,123 @@ func (r *Controller) Reconcile(ctx context.Context) (res reconcile.Result, statu
 		return
 	}
 
-	switch r.rolloutStatus.RollingState {
+	rollingState := r.rolloutStatus.RollingState
+	if r.tryAbortRollout() {
+		r.handleRolloutFailure(true)
+		return res, r.rolloutStatus
+	}
+	r.excludePausedTime()
+	if !r.rolloutSpec.Paused && r.checkProgressDeadline() {
+		r.handleRolloutFailure(true)
+		return res, r.rolloutStatus
+	}
+
+	switch rollingState {
 	case v1alpha1.VerifyingState:
+		if r.rolloutStatus.RolloutStartTime == nil {
+			now := metav1.Now()
+			r.rolloutStatus.RolloutStartTime = &now
+		}
 		r.rolloutStatus = workloadController.Verify(ctx)
 
 	case v1alpha1.InitializingState:
-		if err := r.initializeRollout(ctx); err == nil {
+		if r.initializeRollout(ctx) {
 			r.rolloutStatus = workloadController.Initialize(ctx)
 		}
 
 	case v1alpha1.RollingInBatchesState:
 		r.reconcileBatchInRolling(ctx, workloadController)
+		r.recordBatchStartTime()
 
 	case v1alpha1.FinalisingState:
+		// all the traffic goes to the target before we clean up the source
+		if !r.finalizeTraffic(ctx) {
+			break
+		}
 		r.rolloutStatus = workloadController.Finalize(ctx)
 		// if we are still going to finalize it
 		if r.rolloutStatus.RollingState == v1alpha1.FinalisingState {
 			r.finalizeRollout(ctx)
 		}
 
-	case v1alpha1.RolloutSucceedState:
+	case v1alpha1.RollingBackState:
+		r.reconcileBatchInRollingBack(ctx, workloadController)
+
+	case v1alpha1.RolloutSucceedState, v1alpha1.RolloutRolledBackState:
 		// Nothing to do
 
 	case v1alpha1.RolloutFailedState:
-		// Nothing to do
+		// the rollback policy is handled below
 
 	default:
 		panic(fmt.Sprintf("illegal rollout status %+v", r.rolloutStatus))
 	}
 
+	if r.rolloutStatus.RollingState == v1alpha1.RolloutFailedState {
+		r.handleRolloutFailure(rollingState != v1alpha1.RolloutFailedState)
+	}
+
 	return res, r.rolloutStatus
 }
 
+// handleRolloutFailure applies the rollback policy to the upgraded pods after the rollout failed
+func (r *Controller) handleRolloutFailure(justFailed bool) {
+	switch r.rolloutSpec.RollbackPolicy {
+	case v1alpha1.RevertToSourceRollbackPolicyType:
+		if r.rolloutStatus.UpgradedReplicas == 0 {
+			// nothing to revert
+			return
+		}
+		klog.InfoS("start to roll back the failed rollout", "current batch", r.rolloutStatus.CurrentBatch,
+			"upgraded Replicas", r.rolloutStatus.UpgradedReplicas)
+		r.recorder.Event(r.parentController, event.Normal("Rollback started",
+			fmt.Sprintf("revert %d upgraded pods back to the source starting from the batch num = %d",
+				r.rolloutStatus.UpgradedReplicas, r.rolloutStatus.CurrentBatch)))
+		r.rolloutStatus.StateTransition(v1alpha1.RollingBackEvent)
+
+	case v1alpha1.PauseForManualRollbackPolicyType:
+		if justFailed {
+			r.recorder.Event(r.parentController, event.Warning("Rollout paused for manual decision",
+				fmt.Errorf("the rollout failed at the batch num = %d, set the rollback policy to %s to revert it",
+					r.rolloutStatus.CurrentBatch, v1alpha1.RevertToSourceRollbackPolicyType)))
+		}
+
+	default:
+		// leave the resources as they are
+	}
+}
+
+// reconcile logic when we are reverting a failed rollout
+func (r *Controller) reconcileBatchInRollingBack(ctx context.Context, workloadController workloads.WorkloadController) {
+	if r.rolloutSpec.Paused {
+		r.recorder.Event(r.parentController, event.Normal("Rollback paused", "Rollback paused"))
+		r.rolloutStatus.SetConditions(v1alpha1.NewPositiveCondition("Paused"))
+		return
+	}
+
+	// the traffic goes back to the source before we revert any pod
+	if !r.revertTraffic(ctx) {
+		return
+	}
+
+	switch r.rolloutStatus.BatchRollingState {
+	case v1alpha1.BatchInRollingState:
+		// revert the pods of the current batch
+		r.rolloutStatus = workloadController.RollbackOneBatchPods(ctx)
+
+	case v1alpha1.BatchVerifyingState:
+		// wait for the reverted pods to be available before we move to the previous batch
+		r.rolloutStatus = workloadController.CheckOneBatchRollback(ctx)
+		if r.rolloutStatus.RollingState == v1alpha1.RolloutRolledBackState {
+			r.recorder.Event(r.parentController, event.Normal("Rollout rolled back",
+				fmt.Sprintf("all the upgraded pods are reverted in %d steps", len(r.rolloutStatus.RollbackSteps))))
+		}
+
+	default:
+		panic(fmt.Sprintf("illegal rollback status %+v", r.rolloutStatus))
+	}
+}
+
 // reconcile logic when we are in the middle of rollout
 func (r *Controller) reconcileBatchInRolling(ctx context.Context, workloadController workloads.WorkloadController) {
 
@@ -140,6 +227,10 @@ func (r *Controller) reconcileBatchInRolling(ctx context.Context, workloadContro
 	case v1alpha1.BatchInitializingState:
 		r.initializeOneBatch(ctx)
 
+	case v1alpha1.BatchWaitingApprovalState:
+		// the batch is not rolled until the operator approves it
+		r.tryApproveBatch()
+
 	case v1alpha1.BatchInRollingState:
 		//  still rolling the batch, the batch rolling is not completed yet
 		r.rolloutStatus = workloadController.RolloutOneBatchPods(ctx)
@@ -147,12 +238,16 @@ func (r *Controller) reconcileBatchInRolling(ctx context.Context, workloadContro
 	case v1alpha1.BatchVerifyingState:
 		// verifying if the application is ready to roll
 		// need to check if they meet the availability requirements in the rollout spec.
-		// TODO: evaluate any metrics/analysis
-		r.rolloutStatus = workloadController.CheckOneBatchPods(ctx)
+		// the canary metrics have to be evaluated and in range before we check the pods
+		if r.evaluateCanaryMetrics(ctx) {
+			r.rolloutStatus = workloadController.CheckOneBatchPods(ctx)
+		}
 
 	case v1alpha1.BatchFinalizingState:
-		// all the pods in the are available
-		r.finalizeOneBatch(ctx)
+		// all the pods in the are available, shift the traffic of this batch to them before we move on
+		if r.shiftTraffic(ctx) {
+			r.finalizeOneBatch(ctx)
+		}
 
 	case v1alpha1.BatchReadyState:
 		// all the pods in the are upgraded and their state are ready
@@ -164,46 +259,76 @@ func (r *Controller) reconcileBatchInRolling(ctx context.Context, workloadContro
 	}
 }
 
-// all the common initialize work before we rollout
-func (r *Controller) initializeRollout(ctx context.Context) error {
+// all the common initialize work before we rollout, it returns true if we can initialize the workload
+func (r *Controller) initializeRollout(ctx context.Context) bool {
 	// call the pre-rollout webhooks
-	for _, rw := range r.rolloutSpec.RolloutWebhooks {
-		if rw.Type == v1alpha1.InitializeRolloutHook {
-			err := callWebhook(ctx, r.parentController, v1alpha1.InitializingState, rw)
-			if err != nil {
-				klog.ErrorS(err, "failed to invoke a webhook",
-					"webhook name", rw.Name, "webhook end point", rw.URL)
-				r.rolloutStatus.RolloutFailed("failed to invoke a webhook")
-				return err
-			}
-			klog.InfoS("successfully invoked a pre rollout webhook", "webhook name", rw.Name, "webhook end point",
-				rw.URL)
-		}
-	}
-
-	return nil
+	return r.invokeWebhooks(ctx, r.rolloutSpec.RolloutWebhooks, v1alpha1.InitializeRolloutHook,
+		v1alpha1.InitializingState)
 }
 
 // all the common initialize work before we rollout one batch of resources
 func (r *Controller) initializeOneBatch(ctx context.Context) {
-	rolloutHooks := r.gatherAllWebhooks()
 	// call all the pre-batch rollout webhooks
-	for _, rh := range rolloutHooks {
-		if rh.Type == v1alpha1.PreBatchRolloutHook {
-			err := callWebhook(ctx, r.parentController, v1alpha1.InitializingState, rh)
-			if err != nil {
-				klog.ErrorS(err, "failed to invoke a webhook",
-					"webhook name", rh.Name, "webhook end point", rh.URL)
-				r.rolloutStatus.RolloutFailed("failed to invoke a webhook")
-				return
-			}
-			klog.InfoS("successfully invoked a pre batch webhook", "webhook name", rh.Name, "webhook end point",
-				rh.URL)
-		}
+	if !r.invokeWebhooks(ctx, r.gatherAllWebhooks(), v1alpha1.PreBatchRolloutHook, v1alpha1.InitializingState) {
+		return
+	}
+	// the canary metrics are evaluated again in each batch
+	r.rolloutStatus.CanaryMetricsStatus = nil
+	if r.rolloutSpec.RolloutBatches[r.rolloutStatus.CurrentBatch].RequireApproval {
+		r.recorder.Event(r.parentController, event.Normal("Batch waiting for approval",
+			fmt.Sprintf("the batch num = %d requires a manual approval", r.rolloutStatus.CurrentBatch)))
+		r.rolloutStatus.StateTransition(v1alpha1.BatchWaitingApprovalEvent)
+		return
 	}
 	r.rolloutStatus.StateTransition(v1alpha1.InitializedOneBatchEvent)
 }
 
+// check if the operator approved the current batch
+func (r *Controller) tryApproveBatch() {
+	annotations := r.parentController.GetAnnotations()
+	approvedBatch, ok := annotations[oam.AnnotationRolloutApprovedBatch]
+	if !ok {
+		klog.V(common.LogDebug).InfoS("the current batch is waiting for approval", "current batch",
+			r.rolloutStatus.CurrentBatch)
+		return
+	}
+	// the approval is consumed no matter which batch it is for
+	delete(annotations, oam.AnnotationRolloutApprovedBatch)
+	r.parentController.SetAnnotations(annotations)
+	if approvedBatch != strconv.Itoa(int(r.rolloutStatus.CurrentBatch)) {
+		klog.InfoS("ignore the approval for another batch", "approved batch", approvedBatch,
+			"current batch", r.rolloutStatus.CurrentBatch)
+		return
+	}
+	klog.InfoS("the current batch is approved", "current batch", r.rolloutStatus.CurrentBatch)
+	r.recorder.Event(r.parentController, event.Normal("Batch approved",
+		fmt.Sprintf("the batch num = %d is approved", r.rolloutStatus.CurrentBatch)))
+	r.rolloutStatus.StateTransition(v1alpha1.BatchRolloutApprovedEvent)
+}
+
+// check if the operator wants to abort the rollout, it returns true if the rollout is aborted
+func (r *Controller) tryAbortRollout() bool {
+	annotations := r.parentController.GetAnnotations()
+	if _, ok := annotations[oam.AnnotationRolloutAbort]; !ok {
+		return false
+	}
+	delete(annotations, oam.AnnotationRolloutAbort)
+	r.parentController.SetAnnotations(annotations)
+	switch r.rolloutStatus.RollingState {
+	case v1alpha1.VerifyingState, v1alpha1.InitializingState, v1alpha1.RollingInBatchesState,
+		v1alpha1.FinalisingState:
+		klog.InfoS("the rollout is aborted", "current batch", r.rolloutStatus.CurrentBatch)
+		r.recorder.Event(r.parentController, event.Warning("Rollout aborted",
+			fmt.Errorf("the rollout is aborted at the batch num = %d", r.rolloutStatus.CurrentBatch)))
+		r.rolloutStatus.RolloutFailed("the rollout is aborted by the operator")
+		return true
+	default:
+		klog.InfoS("ignore the abort request since the rollout is not in progress",
+			"rollout state", r.rolloutStatus.RollingState)
+		return false
+	}
+}
+
 func (r *Controller) gatherAllWebhooks() []v1alpha1.RolloutWebhook {
 	// we go through the rollout level webhooks first
 	rolloutHooks := r.rolloutSpec.RolloutWebhooks
@@ -214,6 +339,77 @@ func (r *Controller) gatherAllWebhooks() []v1alpha1.RolloutWebhook {
 	return rolloutHooks
 }
 
+func (r *Controller) gatherAllCanaryMetrics() []v1alpha1.CanaryMetric {
+	// the rollout level metrics are checked in every batch
+	canaryMetrics := r.rolloutSpec.CanaryMetric
+	currentBatch := int(r.rolloutStatus.CurrentBatch)
+	canaryMetrics = append(canaryMetrics, r.rolloutSpec.RolloutBatches[currentBatch].CanaryMetric...)
+	return canaryMetrics
+}
+
+// evaluateCanaryMetrics evaluates the canary metrics of the current batch if their interval is up
+// it returns true if all the metrics have been evaluated in this batch and they are all in range
+// the batch fails as soon as one metric is out of range
+func (r *Controller) evaluateCanaryMetrics(ctx context.Context) bool {
+	evaluated := true
+	for _, metric := range r.gatherAllCanaryMetrics() {
+		if err := validateCanaryMetric(metric); err != nil {
+			r.failBatchOnMetric(err.Error())
+			return false
+		}
+		interval, _ := getMetricInterval(metric)
+		lastStatus := r.getCanaryMetricStatus(metric.Name)
+		if lastStatus != nil && time.Since(lastStatus.LastEvaluationTime.Time) < interval {
+			continue
+		}
+		metricStatus, err := evaluateCanaryMetric(ctx, metric)
+		if err != nil {
+			klog.ErrorS(err, "failed to evaluate a canary metric", "metric name", metric.Name,
+				"query", metric.Query)
+			r.rolloutStatus.RolloutRetry(fmt.Sprintf("failed to evaluate the metric %s: %s", metric.Name, err))
+			if lastStatus == nil {
+				evaluated = false
+			}
+			continue
+		}
+		klog.InfoS("evaluated a canary metric", "metric name", metric.Name, "value", metricStatus.Value,
+			"in range", metricStatus.InRange)
+		r.setCanaryMetricStatus(metricStatus)
+		if !metricStatus.InRange {
+			r.failBatchOnMetric(fmt.Sprintf("the metric %s is out of range, value = %s", metric.Name,
+				metricStatus.Value))
+			return false
+		}
+	}
+	return evaluated
+}
+
+func (r *Controller) getCanaryMetricStatus(name string) *v1alpha1.CanaryMetricStatus {
+	for i := range r.rolloutStatus.CanaryMetricsStatus {
+		if r.rolloutStatus.CanaryMetricsStatus[i].Name == name {
+			return &r.rolloutStatus.CanaryMetricsStatus[i]
+		}
+	}
+	return nil
+}
+
+func (r *Controller) setCanaryMetricStatus(metricStatus v1alpha1.CanaryMetricStatus) {
+	if lastStatus := r.getCanaryMetricStatus(metricStatus.Name); lastStatus != nil {
+		*lastStatus = metricStatus
+		return
+	}
+	r.rolloutStatus.CanaryMetricsStatus = append(r.rolloutStatus.CanaryMetricsStatus, metricStatus)
+}
+
+// failBatchOnMetric fails the current batch and the rollout because of a canary metric
+func (r *Controller) failBatchOnMetric(reason string) {
+	klog.InfoS("the batch failed the canary analysis", "current batch", r.rolloutStatus.CurrentBatch,
+		"reason", reason)
+	r.recorder.Event(r.parentController, event.Warning("Canary analysis failed", fmt.Errorf(reason)))
+	r.rolloutStatus.StateTransition(v1alpha1.BatchRolloutFailedEvent)
+	r.rolloutStatus.SetConditions(v1alpha1.NewNegativeCondition(v1alpha1.BatchRolloutFailed, reason))
+}
+
 // check if we can move to the next batch
 func (r *Controller) tryMovingToNextBatch() {
 	if r.rolloutSpec.BatchPartition == nil || *r.rolloutSpec.BatchPartition > r.rolloutStatus.CurrentBatch {
@@ -226,20 +422,9 @@ func (r *Controller) tryMovingToNextBatch() {
 }
 
 func (r *Controller) finalizeOneBatch(ctx context.Context) {
-	rolloutHooks := r.gatherAllWebhooks()
 	// call all the post-batch rollout webhooks
-	for _, rh := range rolloutHooks {
-		if rh.Type == v1alpha1.PostBatchRolloutHook {
-			err := callWebhook(ctx, r.parentController, v1alpha1.FinalisingState, rh)
-			if err != nil {
-				klog.ErrorS(err, "failed to invoke a webhook",
-					"webhook name", rh.Name, "webhook end point", rh.URL)
-				r.rolloutStatus.RolloutFailed("failed to invoke a webhook")
-				return
-			}
-			klog.InfoS("successfully invoked a post batch webhook", "webhook name", rh.Name, "webhook end point",
-				rh.URL)
-		}
+	if !r.invokeWebhooks(ctx, r.gatherAllWebhooks(), v1alpha1.PostBatchRolloutHook, v1alpha1.FinalisingState) {
+		return
 	}
 	// calculate the next phase
 	currentBatch := int(r.rolloutStatus.CurrentBatch)
@@ -261,19 +446,84 @@ func (r *Controller) finalizeOneBatch(ctx context.Context) {
 // all the common finalize work after we rollout
 func (r *Controller) finalizeRollout(ctx context.Context) {
 	// call the post-rollout webhooks
-	for _, rw := range r.rolloutSpec.RolloutWebhooks {
-		if rw.Type == v1alpha1.FinalizeRolloutHook {
-			err := callWebhook(ctx, r.parentController, v1alpha1.FinalisingState, rw)
-			if err != nil {
-				klog.ErrorS(err, "failed to invoke a webhook",
-					"webhook name", rw.Name, "webhook end point", rw.URL)
-				r.rolloutStatus.RolloutFailed("failed to invoke a post rollout webhook")
+	if !r.invokeWebhooks(ctx, r.rolloutSpec.RolloutWebhooks, v1alpha1.FinalizeRolloutHook,
+		v1alpha1.FinalisingState) {
+		return
+	}
+	r.rolloutStatus.StateTransition(v1alpha1.RollingFinalizedEvent)
+}
+
+// invokeWebhooks calls the webhooks of the hook type in order and follows their decisions
+// it returns true only if all of them let the rollout continue
+func (r *Controller) invokeWebhooks(ctx context.Context, rolloutHooks []v1alpha1.RolloutWebhook,
+	hookType v1alpha1.HookType, phase v1alpha1.RollingState) bool {
+	for _, rh := range rolloutHooks {
+		if rh.Type != hookType {
+			continue
+		}
+		attempt := r.getWebhookAttempt(rh)
+		if attempt != nil && time.Since(attempt.LastAttemptTime.Time) < getWebhookRetryDelay(rh, attempt.Attempts) {
+			klog.V(common.LogDebug).InfoS("waiting to retry a webhook", "webhook name", rh.Name,
+				"attempts", attempt.Attempts)
+			return false
+		}
+		resp, err := callWebhook(ctx, r.client, r.parentController, phase, rh)
+		if err != nil {
+			klog.ErrorS(err, "failed to invoke a webhook",
+				"webhook name", rh.Name, "webhook end point", rh.URL)
+			if isRetriable(err) && r.recordWebhookAttempt(rh) <= getWebhookMaxRetries(rh) {
+				r.rolloutStatus.RolloutRetry(fmt.Sprintf("failed to invoke the %s webhook %s, will retry: %s",
+					hookType, rh.Name, err))
+				return false
 			}
-			klog.InfoS("successfully invoked a post rollout webhook", "webhook name", rw.Name, "webhook end point",
-				rw.URL)
+			r.rolloutStatus.WebhookAttempt = nil
+			r.rolloutStatus.RolloutFailed(fmt.Sprintf("failed to invoke the %s webhook %s", hookType, rh.Name))
+			return false
+		}
+		r.rolloutStatus.WebhookAttempt = nil
+		switch resp.Action {
+		case v1alpha1.PauseWebhookAction:
+			// we will call the webhook again when we come back
+			klog.InfoS("the webhook pauses the rollout", "webhook name", rh.Name, "message", resp.Message)
+			r.recorder.Event(r.parentController, event.Normal("Rollout paused by webhook",
+				fmt.Sprintf("the webhook %s pauses the rollout: %s", rh.Name, resp.Message)))
+			r.rolloutStatus.RolloutRetry(fmt.Sprintf("the webhook %s pauses the rollout: %s", rh.Name,
+				resp.Message))
+			return false
+		case v1alpha1.AbortWebhookAction:
+			klog.InfoS("the webhook aborts the rollout", "webhook name", rh.Name, "message", resp.Message)
+			r.recorder.Event(r.parentController, event.Warning("Rollout aborted by webhook",
+				fmt.Errorf("the webhook %s aborts the rollout: %s", rh.Name, resp.Message)))
+			r.rolloutStatus.RolloutFailed(fmt.Sprintf("the webhook %s aborts the rollout: %s", rh.Name,
+				resp.Message))
+			return false
+		default:
+			klog.InfoS("successfully invoked a webhook", "webhook type", hookType, "webhook name", rh.Name,
+				"webhook end point", rh.URL)
 		}
 	}
-	r.rolloutStatus.StateTransition(v1alpha1.RollingFinalizedEvent)
+	return true
+}
+
+// getWebhookAttempt returns the failed attempts of the webhook if we are retrying it
+func (r *Controller) getWebhookAttempt(rh v1alpha1.RolloutWebhook) *v1alpha1.WebhookAttempt {
+	attempt := r.rolloutStatus.WebhookAttempt
+	if attempt == nil || attempt.Name != rh.Name || attempt.Type != rh.Type {
+		return nil
+	}
+	return attempt
+}
+
+// recordWebhookAttempt records a failed attempt of the webhook and returns the number of the failed attempts
+func (r *Controller) recordWebhookAttempt(rh v1alpha1.RolloutWebhook) int32 {
+	attempt := r.getWebhookAttempt(rh)
+	if attempt == nil {
+		attempt = &v1alpha1.WebhookAttempt{Name: rh.Name, Type: rh.Type}
+		r.rolloutStatus.WebhookAttempt = attempt
+	}
+	attempt.Attempts++
+	attempt.LastAttemptTime = metav1.Now()
+	return attempt.Attempts
 }
 
 // verify that the upgradedReplicas and current batch in the status are valid according to the spec
@@ -289,10 +539,9 @@ func (r *Controller) validateRollingBatchStatus(totalSize int) bool {
 	upgradedReplicas := int(status.UpgradedReplicas)
 	currentBatch := int(status.CurrentBatch)
 	// calculate the lower bound of the possible pod count just before the current batch
-	for i, r := range spec.RolloutBatches {
+	for i := range spec.RolloutBatches {
 		if i < currentBatch {
-			batchSize, _ := intstr.GetValueFromIntOrPercent(&r.Replicas, totalSize, true)
-			podCount += batchSize
+			podCount += workloads.CalculateBatchSize(&spec.RolloutBatches[i], totalSize)
 		}
 	}
 	// the recorded number should be at least as much as the all the pods before the current batch
@@ -306,9 +555,7 @@ func (r *Controller) validateRollingBatchStatus(totalSize int) bool {
 		// avoid round up problems
 		podCount = totalSize
 	} else {
-		batchSize, _ := intstr.GetValueFromIntOrPercent(&spec.RolloutBatches[currentBatch].Replicas,
-			totalSize, true)
-		podCount += batchSize
+		podCount += workloads.CalculateBatchSize(&spec.RolloutBatches[currentBatch], totalSize)
 	}
 	// the recorded number should be not as much as the all the pods including the active batch
 	if podCount < upgradedReplicas {
@@ -332,6 +579,21 @@ func (r *Controller) GetWorkloadController() (workloads.WorkloadController, erro
 		return workloads.NewCloneSetController(r.client, r.recorder, r.parentController,
 			r.rolloutSpec, r.rolloutStatus, target), nil
 
+	case "Deployment":
+		var source *types.NamespacedName
+		if r.sourceWorkload != nil {
+			source = &types.NamespacedName{
+				Namespace: r.sourceWorkload.GetNamespace(),
+				Name:      r.sourceWorkload.GetName(),
+			}
+		}
+		return workloads.NewDeploymentController(r.client, r.recorder, r.parentController,
+			r.rolloutSpec, r.rolloutStatus, target, source), nil
+
+	case "StatefulSet":
+		return workloads.NewStatefulSetController(r.client, r.recorder, r.parentController,
+			r.rolloutSpec, r.rolloutStatus, target), nil
+
 	default:
 		return nil, fmt.Errorf("the workload kind `%s` is not supported", kind)
 	}
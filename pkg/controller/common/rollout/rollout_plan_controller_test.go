@@ -0,0 +1,83 @@
+package rollout
+
+import (
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+func newApprovalTestController(annotations map[string]string) *Controller {
+	return &Controller{
+		recorder: event.NewNopRecorder(),
+		parentController: &v1alpha2.ApplicationDeployment{
+			ObjectMeta: metav1.ObjectMeta{Annotations: annotations},
+		},
+		rolloutSpec: &v1alpha1.RolloutPlan{
+			RolloutBatches: []v1alpha1.RolloutBatch{{}, {RequireApproval: true}},
+		},
+		rolloutStatus: &v1alpha1.RolloutStatus{
+			RollingState:      v1alpha1.RollingInBatchesState,
+			BatchRollingState: v1alpha1.BatchWaitingApprovalState,
+			CurrentBatch:      1,
+		},
+	}
+}
+
+func TestTryApproveBatch(t *testing.T) {
+	r := newApprovalTestController(nil)
+	r.tryApproveBatch()
+	assert.Equal(t, v1alpha1.BatchWaitingApprovalState, r.rolloutStatus.BatchRollingState)
+
+	// an approval for another batch is dropped
+	r = newApprovalTestController(map[string]string{oam.AnnotationRolloutApprovedBatch: "0"})
+	r.tryApproveBatch()
+	assert.Equal(t, v1alpha1.BatchWaitingApprovalState, r.rolloutStatus.BatchRollingState)
+	assert.NotContains(t, r.parentController.GetAnnotations(), oam.AnnotationRolloutApprovedBatch)
+
+	r = newApprovalTestController(map[string]string{oam.AnnotationRolloutApprovedBatch: "1"})
+	r.tryApproveBatch()
+	assert.Equal(t, v1alpha1.BatchInRollingState, r.rolloutStatus.BatchRollingState)
+	assert.NotContains(t, r.parentController.GetAnnotations(), oam.AnnotationRolloutApprovedBatch)
+}
+
+func TestTryAbortRollout(t *testing.T) {
+	r := newApprovalTestController(nil)
+	assert.False(t, r.tryAbortRollout())
+
+	r = newApprovalTestController(map[string]string{oam.AnnotationRolloutAbort: "true"})
+	assert.True(t, r.tryAbortRollout())
+	assert.Equal(t, v1alpha1.RolloutFailedState, r.rolloutStatus.RollingState)
+	assert.NotContains(t, r.parentController.GetAnnotations(), oam.AnnotationRolloutAbort)
+
+	// a finished rollout can't be aborted
+	r = newApprovalTestController(map[string]string{oam.AnnotationRolloutAbort: "true"})
+	r.rolloutStatus.RollingState = v1alpha1.RolloutSucceedState
+	assert.False(t, r.tryAbortRollout())
+	assert.Equal(t, v1alpha1.RolloutSucceedState, r.rolloutStatus.RollingState)
+}
+
+func TestValidateRollingBatchStatusWithPodList(t *testing.T) {
+	r := &Controller{
+		rolloutSpec: &v1alpha1.RolloutPlan{
+			RolloutBatches: []v1alpha1.RolloutBatch{
+				{PodList: []string{"pod-a", "pod-b", "pod-c"}},
+				{PodList: []string{"pod-d"}},
+				{},
+			},
+		},
+		rolloutStatus: &v1alpha1.RolloutStatus{CurrentBatch: 1, UpgradedReplicas: 4},
+	}
+	assert.True(t, r.validateRollingBatchStatus(10))
+
+	// the first batch upgraded 3 pods
+	r.rolloutStatus.UpgradedReplicas = 2
+	assert.False(t, r.validateRollingBatchStatus(10))
+	r.rolloutStatus.UpgradedReplicas = 5
+	assert.False(t, r.validateRollingBatchStatus(10))
+}
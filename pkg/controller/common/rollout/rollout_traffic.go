@@ -0,0 +1,125 @@
+package rollout
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+	"github.com/oam-dev/kubevela/pkg/controller/common"
+	"github.com/oam-dev/kubevela/pkg/controller/standard.oam.dev/v1alpha1/routes/ingress"
+)
+
+// shiftTraffic sends the traffic weight of the current batch to the target
+// it returns true when the route reports that the weight takes effect
+func (r *Controller) shiftTraffic(ctx context.Context) bool {
+	weight := r.rolloutSpec.RolloutBatches[r.rolloutStatus.CurrentBatch].TrafficWeight
+	if r.rolloutSpec.TrafficRouting == nil || weight == nil {
+		return true
+	}
+	canary := v1alpha1.CanaryBackend{
+		BackendService: r.rolloutSpec.TrafficRouting.CanaryService,
+		Weight:         *weight,
+	}
+	return r.updateRouteTraffic(ctx, func(rule *v1alpha1.Rule) {
+		rule.CanaryBackend = canary.DeepCopy()
+	}, weight, *weight)
+}
+
+// finalizeTraffic makes the canary service the backend of the route so that all the traffic goes to the target
+func (r *Controller) finalizeTraffic(ctx context.Context) bool {
+	if r.rolloutSpec.TrafficRouting == nil {
+		return true
+	}
+	canaryService := r.rolloutSpec.TrafficRouting.CanaryService
+	return r.updateRouteTraffic(ctx, func(rule *v1alpha1.Rule) {
+		if rule.Backend == nil {
+			rule.Backend = &v1alpha1.Backend{}
+		}
+		rule.Backend.BackendService = canaryService.DeepCopy()
+		rule.CanaryBackend = nil
+	}, nil, 100)
+}
+
+// revertTraffic removes the canary backends so that all the traffic goes back to the source
+func (r *Controller) revertTraffic(ctx context.Context) bool {
+	if r.rolloutSpec.TrafficRouting == nil {
+		return true
+	}
+	return r.updateRouteTraffic(ctx, func(rule *v1alpha1.Rule) {
+		rule.CanaryBackend = nil
+	}, nil, 0)
+}
+
+// isSourceRule returns true if the backend of the rule is the source service, the rules that route to the
+// canary service after the traffic is finalized are included as well
+func isSourceRule(trafficRouting *v1alpha1.TrafficRouting, rule *v1alpha1.Rule) bool {
+	var serviceName string
+	if rule.Backend != nil && rule.Backend.BackendService != nil {
+		serviceName = rule.Backend.BackendService.ServiceName
+	}
+	if serviceName == trafficRouting.CanaryService.ServiceName {
+		return true
+	}
+	return serviceName == trafficRouting.SourceServiceName
+}
+
+// updateRouteTraffic changes the rules of the route and waits for the route to report the canary weight
+// the traffic weight of the target in the status is only updated after that
+func (r *Controller) updateRouteTraffic(ctx context.Context, mutate func(rule *v1alpha1.Rule),
+	canaryWeight *int32, targetWeight int32) bool {
+	routeName := r.rolloutSpec.TrafficRouting.RouteName
+	var route v1alpha1.Route
+	if err := r.client.Get(ctx, client.ObjectKey{Namespace: r.parentController.GetNamespace(), Name: routeName},
+		&route); err != nil {
+		klog.ErrorS(err, "failed to get the route", "route", routeName)
+		r.rolloutStatus.RolloutRetry(fmt.Sprintf("failed to get the route %s: %s", routeName, err))
+		return false
+	}
+	origin := route.Spec.DeepCopy()
+	sourceRules := 0
+	for i := range route.Spec.Rules {
+		// the rules that serve other services are left alone
+		if !isSourceRule(r.rolloutSpec.TrafficRouting, &route.Spec.Rules[i]) {
+			continue
+		}
+		sourceRules++
+		mutate(&route.Spec.Rules[i])
+	}
+	if sourceRules == 0 {
+		if canaryWeight == nil && targetWeight == 0 {
+			// nothing to revert
+			r.rolloutStatus.TrafficWeight = pointer.Int32Ptr(targetWeight)
+			return true
+		}
+		err := fmt.Errorf("no rule of the route %s routes to the source service", routeName)
+		klog.ErrorS(err, "failed to update the route traffic", "source service",
+			r.rolloutSpec.TrafficRouting.SourceServiceName)
+		r.rolloutStatus.RolloutFailed(err.Error())
+		return false
+	}
+	if !reflect.DeepEqual(*origin, route.Spec) {
+		if err := r.client.Update(ctx, &route); err != nil {
+			klog.ErrorS(err, "failed to update the route", "route", routeName)
+			r.rolloutStatus.RolloutRetry(fmt.Sprintf("failed to update the route %s: %s", routeName, err))
+			return false
+		}
+		klog.InfoS("updated the traffic of the route", "route", routeName, "target weight", targetWeight)
+		r.recorder.Event(r.parentController, event.Normal("Traffic shifted",
+			fmt.Sprintf("the route %s sends %d%% of the traffic to the target", routeName, targetWeight)))
+		// wait for the route to apply it
+		return false
+	}
+	if route.Status.Status != ingress.StatusReady || !reflect.DeepEqual(route.Status.CanaryWeight, canaryWeight) {
+		klog.V(common.LogDebug).InfoS("the route has not applied the traffic weight yet", "route", routeName,
+			"route status", route.Status.Status, "target weight", targetWeight)
+		return false
+	}
+	r.rolloutStatus.TrafficWeight = pointer.Int32Ptr(targetWeight)
+	return true
+}
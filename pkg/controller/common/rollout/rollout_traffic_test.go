@@ -0,0 +1,145 @@
+package rollout
+
+import (
+	"context"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+	"github.com/oam-dev/kubevela/pkg/controller/standard.oam.dev/v1alpha1/routes/ingress"
+)
+
+func newTrafficTestController(t *testing.T) *Controller {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+	route := &v1alpha1.Route{
+		ObjectMeta: metav1.ObjectMeta{Name: "frontend", Namespace: "default"},
+		Spec: v1alpha1.RouteSpec{
+			Host: "frontend.example.com",
+			Rules: []v1alpha1.Rule{{
+				Backend: &v1alpha1.Backend{
+					ReadTimeout:    10,
+					BackendService: &v1alpha1.BackendServiceRef{ServiceName: "frontend-v1", Port: intstr.FromInt(80)},
+				},
+			}, {
+				Path: "/admin",
+				Backend: &v1alpha1.Backend{
+					BackendService: &v1alpha1.BackendServiceRef{ServiceName: "admin", Port: intstr.FromInt(80)},
+				},
+			}},
+		},
+		Status: v1alpha1.RouteStatus{Status: ingress.StatusReady},
+	}
+	return &Controller{
+		client:   fake.NewFakeClientWithScheme(scheme, route),
+		recorder: event.NewNopRecorder(),
+		parentController: &v1alpha2.ApplicationDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "frontend", Namespace: "default"},
+		},
+		rolloutSpec: &v1alpha1.RolloutPlan{
+			RolloutBatches: []v1alpha1.RolloutBatch{
+				{TrafficWeight: pointer.Int32Ptr(20)},
+				{},
+			},
+			TrafficRouting: &v1alpha1.TrafficRouting{
+				RouteName:         "frontend",
+				CanaryService:     v1alpha1.BackendServiceRef{ServiceName: "frontend-v2", Port: intstr.FromInt(80)},
+				SourceServiceName: "frontend-v1",
+			},
+		},
+		rolloutStatus: &v1alpha1.RolloutStatus{
+			RollingState:      v1alpha1.RollingInBatchesState,
+			BatchRollingState: v1alpha1.BatchFinalizingState,
+		},
+	}
+}
+
+// setRouteCanaryWeight mocks the route controller reporting the canary weight
+func setRouteCanaryWeight(t *testing.T, r *Controller, weight *int32) *v1alpha1.Route {
+	var route v1alpha1.Route
+	assert.NoError(t, r.client.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "frontend"},
+		&route))
+	route.Status.CanaryWeight = weight
+	assert.NoError(t, r.client.Status().Update(context.Background(), &route))
+	return &route
+}
+
+func TestShiftTraffic(t *testing.T) {
+	ctx := context.Background()
+	r := newTrafficTestController(t)
+
+	// the route is updated first
+	assert.False(t, r.shiftTraffic(ctx))
+	route := setRouteCanaryWeight(t, r, nil)
+	assert.Equal(t, &v1alpha1.CanaryBackend{
+		BackendService: v1alpha1.BackendServiceRef{ServiceName: "frontend-v2", Port: intstr.FromInt(80)},
+		Weight:         20,
+	}, route.Spec.Rules[0].CanaryBackend)
+	// the rules of the other services are not shifted
+	assert.Nil(t, route.Spec.Rules[1].CanaryBackend)
+	// then we wait for the route to apply it
+	assert.False(t, r.shiftTraffic(ctx))
+	assert.Nil(t, r.rolloutStatus.TrafficWeight)
+	setRouteCanaryWeight(t, r, pointer.Int32Ptr(20))
+	assert.True(t, r.shiftTraffic(ctx))
+	assert.Equal(t, int32(20), *r.rolloutStatus.TrafficWeight)
+
+	// the batch without a traffic weight keeps the traffic as it is
+	r.rolloutStatus.CurrentBatch = 1
+	assert.True(t, r.shiftTraffic(ctx))
+	assert.Equal(t, int32(20), *r.rolloutStatus.TrafficWeight)
+}
+
+func TestFinalizeTraffic(t *testing.T) {
+	ctx := context.Background()
+	r := newTrafficTestController(t)
+	assert.False(t, r.shiftTraffic(ctx))
+	setRouteCanaryWeight(t, r, pointer.Int32Ptr(20))
+	assert.True(t, r.shiftTraffic(ctx))
+
+	assert.False(t, r.finalizeTraffic(ctx))
+	route := setRouteCanaryWeight(t, r, nil)
+	assert.Nil(t, route.Spec.Rules[0].CanaryBackend)
+	assert.Equal(t, "frontend-v2", route.Spec.Rules[0].Backend.BackendService.ServiceName)
+	assert.Equal(t, 10, route.Spec.Rules[0].Backend.ReadTimeout)
+	assert.Equal(t, "admin", route.Spec.Rules[1].Backend.BackendService.ServiceName)
+	assert.True(t, r.finalizeTraffic(ctx))
+	assert.Equal(t, int32(100), *r.rolloutStatus.TrafficWeight)
+}
+
+func TestShiftTrafficNoSourceRule(t *testing.T) {
+	r := newTrafficTestController(t)
+	r.rolloutSpec.TrafficRouting.SourceServiceName = "backend"
+	assert.False(t, r.shiftTraffic(context.Background()))
+	assert.Equal(t, v1alpha1.RolloutFailedState, r.rolloutStatus.RollingState)
+
+	// the rules without a backend service route to the discovered source service by default
+	assert.True(t, isSourceRule(&v1alpha1.TrafficRouting{}, &v1alpha1.Rule{}))
+	assert.False(t, isSourceRule(&v1alpha1.TrafficRouting{}, &v1alpha1.Rule{
+		Backend: &v1alpha1.Backend{BackendService: &v1alpha1.BackendServiceRef{ServiceName: "admin"}},
+	}))
+}
+
+func TestRevertTraffic(t *testing.T) {
+	ctx := context.Background()
+	r := newTrafficTestController(t)
+	assert.False(t, r.shiftTraffic(ctx))
+	setRouteCanaryWeight(t, r, pointer.Int32Ptr(20))
+	assert.True(t, r.shiftTraffic(ctx))
+
+	assert.False(t, r.revertTraffic(ctx))
+	route := setRouteCanaryWeight(t, r, nil)
+	assert.Nil(t, route.Spec.Rules[0].CanaryBackend)
+	assert.Equal(t, "frontend-v1", route.Spec.Rules[0].Backend.BackendService.ServiceName)
+	assert.True(t, r.revertTraffic(ctx))
+	assert.Equal(t, int32(0), *r.rolloutStatus.TrafficWeight)
+}
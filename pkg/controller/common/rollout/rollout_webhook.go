@@ -3,21 +3,108 @@ package rollout
 import (
 	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strconv"
+	"time"
 
-	"k8s.io/client-go/util/retry"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
 	"github.com/oam-dev/kubevela/pkg/controller/common"
 )
 
-// issue an http call to the an end ponit
-func makeHTTPRequest(ctx context.Context, webhookEndPoint string, payload interface{}) ([]byte, int, error) {
+const (
+	// the default timeout of each webhook request
+	defaultWebhookTimeout = 10 * time.Second
+	// the default number of retries of a webhook request
+	defaultWebhookRetries = 3
+	// the default wait before the first retry
+	defaultWebhookRetryBackoff = time.Second
+)
+
+// webhookRequestOptions controls how a webhook request is issued
+type webhookRequestOptions struct {
+	timeout    time.Duration
+	signingKey []byte
+}
+
+// retriableError is an error that we can retry on
+type retriableError struct {
+	error
+}
+
+// isRetriable returns true if the webhook request can be retried
+func isRetriable(err error) bool {
+	_, ok := err.(retriableError)
+	return ok
+}
+
+// getWebhookMaxRetries returns the number of retries of a webhook request
+func getWebhookMaxRetries(w v1alpha1.RolloutWebhook) int32 {
+	if w.MaxRetries != nil {
+		return *w.MaxRetries
+	}
+	return defaultWebhookRetries
+}
+
+// getWebhookRetryDelay returns how long we wait after the given number of failed attempts before the next one,
+// the wait doubles after each retry
+func getWebhookRetryDelay(w v1alpha1.RolloutWebhook, attempts int32) time.Duration {
+	delay := defaultWebhookRetryBackoff
+	if w.RetryBackoffSeconds != nil {
+		delay = time.Duration(*w.RetryBackoffSeconds) * time.Second
+	}
+	for i := int32(1); i < attempts; i++ {
+		delay *= 2
+	}
+	return delay
+}
+
+// getWebhookRequestOptions reads the request options from the webhook and fills in the defaults
+func getWebhookRequestOptions(ctx context.Context, c client.Reader, namespace string,
+	w v1alpha1.RolloutWebhook) (*webhookRequestOptions, error) {
+	opts := &webhookRequestOptions{
+		timeout: defaultWebhookTimeout,
+	}
+	if w.TimeoutSeconds != nil {
+		opts.timeout = time.Duration(*w.TimeoutSeconds) * time.Second
+	}
+	if w.SecretRef != nil {
+		var secret corev1.Secret
+		if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: w.SecretRef.Name}, &secret); err != nil {
+			return nil, fmt.Errorf("failed to get the secret of the webhook %s: %w", w.Name, err)
+		}
+		key, ok := secret.Data[w.SecretRef.Key]
+		if !ok || len(key) == 0 {
+			return nil, fmt.Errorf("the key %s is not found in the secret %s of the webhook %s",
+				w.SecretRef.Key, w.SecretRef.Name, w.Name)
+		}
+		opts.signingKey = key
+	}
+	return opts, nil
+}
+
+// signPayload returns the signature of the timestamp and the payload in the format of the signature header
+func signPayload(key []byte, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(timestamp + "."))
+	_, _ = mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// issue an http call to the an end ponit, the request is not retried here as we can't block the reconcile,
+// the caller retries a retriableError in the later reconciles
+func makeHTTPRequest(ctx context.Context, webhookEndPoint string, payload interface{},
+	opts *webhookRequestOptions) ([]byte, int, error) {
 	payloadBin, err := json.Marshal(payload)
 	if err != nil {
 		return nil, http.StatusInternalServerError, err
@@ -28,50 +115,51 @@ func makeHTTPRequest(ctx context.Context, webhookEndPoint string, payload interf
 		return nil, http.StatusInternalServerError, err
 	}
 
-	req, err := http.NewRequestWithContext(context.Background(), "POST", hook.String(), bytes.NewBuffer(payloadBin))
+	statusCode, body, err := doHTTPRequest(ctx, hook.String(), payloadBin, opts)
 	if err != nil {
+		klog.InfoS("the webhook request failed", "webhook end point", webhookEndPoint, "error", err)
 		return nil, http.StatusInternalServerError, err
 	}
-	req.Header.Set("Content-Type", "application/json")
+	return body, statusCode, nil
+}
 
-	// issue request with retry
-	var r *http.Response
-	var body []byte
-	err = retry.OnError(retry.DefaultBackoff,
-		func(error) bool {
-			// not sure what not to retry on
-			return true
-		}, func() error {
-			var requestErr error
-			r, requestErr = http.DefaultClient.Do(req.WithContext(ctx))
-			defer func() {
-				_ = r.Body.Close()
-			}()
-			if requestErr != nil {
-				return requestErr
-			}
-			body, requestErr = ioutil.ReadAll(r.Body)
-			if requestErr != nil {
-				return requestErr
-			}
-			if r.StatusCode == http.StatusInternalServerError ||
-				r.StatusCode == http.StatusServiceUnavailable {
-				requestErr = fmt.Errorf("internal server error, status code = %d", r.StatusCode)
-			}
-			return requestErr
-		})
-
-	// failed even with retry
+// doHTTPRequest makes one http POST with the timeout, it returns a retriableError if we can retry
+func doHTTPRequest(ctx context.Context, endpoint string, payload []byte,
+	opts *webhookRequestOptions) (int, []byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, opts.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(payload))
 	if err != nil {
-		return nil, http.StatusInternalServerError, err
+		return 0, nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(opts.signingKey) != 0 {
+		// the receiver can reject a replayed request by the signed timestamp
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(v1alpha1.RolloutWebhookTimestampHeader, timestamp)
+		req.Header.Set(v1alpha1.RolloutWebhookSignatureHeader, signPayload(opts.signingKey, timestamp, payload))
+	}
+	r, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, nil, retriableError{err}
+	}
+	defer func() {
+		_ = r.Body.Close()
+	}()
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return 0, nil, retriableError{err}
 	}
-	return body, r.StatusCode, nil
+	if r.StatusCode >= http.StatusInternalServerError || r.StatusCode == http.StatusTooManyRequests {
+		return r.StatusCode, body, retriableError{fmt.Errorf("server error, status code = %d", r.StatusCode)}
+	}
+	return r.StatusCode, body, nil
 }
 
-// callWebhook does a HTTP POST to an external service and
-// returns an error if the response status code is non-2xx
-func callWebhook(ctx context.Context, resource klog.KMetadata, phase v1alpha1.RollingState,
-	w v1alpha1.RolloutWebhook) error {
+// callWebhook does a HTTP POST to an external service and returns the decision of the webhook
+// it returns an error if the response status code is not expected
+func callWebhook(ctx context.Context, c client.Reader, resource klog.KMetadata, phase v1alpha1.RollingState,
+	w v1alpha1.RolloutWebhook) (*v1alpha1.RolloutWebhookResponse, error) {
 	payload := v1alpha1.RolloutWebhookPayload{
 		Name:      resource.GetName(),
 		Namespace: resource.GetNamespace(),
@@ -81,17 +169,21 @@ func callWebhook(ctx context.Context, resource klog.KMetadata, phase v1alpha1.Ro
 	if w.Metadata != nil {
 		payload.Metadata = *w.Metadata
 	}
+	opts, err := getWebhookRequestOptions(ctx, c, resource.GetNamespace(), w)
+	if err != nil {
+		return nil, err
+	}
 	// make the http request
-	_, status, err := makeHTTPRequest(ctx, w.URL, payload)
+	body, status, err := makeHTTPRequest(ctx, w.URL, payload, opts)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if len(w.ExpectedStatus) == 0 {
 		if status > http.StatusAccepted {
 			err := fmt.Errorf("we fail the webhook request based on status, http status = %d", status)
-			return err
+			return nil, err
 		}
-		return nil
+		return parseWebhookResponse(body)
 	}
 	// check if the returned status is expected
 	accepted := false
@@ -104,7 +196,25 @@ func callWebhook(ctx context.Context, resource klog.KMetadata, phase v1alpha1.Ro
 	if !accepted {
 		err := fmt.Errorf("http request to the webhook not accepeted, http status = %d", status)
 		klog.V(common.LogDebug).InfoS("the status is not expected", "expected status", w.ExpectedStatus)
-		return err
+		return nil, err
+	}
+	return parseWebhookResponse(body)
+}
+
+// parseWebhookResponse gets the decision from the response body
+// the rollout continues if the webhook doesn't return a JSON decision
+func parseWebhookResponse(body []byte) (*v1alpha1.RolloutWebhookResponse, error) {
+	resp := &v1alpha1.RolloutWebhookResponse{}
+	if err := json.Unmarshal(body, resp); err != nil {
+		klog.V(common.LogDebugWithContent).InfoS("the webhook response is not a decision", "body", string(body))
+		resp = &v1alpha1.RolloutWebhookResponse{}
+	}
+	switch resp.Action {
+	case "":
+		resp.Action = v1alpha1.ContinueWebhookAction
+	case v1alpha1.ContinueWebhookAction, v1alpha1.PauseWebhookAction, v1alpha1.AbortWebhookAction:
+	default:
+		return nil, fmt.Errorf("the webhook returns an unknown action `%s`", resp.Action)
 	}
-	return nil
+	return resp, nil
 }
@@ -0,0 +1,192 @@
+package rollout
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+)
+
+var testParent = &v1alpha2.ApplicationDeployment{
+	ObjectMeta: metav1.ObjectMeta{Name: "rollout", Namespace: "default"},
+}
+
+func TestCallWebhookSigned(t *testing.T) {
+	var signature string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		timestamp := r.Header.Get(v1alpha1.RolloutWebhookTimestampHeader)
+		if len(timestamp) != 0 &&
+			r.Header.Get(v1alpha1.RolloutWebhookSignatureHeader) == signPayload([]byte("s3cret"), timestamp, body) {
+			signature = "valid"
+		}
+		_, _ = w.Write([]byte(`{"action":"pause","message":"waiting for the load test"}`))
+	}))
+	defer server.Close()
+
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	c := fake.NewFakeClientWithScheme(scheme, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "hook-secret", Namespace: "default"},
+		Data:       map[string][]byte{"token": []byte("s3cret")},
+	})
+	resp, err := callWebhook(context.Background(), c, testParent, v1alpha1.InitializingState, v1alpha1.RolloutWebhook{
+		Name:      "load-test",
+		URL:       server.URL,
+		SecretRef: &v1alpha1.WebhookSecretKeySelector{Name: "hook-secret", Key: "token"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "valid", signature)
+	assert.Equal(t, v1alpha1.PauseWebhookAction, resp.Action)
+	assert.Equal(t, "waiting for the load test", resp.Message)
+
+	// the secret has to exist
+	_, err = callWebhook(context.Background(), c, testParent, v1alpha1.InitializingState, v1alpha1.RolloutWebhook{
+		Name:      "load-test",
+		URL:       server.URL,
+		SecretRef: &v1alpha1.WebhookSecretKeySelector{Name: "hook-secret", Key: "missing"},
+	})
+	assert.Error(t, err)
+}
+
+func TestInvokeWebhooksRetry(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	hook := v1alpha1.RolloutWebhook{
+		Type:                v1alpha1.InitializeRolloutHook,
+		Name:                "flaky",
+		URL:                 server.URL,
+		MaxRetries:          pointer.Int32Ptr(2),
+		RetryBackoffSeconds: pointer.Int32Ptr(0),
+	}
+	r := &Controller{
+		recorder:         event.NewNopRecorder(),
+		parentController: testParent.DeepCopy(),
+		rolloutSpec:      &v1alpha1.RolloutPlan{RolloutWebhooks: []v1alpha1.RolloutWebhook{hook}},
+		rolloutStatus:    &v1alpha1.RolloutStatus{RollingState: v1alpha1.InitializingState},
+	}
+	// each reconcile makes one attempt and records it in the status
+	assert.False(t, r.initializeRollout(context.Background()))
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, int32(1), r.rolloutStatus.WebhookAttempt.Attempts)
+	assert.False(t, r.initializeRollout(context.Background()))
+	assert.Equal(t, int32(2), r.rolloutStatus.WebhookAttempt.Attempts)
+	assert.Equal(t, v1alpha1.InitializingState, r.rolloutStatus.RollingState)
+	// a response that is not a decision lets the rollout continue
+	assert.True(t, r.initializeRollout(context.Background()))
+	assert.Equal(t, 3, calls)
+	assert.Nil(t, r.rolloutStatus.WebhookAttempt)
+
+	// give up after the retries
+	calls = 0
+	r.rolloutSpec.RolloutWebhooks[0].MaxRetries = pointer.Int32Ptr(1)
+	assert.False(t, r.initializeRollout(context.Background()))
+	assert.False(t, r.initializeRollout(context.Background()))
+	assert.Equal(t, v1alpha1.RolloutFailedState, r.rolloutStatus.RollingState)
+	assert.Equal(t, 2, calls)
+
+	// the retry waits for the backoff in the later reconciles instead of sleeping
+	calls = 0
+	r.rolloutSpec.RolloutWebhooks[0].RetryBackoffSeconds = pointer.Int32Ptr(60)
+	r.rolloutStatus = &v1alpha1.RolloutStatus{RollingState: v1alpha1.InitializingState}
+	assert.False(t, r.initializeRollout(context.Background()))
+	assert.False(t, r.initializeRollout(context.Background()))
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, int32(1), r.rolloutStatus.WebhookAttempt.Attempts)
+}
+
+func TestGetWebhookRetryDelay(t *testing.T) {
+	hook := v1alpha1.RolloutWebhook{RetryBackoffSeconds: pointer.Int32Ptr(2)}
+	assert.Equal(t, 2*time.Second, getWebhookRetryDelay(hook, 1))
+	assert.Equal(t, 8*time.Second, getWebhookRetryDelay(hook, 3))
+	assert.Equal(t, defaultWebhookRetryBackoff, getWebhookRetryDelay(v1alpha1.RolloutWebhook{}, 1))
+}
+
+func TestCallWebhookNoRetryOnRejection(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	_, err := callWebhook(context.Background(), nil, testParent, v1alpha1.InitializingState, v1alpha1.RolloutWebhook{
+		Name:                "reject",
+		URL:                 server.URL,
+		RetryBackoffSeconds: pointer.Int32Ptr(0),
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestParseWebhookResponse(t *testing.T) {
+	resp, err := parseWebhookResponse([]byte(`{"action":"abort"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, v1alpha1.AbortWebhookAction, resp.Action)
+
+	resp, err = parseWebhookResponse(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, v1alpha1.ContinueWebhookAction, resp.Action)
+
+	_, err = parseWebhookResponse([]byte(`{"action":"rollback"}`))
+	assert.Error(t, err)
+}
+
+func TestInvokeWebhooksDecision(t *testing.T) {
+	var action string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"action":"` + action + `"}`))
+	}))
+	defer server.Close()
+
+	newController := func() *Controller {
+		return &Controller{
+			recorder:         event.NewNopRecorder(),
+			parentController: testParent.DeepCopy(),
+			rolloutSpec: &v1alpha1.RolloutPlan{
+				RolloutWebhooks: []v1alpha1.RolloutWebhook{
+					{Type: v1alpha1.InitializeRolloutHook, Name: "gate", URL: server.URL},
+				},
+			},
+			rolloutStatus: &v1alpha1.RolloutStatus{RollingState: v1alpha1.InitializingState},
+		}
+	}
+
+	action = "continue"
+	r := newController()
+	assert.True(t, r.initializeRollout(context.Background()))
+
+	// the rollout stays in the same state when the webhook pauses it
+	action = "pause"
+	r = newController()
+	assert.False(t, r.initializeRollout(context.Background()))
+	assert.Equal(t, v1alpha1.InitializingState, r.rolloutStatus.RollingState)
+
+	action = "abort"
+	r = newController()
+	assert.False(t, r.initializeRollout(context.Background()))
+	assert.Equal(t, v1alpha1.RolloutFailedState, r.rolloutStatus.RollingState)
+}
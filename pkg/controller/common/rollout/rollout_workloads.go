@@ -1,4 +1,4 @@
-package applicationdeployment
+package rollout
 
 import (
 	"context"
@@ -7,6 +7,7 @@ import (
 	"github.com/pkg/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	corev1alpha2 "github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
 	"github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2/applicationconfiguration"
@@ -15,8 +16,8 @@ import (
 	appUtil "github.com/oam-dev/kubevela/pkg/webhook/core.oam.dev/v1alpha2/applicationdeployment"
 )
 
-// extractWorkloads extracts the workloads from the source and target applicationConfig
-func (r *Reconciler) extractWorkloads(ctx context.Context, componentList []string, targetApp,
+// ExtractWorkloads extracts the workloads from the source and target applicationConfig
+func ExtractWorkloads(ctx context.Context, c client.Reader, componentList []string, targetApp,
 	sourceApp *corev1alpha2.ApplicationConfiguration) (*unstructured.Unstructured, *unstructured.Unstructured, error) {
 	var componentName string
 	if len(componentList) == 0 {
@@ -33,13 +34,13 @@ func (r *Reconciler) extractWorkloads(ctx context.Context, componentList []strin
 	}
 	// get the workload definition
 	// the validator webhook has checked that source and the target are the same type
-	targetWorkload, err := r.fetchWorkload(ctx, componentName, targetApp)
+	targetWorkload, err := fetchWorkload(ctx, c, componentName, targetApp)
 	if err != nil {
 		return nil, nil, err
 	}
 	klog.InfoS("get the target workload we need to work on", "targetWorkload", klog.KObj(targetWorkload))
 	if sourceApp != nil {
-		sourceWorkload, err := r.fetchWorkload(ctx, componentName, sourceApp)
+		sourceWorkload, err := fetchWorkload(ctx, c, componentName, sourceApp)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -50,7 +51,7 @@ func (r *Reconciler) extractWorkloads(ctx context.Context, componentList []strin
 }
 
 // fetchWorkload based on the component and the appConfig
-func (r *Reconciler) fetchWorkload(ctx context.Context, componentName string,
+func fetchWorkload(ctx context.Context, c client.Reader, componentName string,
 	targetApp *corev1alpha2.ApplicationConfiguration) (*unstructured.Unstructured, error) {
 	var targetAcc *corev1alpha2.ApplicationConfigurationComponent
 	for _, acc := range targetApp.Spec.Components {
@@ -72,7 +73,7 @@ func (r *Reconciler) fetchWorkload(ctx context.Context, componentName string,
 	}
 
 	// get the component given the component revision
-	component, _, err := oamutil.GetComponent(ctx, r, *targetAcc, targetApp.GetNamespace())
+	component, _, err := oamutil.GetComponent(ctx, c, *targetAcc, targetApp.GetNamespace())
 	if err != nil {
 		return nil, errors.Wrap(err, fmt.Sprintf("failed to get component given its revision %s",
 			targetAcc.RevisionName))
@@ -83,10 +84,17 @@ func (r *Reconciler) fetchWorkload(ctx context.Context, componentName string,
 		return nil, errors.Wrap(err, fmt.Sprintf("failed to get component given revision %s", targetAcc.RevisionName))
 	}
 	// reuse the same appConfig controller logic that determines the workload name given an ACC
-	applicationconfiguration.SetAppWorkloadInstanceName(componentName, w, revision)
+	inPlaceUpgrade := applicationconfiguration.IsInPlaceUpgrade(targetApp)
+	applicationconfiguration.SetAppWorkloadInstanceName(componentName, w, revision, inPlaceUpgrade)
+	if inPlaceUpgrade {
+		err = applicationconfiguration.AdoptRevisionedStatefulSet(ctx, c, targetApp.GetNamespace(), targetApp.GetName(), w)
+		if err != nil {
+			return nil, err
+		}
+	}
 	klog.InfoS("get the workload we need to work on", "workload gvk", w.GroupVersionKind(), "workload name", w.GetName())
 	// get the real workload object from api-server given GVK and name
-	workload, err := oamutil.GetObjectGivenGVKAndName(ctx, r, w.GroupVersionKind(), targetApp.GetNamespace(), w.GetName())
+	workload, err := oamutil.GetObjectGivenGVKAndName(ctx, c, w.GroupVersionKind(), targetApp.GetNamespace(), w.GetName())
 	if err != nil {
 		return nil, errors.Wrap(err, fmt.Sprintf("failed to get workload %s with gvk %+v ", w.GetName(), w.GroupVersionKind()))
 	}
@@ -3,10 +3,13 @@ package workloads
 import (
 	"context"
 	"fmt"
+	"reflect"
 
 	"github.com/crossplane/crossplane-runtime/pkg/event"
+	appspub "github.com/openkruise/kruise-api/apps/pub"
 	kruise "github.com/openkruise/kruise-api/apps/v1alpha1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/klog/v2"
@@ -17,6 +20,9 @@ import (
 	"github.com/oam-dev/kubevela/pkg/oam"
 )
 
+// selectedPodPriority is the update priority weight of the pods selected by the pod list of a batch
+const selectedPodPriority = 100
+
 // CloneSetController is responsible for handle Cloneset type of workloads
 type CloneSetController struct {
 	client           client.Client
@@ -92,6 +98,27 @@ func (c *CloneSetController) Verify(ctx context.Context) (status *v1alpha1.Rollo
 		return
 	}
 
+	// the pods in the pod lists have to belong to the cloneset
+	if hasBatchPodList(c.rolloutSpec) {
+		pods, err := listWorkloadPods(ctx, c.client, c.cloneSet.GetNamespace(), c.cloneSet.Spec.Selector)
+		if err != nil {
+			verifyErr = err
+			c.rolloutStatus.RolloutRetry(verifyErr.Error())
+			return
+		}
+		if verifyErr = verifyBatchPodList(c.rolloutSpec, pods); verifyErr != nil {
+			c.rolloutStatus.RolloutFailed(verifyErr.Error())
+			return
+		}
+		// the pod lists are upgraded first by our priority strategy, we don't override the one set by the user
+		priorityStrategy := c.cloneSet.Spec.UpdateStrategy.PriorityStrategy
+		if priorityStrategy != nil && !reflect.DeepEqual(priorityStrategy, selectedPodPriorityStrategy()) {
+			verifyErr = fmt.Errorf("the cloneset has its own update priority strategy, the pod list is not supported")
+			c.rolloutStatus.RolloutFailed(verifyErr.Error())
+			return
+		}
+	}
+
 	// the rollout batch partition is either automatic or zero
 	if c.rolloutSpec.BatchPartition != nil && *c.rolloutSpec.BatchPartition != 0 {
 		verifyErr = fmt.Errorf("the rollout plan has to start from zero, partition= %d", *c.rolloutSpec.BatchPartition)
@@ -132,11 +159,23 @@ func (c *CloneSetController) Initialize(ctx context.Context) *v1alpha1.RolloutSt
 func (c *CloneSetController) RolloutOneBatchPods(ctx context.Context) *v1alpha1.RolloutStatus {
 	// calculate what's the total pods that should be upgraded given the currentBatch in the status
 	cloneSetSize, _ := c.Size(ctx)
-	newPodTarget := c.calculateNewPodTarget(int(cloneSetSize))
+	newPodTarget := calculateNewBatchTarget(c.rolloutSpec, int(c.rolloutStatus.CurrentBatch), int(cloneSetSize))
+	// label the pods in the pod list so that the Cloneset upgrades them first
+	podList := c.rolloutSpec.RolloutBatches[c.rolloutStatus.CurrentBatch].PodList
+	if len(podList) != 0 {
+		if err := selectBatchPods(ctx, c.client, c.cloneSet.GetNamespace(), podList); err != nil {
+			c.recorder.Event(c.parentController, event.Warning("Failed to select the pods of the batch", err))
+			c.rolloutStatus.RolloutRetry(err.Error())
+			return c.rolloutStatus
+		}
+	}
 	// set the Partition as the desired number of pods in old revisions.
 	clonePatch := client.MergeFrom(c.cloneSet.DeepCopyObject())
 	c.cloneSet.Spec.UpdateStrategy.Partition = &intstr.IntOrString{Type: intstr.Int,
 		IntVal: cloneSetSize - int32(newPodTarget)}
+	if len(podList) != 0 {
+		c.cloneSet.Spec.UpdateStrategy.PriorityStrategy = selectedPodPriorityStrategy()
+	}
 	// patch the Cloneset
 	if err := c.client.Patch(ctx, c.cloneSet, clonePatch, client.FieldOwner(c.parentController.GetUID())); err != nil {
 		c.recorder.Event(c.parentController, event.Warning("Failed to patch update the Cloneset", err))
@@ -155,14 +194,11 @@ func (c *CloneSetController) RolloutOneBatchPods(ctx context.Context) *v1alpha1.
 // CheckOneBatchPods checks to see if the pods are all available according to
 func (c *CloneSetController) CheckOneBatchPods(ctx context.Context) *v1alpha1.RolloutStatus {
 	cloneSetSize, _ := c.Size(ctx)
-	newPodTarget := c.calculateNewPodTarget(int(cloneSetSize))
+	newPodTarget := calculateNewBatchTarget(c.rolloutSpec, int(c.rolloutStatus.CurrentBatch), int(cloneSetSize))
 	// get the number of ready pod from cloneset
 	readyPodCount := int(c.cloneSet.Status.UpdatedReadyReplicas)
 	currentBatch := c.rolloutSpec.RolloutBatches[c.rolloutStatus.CurrentBatch]
-	unavail := 0
-	if currentBatch.MaxUnavailable != nil {
-		unavail, _ = intstr.GetValueFromIntOrPercent(currentBatch.MaxUnavailable, int(cloneSetSize), true)
-	}
+	unavail := calculateMaxUnavailable(c.rolloutSpec, int(c.rolloutStatus.CurrentBatch), int(cloneSetSize))
 	klog.V(common.LogDebug).InfoS("checking the rolling out progress", "current batch", currentBatch,
 		"new pod count target", newPodTarget, "new ready pod count", readyPodCount,
 		"max unavailable pod allowed", unavail)
@@ -188,12 +224,93 @@ func (c *CloneSetController) FinalizeOneBatch(ctx context.Context) *v1alpha1.Rol
 	return c.rolloutStatus
 }
 
-// Finalize makes sure the Cloneset is all upgraded
+// RollbackOneBatchPods moves the partition back to where it was before the current batch
+// and deletes the extra upgraded pods so that the Cloneset recreates them with the source revision
+func (c *CloneSetController) RollbackOneBatchPods(ctx context.Context) *v1alpha1.RolloutStatus {
+	cloneSetSize, err := c.Size(ctx)
+	if err != nil {
+		return c.rolloutStatus
+	}
+	revertTarget := calculateRevertTarget(c.rolloutSpec, int(c.rolloutStatus.CurrentBatch), int(cloneSetSize))
+	clonePatch := client.MergeFrom(c.cloneSet.DeepCopyObject())
+	c.cloneSet.Spec.UpdateStrategy.Partition = &intstr.IntOrString{Type: intstr.Int,
+		IntVal: cloneSetSize - int32(revertTarget)}
+	if err := c.client.Patch(ctx, c.cloneSet, clonePatch, client.FieldOwner(c.parentController.GetUID())); err != nil {
+		c.recorder.Event(c.parentController, event.Warning("Failed to patch update the Cloneset", err))
+		c.rolloutStatus.RolloutRetry(err.Error())
+		return c.rolloutStatus
+	}
+	pods, err := listWorkloadPods(ctx, c.client, c.cloneSet.GetNamespace(), c.cloneSet.Spec.Selector)
+	if err != nil {
+		c.rolloutStatus.RolloutRetry(err.Error())
+		return c.rolloutStatus
+	}
+	upgradedPods := filterPodsByRevision(pods, c.cloneSet.Status.UpdateRevision)
+	for i := revertTarget; i < len(upgradedPods); i++ {
+		if err := c.client.Delete(ctx, &upgradedPods[i]); client.IgnoreNotFound(err) != nil {
+			c.recorder.Event(c.parentController, event.Warning("Failed to delete an upgraded pod", err))
+			c.rolloutStatus.RolloutRetry(err.Error())
+			return c.rolloutStatus
+		}
+	}
+	// record the rollback
+	klog.InfoS("rolled back one batch", "current batch", c.rolloutStatus.CurrentBatch)
+	c.recorder.Event(c.parentController, event.Normal("Rollback",
+		fmt.Sprintf("rolled back the batch num = %d", c.rolloutStatus.CurrentBatch)))
+	startRollbackStep(c.rolloutStatus, int32(revertTarget))
+	c.rolloutStatus.StateTransition(v1alpha1.BatchRolloutVerifyingEvent)
+	return c.rolloutStatus
+}
+
+// CheckOneBatchRollback checks to see if the extra upgraded pods are gone and the Cloneset is available again
+func (c *CloneSetController) CheckOneBatchRollback(ctx context.Context) *v1alpha1.RolloutStatus {
+	cloneSetSize, err := c.Size(ctx)
+	if err != nil {
+		return c.rolloutStatus
+	}
+	currentBatch := int(c.rolloutStatus.CurrentBatch)
+	revertTarget := calculateRevertTarget(c.rolloutSpec, currentBatch, int(cloneSetSize))
+	unavail := calculateMaxUnavailable(c.rolloutSpec, currentBatch, int(cloneSetSize))
+	c.rolloutStatus.UpgradedReadyReplicas = c.cloneSet.Status.UpdatedReadyReplicas
+	if int(c.cloneSet.Status.UpdatedReplicas) > revertTarget ||
+		int(c.cloneSet.Status.ReadyReplicas)+unavail < int(cloneSetSize) {
+		klog.V(common.LogDebug).InfoS("the batch is not rolled back yet", "current batch", currentBatch)
+		c.rolloutStatus.RolloutRetry("the batch is not rolled back yet")
+		return c.rolloutStatus
+	}
+	c.recorder.Event(c.parentController, event.Normal("Batch Rolled Back",
+		fmt.Sprintf("the batch num = %d is rolled back", c.rolloutStatus.CurrentBatch)))
+	completeRollbackStep(c.rolloutStatus)
+	c.rolloutStatus.StateTransition(v1alpha1.OneBatchRolledBackEvent)
+	return c.rolloutStatus
+}
+
+// Finalize makes sure the Cloneset is all upgraded and cleans up the pods selected by the pod lists
 func (c *CloneSetController) Finalize(ctx context.Context) *v1alpha1.RolloutStatus {
 	if c.fetchCloneSet(ctx) != nil {
 		return c.rolloutStatus
 	}
-
+	if !hasBatchPodList(c.rolloutSpec) {
+		return c.rolloutStatus
+	}
+	pods, err := listWorkloadPods(ctx, c.client, c.cloneSet.GetNamespace(), c.cloneSet.Spec.Selector)
+	if err == nil {
+		err = unselectPods(ctx, c.client, pods)
+	}
+	if err != nil {
+		c.recorder.Event(c.parentController, event.Warning("Failed to clean up the selected pods", err))
+		c.rolloutStatus.RolloutRetry(err.Error())
+		return c.rolloutStatus
+	}
+	if reflect.DeepEqual(c.cloneSet.Spec.UpdateStrategy.PriorityStrategy, selectedPodPriorityStrategy()) {
+		clonePatch := client.MergeFrom(c.cloneSet.DeepCopyObject())
+		c.cloneSet.Spec.UpdateStrategy.PriorityStrategy = nil
+		if err := c.client.Patch(ctx, c.cloneSet, clonePatch, client.FieldOwner(c.parentController.GetUID())); err != nil {
+			c.recorder.Event(c.parentController, event.Warning("Failed to patch update the Cloneset", err))
+			c.rolloutStatus.RolloutRetry(err.Error())
+			return c.rolloutStatus
+		}
+	}
 	return c.rolloutStatus
 }
 
@@ -215,6 +332,20 @@ func (c *CloneSetController) verifyRolloutBatchReplicaValue(totalReplicas int32)
 	return nil
 }
 
+// selectedPodPriorityStrategy makes the Cloneset upgrade the pods selected by the pod list of a batch first
+func selectedPodPriorityStrategy() *appspub.UpdatePriorityStrategy {
+	return &appspub.UpdatePriorityStrategy{
+		WeightPriority: []appspub.UpdatePriorityWeightTerm{
+			{
+				Weight: selectedPodPriority,
+				MatchSelector: metav1.LabelSelector{
+					MatchLabels: map[string]string{oam.LabelRolloutSelectedPod: "true"},
+				},
+			},
+		},
+	}
+}
+
 func (c *CloneSetController) fetchCloneSet(ctx context.Context) error {
 	// get the cloneSet
 	workload := kruise.CloneSet{}
@@ -229,26 +360,3 @@ func (c *CloneSetController) fetchCloneSet(ctx context.Context) error {
 	c.cloneSet = &workload
 	return nil
 }
-
-func (c *CloneSetController) calculateNewPodTarget(cloneSetSize int) int {
-	currentBatch := int(c.rolloutStatus.CurrentBatch)
-	newPodTarget := 0
-	if currentBatch == len(c.rolloutSpec.RolloutBatches)-1 {
-		// special handle the last batch, we ignore the rest of the batch in case there are rounding errors
-		klog.InfoS("use the cloneset size as the total pod target for the last rolling batch",
-			"current batch", currentBatch, "new version pod target", newPodTarget)
-		newPodTarget = cloneSetSize
-	} else {
-		for i, r := range c.rolloutSpec.RolloutBatches {
-			batchSize, _ := intstr.GetValueFromIntOrPercent(&r.Replicas, cloneSetSize, true)
-			if i <= currentBatch {
-				newPodTarget += batchSize
-			} else {
-				break
-			}
-		}
-		klog.InfoS("Calculated the number of new version pod", "current batch", currentBatch,
-			"new version pod target", newPodTarget)
-	}
-	return newPodTarget
-}
@@ -0,0 +1,153 @@
+package workloads
+
+import (
+	"context"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	appspub "github.com/openkruise/kruise-api/apps/pub"
+	kruise "github.com/openkruise/kruise-api/apps/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+func newTestPod(name string, labels map[string]string) *corev1.Pod {
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: labels}}
+}
+
+func TestCloneSetRolloutPodList(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, kruise.AddToScheme(scheme))
+	appLabels := map[string]string{"app": "frontend"}
+	partition := intstr.FromInt(3)
+	cloneSet := &kruise.CloneSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "frontend", Namespace: "default"},
+		Spec: kruise.CloneSetSpec{
+			Replicas:       pointer.Int32Ptr(3),
+			Selector:       &metav1.LabelSelector{MatchLabels: appLabels},
+			UpdateStrategy: kruise.CloneSetUpdateStrategy{Partition: &partition},
+		},
+		Status: kruise.CloneSetStatus{UpdateRevision: "frontend-v2"},
+	}
+	c := fake.NewFakeClientWithScheme(scheme, cloneSet, newTestPod("frontend-a", appLabels),
+		newTestPod("frontend-b", appLabels), newTestPod("frontend-c", appLabels))
+	workload := types.NamespacedName{Namespace: "default", Name: "frontend"}
+	rolloutSpec := &v1alpha1.RolloutPlan{
+		RolloutBatches: []v1alpha1.RolloutBatch{
+			{PodList: []string{"frontend-b"}},
+			{Replicas: intstr.FromInt(2)},
+		},
+	}
+	rolloutStatus := &v1alpha1.RolloutStatus{RollingState: v1alpha1.VerifyingState}
+	newController := func() *CloneSetController {
+		return NewCloneSetController(c, event.NewNopRecorder(), &v1alpha2.ApplicationDeployment{},
+			rolloutSpec, rolloutStatus, workload)
+	}
+
+	status := newController().Verify(ctx)
+	assert.Equal(t, v1alpha1.InitializingState, status.RollingState)
+
+	// the listed pod is labeled and upgraded first
+	status.RollingState = v1alpha1.RollingInBatchesState
+	status.BatchRollingState = v1alpha1.BatchInRollingState
+	status = newController().RolloutOneBatchPods(ctx)
+	assert.Equal(t, v1alpha1.BatchVerifyingState, status.BatchRollingState)
+	assert.Equal(t, int32(1), status.UpgradedReplicas)
+	var pod corev1.Pod
+	assert.NoError(t, c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "frontend-b"}, &pod))
+	assert.Equal(t, "true", pod.Labels[oam.LabelRolloutSelectedPod])
+	var cs kruise.CloneSet
+	assert.NoError(t, c.Get(ctx, workload, &cs))
+	assert.Equal(t, int32(2), cs.Spec.UpdateStrategy.Partition.IntVal)
+	assert.Equal(t, selectedPodPriorityStrategy(), cs.Spec.UpdateStrategy.PriorityStrategy)
+
+	// the finalizer cleans up
+	newController().Finalize(ctx)
+	var finalizedPod corev1.Pod
+	assert.NoError(t, c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "frontend-b"}, &finalizedPod))
+	assert.NotContains(t, finalizedPod.Labels, oam.LabelRolloutSelectedPod)
+	var finalizedCloneSet kruise.CloneSet
+	assert.NoError(t, c.Get(ctx, workload, &finalizedCloneSet))
+	assert.Nil(t, finalizedCloneSet.Spec.UpdateStrategy.PriorityStrategy)
+}
+
+func TestCloneSetVerifyPodList(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, kruise.AddToScheme(scheme))
+	appLabels := map[string]string{"app": "frontend"}
+	cloneSet := &kruise.CloneSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "frontend", Namespace: "default"},
+		Spec: kruise.CloneSetSpec{
+			Replicas: pointer.Int32Ptr(2),
+			Selector: &metav1.LabelSelector{MatchLabels: appLabels},
+		},
+		Status: kruise.CloneSetStatus{UpdateRevision: "frontend-v2"},
+	}
+	c := fake.NewFakeClientWithScheme(scheme, cloneSet, newTestPod("frontend-a", appLabels),
+		newTestPod("backend-a", map[string]string{"app": "backend"}))
+	rolloutSpec := &v1alpha1.RolloutPlan{
+		RolloutBatches: []v1alpha1.RolloutBatch{
+			{PodList: []string{"backend-a"}},
+			{Replicas: intstr.FromInt(1)},
+		},
+	}
+	status := NewCloneSetController(c, event.NewNopRecorder(), &v1alpha2.ApplicationDeployment{}, rolloutSpec,
+		&v1alpha1.RolloutStatus{RollingState: v1alpha1.VerifyingState},
+		types.NamespacedName{Namespace: "default", Name: "frontend"}).Verify(ctx)
+	assert.Equal(t, v1alpha1.RolloutFailedState, status.RollingState)
+}
+
+func TestCloneSetVerifyPodListPriorityStrategy(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, kruise.AddToScheme(scheme))
+	appLabels := map[string]string{"app": "frontend"}
+	partition := intstr.FromInt(2)
+	// the user upgrades the pods by their own priority
+	cloneSet := &kruise.CloneSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "frontend", Namespace: "default"},
+		Spec: kruise.CloneSetSpec{
+			Replicas: pointer.Int32Ptr(2),
+			Selector: &metav1.LabelSelector{MatchLabels: appLabels},
+			UpdateStrategy: kruise.CloneSetUpdateStrategy{
+				Partition: &partition,
+				PriorityStrategy: &appspub.UpdatePriorityStrategy{
+					WeightPriority: []appspub.UpdatePriorityWeightTerm{{
+						Weight:        10,
+						MatchSelector: metav1.LabelSelector{MatchLabels: map[string]string{"zone": "a"}},
+					}},
+				},
+			},
+		},
+		Status: kruise.CloneSetStatus{UpdateRevision: "frontend-v2"},
+	}
+	c := fake.NewFakeClientWithScheme(scheme, cloneSet, newTestPod("frontend-a", appLabels),
+		newTestPod("frontend-b", appLabels))
+	rolloutSpec := &v1alpha1.RolloutPlan{
+		RolloutBatches: []v1alpha1.RolloutBatch{
+			{PodList: []string{"frontend-a"}},
+			{Replicas: intstr.FromInt(1)},
+		},
+	}
+	status := NewCloneSetController(c, event.NewNopRecorder(), &v1alpha2.ApplicationDeployment{}, rolloutSpec,
+		&v1alpha1.RolloutStatus{RollingState: v1alpha1.VerifyingState},
+		types.NamespacedName{Namespace: "default", Name: "frontend"}).Verify(ctx)
+	assert.Equal(t, v1alpha1.RolloutFailedState, status.RollingState)
+}
@@ -1,11 +1,20 @@
 package workloads
 
 import (
+	"context"
 	"fmt"
 
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	oamutil "github.com/oam-dev/kubevela/pkg/oam/util"
 )
 
 // VerifySumOfBatchSizes verifies that the the sum of all the batch replicas is valid given the total replica
@@ -14,9 +23,7 @@ func VerifySumOfBatchSizes(rolloutSpec *v1alpha1.RolloutPlan, totalReplicas int3
 	// if not set, the sum of all the batch sizes minus the last batch cannot be more than the totalReplicas
 	totalRollout := 0
 	for i := 0; i < len(rolloutSpec.RolloutBatches)-1; i++ {
-		rb := rolloutSpec.RolloutBatches[i]
-		batchSize, _ := intstr.GetValueFromIntOrPercent(&rb.Replicas, int(totalReplicas), true)
-		totalRollout += batchSize
+		totalRollout += CalculateBatchSize(&rolloutSpec.RolloutBatches[i], int(totalReplicas))
 	}
 	if totalRollout >= int(totalReplicas) {
 		return fmt.Errorf("the rollout plan batch size mismatch, total batch size = %d, totalReplicas size = %d",
@@ -26,8 +33,8 @@ func VerifySumOfBatchSizes(rolloutSpec *v1alpha1.RolloutPlan, totalReplicas int3
 	// include the last batch if it has an int value
 	// we ignore the last batch percentage since it is very likely to cause rounding errors
 	lastBatch := rolloutSpec.RolloutBatches[len(rolloutSpec.RolloutBatches)-1]
-	if lastBatch.Replicas.Type == intstr.Int {
-		totalRollout += int(lastBatch.Replicas.IntVal)
+	if len(lastBatch.PodList) != 0 || lastBatch.Replicas.Type == intstr.Int {
+		totalRollout += CalculateBatchSize(&lastBatch, int(totalReplicas))
 		// now that they should be the same
 		if totalRollout != int(totalReplicas) {
 			return fmt.Errorf("the rollout plan batch size mismatch, total batch size = %d, totalReplicas size = %d",
@@ -36,3 +43,180 @@ func VerifySumOfBatchSizes(rolloutSpec *v1alpha1.RolloutPlan, totalReplicas int3
 	}
 	return nil
 }
+
+// calculateNewBatchTarget calculates the total number of pods that should be upgraded after the current batch
+// the last batch always takes the whole workload size in case there are rounding errors
+func calculateNewBatchTarget(rolloutSpec *v1alpha1.RolloutPlan, currentBatch, workloadSize int) int {
+	if currentBatch == len(rolloutSpec.RolloutBatches)-1 {
+		// special handle the last batch, we ignore the rest of the batch in case there are rounding errors
+		klog.InfoS("use the workload size as the total pod target for the last rolling batch",
+			"current batch", currentBatch, "new version pod target", workloadSize)
+		return workloadSize
+	}
+	newPodTarget := 0
+	for i := range rolloutSpec.RolloutBatches {
+		if i <= currentBatch {
+			newPodTarget += CalculateBatchSize(&rolloutSpec.RolloutBatches[i], workloadSize)
+		} else {
+			break
+		}
+	}
+	klog.InfoS("Calculated the number of new version pod", "current batch", currentBatch,
+		"new version pod target", newPodTarget)
+	return newPodTarget
+}
+
+const (
+	// podDeletionCostAnnotation tells the replicaSet which pods to delete first when it scales down
+	podDeletionCostAnnotation = "controller.kubernetes.io/pod-deletion-cost"
+	// selectedPodDeletionCost is the deletion cost of the pods selected by the pod list of a batch
+	selectedPodDeletionCost = "-1000"
+)
+
+// CalculateBatchSize returns the number of pods in a batch, it's the size of the pod list if the batch has one
+func CalculateBatchSize(rb *v1alpha1.RolloutBatch, workloadSize int) int {
+	if len(rb.PodList) != 0 {
+		return len(rb.PodList)
+	}
+	batchSize, _ := intstr.GetValueFromIntOrPercent(&rb.Replicas, workloadSize, true)
+	return batchSize
+}
+
+// hasBatchPodList checks if any batch of the rollout plan selects the pods by name
+func hasBatchPodList(rolloutSpec *v1alpha1.RolloutPlan) bool {
+	for _, rb := range rolloutSpec.RolloutBatches {
+		if len(rb.PodList) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyBatchPodList checks that the pods in the pod list of every batch belong to the workload
+func verifyBatchPodList(rolloutSpec *v1alpha1.RolloutPlan, pods []corev1.Pod) error {
+	workloadPods := make(map[string]bool, len(pods))
+	for _, pod := range pods {
+		workloadPods[pod.Name] = true
+	}
+	for i, rb := range rolloutSpec.RolloutBatches {
+		for _, podName := range rb.PodList {
+			if !workloadPods[podName] {
+				return fmt.Errorf("the pod %s in the batch num = %d does not belong to the workload", podName, i)
+			}
+		}
+	}
+	return nil
+}
+
+// selectBatchPods labels the pods in the pod list of the current batch so that the workload can find them
+func selectBatchPods(ctx context.Context, c client.Client, namespace string, podList []string) error {
+	for _, podName := range podList {
+		var pod corev1.Pod
+		if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: podName}, &pod); err != nil {
+			if apierrors.IsNotFound(err) {
+				// the pod might be upgraded already
+				continue
+			}
+			return err
+		}
+		if pod.Labels[oam.LabelRolloutSelectedPod] == "true" {
+			continue
+		}
+		podPatch := client.MergeFrom(pod.DeepCopy())
+		pod.SetLabels(oamutil.MergeMapOverrideWithDst(pod.GetLabels(),
+			map[string]string{oam.LabelRolloutSelectedPod: "true"}))
+		if err := c.Patch(ctx, &pod, podPatch); err != nil {
+			return err
+		}
+		klog.InfoS("selected the pod for the current batch", "pod", klog.KObj(&pod))
+	}
+	return nil
+}
+
+// unselectPods removes the label from the pods selected by the rollout batches
+func unselectPods(ctx context.Context, c client.Client, pods []corev1.Pod) error {
+	for i := range pods {
+		pod := &pods[i]
+		if _, ok := pod.Labels[oam.LabelRolloutSelectedPod]; !ok {
+			continue
+		}
+		podPatch := client.MergeFrom(pod.DeepCopy())
+		delete(pod.Labels, oam.LabelRolloutSelectedPod)
+		if err := c.Patch(ctx, pod, podPatch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// calculateMaxUnavailable returns the number of pods that are allowed to be unavailable in the current batch
+func calculateMaxUnavailable(rolloutSpec *v1alpha1.RolloutPlan, currentBatch, workloadSize int) int {
+	unavail := 0
+	if maxUnavailable := rolloutSpec.RolloutBatches[currentBatch].MaxUnavailable; maxUnavailable != nil {
+		unavail, _ = intstr.GetValueFromIntOrPercent(maxUnavailable, workloadSize, true)
+	}
+	return unavail
+}
+
+// calculateRevertTarget calculates the number of pods left in the target after we revert the current batch
+func calculateRevertTarget(rolloutSpec *v1alpha1.RolloutPlan, currentBatch, workloadSize int) int {
+	if currentBatch == 0 {
+		return 0
+	}
+	return calculateNewBatchTarget(rolloutSpec, currentBatch-1, workloadSize)
+}
+
+// startRollbackStep records that we start to revert the current batch
+func startRollbackStep(rolloutStatus *v1alpha1.RolloutStatus, upgradedReplicas int32) {
+	rolloutStatus.UpgradedReplicas = upgradedReplicas
+	rolloutStatus.RollbackSteps = append(rolloutStatus.RollbackSteps, v1alpha1.RollbackStep{
+		Batch:            rolloutStatus.CurrentBatch,
+		UpgradedReplicas: upgradedReplicas,
+		StartTime:        metav1.Now(),
+	})
+}
+
+// completeRollbackStep records that the reverted pods of the current batch are available
+func completeRollbackStep(rolloutStatus *v1alpha1.RolloutStatus) {
+	if len(rolloutStatus.RollbackSteps) == 0 {
+		return
+	}
+	now := metav1.Now()
+	rolloutStatus.RollbackSteps[len(rolloutStatus.RollbackSteps)-1].CompletionTime = &now
+}
+
+// listWorkloadPods lists all the pods that belong to a workload given its selector
+func listWorkloadPods(ctx context.Context, c client.Client, namespace string,
+	labelSelector *metav1.LabelSelector) ([]corev1.Pod, error) {
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return nil, err
+	}
+	var pods corev1.PodList
+	if err := c.List(ctx, &pods, client.InNamespace(namespace),
+		client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, err
+	}
+	return pods.Items, nil
+}
+
+// filterPodsByRevision returns the pods that are on the given revision
+func filterPodsByRevision(pods []corev1.Pod, revision string) []corev1.Pod {
+	var revisionPods []corev1.Pod
+	for _, pod := range pods {
+		if pod.Labels[apps.ControllerRevisionHashLabelKey] == revision {
+			revisionPods = append(revisionPods, pod)
+		}
+	}
+	return revisionPods
+}
+
+// isPodReady checks if the pod has a true ready condition
+func isPodReady(pod *corev1.Pod) bool {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady && cond.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package workloads
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+)
+
+func TestBatchSizeWithPodList(t *testing.T) {
+	rolloutSpec := &v1alpha1.RolloutPlan{
+		RolloutBatches: []v1alpha1.RolloutBatch{
+			{PodList: []string{"pod-a", "pod-b"}},
+			{Replicas: intstr.FromString("50%")},
+			{Replicas: intstr.FromInt(2)},
+		},
+	}
+	assert.Equal(t, 2, CalculateBatchSize(&rolloutSpec.RolloutBatches[0], 8))
+	assert.Equal(t, 4, CalculateBatchSize(&rolloutSpec.RolloutBatches[1], 8))
+	assert.NoError(t, VerifySumOfBatchSizes(rolloutSpec, 8))
+	assert.Equal(t, 2, calculateNewBatchTarget(rolloutSpec, 0, 8))
+	assert.Equal(t, 6, calculateNewBatchTarget(rolloutSpec, 1, 8))
+
+	// the pod list of the last batch counts
+	rolloutSpec.RolloutBatches[2] = v1alpha1.RolloutBatch{PodList: []string{"pod-c"}}
+	assert.Error(t, VerifySumOfBatchSizes(rolloutSpec, 8))
+	assert.NoError(t, VerifySumOfBatchSizes(rolloutSpec, 7))
+}
+
+func TestVerifyBatchPodList(t *testing.T) {
+	rolloutSpec := &v1alpha1.RolloutPlan{
+		RolloutBatches: []v1alpha1.RolloutBatch{
+			{PodList: []string{"pod-a"}},
+			{Replicas: intstr.FromInt(1)},
+		},
+	}
+	assert.True(t, hasBatchPodList(rolloutSpec))
+	pods := []corev1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Name: "pod-a"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "pod-b"}},
+	}
+	assert.NoError(t, verifyBatchPodList(rolloutSpec, pods))
+	assert.Error(t, verifyBatchPodList(rolloutSpec, pods[1:]))
+
+	assert.False(t, hasBatchPodList(&v1alpha1.RolloutPlan{
+		RolloutBatches: []v1alpha1.RolloutBatch{{Replicas: intstr.FromInt(1)}},
+	}))
+}
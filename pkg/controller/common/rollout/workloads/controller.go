@@ -34,6 +34,13 @@ type WorkloadController interface {
 	// it also needs to handle the corner cases around the very last batch
 	FinalizeOneBatch(ctx context.Context) *v1alpha1.RolloutStatus
 
+	// RollbackOneBatchPods reverts the pods of the current batch back to the source revision
+	// it is called in reverse batch order after the rollout fails
+	RollbackOneBatchPods(ctx context.Context) *v1alpha1.RolloutStatus
+
+	// CheckOneBatchRollback checks to see if the reverted pods of the current batch are all available
+	CheckOneBatchRollback(ctx context.Context) *v1alpha1.RolloutStatus
+
 	// Finalize makes sure the resources are in a good final state.
 	// For example, we may remove the source object to prevent scalar traits to ever work
 	// and we will call the finalize rollout web hooks
@@ -0,0 +1,427 @@
+package workloads
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+	"github.com/oam-dev/kubevela/pkg/controller/common"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+// DeploymentController is responsible for handle rollout between two Deployments
+// the target deployment is scaled up batch by batch while the source deployment is scaled down
+type DeploymentController struct {
+	client           client.Client
+	recorder         event.Recorder
+	parentController oam.Object
+
+	rolloutSpec          *v1alpha1.RolloutPlan
+	rolloutStatus        *v1alpha1.RolloutStatus
+	targetNamespacedName types.NamespacedName
+	// the source is empty if it's the first time to deploy the workload
+	sourceNamespacedName *types.NamespacedName
+	targetDeploy         *apps.Deployment
+	sourceDeploy         *apps.Deployment
+}
+
+// NewDeploymentController creates a new Deployment rollout controller
+func NewDeploymentController(client client.Client, recorder event.Recorder, parentController oam.Object,
+	rolloutSpec *v1alpha1.RolloutPlan, rolloutStatus *v1alpha1.RolloutStatus, targetNamespacedName types.NamespacedName,
+	sourceNamespacedName *types.NamespacedName) *DeploymentController {
+	return &DeploymentController{
+		client:               client,
+		recorder:             recorder,
+		parentController:     parentController,
+		rolloutSpec:          rolloutSpec,
+		rolloutStatus:        rolloutStatus,
+		targetNamespacedName: targetNamespacedName,
+		sourceNamespacedName: sourceNamespacedName,
+	}
+}
+
+// Size returns the total number of pods that the rollout ends up with
+func (c *DeploymentController) Size(ctx context.Context) (int32, error) {
+	if c.rolloutStatus.RolloutTargetSize != 0 {
+		return c.rolloutStatus.RolloutTargetSize, nil
+	}
+	if err := c.fetchDeployments(ctx); err != nil {
+		return 0, err
+	}
+	return c.calculateRolloutTargetSize(), nil
+}
+
+// Verify verifies that the target rollout resource is consistent with the rollout spec
+func (c *DeploymentController) Verify(ctx context.Context) (status *v1alpha1.RolloutStatus) {
+	var verifyErr error
+	status = c.rolloutStatus
+
+	defer func() {
+		if verifyErr != nil {
+			klog.Error(verifyErr)
+			c.recorder.Event(c.parentController, event.Warning("VerifyFailed", verifyErr))
+		}
+	}()
+
+	if verifyErr = c.fetchDeployments(ctx); verifyErr != nil {
+		return
+	}
+
+	// make sure that there are changes in the pod template
+	targetHash := computePodTemplateHash(c.targetDeploy)
+	if targetHash == c.rolloutStatus.LastAppliedPodTemplateIdentifier ||
+		(c.sourceDeploy != nil && targetHash == computePodTemplateHash(c.sourceDeploy)) {
+		verifyErr = fmt.Errorf("there is no difference between the source and target, hash = %s", targetHash)
+		c.rolloutStatus.RolloutFailed(verifyErr.Error())
+		return
+	}
+	// record the new pod template hash
+	c.rolloutStatus.NewPodTemplateIdentifier = targetHash
+
+	// the source deployment has to be stable before we take it over
+	if c.sourceDeploy != nil && c.sourceDeploy.Status.UpdatedReplicas != getDeploymentReplicas(c.sourceDeploy) {
+		verifyErr = fmt.Errorf("the source deployment %s is still in the middle of updating, updated replicas = %d",
+			c.sourceDeploy.GetName(), c.sourceDeploy.Status.UpdatedReplicas)
+		c.rolloutStatus.RolloutFailed(verifyErr.Error())
+		return
+	}
+
+	totalReplicas := c.calculateRolloutTargetSize()
+	// check if the rollout batch replicas added up to the total replicas
+	if c.sourceDeploy != nil && c.rolloutSpec.TargetSize != nil &&
+		*c.rolloutSpec.TargetSize != getDeploymentReplicas(c.sourceDeploy) {
+		verifyErr = fmt.Errorf("the rollout plan is attempting to scale the deployment, target = %d, source size = %d",
+			*c.rolloutSpec.TargetSize, getDeploymentReplicas(c.sourceDeploy))
+		c.rolloutStatus.RolloutFailed(verifyErr.Error())
+		return
+	}
+	if verifyErr = VerifySumOfBatchSizes(c.rolloutSpec, totalReplicas); verifyErr != nil {
+		c.rolloutStatus.RolloutFailed(verifyErr.Error())
+		return
+	}
+
+	// the pods in the pod lists are the source pods to replace
+	if hasBatchPodList(c.rolloutSpec) {
+		if c.sourceDeploy == nil {
+			verifyErr = fmt.Errorf("the pod list of the rollout batches requires a source deployment")
+			c.rolloutStatus.RolloutFailed(verifyErr.Error())
+			return
+		}
+		pods, err := listWorkloadPods(ctx, c.client, c.sourceDeploy.GetNamespace(), c.sourceDeploy.Spec.Selector)
+		if err != nil {
+			verifyErr = err
+			c.rolloutStatus.RolloutRetry(verifyErr.Error())
+			return
+		}
+		if verifyErr = verifyBatchPodList(c.rolloutSpec, pods); verifyErr != nil {
+			c.rolloutStatus.RolloutFailed(verifyErr.Error())
+			return
+		}
+	}
+
+	// the rollout batch partition is either automatic or zero
+	if c.rolloutSpec.BatchPartition != nil && *c.rolloutSpec.BatchPartition != 0 {
+		verifyErr = fmt.Errorf("the rollout plan has to start from zero, partition= %d", *c.rolloutSpec.BatchPartition)
+		c.rolloutStatus.RolloutFailed(verifyErr.Error())
+		return
+	}
+	c.rolloutStatus.RolloutTargetSize = totalReplicas
+
+	// mark the rollout verified
+	c.recorder.Event(c.parentController, event.Normal("Verified",
+		"Rollout spec and the Deployment resources are verified"))
+	c.rolloutStatus.StateTransition(v1alpha1.RollingSpecVerifiedEvent)
+	return c.rolloutStatus
+}
+
+// Initialize makes sure that the target deployment starts from zero so that we can scale it up batch by batch.
+// The target is created paused by the application, it's resumed in the same patch or it never creates the pods
+func (c *DeploymentController) Initialize(ctx context.Context) *v1alpha1.RolloutStatus {
+	if c.fetchDeployments(ctx) != nil {
+		return c.rolloutStatus
+	}
+
+	if c.targetDeploy.Spec.Paused || c.targetDeploy.Spec.Replicas == nil || *c.targetDeploy.Spec.Replicas != 0 {
+		deployPatch := client.MergeFrom(c.targetDeploy.DeepCopyObject())
+		c.targetDeploy.Spec.Paused = false
+		c.targetDeploy.Spec.Replicas = pointer.Int32Ptr(0)
+		if err := c.client.Patch(ctx, c.targetDeploy, deployPatch, client.FieldOwner(c.parentController.GetUID())); err != nil {
+			c.recorder.Event(c.parentController, event.Warning("Failed to initialize the Deployment", err))
+			c.rolloutStatus.RolloutRetry(err.Error())
+			return c.rolloutStatus
+		}
+		klog.InfoS("initialized the target deployment", "deployment", klog.KObj(c.targetDeploy))
+	}
+
+	// mark the rollout initialized
+	c.recorder.Event(c.parentController, event.Normal("Initialized", "Rollout resource are initialized"))
+	c.rolloutStatus.StateTransition(v1alpha1.RollingInitializedEvent)
+	return c.rolloutStatus
+}
+
+// RolloutOneBatchPods calculates the number of pods we can upgrade once according to the rollout spec
+// and then scales the target deployment up to it. The source deployment is scaled down first
+// only if the rollout strategy is decrease first
+func (c *DeploymentController) RolloutOneBatchPods(ctx context.Context) *v1alpha1.RolloutStatus {
+	if c.fetchDeployments(ctx) != nil {
+		return c.rolloutStatus
+	}
+	totalSize, err := c.Size(ctx)
+	if err != nil {
+		c.rolloutStatus.RolloutRetry(err.Error())
+		return c.rolloutStatus
+	}
+	newPodTarget := calculateNewBatchTarget(c.rolloutSpec, int(c.rolloutStatus.CurrentBatch), int(totalSize))
+	// make sure that the pods in the pod list are the first to go when the source deployment scales down
+	if err := c.markSourcePodsToDelete(ctx,
+		c.rolloutSpec.RolloutBatches[c.rolloutStatus.CurrentBatch].PodList); err != nil {
+		c.recorder.Event(c.parentController, event.Warning("Failed to select the pods of the batch", err))
+		c.rolloutStatus.RolloutRetry(err.Error())
+		return c.rolloutStatus
+	}
+
+	if c.rolloutSpec.RolloutStrategy != nil && *c.rolloutSpec.RolloutStrategy == v1alpha1.DecreaseFirstRolloutStrategyType {
+		if err := c.scaleDeployment(ctx, c.sourceDeploy, totalSize-int32(newPodTarget)); err != nil {
+			return c.rolloutStatus
+		}
+	}
+	if err := c.scaleDeployment(ctx, c.targetDeploy, int32(newPodTarget)); err != nil {
+		return c.rolloutStatus
+	}
+	// record the upgrade
+	klog.InfoS("upgraded one batch", "current batch", c.rolloutStatus.CurrentBatch)
+	c.recorder.Event(c.parentController, event.Normal("Rollout",
+		fmt.Sprintf("upgraded the batch num = %d", c.rolloutStatus.CurrentBatch)))
+	c.rolloutStatus.StateTransition(v1alpha1.BatchRolloutVerifyingEvent)
+	c.rolloutStatus.UpgradedReplicas = int32(newPodTarget)
+	return c.rolloutStatus
+}
+
+// CheckOneBatchPods checks to see if the pods of the target deployment are all available
+// and scales down the source deployment once they are
+func (c *DeploymentController) CheckOneBatchPods(ctx context.Context) *v1alpha1.RolloutStatus {
+	if c.fetchDeployments(ctx) != nil {
+		return c.rolloutStatus
+	}
+	// the ready replicas are stale until the deployment controller observes the new spec
+	if c.targetDeploy.Status.ObservedGeneration < c.targetDeploy.Generation {
+		klog.V(common.LogDebug).InfoS("the target deployment status is not up to date",
+			"generation", c.targetDeploy.Generation, "observed generation", c.targetDeploy.Status.ObservedGeneration)
+		c.rolloutStatus.RolloutRetry("the target deployment status is not up to date")
+		return c.rolloutStatus
+	}
+	totalSize, err := c.Size(ctx)
+	if err != nil {
+		c.rolloutStatus.RolloutRetry(err.Error())
+		return c.rolloutStatus
+	}
+	currentBatch := int(c.rolloutStatus.CurrentBatch)
+	newPodTarget := calculateNewBatchTarget(c.rolloutSpec, currentBatch, int(totalSize))
+	// get the number of ready pod from the target deployment
+	readyPodCount := int(c.targetDeploy.Status.ReadyReplicas)
+	unavail := calculateMaxUnavailable(c.rolloutSpec, currentBatch, int(totalSize))
+	klog.V(common.LogDebug).InfoS("checking the rolling out progress", "current batch", currentBatch,
+		"new pod count target", newPodTarget, "new ready pod count", readyPodCount,
+		"max unavailable pod allowed", unavail)
+	c.rolloutStatus.UpgradedReadyReplicas = int32(readyPodCount)
+	if unavail+readyPodCount < newPodTarget {
+		// continue to verify
+		klog.V(common.LogDebug).InfoS("the batch is not ready yet", "current batch", currentBatch)
+		c.rolloutStatus.RolloutRetry("the batch is not ready yet")
+		return c.rolloutStatus
+	}
+	// the new pods are ready, it's safe to remove the same number of pods from the source
+	if err := c.scaleDeployment(ctx, c.sourceDeploy, totalSize-int32(newPodTarget)); err != nil {
+		return c.rolloutStatus
+	}
+	// record the successful upgrade
+	klog.InfoS("pods are ready", "current batch", currentBatch)
+	c.recorder.Event(c.parentController, event.Normal("Batch Available",
+		fmt.Sprintf("the batch num = %d is available", c.rolloutStatus.CurrentBatch)))
+	c.rolloutStatus.StateTransition(v1alpha1.OneBatchAvailableEvent)
+	c.rolloutStatus.LastAppliedPodTemplateIdentifier = c.rolloutStatus.NewPodTemplateIdentifier
+	return c.rolloutStatus
+}
+
+// FinalizeOneBatch makes sure that the rollout status are updated correctly
+func (c *DeploymentController) FinalizeOneBatch(ctx context.Context) *v1alpha1.RolloutStatus {
+	// nothing to do for now
+	return c.rolloutStatus
+}
+
+// RollbackOneBatchPods scales the source deployment back up and the target deployment back down
+// to the size before the current batch
+func (c *DeploymentController) RollbackOneBatchPods(ctx context.Context) *v1alpha1.RolloutStatus {
+	if c.fetchDeployments(ctx) != nil {
+		return c.rolloutStatus
+	}
+	totalSize, err := c.Size(ctx)
+	if err != nil {
+		c.rolloutStatus.RolloutRetry(err.Error())
+		return c.rolloutStatus
+	}
+	revertTarget := calculateRevertTarget(c.rolloutSpec, int(c.rolloutStatus.CurrentBatch), int(totalSize))
+	// bring the source back first so that we don't lose any capacity
+	if err := c.scaleDeployment(ctx, c.sourceDeploy, totalSize-int32(revertTarget)); err != nil {
+		return c.rolloutStatus
+	}
+	if err := c.scaleDeployment(ctx, c.targetDeploy, int32(revertTarget)); err != nil {
+		return c.rolloutStatus
+	}
+	// record the rollback
+	klog.InfoS("rolled back one batch", "current batch", c.rolloutStatus.CurrentBatch)
+	c.recorder.Event(c.parentController, event.Normal("Rollback",
+		fmt.Sprintf("rolled back the batch num = %d", c.rolloutStatus.CurrentBatch)))
+	startRollbackStep(c.rolloutStatus, int32(revertTarget))
+	c.rolloutStatus.StateTransition(v1alpha1.BatchRolloutVerifyingEvent)
+	return c.rolloutStatus
+}
+
+// CheckOneBatchRollback checks to see if the pods of the source deployment are all available again
+func (c *DeploymentController) CheckOneBatchRollback(ctx context.Context) *v1alpha1.RolloutStatus {
+	if c.fetchDeployments(ctx) != nil {
+		return c.rolloutStatus
+	}
+	totalSize, err := c.Size(ctx)
+	if err != nil {
+		c.rolloutStatus.RolloutRetry(err.Error())
+		return c.rolloutStatus
+	}
+	currentBatch := int(c.rolloutStatus.CurrentBatch)
+	revertTarget := calculateRevertTarget(c.rolloutSpec, currentBatch, int(totalSize))
+	unavail := calculateMaxUnavailable(c.rolloutSpec, currentBatch, int(totalSize))
+	c.rolloutStatus.UpgradedReadyReplicas = c.targetDeploy.Status.ReadyReplicas
+	if c.sourceDeploy != nil && int(c.sourceDeploy.Status.ReadyReplicas)+unavail < int(totalSize)-revertTarget {
+		klog.V(common.LogDebug).InfoS("the batch is not rolled back yet", "current batch", currentBatch)
+		c.rolloutStatus.RolloutRetry("the batch is not rolled back yet")
+		return c.rolloutStatus
+	}
+	c.recorder.Event(c.parentController, event.Normal("Batch Rolled Back",
+		fmt.Sprintf("the batch num = %d is rolled back", c.rolloutStatus.CurrentBatch)))
+	completeRollbackStep(c.rolloutStatus)
+	c.rolloutStatus.StateTransition(v1alpha1.OneBatchRolledBackEvent)
+	return c.rolloutStatus
+}
+
+// Finalize makes sure the source deployment is scaled down to zero
+func (c *DeploymentController) Finalize(ctx context.Context) *v1alpha1.RolloutStatus {
+	if c.fetchDeployments(ctx) != nil {
+		return c.rolloutStatus
+	}
+	_ = c.scaleDeployment(ctx, c.sourceDeploy, 0)
+	return c.rolloutStatus
+}
+
+/* --------------------
+The functions below are helper functions
+--------------------- */
+func (c *DeploymentController) fetchDeployments(ctx context.Context) error {
+	var err error
+	if c.targetDeploy, err = c.fetchDeployment(ctx, c.targetNamespacedName); err != nil {
+		return err
+	}
+	if c.sourceNamespacedName != nil {
+		if c.sourceDeploy, err = c.fetchDeployment(ctx, *c.sourceNamespacedName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *DeploymentController) fetchDeployment(ctx context.Context,
+	namespacedName types.NamespacedName) (*apps.Deployment, error) {
+	workload := apps.Deployment{}
+	err := c.client.Get(ctx, namespacedName, &workload)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			c.recorder.Event(c.parentController, event.Warning("Failed to get the Deployment", err))
+		}
+		c.rolloutStatus.RolloutRetry(err.Error())
+		return nil, err
+	}
+	return &workload, nil
+}
+
+// the total number of pods is the size of the source unless the plan says otherwise
+func (c *DeploymentController) calculateRolloutTargetSize() int32 {
+	if c.rolloutSpec.TargetSize != nil {
+		return *c.rolloutSpec.TargetSize
+	}
+	if c.sourceDeploy != nil {
+		return getDeploymentReplicas(c.sourceDeploy)
+	}
+	return getDeploymentReplicas(c.targetDeploy)
+}
+
+// scaleDeployment patches the replicas of a deployment if it's different from the desired number
+func (c *DeploymentController) scaleDeployment(ctx context.Context, deploy *apps.Deployment, replicas int32) error {
+	if deploy == nil || (deploy.Spec.Replicas != nil && *deploy.Spec.Replicas == replicas) {
+		return nil
+	}
+	deployPatch := client.MergeFrom(deploy.DeepCopyObject())
+	deploy.Spec.Replicas = &replicas
+	if err := c.client.Patch(ctx, deploy, deployPatch, client.FieldOwner(c.parentController.GetUID())); err != nil {
+		c.recorder.Event(c.parentController, event.Warning("Failed to scale the Deployment", err))
+		c.rolloutStatus.RolloutRetry(err.Error())
+		return err
+	}
+	klog.InfoS("scaled the deployment", "deployment", klog.KObj(deploy), "replicas", replicas)
+	return nil
+}
+
+// markSourcePodsToDelete sets the lowest deletion cost on the source pods in the pod list
+// so that the replicaSet deletes them before the others when the source deployment scales down
+func (c *DeploymentController) markSourcePodsToDelete(ctx context.Context, podList []string) error {
+	if c.sourceDeploy == nil {
+		return nil
+	}
+	for _, podName := range podList {
+		var pod corev1.Pod
+		if err := c.client.Get(ctx, client.ObjectKey{Namespace: c.sourceDeploy.GetNamespace(), Name: podName},
+			&pod); err != nil {
+			if apierrors.IsNotFound(err) {
+				// the pod is deleted already
+				continue
+			}
+			return err
+		}
+		if pod.Annotations[podDeletionCostAnnotation] == selectedPodDeletionCost {
+			continue
+		}
+		podPatch := client.MergeFrom(pod.DeepCopy())
+		pod.SetAnnotations(util.MergeMapOverrideWithDst(pod.GetAnnotations(),
+			map[string]string{podDeletionCostAnnotation: selectedPodDeletionCost}))
+		if err := c.client.Patch(ctx, &pod, podPatch); err != nil {
+			return err
+		}
+		klog.InfoS("selected the source pod for the current batch", "pod", klog.KObj(&pod))
+	}
+	return nil
+}
+
+// getDeploymentReplicas returns the replicas of a deployment, default is 1
+func getDeploymentReplicas(deploy *apps.Deployment) int32 {
+	if deploy.Spec.Replicas == nil {
+		return 1
+	}
+	return *deploy.Spec.Replicas
+}
+
+// computePodTemplateHash returns a hash value that uniquely represents the pod template of a deployment
+func computePodTemplateHash(deploy *apps.Deployment) string {
+	hasher := fnv.New32a()
+	util.DeepHashObject(hasher, deploy.Spec.Template)
+	return rand.SafeEncodeString(fmt.Sprint(hasher.Sum32()))
+}
@@ -0,0 +1,270 @@
+package workloads
+
+import (
+	"context"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/stretchr/testify/assert"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+)
+
+func newTestDeployment(name string, replicas int32, image string) *apps.Deployment {
+	return &apps.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: apps.DeploymentSpec{
+			Replicas: pointer.Int32Ptr(replicas),
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: image}}},
+			},
+		},
+		Status: apps.DeploymentStatus{UpdatedReplicas: replicas},
+	}
+}
+
+func TestDeploymentRollout(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	c := fake.NewFakeClientWithScheme(scheme, newTestDeployment("source", 4, "app:v1"),
+		newTestDeployment("target", 4, "app:v2"))
+	target := types.NamespacedName{Namespace: "default", Name: "target"}
+	source := types.NamespacedName{Namespace: "default", Name: "source"}
+	rolloutSpec := &v1alpha1.RolloutPlan{
+		RolloutBatches: []v1alpha1.RolloutBatch{
+			{Replicas: intstr.FromInt(1)},
+			{Replicas: intstr.FromInt(3)},
+		},
+	}
+	rolloutStatus := &v1alpha1.RolloutStatus{RollingState: v1alpha1.VerifyingState}
+	newController := func() *DeploymentController {
+		return NewDeploymentController(c, event.NewNopRecorder(), &v1alpha2.ApplicationDeployment{},
+			rolloutSpec, rolloutStatus, target, &source)
+	}
+
+	status := newController().Verify(ctx)
+	assert.Equal(t, v1alpha1.InitializingState, status.RollingState)
+	assert.Equal(t, int32(4), status.RolloutTargetSize)
+	assert.NotEmpty(t, status.NewPodTemplateIdentifier)
+
+	status = newController().Initialize(ctx)
+	assert.Equal(t, v1alpha1.RollingInBatchesState, status.RollingState)
+	var deploy apps.Deployment
+	assert.NoError(t, c.Get(ctx, target, &deploy))
+	assert.Equal(t, int32(0), *deploy.Spec.Replicas)
+
+	// the first batch scales up the target only
+	status.BatchRollingState = v1alpha1.BatchInRollingState
+	status = newController().RolloutOneBatchPods(ctx)
+	assert.Equal(t, v1alpha1.BatchVerifyingState, status.BatchRollingState)
+	assert.Equal(t, int32(1), status.UpgradedReplicas)
+	assert.NoError(t, c.Get(ctx, target, &deploy))
+	assert.Equal(t, int32(1), *deploy.Spec.Replicas)
+	assert.NoError(t, c.Get(ctx, source, &deploy))
+	assert.Equal(t, int32(4), *deploy.Spec.Replicas)
+
+	// the source is not scaled down until the new pods are ready
+	status = newController().CheckOneBatchPods(ctx)
+	assert.Equal(t, v1alpha1.BatchVerifyingState, status.BatchRollingState)
+	assert.NoError(t, c.Get(ctx, target, &deploy))
+	deploy.Status.ReadyReplicas = 1
+	assert.NoError(t, c.Status().Update(ctx, &deploy))
+	status = newController().CheckOneBatchPods(ctx)
+	assert.Equal(t, v1alpha1.BatchFinalizingState, status.BatchRollingState)
+	assert.NoError(t, c.Get(ctx, source, &deploy))
+	assert.Equal(t, int32(3), *deploy.Spec.Replicas)
+}
+
+func TestDeploymentCheckStaleStatus(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	// the deployment controller has not observed the scaled target yet
+	targetDeploy := newTestDeployment("target", 1, "app:v2")
+	targetDeploy.Generation = 2
+	targetDeploy.Status.ObservedGeneration = 1
+	targetDeploy.Status.ReadyReplicas = 4
+	c := fake.NewFakeClientWithScheme(scheme, newTestDeployment("source", 4, "app:v1"), targetDeploy)
+	source := types.NamespacedName{Namespace: "default", Name: "source"}
+	rolloutSpec := &v1alpha1.RolloutPlan{
+		RolloutBatches: []v1alpha1.RolloutBatch{{Replicas: intstr.FromInt(1)}, {Replicas: intstr.FromInt(3)}},
+	}
+	rolloutStatus := &v1alpha1.RolloutStatus{RollingState: v1alpha1.RollingInBatchesState,
+		BatchRollingState: v1alpha1.BatchVerifyingState, RolloutTargetSize: 4}
+	status := NewDeploymentController(c, event.NewNopRecorder(), &v1alpha2.ApplicationDeployment{},
+		rolloutSpec, rolloutStatus, types.NamespacedName{Namespace: "default", Name: "target"}, &source).
+		CheckOneBatchPods(ctx)
+	assert.Equal(t, v1alpha1.BatchVerifyingState, status.BatchRollingState)
+	var deploy apps.Deployment
+	assert.NoError(t, c.Get(ctx, source, &deploy))
+	assert.Equal(t, int32(4), *deploy.Spec.Replicas)
+}
+
+func TestDeploymentVerifyNoChange(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	c := fake.NewFakeClientWithScheme(scheme, newTestDeployment("source", 4, "app:v1"),
+		newTestDeployment("target", 4, "app:v1"))
+	source := types.NamespacedName{Namespace: "default", Name: "source"}
+	rolloutSpec := &v1alpha1.RolloutPlan{
+		RolloutBatches: []v1alpha1.RolloutBatch{{Replicas: intstr.FromInt(4)}},
+	}
+	rolloutStatus := &v1alpha1.RolloutStatus{RollingState: v1alpha1.VerifyingState}
+	status := NewDeploymentController(c, event.NewNopRecorder(), &v1alpha2.ApplicationDeployment{},
+		rolloutSpec, rolloutStatus, types.NamespacedName{Namespace: "default", Name: "target"}, &source).
+		Verify(context.Background())
+	assert.Equal(t, v1alpha1.RolloutFailedState, status.RollingState)
+}
+
+func TestDeploymentRollback(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	// the rollout failed in the second batch with 3 pods upgraded
+	c := fake.NewFakeClientWithScheme(scheme, newTestDeployment("source", 1, "app:v1"),
+		newTestDeployment("target", 3, "app:v2"))
+	target := types.NamespacedName{Namespace: "default", Name: "target"}
+	source := types.NamespacedName{Namespace: "default", Name: "source"}
+	rolloutSpec := &v1alpha1.RolloutPlan{
+		RollbackPolicy: v1alpha1.RevertToSourceRollbackPolicyType,
+		RolloutBatches: []v1alpha1.RolloutBatch{
+			{Replicas: intstr.FromInt(1)},
+			{Replicas: intstr.FromInt(2)},
+			{Replicas: intstr.FromInt(1)},
+		},
+	}
+	rolloutStatus := &v1alpha1.RolloutStatus{
+		RollingState:      v1alpha1.RolloutFailedState,
+		CurrentBatch:      1,
+		RolloutTargetSize: 4,
+		UpgradedReplicas:  3,
+	}
+	rolloutStatus.StateTransition(v1alpha1.RollingBackEvent)
+	newController := func() *DeploymentController {
+		return NewDeploymentController(c, event.NewNopRecorder(), &v1alpha2.ApplicationDeployment{},
+			rolloutSpec, rolloutStatus, target, &source)
+	}
+
+	// revert the second batch
+	status := newController().RollbackOneBatchPods(ctx)
+	assert.Equal(t, v1alpha1.BatchVerifyingState, status.BatchRollingState)
+	var deploy apps.Deployment
+	assert.NoError(t, c.Get(ctx, source, &deploy))
+	assert.Equal(t, int32(3), *deploy.Spec.Replicas)
+	assert.NoError(t, c.Get(ctx, target, &deploy))
+	assert.Equal(t, int32(1), *deploy.Spec.Replicas)
+	assert.Len(t, status.RollbackSteps, 1)
+
+	// wait for the source pods to come back
+	status = newController().CheckOneBatchRollback(ctx)
+	assert.Equal(t, v1alpha1.BatchVerifyingState, status.BatchRollingState)
+	assert.NoError(t, c.Get(ctx, source, &deploy))
+	deploy.Status.ReadyReplicas = 3
+	assert.NoError(t, c.Status().Update(ctx, &deploy))
+	status = newController().CheckOneBatchRollback(ctx)
+	assert.Equal(t, v1alpha1.BatchInRollingState, status.BatchRollingState)
+	assert.Equal(t, int32(0), status.CurrentBatch)
+	assert.NotNil(t, status.RollbackSteps[0].CompletionTime)
+
+	// revert the first batch
+	status = newController().RollbackOneBatchPods(ctx)
+	assert.NoError(t, c.Get(ctx, target, &deploy))
+	assert.Equal(t, int32(0), *deploy.Spec.Replicas)
+	assert.NoError(t, c.Get(ctx, source, &deploy))
+	deploy.Status.ReadyReplicas = 4
+	assert.NoError(t, c.Status().Update(ctx, &deploy))
+	status = newController().CheckOneBatchRollback(ctx)
+	assert.Equal(t, v1alpha1.RolloutRolledBackState, status.RollingState)
+	assert.Equal(t, int32(0), status.UpgradedReplicas)
+	assert.Len(t, status.RollbackSteps, 2)
+}
+
+func TestDeploymentRolloutPodList(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	appLabels := map[string]string{"app": "source"}
+	sourceDeploy := newTestDeployment("source", 2, "app:v1")
+	sourceDeploy.Spec.Selector = &metav1.LabelSelector{MatchLabels: appLabels}
+	c := fake.NewFakeClientWithScheme(scheme, sourceDeploy, newTestDeployment("target", 2, "app:v2"),
+		newTestPod("source-a", appLabels), newTestPod("source-b", appLabels))
+	target := types.NamespacedName{Namespace: "default", Name: "target"}
+	source := types.NamespacedName{Namespace: "default", Name: "source"}
+	rolloutSpec := &v1alpha1.RolloutPlan{
+		RolloutBatches: []v1alpha1.RolloutBatch{
+			{PodList: []string{"source-b"}},
+			{Replicas: intstr.FromInt(1)},
+		},
+	}
+	rolloutStatus := &v1alpha1.RolloutStatus{RollingState: v1alpha1.VerifyingState}
+	newController := func() *DeploymentController {
+		return NewDeploymentController(c, event.NewNopRecorder(), &v1alpha2.ApplicationDeployment{},
+			rolloutSpec, rolloutStatus, target, &source)
+	}
+	status := newController().Verify(ctx)
+	assert.Equal(t, v1alpha1.InitializingState, status.RollingState)
+
+	// the listed source pod is the first to be deleted
+	status.RollingState = v1alpha1.RollingInBatchesState
+	status.BatchRollingState = v1alpha1.BatchInRollingState
+	status = newController().RolloutOneBatchPods(ctx)
+	assert.Equal(t, v1alpha1.BatchVerifyingState, status.BatchRollingState)
+	var pod corev1.Pod
+	assert.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "source-b"}, &pod))
+	assert.Equal(t, selectedPodDeletionCost, pod.Annotations[podDeletionCostAnnotation])
+	var otherPod corev1.Pod
+	assert.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "source-a"}, &otherPod))
+	assert.NotContains(t, otherPod.Annotations, podDeletionCostAnnotation)
+
+	// the listed pods have to come from the source
+	rolloutSpec.RolloutBatches[0].PodList = []string{"target-a"}
+	status = NewDeploymentController(c, event.NewNopRecorder(), &v1alpha2.ApplicationDeployment{}, rolloutSpec,
+		&v1alpha1.RolloutStatus{RollingState: v1alpha1.VerifyingState}, target, &source).Verify(ctx)
+	assert.Equal(t, v1alpha1.RolloutFailedState, status.RollingState)
+}
+
+func TestDeploymentInitializePausedTarget(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	// the application creates the target paused for the rollout to take over
+	targetDeploy := newTestDeployment("target", 4, "app:v2")
+	targetDeploy.Spec.Paused = true
+	c := fake.NewFakeClientWithScheme(scheme, newTestDeployment("source", 4, "app:v1"), targetDeploy)
+	target := types.NamespacedName{Namespace: "default", Name: "target"}
+	source := types.NamespacedName{Namespace: "default", Name: "source"}
+	rolloutSpec := &v1alpha1.RolloutPlan{
+		RolloutBatches: []v1alpha1.RolloutBatch{{Replicas: intstr.FromInt(4)}},
+	}
+	rolloutStatus := &v1alpha1.RolloutStatus{RollingState: v1alpha1.VerifyingState}
+	newController := func() *DeploymentController {
+		return NewDeploymentController(c, event.NewNopRecorder(), &v1alpha2.ApplicationDeployment{},
+			rolloutSpec, rolloutStatus, target, &source)
+	}
+	status := newController().Verify(ctx)
+	assert.Equal(t, v1alpha1.InitializingState, status.RollingState)
+
+	status = newController().Initialize(ctx)
+	assert.Equal(t, v1alpha1.RollingInBatchesState, status.RollingState)
+	var deploy apps.Deployment
+	assert.NoError(t, c.Get(ctx, target, &deploy))
+	assert.False(t, deploy.Spec.Paused)
+	assert.Equal(t, int32(0), *deploy.Spec.Replicas)
+
+	// the batches fail to retry if the deployments are gone
+	assert.NoError(t, c.Delete(ctx, &deploy))
+	status.BatchRollingState = v1alpha1.BatchInRollingState
+	status = newController().RolloutOneBatchPods(ctx)
+	assert.Equal(t, v1alpha1.BatchInRollingState, status.BatchRollingState)
+}
@@ -0,0 +1,373 @@
+package workloads
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+	"github.com/oam-dev/kubevela/pkg/controller/common"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+// StatefulSetController is responsible for handle StatefulSet type of workloads
+// it upgrades the pods in place by moving the rolling update partition
+type StatefulSetController struct {
+	client           client.Client
+	recorder         event.Recorder
+	parentController oam.Object
+
+	rolloutSpec            *v1alpha1.RolloutPlan
+	rolloutStatus          *v1alpha1.RolloutStatus
+	workloadNamespacedName types.NamespacedName
+	statefulSet            *apps.StatefulSet
+}
+
+// NewStatefulSetController creates a new StatefulSet controller
+func NewStatefulSetController(client client.Client, recorder event.Recorder, parentController oam.Object,
+	rolloutSpec *v1alpha1.RolloutPlan, rolloutStatus *v1alpha1.RolloutStatus,
+	workloadName types.NamespacedName) *StatefulSetController {
+	return &StatefulSetController{
+		client:                 client,
+		recorder:               recorder,
+		parentController:       parentController,
+		rolloutSpec:            rolloutSpec,
+		rolloutStatus:          rolloutStatus,
+		workloadNamespacedName: workloadName,
+	}
+}
+
+// Size fetches the StatefulSet and returns the replicas (not the actual number of pods)
+func (c *StatefulSetController) Size(ctx context.Context) (int32, error) {
+	if c.statefulSet == nil {
+		err := c.fetchStatefulSet(ctx)
+		if err != nil {
+			return 0, err
+		}
+	}
+	// default is 1
+	if c.statefulSet.Spec.Replicas == nil {
+		return 1, nil
+	}
+	return *c.statefulSet.Spec.Replicas, nil
+}
+
+// Verify verifies that the target rollout resource is consistent with the rollout spec
+func (c *StatefulSetController) Verify(ctx context.Context) (status *v1alpha1.RolloutStatus) {
+	var verifyErr error
+	status = c.rolloutStatus
+
+	defer func() {
+		if verifyErr != nil {
+			klog.Error(verifyErr)
+			c.recorder.Event(c.parentController, event.Warning("VerifyFailed", verifyErr))
+		}
+	}()
+
+	if verifyErr = c.fetchStatefulSet(ctx); verifyErr != nil {
+		return
+	}
+
+	// only the rolling update strategy honors the partition
+	if c.statefulSet.Spec.UpdateStrategy.Type == apps.OnDeleteStatefulSetStrategyType {
+		verifyErr = fmt.Errorf("the statefulset update strategy has to be %s", apps.RollingUpdateStatefulSetStrategyType)
+		c.rolloutStatus.RolloutFailed(verifyErr.Error())
+		return
+	}
+
+	// the update revision is stale until the statefulset controller observes the new pod template
+	if c.statefulSet.Status.ObservedGeneration < c.statefulSet.Generation {
+		verifyErr = fmt.Errorf("the statefulset status is not up to date, generation = %d, observed generation = %d",
+			c.statefulSet.Generation, c.statefulSet.Status.ObservedGeneration)
+		c.rolloutStatus.RolloutRetry(verifyErr.Error())
+		return
+	}
+
+	// make sure that there are changes in the pod template
+	targetHash := c.statefulSet.Status.UpdateRevision
+	if targetHash == c.rolloutStatus.LastAppliedPodTemplateIdentifier ||
+		targetHash == c.statefulSet.Status.CurrentRevision {
+		verifyErr = fmt.Errorf("there is no difference between the source and target, hash = %s", targetHash)
+		c.rolloutStatus.RolloutFailed(verifyErr.Error())
+		return
+	}
+	// record the new pod template hash
+	c.rolloutStatus.NewPodTemplateIdentifier = targetHash
+
+	// check if the rollout spec is compatible with the current state
+	totalReplicas, err := c.Size(ctx)
+	if err != nil {
+		verifyErr = err
+		return
+	}
+
+	// check if the rollout batch replicas added up to the StatefulSet replicas
+	if c.rolloutSpec.TargetSize != nil && *c.rolloutSpec.TargetSize != totalReplicas {
+		verifyErr = fmt.Errorf("the rollout plan is attempting to scale the statefulset, target = %d, statefulset size = %d",
+			*c.rolloutSpec.TargetSize, totalReplicas)
+		c.rolloutStatus.RolloutFailed(verifyErr.Error())
+		return
+	}
+	if verifyErr = VerifySumOfBatchSizes(c.rolloutSpec, totalReplicas); verifyErr != nil {
+		c.rolloutStatus.RolloutFailed(verifyErr.Error())
+		return
+	}
+
+	// the StatefulSet upgrades the pods in the reverse ordinal order, we can't pick the pods
+	if hasBatchPodList(c.rolloutSpec) {
+		verifyErr = fmt.Errorf("the statefulset upgrades the pods in the ordinal order, the pod list is not supported")
+		c.rolloutStatus.RolloutFailed(verifyErr.Error())
+		return
+	}
+
+	// the rollout batch partition is either automatic or zero
+	if c.rolloutSpec.BatchPartition != nil && *c.rolloutSpec.BatchPartition != 0 {
+		verifyErr = fmt.Errorf("the rollout plan has to start from zero, partition= %d", *c.rolloutSpec.BatchPartition)
+		c.rolloutStatus.RolloutFailed(verifyErr.Error())
+		return
+	}
+	c.rolloutStatus.RolloutTargetSize = totalReplicas
+
+	// mark the rollout verified
+	c.recorder.Event(c.parentController, event.Normal("Verified",
+		"Rollout spec and the StatefulSet resource are verified"))
+	c.rolloutStatus.StateTransition(v1alpha1.RollingSpecVerifiedEvent)
+	return c.rolloutStatus
+}
+
+// Initialize makes sure that the StatefulSet is ready to be upgraded, the partition is moved to the replicas
+// so that no pod is upgraded before the first batch
+func (c *StatefulSetController) Initialize(ctx context.Context) *v1alpha1.RolloutStatus {
+	statefulSetSize, err := c.Size(ctx)
+	if err != nil {
+		return c.rolloutStatus
+	}
+	if c.getPartition() != statefulSetSize {
+		if err := c.patchPartition(ctx, statefulSetSize); err != nil {
+			return c.rolloutStatus
+		}
+	}
+
+	// mark the rollout initialized, the partition protects all the pods
+	c.recorder.Event(c.parentController, event.Normal("Initialized", "Rollout resource are initialized"))
+	c.rolloutStatus.StateTransition(v1alpha1.RollingInitializedEvent)
+	return c.rolloutStatus
+}
+
+// RolloutOneBatchPods calculates the number of pods we can upgrade once according to the rollout spec
+// and then set the partition accordingly
+func (c *StatefulSetController) RolloutOneBatchPods(ctx context.Context) *v1alpha1.RolloutStatus {
+	// calculate what's the total pods that should be upgraded given the currentBatch in the status
+	statefulSetSize, err := c.Size(ctx)
+	if err != nil {
+		return c.rolloutStatus
+	}
+	newPodTarget := calculateNewBatchTarget(c.rolloutSpec, int(c.rolloutStatus.CurrentBatch), int(statefulSetSize))
+	// set the Partition as the desired number of pods in old revisions.
+	// the pods with an ordinal that is greater than or equal to the partition will be updated
+	if err := c.patchPartition(ctx, statefulSetSize-int32(newPodTarget)); err != nil {
+		return c.rolloutStatus
+	}
+	// record the upgrade
+	klog.InfoS("upgraded one batch", "current batch", c.rolloutStatus.CurrentBatch)
+	c.recorder.Event(c.parentController, event.Normal("Rollout",
+		fmt.Sprintf("upgraded the batch num = %d", c.rolloutStatus.CurrentBatch)))
+	c.rolloutStatus.StateTransition(v1alpha1.BatchRolloutVerifyingEvent)
+	c.rolloutStatus.UpgradedReplicas = int32(newPodTarget)
+	return c.rolloutStatus
+}
+
+// CheckOneBatchPods checks to see if the pods of the new revision are all available
+func (c *StatefulSetController) CheckOneBatchPods(ctx context.Context) *v1alpha1.RolloutStatus {
+	statefulSetSize, err := c.Size(ctx)
+	if err != nil {
+		return c.rolloutStatus
+	}
+	currentBatch := int(c.rolloutStatus.CurrentBatch)
+	newPodTarget := calculateNewBatchTarget(c.rolloutSpec, currentBatch, int(statefulSetSize))
+	// the StatefulSet status does not tell us how many updated pods are ready, so we count the pods ourselves
+	readyPodCount, err := c.countUpdatedReadyPods(ctx)
+	if err != nil {
+		c.rolloutStatus.RolloutRetry(err.Error())
+		return c.rolloutStatus
+	}
+	unavail := calculateMaxUnavailable(c.rolloutSpec, currentBatch, int(statefulSetSize))
+	klog.V(common.LogDebug).InfoS("checking the rolling out progress", "current batch", currentBatch,
+		"new pod count target", newPodTarget, "new ready pod count", readyPodCount,
+		"max unavailable pod allowed", unavail)
+	c.rolloutStatus.UpgradedReadyReplicas = int32(readyPodCount)
+	if unavail+readyPodCount >= newPodTarget {
+		// record the successful upgrade
+		klog.InfoS("pods are ready", "current batch", currentBatch)
+		c.recorder.Event(c.parentController, event.Normal("Batch Available",
+			fmt.Sprintf("the batch num = %d is available", c.rolloutStatus.CurrentBatch)))
+		c.rolloutStatus.StateTransition(v1alpha1.OneBatchAvailableEvent)
+		c.rolloutStatus.LastAppliedPodTemplateIdentifier = c.rolloutStatus.NewPodTemplateIdentifier
+	} else {
+		// continue to verify
+		klog.V(common.LogDebug).InfoS("the batch is not ready yet", "current batch", currentBatch)
+		c.rolloutStatus.RolloutRetry("the batch is not ready yet")
+	}
+	return c.rolloutStatus
+}
+
+// FinalizeOneBatch makes sure that the rollout status are updated correctly
+func (c *StatefulSetController) FinalizeOneBatch(ctx context.Context) *v1alpha1.RolloutStatus {
+	// nothing to do for now
+	return c.rolloutStatus
+}
+
+// RollbackOneBatchPods moves the partition back to where it was before the current batch.
+// The StatefulSet does not revert the pods below the partition by itself, so we delete the upgraded ones
+// and let the StatefulSet recreate them with the current revision
+func (c *StatefulSetController) RollbackOneBatchPods(ctx context.Context) *v1alpha1.RolloutStatus {
+	statefulSetSize, err := c.Size(ctx)
+	if err != nil {
+		return c.rolloutStatus
+	}
+	revertTarget := calculateRevertTarget(c.rolloutSpec, int(c.rolloutStatus.CurrentBatch), int(statefulSetSize))
+	partition := statefulSetSize - int32(revertTarget)
+	if err := c.patchPartition(ctx, partition); err != nil {
+		return c.rolloutStatus
+	}
+	pods, err := listWorkloadPods(ctx, c.client, c.statefulSet.GetNamespace(), c.statefulSet.Spec.Selector)
+	if err != nil {
+		c.rolloutStatus.RolloutRetry(err.Error())
+		return c.rolloutStatus
+	}
+	upgradedPods := filterPodsByRevision(pods, c.statefulSet.Status.UpdateRevision)
+	for i := range upgradedPods {
+		if getPodOrdinal(&upgradedPods[i]) >= int(partition) {
+			continue
+		}
+		if err := c.client.Delete(ctx, &upgradedPods[i]); client.IgnoreNotFound(err) != nil {
+			c.recorder.Event(c.parentController, event.Warning("Failed to delete an upgraded pod", err))
+			c.rolloutStatus.RolloutRetry(err.Error())
+			return c.rolloutStatus
+		}
+	}
+	// record the rollback
+	klog.InfoS("rolled back one batch", "current batch", c.rolloutStatus.CurrentBatch)
+	c.recorder.Event(c.parentController, event.Normal("Rollback",
+		fmt.Sprintf("rolled back the batch num = %d", c.rolloutStatus.CurrentBatch)))
+	startRollbackStep(c.rolloutStatus, int32(revertTarget))
+	c.rolloutStatus.StateTransition(v1alpha1.BatchRolloutVerifyingEvent)
+	return c.rolloutStatus
+}
+
+// CheckOneBatchRollback checks to see if the upgraded pods below the partition are gone
+// and the StatefulSet is available again
+func (c *StatefulSetController) CheckOneBatchRollback(ctx context.Context) *v1alpha1.RolloutStatus {
+	statefulSetSize, err := c.Size(ctx)
+	if err != nil {
+		return c.rolloutStatus
+	}
+	currentBatch := int(c.rolloutStatus.CurrentBatch)
+	revertTarget := calculateRevertTarget(c.rolloutSpec, currentBatch, int(statefulSetSize))
+	unavail := calculateMaxUnavailable(c.rolloutSpec, currentBatch, int(statefulSetSize))
+	pods, err := listWorkloadPods(ctx, c.client, c.statefulSet.GetNamespace(), c.statefulSet.Spec.Selector)
+	if err != nil {
+		c.rolloutStatus.RolloutRetry(err.Error())
+		return c.rolloutStatus
+	}
+	upgradedPods := filterPodsByRevision(pods, c.statefulSet.Status.UpdateRevision)
+	if len(upgradedPods) > revertTarget || int(c.statefulSet.Status.ReadyReplicas)+unavail < int(statefulSetSize) {
+		klog.V(common.LogDebug).InfoS("the batch is not rolled back yet", "current batch", currentBatch)
+		c.rolloutStatus.RolloutRetry("the batch is not rolled back yet")
+		return c.rolloutStatus
+	}
+	c.recorder.Event(c.parentController, event.Normal("Batch Rolled Back",
+		fmt.Sprintf("the batch num = %d is rolled back", c.rolloutStatus.CurrentBatch)))
+	completeRollbackStep(c.rolloutStatus)
+	c.rolloutStatus.StateTransition(v1alpha1.OneBatchRolledBackEvent)
+	return c.rolloutStatus
+}
+
+// Finalize makes sure the StatefulSet is all upgraded
+func (c *StatefulSetController) Finalize(ctx context.Context) *v1alpha1.RolloutStatus {
+	if c.fetchStatefulSet(ctx) != nil {
+		return c.rolloutStatus
+	}
+
+	return c.rolloutStatus
+}
+
+/* --------------------
+The functions below are helper functions
+--------------------- */
+func (c *StatefulSetController) fetchStatefulSet(ctx context.Context) error {
+	// get the statefulSet
+	workload := apps.StatefulSet{}
+	err := c.client.Get(ctx, c.workloadNamespacedName, &workload)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			c.recorder.Event(c.parentController, event.Warning("Failed to get the StatefulSet", err))
+		}
+		c.rolloutStatus.RolloutRetry(err.Error())
+		return err
+	}
+	c.statefulSet = &workload
+	return nil
+}
+
+// patchPartition sets the rolling update partition of the StatefulSet,
+// the pods with an ordinal that is greater than or equal to the partition are on the update revision
+func (c *StatefulSetController) patchPartition(ctx context.Context, partition int32) error {
+	stsPatch := client.MergeFrom(c.statefulSet.DeepCopyObject())
+	c.statefulSet.Spec.UpdateStrategy.Type = apps.RollingUpdateStatefulSetStrategyType
+	c.statefulSet.Spec.UpdateStrategy.RollingUpdate = &apps.RollingUpdateStatefulSetStrategy{
+		Partition: &partition,
+	}
+	if err := c.client.Patch(ctx, c.statefulSet, stsPatch, client.FieldOwner(c.parentController.GetUID())); err != nil {
+		c.recorder.Event(c.parentController, event.Warning("Failed to patch update the StatefulSet", err))
+		c.rolloutStatus.RolloutRetry(err.Error())
+		return err
+	}
+	return nil
+}
+
+// getPartition returns the partition of the StatefulSet, default is 0
+func (c *StatefulSetController) getPartition() int32 {
+	rollingUpdate := c.statefulSet.Spec.UpdateStrategy.RollingUpdate
+	if rollingUpdate == nil || rollingUpdate.Partition == nil {
+		return 0
+	}
+	return *rollingUpdate.Partition
+}
+
+// countUpdatedReadyPods counts the ready pods that are already on the update revision
+func (c *StatefulSetController) countUpdatedReadyPods(ctx context.Context) (int, error) {
+	pods, err := listWorkloadPods(ctx, c.client, c.statefulSet.GetNamespace(), c.statefulSet.Spec.Selector)
+	if err != nil {
+		return 0, err
+	}
+	readyPodCount := 0
+	for _, pod := range filterPodsByRevision(pods, c.statefulSet.Status.UpdateRevision) {
+		if isPodReady(&pod) {
+			readyPodCount++
+		}
+	}
+	return readyPodCount, nil
+}
+
+// getPodOrdinal returns the ordinal of a StatefulSet pod which is the suffix of its name
+func getPodOrdinal(pod *corev1.Pod) int {
+	idx := strings.LastIndex(pod.GetName(), "-")
+	if idx < 0 {
+		return -1
+	}
+	ordinal, err := strconv.Atoi(pod.GetName()[idx+1:])
+	if err != nil {
+		return -1
+	}
+	return ordinal
+}
@@ -0,0 +1,84 @@
+package workloads
+
+import (
+	"context"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/stretchr/testify/assert"
+	apps "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+)
+
+func TestStatefulSetInitialize(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	// the statefulset is not paused by the partition yet
+	c := fake.NewFakeClientWithScheme(scheme, &apps.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+		Spec:       apps.StatefulSetSpec{Replicas: pointer.Int32Ptr(3)},
+		Status:     apps.StatefulSetStatus{CurrentRevision: "db-1", UpdateRevision: "db-2"},
+	})
+	workload := types.NamespacedName{Namespace: "default", Name: "db"}
+	rolloutSpec := &v1alpha1.RolloutPlan{
+		RolloutBatches: []v1alpha1.RolloutBatch{{Replicas: intstr.FromInt(1)}, {Replicas: intstr.FromInt(2)}},
+	}
+	rolloutStatus := &v1alpha1.RolloutStatus{RollingState: v1alpha1.VerifyingState}
+	newController := func() *StatefulSetController {
+		return NewStatefulSetController(c, event.NewNopRecorder(), &v1alpha2.ApplicationDeployment{},
+			rolloutSpec, rolloutStatus, workload)
+	}
+
+	status := newController().Verify(ctx)
+	assert.Equal(t, v1alpha1.InitializingState, status.RollingState)
+	assert.Equal(t, int32(3), status.RolloutTargetSize)
+
+	status = newController().Initialize(ctx)
+	assert.Equal(t, v1alpha1.RollingInBatchesState, status.RollingState)
+	var sts apps.StatefulSet
+	assert.NoError(t, c.Get(ctx, workload, &sts))
+	assert.Equal(t, apps.RollingUpdateStatefulSetStrategyType, sts.Spec.UpdateStrategy.Type)
+	assert.Equal(t, int32(3), *sts.Spec.UpdateStrategy.RollingUpdate.Partition)
+
+	// the first batch moves the partition to upgrade one pod
+	status.BatchRollingState = v1alpha1.BatchInRollingState
+	status = newController().RolloutOneBatchPods(ctx)
+	assert.Equal(t, v1alpha1.BatchVerifyingState, status.BatchRollingState)
+	assert.NoError(t, c.Get(ctx, workload, &sts))
+	assert.Equal(t, int32(2), *sts.Spec.UpdateStrategy.RollingUpdate.Partition)
+
+	// the batch is retried if the statefulset is gone
+	assert.NoError(t, c.Delete(ctx, &sts))
+	status.BatchRollingState = v1alpha1.BatchInRollingState
+	status = newController().RolloutOneBatchPods(ctx)
+	assert.Equal(t, v1alpha1.BatchInRollingState, status.BatchRollingState)
+}
+
+func TestStatefulSetVerifyStaleStatus(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	// the statefulset controller has not observed the new pod template yet
+	c := fake.NewFakeClientWithScheme(scheme, &apps.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default", Generation: 2},
+		Spec:       apps.StatefulSetSpec{Replicas: pointer.Int32Ptr(3)},
+		Status:     apps.StatefulSetStatus{ObservedGeneration: 1, CurrentRevision: "db-1", UpdateRevision: "db-1"},
+	})
+	rolloutSpec := &v1alpha1.RolloutPlan{
+		RolloutBatches: []v1alpha1.RolloutBatch{{Replicas: intstr.FromInt(3)}},
+	}
+	rolloutStatus := &v1alpha1.RolloutStatus{RollingState: v1alpha1.VerifyingState}
+	status := NewStatefulSetController(c, event.NewNopRecorder(), &v1alpha2.ApplicationDeployment{},
+		rolloutSpec, rolloutStatus, types.NamespacedName{Namespace: "default", Name: "db"}).
+		Verify(context.Background())
+	assert.Equal(t, v1alpha1.VerifyingState, status.RollingState)
+}
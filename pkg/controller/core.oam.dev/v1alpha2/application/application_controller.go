@@ -22,12 +22,15 @@ import (
 	"time"
 
 	"github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
 	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -47,12 +50,20 @@ const RolloutReconcileWaitTime = time.Second * 3
 type Reconciler struct {
 	client.Client
 	dm     discoverymapper.DiscoveryMapper
+	record event.Recorder
 	Log    logr.Logger
 	Scheme *runtime.Scheme
+	// apiReader reads the cluster without the cache for the processing tasks of the templates
+	apiReader client.Reader
+	// healthWatcher watches the resources of the applications to check their health
+	healthWatcher *healthWatcher
 }
 
 // +kubebuilder:rbac:groups=core.oam.dev,resources=applications,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core.oam.dev,resources=applications/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=core.oam.dev,resources=applications/finalizers,verbs=update
+// +kubebuilder:rbac:groups=core.oam.dev,resources=applicationrevisions,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=events,verbs=get;list;create;update;patch
 
 // Reconcile process app event
 func (r *Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
@@ -69,43 +80,82 @@ func (r *Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		return ctrl.Result{}, err
 	}
 
-	// TODO: check finalizer
+	handler := &appHandler{r: r, app: app, logger: applog}
 	if app.DeletionTimestamp != nil {
-		return ctrl.Result{}, nil
+		if !meta.FinalizerExists(&app.ObjectMeta, appFinalizer) {
+			return ctrl.Result{}, nil
+		}
+		applog.Info("delete the resources generated from the application")
+		if err := handler.finalizeResources(ctx); err != nil {
+			applog.Error(err, "[Handle finalize]")
+			app.Status.SetConditions(errorCondition("Finalized", err))
+			return handler.handleErr(err)
+		}
+		meta.RemoveFinalizer(&app.ObjectMeta, appFinalizer)
+		return ctrl.Result{}, r.Update(ctx, app)
+	}
+	if registerFinalizer(app) {
+		if err := r.Update(ctx, app); err != nil {
+			return ctrl.Result{}, err
+		}
 	}
 
 	applog.Info("Start Rendering")
 
 	app.Status.Phase = v1alpha2.ApplicationRendering
-	handler := &appHandler{r, app, applog}
 
 	applog.Info("parse template")
 	// parse template
-	appParser := appfile.NewApplicationParser(r.Client, r.dm)
-
-	appfile, err := appParser.GenerateAppFile(app.Name, app)
-	if err != nil {
+	appParser := appfile.NewApplicationParser(r.Client, r.dm).WithAPIReader(r.apiReader)
+	if err := handler.prepareAppRevision(ctx, appParser); err != nil {
 		applog.Error(err, "[Handle Parse]")
 		app.Status.SetConditions(errorCondition("Parsed", err))
 		return handler.handleErr(err)
 	}
 
-	app.Status.SetConditions(readyCondition("Parsed"))
+	// the components that fail to render are reported in the status, the others are still applied
+	appfile, renderErrs := appParser.GeneratePartialAppFile(app.Name, app)
+	appfile.RevisionName, appfile.Revision = handler.renderRevision()
+	if len(renderErrs) != 0 {
+		err := aggregateRenderErrors(renderErrs)
+		applog.Error(err, "[Handle Parse]")
+		app.Status.SetConditions(errorCondition("Parsed", err))
+	} else {
+		app.Status.SetConditions(readyCondition("Parsed"))
+	}
 
 	applog.Info("build template")
 	// build template to applicationconfig & component
-	ac, comps, err := appParser.GenerateApplicationConfiguration(appfile, app.Namespace)
-	if err != nil {
+	ac, comps, buildErrs := appParser.GeneratePartialApplicationConfiguration(appfile, app.Namespace)
+	if len(buildErrs) != 0 {
+		err := aggregateRenderErrors(buildErrs)
 		applog.Error(err, "[Handle GenerateApplicationConfiguration]")
 		app.Status.SetConditions(errorCondition("Built", err))
-		return handler.handleErr(err)
+		renderErrs = append(renderErrs, buildErrs...)
+	} else {
+		app.Status.SetConditions(readyCondition("Built"))
+	}
+	if len(comps) == 0 && len(renderErrs) != 0 {
+		// nothing to apply if none of the components renders
+		app.Status.Services, _ = handler.statusAggregate(appfile, renderErrs)
+		return handler.handleErr(aggregateRenderErrors(renderErrs))
 	}
 	// pass the App label and annotation to ac except some app specific ones
 	oamutil.PassLabelAndAnnotation(app, ac)
-	oamutil.RemoveAnnotations(ac, []string{oam.AnnotationAppRollout})
-	app.Status.SetConditions(readyCondition("Built"))
+	oamutil.RemoveAnnotations(ac, []string{oam.AnnotationAppRollout, oam.AnnotationRolloutApprovedBatch,
+		oam.AnnotationRolloutAbort, oam.AnnotationRolloutUndoRevision})
+	// remember the revision that runs before we apply the new one
+	var prevRevision string
+	if app.Status.LatestRevision != nil {
+		prevRevision = app.Status.LatestRevision.Name
+	}
 	applog.Info("apply appConfig & component to the cluster")
 	// apply appConfig & component to the cluster
+	if err := handler.retainFailedComponents(ctx, ac, renderErrs); err != nil {
+		applog.Error(err, "[Handle apply]")
+		app.Status.SetConditions(errorCondition("Applied", err))
+		return handler.handleErr(err)
+	}
 	if err := handler.apply(ctx, ac, comps); err != nil {
 		applog.Error(err, "[Handle apply]")
 		app.Status.SetConditions(errorCondition("Applied", err))
@@ -114,18 +164,49 @@ func (r *Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 
 	app.Status.SetConditions(readyCondition("Applied"))
 
+	applog.Info("garbage collect the resources of the removed components")
+	if err := handler.trackResources(ctx, comps, prevRevision); err != nil {
+		applog.Error(err, "[Handle garbage collection]")
+		app.Status.SetConditions(errorCondition("GarbageCollected", err))
+		return handler.handleErr(err)
+	}
+
+	if app.Spec.RolloutPlan != nil {
+		applog.Info("reconcile the rollout plan")
+		result, inProgress, err := handler.reconcileRollout(ctx, prevRevision)
+		if err != nil {
+			applog.Error(err, "[Handle rollout]")
+			app.Status.SetConditions(errorCondition("Rollout", err))
+			return handler.handleErr(err)
+		}
+		if inProgress {
+			app.Status.Phase = v1alpha2.ApplicationRollingOut
+			return result, r.UpdateStatus(ctx, app)
+		}
+	}
+
 	app.Status.Phase = v1alpha2.ApplicationHealthChecking
-	applog.Info("check application health status")
-	// check application health status
-	appCompStatus, healthy, err := handler.statusAggregate(appfile)
+	// the changes of the workloads and traits trigger the health check without rendering the application again
+	watchErr := r.watchAppResources(ctx, app)
+	if watchErr != nil {
+		applog.Info("cannot watch the resources of the application, check health periodically", "reason", watchErr)
+	}
+	dependency, err := r.dependencyStatus(ctx, app)
 	if err != nil {
-		app.Status.SetConditions(errorCondition("HealthCheck", err))
+		applog.Error(err, "[Handle dependency]")
 		return handler.handleErr(err)
 	}
+	app.Status.Dependency = dependency
+	applog.Info("check application health status")
+	// check application health status
+	appCompStatus, healthy := handler.statusAggregate(appfile, renderErrs)
 	if !healthy {
 		app.Status.SetConditions(errorCondition("HealthCheck", errors.New("not healthy")))
 
 		app.Status.Services = appCompStatus
+		if watchErr == nil {
+			return ctrl.Result{}, r.UpdateStatus(ctx, app)
+		}
 		// unhealthy will check again after 10s
 		return ctrl.Result{RequeueAfter: time.Second * 10}, r.Status().Update(ctx, app)
 	}
@@ -146,14 +227,37 @@ func (r *Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	return ctrl.Result{}, r.UpdateStatus(ctx, app)
 }
 
+// aggregateRenderErrors merges the errors of the components that fail to render
+func aggregateRenderErrors(renderErrs []*appfile.RenderError) error {
+	errs := make([]error, 0, len(renderErrs))
+	for _, renderErr := range renderErrs {
+		errs = append(errs, renderErr)
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
 // SetupWithManager install to manager
 func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.record = event.NewAPIRecorder(mgr.GetEventRecorderFor("Application")).
+		WithAnnotations("controller", "Application")
+	if err := r.setupHealthController(mgr); err != nil {
+		return err
+	}
 	// If Application Own these two child objects, AC status change will notify application controller and recursively update AC again, and trigger application event again...
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&v1alpha2.Application{}).
+		WithEventFilter(appChangedPredicate).
 		Complete(r)
 }
 
+// taskReader returns the reader of the processing tasks, it's the uncached reader if there is one
+func (r *Reconciler) taskReader() client.Reader {
+	if r.apiReader != nil {
+		return r.apiReader
+	}
+	return r.Client
+}
+
 // UpdateStatus updates v1alpha2.Application's Status with retry.RetryOnConflict
 func (r *Reconciler) UpdateStatus(ctx context.Context, app *v1alpha2.Application, opts ...client.UpdateOption) error {
 	status := app.DeepCopy().Status
@@ -173,10 +277,11 @@ func Setup(mgr ctrl.Manager, _ core.Args, _ logging.Logger) error {
 		return fmt.Errorf("create discovery dm fail %w", err)
 	}
 	reconciler := Reconciler{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("Application"),
-		Scheme: mgr.GetScheme(),
-		dm:     dm,
+		Client:    mgr.GetClient(),
+		apiReader: mgr.GetAPIReader(),
+		Log:       ctrl.Log.WithName("Application"),
+		Scheme:    mgr.GetScheme(),
+		dm:        dm,
 	}
 	return reconciler.SetupWithManager(mgr)
 }
@@ -42,6 +42,7 @@ import (
 	"sigs.k8s.io/yaml"
 
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	velahttp "github.com/oam-dev/kubevela/pkg/builtin/http"
 	"github.com/oam-dev/kubevela/pkg/controller/utils"
 	"github.com/oam-dev/kubevela/pkg/oam"
 	"github.com/oam-dev/kubevela/pkg/oam/util"
@@ -659,6 +660,10 @@ var _ = Describe("Test Application Controller", func() {
 	It("app-with-trait will create workload and trait with http task", func() {
 		s := NewMock()
 		defer s.Close()
+		opts := velahttp.DefaultOptions
+		opts.AllowedURLs = []string{"http://127.0.0.1:8090"}
+		Expect(velahttp.SetOptions(opts)).Should(BeNil())
+		defer velahttp.SetOptions(velahttp.DefaultOptions)
 		expTrait := expectScalerTrait(appWithTrait.Spec.Components[0].Name, appWithTrait.Name)
 		expTrait.Object["spec"].(map[string]interface{})["token"] = "test-token"
 
@@ -1051,6 +1056,16 @@ var _ = Describe("Test Application Controller", func() {
 			}
 			return string(checkApp.Status.Phase)
 		}(), 5*time.Second, time.Second).Should(BeEquivalentTo(v1alpha2.ApplicationRunning))
+		Expect(len(checkApp.Status.Services)).Should(Equal(1))
+		svcStatus := &checkApp.Status.Services[0]
+		Expect(svcStatus.GetCondition(v1alpha2.TypeRendered).Status).Should(Equal(corev1.ConditionTrue))
+		Expect(svcStatus.GetCondition(v1alpha2.TypeHealthy).Status).Should(Equal(corev1.ConditionTrue))
+		Expect(len(svcStatus.Traits)).Should(Equal(1))
+		Expect(svcStatus.Traits[0].GetCondition(v1alpha2.TypeRendered).Status).Should(Equal(corev1.ConditionTrue))
+		Expect(svcStatus.Traits[0].GetCondition(v1alpha2.TypeHealthy).Status).Should(Equal(corev1.ConditionTrue))
+		// the conditions are checked, compare the rest of the status
+		svcStatus.ConditionedStatus = v1alpha1.ConditionedStatus{}
+		svcStatus.Traits[0].ConditionedStatus = v1alpha1.ConditionedStatus{}
 		Expect(checkApp.Status.Services).Should(BeEquivalentTo([]v1alpha2.ApplicationComponentStatus{
 			{
 				Name:    compName,
@@ -20,12 +20,12 @@ import (
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/utils/pointer"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
 	"github.com/oam-dev/kubevela/pkg/appfile"
 	"github.com/oam-dev/kubevela/pkg/controller/common"
 	"github.com/oam-dev/kubevela/pkg/controller/utils"
-	"github.com/oam-dev/kubevela/pkg/dsl/process"
 	"github.com/oam-dev/kubevela/pkg/oam"
 	oamutil "github.com/oam-dev/kubevela/pkg/oam/util"
 )
@@ -49,10 +49,131 @@ func readyCondition(tpy string) runtimev1alpha1.Condition {
 	}
 }
 
+func renderedCondition() runtimev1alpha1.Condition {
+	return runtimev1alpha1.Condition{
+		Type:               v1alpha2.TypeRendered,
+		Status:             v1.ConditionTrue,
+		Reason:             v1alpha2.ReasonRendered,
+		LastTransitionTime: metav1.NewTime(time.Now()),
+	}
+}
+
+func renderErrorCondition(reason runtimev1alpha1.ConditionReason, err error) runtimev1alpha1.Condition {
+	return runtimev1alpha1.Condition{
+		Type:               v1alpha2.TypeRendered,
+		Status:             v1.ConditionFalse,
+		Reason:             reason,
+		LastTransitionTime: metav1.NewTime(time.Now()),
+		Message:            err.Error(),
+	}
+}
+
+func healthyCondition(healthy bool, unhealthyMessage string) runtimev1alpha1.Condition {
+	if !healthy {
+		return runtimev1alpha1.Condition{
+			Type:               v1alpha2.TypeHealthy,
+			Status:             v1.ConditionFalse,
+			Reason:             v1alpha2.ReasonUnhealthy,
+			LastTransitionTime: metav1.NewTime(time.Now()),
+			Message:            unhealthyMessage,
+		}
+	}
+	return runtimev1alpha1.Condition{
+		Type:               v1alpha2.TypeHealthy,
+		Status:             v1.ConditionTrue,
+		Reason:             v1alpha2.ReasonHealthy,
+		LastTransitionTime: metav1.NewTime(time.Now()),
+	}
+}
+
+func healthCheckErrorCondition(err error) runtimev1alpha1.Condition {
+	return runtimev1alpha1.Condition{
+		Type:               v1alpha2.TypeHealthy,
+		Status:             v1.ConditionFalse,
+		Reason:             v1alpha2.ReasonHealthCheckError,
+		LastTransitionTime: metav1.NewTime(time.Now()),
+		Message:            err.Error(),
+	}
+}
+
+// newComponentStatus starts the status of a component with the conditions in its last status,
+// the conditions that don't change keep their last transition time
+func (h *appHandler) newComponentStatus(comp v1alpha2.ApplicationComponent) v1alpha2.ApplicationComponentStatus {
+	status := v1alpha2.ApplicationComponentStatus{
+		Name:    comp.Name,
+		Healthy: true,
+	}
+	var lastStatus *v1alpha2.ApplicationComponentStatus
+	for i := range h.app.Status.Services {
+		if h.app.Status.Services[i].Name == comp.Name {
+			lastStatus = &h.app.Status.Services[i]
+			status.ConditionedStatus = *lastStatus.ConditionedStatus.DeepCopy()
+			break
+		}
+	}
+	for _, tr := range comp.Traits {
+		traitStatus := v1alpha2.ApplicationTraitStatus{
+			Type:    tr.Name,
+			Healthy: true,
+		}
+		if lastStatus != nil {
+			if lastTraitStatus := findTraitStatus(lastStatus, tr.Name); lastTraitStatus != nil {
+				traitStatus.ConditionedStatus = *lastTraitStatus.ConditionedStatus.DeepCopy()
+			}
+		}
+		status.Traits = append(status.Traits, traitStatus)
+	}
+	return status
+}
+
+func findTraitStatus(status *v1alpha2.ApplicationComponentStatus, traitType string) *v1alpha2.ApplicationTraitStatus {
+	for i := range status.Traits {
+		if status.Traits[i].Type == traitType {
+			return &status.Traits[i]
+		}
+	}
+	return nil
+}
+
+// setRenderError marks the component as unhealthy as it fails to render, the trait to blame is marked as well
+func setRenderError(status *v1alpha2.ApplicationComponentStatus, renderErr *appfile.RenderError) {
+	status.Healthy = false
+	for i := range status.Traits {
+		status.Traits[i].Healthy = false
+	}
+	if len(renderErr.Trait) == 0 {
+		status.SetConditions(renderErrorCondition(v1alpha2.ReasonRenderError, renderErr))
+		return
+	}
+	status.SetConditions(renderErrorCondition(v1alpha2.ReasonTraitRenderError, renderErr))
+	if traitStatus := findTraitStatus(status, renderErr.Trait); traitStatus != nil {
+		traitStatus.SetConditions(renderErrorCondition(v1alpha2.ReasonRenderError, renderErr))
+	}
+}
+
+func setComponentHealthCheckError(status *v1alpha2.ApplicationComponentStatus, err error) {
+	status.Healthy = false
+	status.SetConditions(healthCheckErrorCondition(err))
+}
+
+func setTraitHealthCheckError(status *v1alpha2.ApplicationTraitStatus, err error) {
+	status.Healthy = false
+	status.SetConditions(healthCheckErrorCondition(err))
+}
+
 type appHandler struct {
 	r      *Reconciler
 	app    *v1alpha2.Application
 	logger logr.Logger
+
+	// parser renders the application, it knows the definitions the application is rendered with
+	parser *appfile.Parser
+	// appSpecHash is the hash value of the application spec
+	appSpecHash string
+	// appSpecChanged is true if the latest application revision is generated from a different application spec
+	appSpecChanged bool
+	// latestAppRevision is the number of the latest application revision, it can be ahead of the latest appConfig
+	latestAppRevision int64
 }
 
 func (h *appHandler) handleErr(err error) (ctrl.Result, error) {
@@ -72,7 +193,7 @@ func (h *appHandler) handleErr(err error) (ctrl.Result, error) {
 // 1. set ownerReference for ApplicationConfiguration and Components
 // 2. update AC's components using the component revision name
 // 3. update or create the AC with new revision and remember it in the application status
-// 4. garbage collect unused components
+// the components removed from the application are garbage collected by the resource tracker
 func (h *appHandler) apply(ctx context.Context, ac *v1alpha2.ApplicationConfiguration, comps []*v1alpha2.Component) error {
 	owners := []metav1.OwnerReference{{
 		APIVersion: v1alpha2.SchemeGroupVersion.String(),
@@ -115,88 +236,144 @@ func (h *appHandler) apply(ctx context.Context, ac *v1alpha2.ApplicationConfigur
 	ac.SetAnnotations(oamutil.MergeMapOverrideWithDst(ac.GetAnnotations(), map[string]string{
 		oam.AnnotationRollingComponent: strings.Join(newComponents, common.RollingComponentsSep),
 	}))
-	if err := h.createOrUpdateAppConfig(ctx, ac); err != nil {
-		return err
+	if hasRolloutLogic {
+		// the workloads are upgraded in place by the rollout instead of being replaced
+		ac.SetAnnotations(oamutil.MergeMapOverrideWithDst(ac.GetAnnotations(), map[string]string{
+			oam.AnnotationInPlaceUpgrade: strconv.FormatBool(true),
+		}))
 	}
+	return h.createOrUpdateAppConfig(ctx, ac, comps)
+}
 
-	// Garbage Collection for no used Components.
-	// There's no need to ApplicationConfiguration Garbage Collection, it has the same name with Application.
-	for _, comp := range h.app.Status.Components {
-		var exist = false
-		for _, cc := range comps {
-			if comp.Name == cc.Name {
-				exist = true
-				break
-			}
+// statusAggregate checks the health of the components and their traits, the components that fail to render are
+// reported with the render errors. It returns false if any of the components is not healthy.
+func (h *appHandler) statusAggregate(af *appfile.Appfile,
+	renderErrs []*appfile.RenderError) ([]v1alpha2.ApplicationComponentStatus, bool) {
+	workloads := make(map[string]*appfile.Workload, len(af.Workloads))
+	for _, wl := range af.Workloads {
+		workloads[wl.Name] = wl
+	}
+	failed := make(map[string]*appfile.RenderError, len(renderErrs))
+	for _, renderErr := range renderErrs {
+		failed[renderErr.Component] = renderErr
+	}
+	var appStatus []v1alpha2.ApplicationComponentStatus
+	var healthy = true
+	for _, comp := range h.app.Spec.Components {
+		status := h.newComponentStatus(comp)
+		if renderErr, exist := failed[comp.Name]; exist {
+			setRenderError(&status, renderErr)
+			healthy = false
+			appStatus = append(appStatus, status)
+			continue
 		}
-		if exist {
+		wl, exist := workloads[comp.Name]
+		if !exist {
 			continue
 		}
-		// Component not exits in current Application, should be deleted
-		var oldC = &v1alpha2.Component{ObjectMeta: metav1.ObjectMeta{Name: comp.Name, Namespace: ac.Namespace}}
-		if err := h.r.Delete(ctx, oldC); err != nil {
-			return err
+		status.SetConditions(renderedCondition())
+		for i := range status.Traits {
+			status.Traits[i].SetConditions(renderedCondition())
+		}
+		if !h.checkWorkloadHealth(af, wl, &status) {
+			healthy = false
 		}
+		appStatus = append(appStatus, status)
 	}
-	return nil
+	return appStatus, healthy
 }
 
-func (h *appHandler) statusAggregate(appfile *appfile.Appfile) ([]v1alpha2.ApplicationComponentStatus, bool, error) {
-	var appStatus []v1alpha2.ApplicationComponentStatus
-	var healthy = true
-	for _, wl := range appfile.Workloads {
-		var status = v1alpha2.ApplicationComponentStatus{
-			Name:    wl.Name,
-			Healthy: true,
-		}
-		pCtx := process.NewContext(wl.Name, appfile.Name)
-		if err := wl.EvalContext(pCtx); err != nil {
-			return nil, false, errors.WithMessagef(err, "app=%s, comp=%s, evaluate context error", appfile.Name, wl.Name)
-		}
-		for _, tr := range wl.Traits {
-			if err := tr.EvalContext(pCtx); err != nil {
-				return nil, false, errors.WithMessagef(err, "app=%s, comp=%s, trait=%s, evaluate context error", appfile.Name, wl.Name, tr.Name)
+// checkWorkloadHealth checks the health of the workload and its traits, it returns false if any of them is not healthy
+func (h *appHandler) checkWorkloadHealth(af *appfile.Appfile, wl *appfile.Workload,
+	status *v1alpha2.ApplicationComponentStatus) bool {
+	appName := af.Name
+	pCtx, err := af.PrepareHealthCheckContext(h.r.taskReader(), wl, h.app.Namespace)
+	if err != nil {
+		setComponentHealthCheckError(status, errors.WithMessagef(err, "app=%s, comp=%s, evaluate context error",
+			appName, wl.Name))
+		return false
+	}
+	for _, tr := range wl.Traits {
+		if err := tr.EvalContext(pCtx); err != nil {
+			err = errors.WithMessagef(err, "app=%s, comp=%s, trait=%s, evaluate context error", appName, wl.Name, tr.Name)
+			setComponentHealthCheckError(status, err)
+			if traitStatus := findTraitStatus(status, tr.Name); traitStatus != nil {
+				setTraitHealthCheckError(traitStatus, err)
 			}
+			return false
 		}
+	}
 
-		workloadHealth, err := wl.EvalHealth(pCtx, h.r, h.app.Namespace)
-		if err != nil {
-			return nil, false, errors.WithMessagef(err, "app=%s, comp=%s, check health error", appfile.Name, wl.Name)
+	workloadHealth, err := wl.EvalHealth(pCtx, h.r, h.app.Namespace)
+	if err != nil {
+		setComponentHealthCheckError(status, errors.WithMessagef(err, "app=%s, comp=%s, check health error",
+			appName, wl.Name))
+		return false
+	}
+	status.Message, err = wl.EvalStatus(pCtx, h.r, h.app.Namespace)
+	if err != nil {
+		setComponentHealthCheckError(status, errors.WithMessagef(err,
+			"app=%s, comp=%s, evaluate workload status message error", appName, wl.Name))
+		return false
+	}
+	// TODO(wonderflow): we should add a custom way to let the template say why it's unhealthy, only a bool flag is not enough
+	status.Healthy = workloadHealth
+	status.SetConditions(healthyCondition(workloadHealth, "the workload is not healthy"))
+
+	healthy := workloadHealth
+	for _, tr := range wl.Traits {
+		traitStatus := findTraitStatus(status, tr.Name)
+		if traitStatus == nil {
+			continue
 		}
-		if !workloadHealth {
-			// TODO(wonderflow): we should add a custom way to let the template say why it's unhealthy, only a bool flag is not enough
-			status.Healthy = false
+		traitHealth, err := tr.EvalHealth(pCtx, h.r, h.app.Namespace)
+		if err != nil {
+			setTraitHealthCheckError(traitStatus, errors.WithMessagef(err,
+				"app=%s, comp=%s, trait=%s, check health error", appName, wl.Name, tr.Name))
 			healthy = false
+			continue
 		}
-		status.Message, err = wl.EvalStatus(pCtx, h.r, h.app.Namespace)
+		traitStatus.Message, err = tr.EvalStatus(pCtx, h.r, h.app.Namespace)
 		if err != nil {
-			return nil, false, errors.WithMessagef(err, "app=%s, comp=%s, evaluate workload status message error", appfile.Name, wl.Name)
+			setTraitHealthCheckError(traitStatus, errors.WithMessagef(err,
+				"app=%s, comp=%s, trait=%s, evaluate status message error", appName, wl.Name, tr.Name))
+			healthy = false
+			continue
 		}
-		var traitStatusList []v1alpha2.ApplicationTraitStatus
-		for _, trait := range wl.Traits {
-			var traitStatus = v1alpha2.ApplicationTraitStatus{
-				Type:    trait.Name,
-				Healthy: true,
-			}
-			traitHealth, err := trait.EvalHealth(pCtx, h.r, h.app.Namespace)
-			if err != nil {
-				return nil, false, errors.WithMessagef(err, "app=%s, comp=%s, trait=%s, check health error", appfile.Name, wl.Name, trait.Name)
-			}
-			if !traitHealth {
-				// TODO(wonderflow): we should add a custom way to let the template say why it's unhealthy, only a bool flag is not enough
-				traitStatus.Healthy = false
-				healthy = false
-			}
-			traitStatus.Message, err = trait.EvalStatus(pCtx, h.r, h.app.Namespace)
-			if err != nil {
-				return nil, false, errors.WithMessagef(err, "app=%s, comp=%s, trait=%s, evaluate status message error", appfile.Name, wl.Name, trait.Name)
-			}
-			traitStatusList = append(traitStatusList, traitStatus)
+		traitStatus.Healthy = traitHealth
+		traitStatus.SetConditions(healthyCondition(traitHealth, "the trait is not healthy"))
+		healthy = healthy && traitHealth
+	}
+	return healthy
+}
+
+// retainFailedComponents keeps the components that fail to render in the appConfig the same as they are in the
+// latest revision, so that they keep running while the other components are updated
+func (h *appHandler) retainFailedComponents(ctx context.Context, ac *v1alpha2.ApplicationConfiguration,
+	renderErrs []*appfile.RenderError) error {
+	if len(renderErrs) == 0 || h.app.Status.LatestRevision == nil {
+		return nil
+	}
+	var curAppConfig v1alpha2.ApplicationConfiguration
+	if err := h.r.Get(ctx, ctypes.NamespacedName{Namespace: h.app.Namespace, Name: h.app.Status.LatestRevision.Name},
+		&curAppConfig); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	failed := make(map[string]bool, len(renderErrs))
+	for _, renderErr := range renderErrs {
+		failed[renderErr.Component] = true
+	}
+	for _, acc := range curAppConfig.Spec.Components {
+		compName := acc.ComponentName
+		if len(acc.RevisionName) != 0 {
+			compName = utils.ExtractComponentName(acc.RevisionName)
+		}
+		if failed[compName] {
+			h.logger.Info("keep the component that fails to render", "component name", compName)
+			ac.Spec.Components = append(ac.Spec.Components, acc)
 		}
-		status.Traits = traitStatusList
-		appStatus = append(appStatus, status)
 	}
-	return appStatus, healthy, nil
+	return nil
 }
 
 // createOrUpdateComponent creates a component if not exist and update if exists.
@@ -278,7 +455,8 @@ func (h *appHandler) createOrUpdateComponent(ctx context.Context, comp *v1alpha2
 
 // createOrUpdateAppConfig will find the latest revision of the AC according
 // it will create a new revision if the appConfig is different from the existing one
-func (h *appHandler) createOrUpdateAppConfig(ctx context.Context, appConfig *v1alpha2.ApplicationConfiguration) error {
+func (h *appHandler) createOrUpdateAppConfig(ctx context.Context, appConfig *v1alpha2.ApplicationConfiguration,
+	comps []*v1alpha2.Component) error {
 	var curAppConfig v1alpha2.ApplicationConfiguration
 	// initialized
 	if h.app.Status.LatestRevision == nil {
@@ -306,15 +484,25 @@ func (h *appHandler) createOrUpdateAppConfig(ctx context.Context, appConfig *v1a
 		}
 		h.logger.Info("create a new appConfig", "application name", h.app.GetName(),
 			"latest revision that does not exist", h.app.Status.LatestRevision.Name)
-		return h.createNewAppConfig(ctx, appConfig)
+		return h.createNewAppConfig(ctx, appConfig, comps)
 	}
 
 	// check if the old AC has the same HASH value
 	if curAppConfig.GetLabels()[oam.LabelAppConfigHash] == appConfig.GetLabels()[oam.LabelAppConfigHash] {
 		// Just to be safe that it's not because of a random Hash collision
 		if apiequality.Semantic.DeepEqual(&curAppConfig.Spec, &appConfig.Spec) {
-			// same spec, no need to create another AC
-			return nil
+			// the application revision of the latest appConfig is missing if its creation failed
+			created, err := h.ensureAppRevision(ctx, &curAppConfig, comps)
+			if err != nil || created || !h.appSpecChanged {
+				// same spec, no need to create another AC
+				return err
+			}
+			// a new application spec gets a new application revision, it records the existing AC
+			nextRevision := h.nextRevision()
+			h.logger.Info("the application spec changed without changing the appConfig",
+				"application name", h.app.GetName(), "appConfig", curAppConfig.Name, "revision", nextRevision)
+			return h.createAppRevision(ctx, utils.ConstructRevisionName(h.app.Name, nextRevision), &curAppConfig,
+				comps)
 		}
 		h.logger.Info("encountered a different app spec with same hash", "current spec",
 			curAppConfig.Spec, "new appConfig spec", appConfig.Spec)
@@ -322,12 +510,13 @@ func (h *appHandler) createOrUpdateAppConfig(ctx context.Context, appConfig *v1a
 	// create the next version
 	h.logger.Info("create a new appConfig", "application name", h.app.GetName(),
 		"latest revision that does not match the appConfig", h.app.Status.LatestRevision.Name)
-	return h.createNewAppConfig(ctx, appConfig)
+	return h.createNewAppConfig(ctx, appConfig, comps)
 }
 
-// create a new appConfig given the latest revision in the application
-func (h *appHandler) createNewAppConfig(ctx context.Context, appConfig *v1alpha2.ApplicationConfiguration) error {
-	nextRevision := h.app.Status.LatestRevision.Revision + 1
+// create a new appConfig and the application revision given the latest revision in the application
+func (h *appHandler) createNewAppConfig(ctx context.Context, appConfig *v1alpha2.ApplicationConfiguration,
+	comps []*v1alpha2.Component) error {
+	nextRevision := h.nextRevision()
 	revisionName := utils.ConstructRevisionName(h.app.Name, nextRevision)
 	// update the next revision in the application's status
 	h.app.Status.LatestRevision = &v1alpha2.Revision{
@@ -349,5 +538,8 @@ func (h *appHandler) createNewAppConfig(ctx context.Context, appConfig *v1alpha2
 	h.logger.Info("recorded the latest appConfig revision", "application name", h.app.GetName(),
 		"latest revision", revisionName)
 	// it ok if the create failed, we will create again in  the next loop
-	return h.r.Create(ctx, appConfig)
+	if err := h.r.Create(ctx, appConfig); err != nil {
+		return err
+	}
+	return h.createAppRevision(ctx, revisionName, appConfig, comps)
 }
@@ -2,15 +2,19 @@ package application
 
 import (
 	"context"
+	"errors"
 	"math/rand"
 	"strconv"
 	"strings"
+	"testing"
 	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -18,6 +22,7 @@ import (
 
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
 	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+	"github.com/oam-dev/kubevela/pkg/appfile"
 	"github.com/oam-dev/kubevela/pkg/controller/utils"
 	"github.com/oam-dev/kubevela/pkg/oam"
 	oamutil "github.com/oam-dev/kubevela/pkg/oam/util"
@@ -98,7 +103,7 @@ var _ = Describe("Test Application apply", func() {
 		app.Name = "test-revision"
 		Expect(handler.r.Create(ctx, app)).NotTo(HaveOccurred())
 		// Test create or update
-		err := handler.createOrUpdateAppConfig(ctx, appConfig.DeepCopy())
+		err := handler.createOrUpdateAppConfig(ctx, appConfig.DeepCopy(), nil)
 		Expect(err).ToNot(HaveOccurred())
 		// verify
 		curApp := &v1alpha2.Application{}
@@ -125,7 +130,7 @@ var _ = Describe("Test Application apply", func() {
 
 		By("[TEST] apply the same appConfig mimic application controller, should do nothing")
 		// this should not lead to a new AC
-		err = handler.createOrUpdateAppConfig(ctx, appConfig.DeepCopy())
+		err = handler.createOrUpdateAppConfig(ctx, appConfig.DeepCopy(), nil)
 		Expect(err).ToNot(HaveOccurred())
 		// verify the app latest revision is not changed
 		Eventually(
@@ -153,7 +158,7 @@ var _ = Describe("Test Application apply", func() {
 		curAC.SetAnnotations(cl)
 		Expect(handler.r.Update(ctx, curAC)).NotTo(HaveOccurred())
 		// this should not lead to a new AC
-		err = handler.createOrUpdateAppConfig(ctx, curAC.DeepCopy())
+		err = handler.createOrUpdateAppConfig(ctx, curAC.DeepCopy(), nil)
 		Expect(err).ToNot(HaveOccurred())
 		// verify the app latest revision is not changed
 		Eventually(
@@ -197,7 +202,7 @@ var _ = Describe("Test Application apply", func() {
 			},
 		}
 		// this should lead to a new AC
-		err = handler.createOrUpdateAppConfig(ctx, appConfig)
+		err = handler.createOrUpdateAppConfig(ctx, appConfig, nil)
 		Expect(err).ToNot(HaveOccurred())
 		// verify the app latest revision is not changed
 		Eventually(
@@ -304,3 +309,81 @@ var _ = Describe("Test Application apply", func() {
 	})
 
 })
+
+func TestStatusAggregate(t *testing.T) {
+	h := newTrackerTestHandler(t)
+	h.app.Spec.Components = []v1alpha2.ApplicationComponent{
+		{Name: "good", Traits: []v1alpha2.ApplicationTrait{{Name: "ingress"}}},
+		{Name: "bad", Traits: []v1alpha2.ApplicationTrait{{Name: "scaler"}, {Name: "broken"}}},
+	}
+	lastTransitionTime := metav1.NewTime(time.Now().Add(-time.Hour))
+	h.app.Status.Services = []v1alpha2.ApplicationComponentStatus{{
+		Name: "good",
+		ConditionedStatus: runtimev1alpha1.ConditionedStatus{Conditions: []runtimev1alpha1.Condition{{
+			Type:               v1alpha2.TypeRendered,
+			Status:             corev1.ConditionTrue,
+			Reason:             v1alpha2.ReasonRendered,
+			LastTransitionTime: lastTransitionTime,
+		}}},
+	}}
+	af := &appfile.Appfile{Name: "myapp", Workloads: []*appfile.Workload{{
+		Name:     "good",
+		Template: `output: {apiVersion: "v1", kind: "ConfigMap"}`,
+		Traits: []*appfile.Trait{{
+			Name:              "ingress",
+			Template:          `outputs: service: {apiVersion: "v1", kind: "Service"}`,
+			HealthCheckPolicy: `isHealth: context.outputs.service.spec.clusterIP != ""`,
+		}},
+	}}}
+	renderErrs := []*appfile.RenderError{{Component: "bad", Trait: "broken", Err: errors.New("bad template")}}
+
+	status, healthy := h.statusAggregate(af, renderErrs)
+	assert.False(t, healthy)
+	assert.Len(t, status, 2)
+
+	good := status[0]
+	assert.Equal(t, "good", good.Name)
+	assert.True(t, good.Healthy)
+	rendered := good.GetCondition(v1alpha2.TypeRendered)
+	assert.Equal(t, corev1.ConditionTrue, rendered.Status)
+	// the condition that doesn't change keeps its last transition time
+	assert.Equal(t, lastTransitionTime, rendered.LastTransitionTime)
+	assert.Equal(t, v1alpha2.ReasonHealthy, good.GetCondition(v1alpha2.TypeHealthy).Reason)
+	// the service of the trait is not found in the cluster
+	assert.False(t, good.Traits[0].Healthy)
+	assert.Equal(t, corev1.ConditionTrue, good.Traits[0].GetCondition(v1alpha2.TypeRendered).Status)
+	assert.Equal(t, v1alpha2.ReasonHealthCheckError, good.Traits[0].GetCondition(v1alpha2.TypeHealthy).Reason)
+
+	bad := status[1]
+	assert.Equal(t, "bad", bad.Name)
+	assert.False(t, bad.Healthy)
+	assert.Equal(t, v1alpha2.ReasonTraitRenderError, bad.GetCondition(v1alpha2.TypeRendered).Reason)
+	assert.Equal(t, "bad template", bad.GetCondition(v1alpha2.TypeRendered).Message)
+	assert.Equal(t, corev1.ConditionUnknown, bad.Traits[0].GetCondition(v1alpha2.TypeRendered).Status)
+	assert.Equal(t, "broken", bad.Traits[1].Type)
+	assert.Equal(t, corev1.ConditionFalse, bad.Traits[1].GetCondition(v1alpha2.TypeRendered).Status)
+	assert.Equal(t, v1alpha2.ReasonRenderError, bad.Traits[1].GetCondition(v1alpha2.TypeRendered).Reason)
+}
+
+func TestRetainFailedComponents(t *testing.T) {
+	ctx := context.Background()
+	latest := newTrackerTestAppConfig("myapp-v2")
+	latest.Spec.Components = []v1alpha2.ApplicationConfigurationComponent{
+		{RevisionName: "frontend-v3"},
+		{RevisionName: "backend-v1"},
+	}
+	h := newTrackerTestHandler(t, latest)
+	ac := &v1alpha2.ApplicationConfiguration{Spec: v1alpha2.ApplicationConfigurationSpec{
+		Components: []v1alpha2.ApplicationConfigurationComponent{{ComponentName: "frontend"}},
+	}}
+	assert.NoError(t, h.retainFailedComponents(ctx, ac, nil))
+	assert.Len(t, ac.Spec.Components, 1)
+
+	// the backend keeps running the revision in the latest appConfig
+	renderErrs := []*appfile.RenderError{{Component: "backend", Err: errors.New("bad template")}}
+	assert.NoError(t, h.retainFailedComponents(ctx, ac, renderErrs))
+	assert.Equal(t, []v1alpha2.ApplicationConfigurationComponent{
+		{ComponentName: "frontend"},
+		{RevisionName: "backend-v1"},
+	}, ac.Spec.Components)
+}
@@ -0,0 +1,319 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+
+	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/pkg/appfile"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/discoverymapper"
+)
+
+// healthWatcher watches the workloads and traits generated from the applications. A change of them triggers
+// the health check of the application only, the application is not rendered and applied again.
+type healthWatcher struct {
+	controller controller.Controller
+	dm         discoverymapper.DiscoveryMapper
+
+	mu sync.Mutex
+	// watched records the kinds of resources that are watched already
+	watched map[schema.GroupVersionKind]bool
+}
+
+func newHealthWatcher(c controller.Controller, dm discoverymapper.DiscoveryMapper) *healthWatcher {
+	return &healthWatcher{
+		controller: c,
+		dm:         dm,
+		watched:    make(map[schema.GroupVersionKind]bool),
+	}
+}
+
+// watchResources starts to watch the kinds of the resources if they are not watched yet
+func (w *healthWatcher) watchResources(refs []runtimev1alpha1.TypedReference) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, ref := range refs {
+		gv, err := schema.ParseGroupVersion(ref.APIVersion)
+		if err != nil {
+			return err
+		}
+		// the components are not health checked
+		if gv.Group == v1alpha2.Group && ref.Kind == v1alpha2.ComponentKind {
+			continue
+		}
+		mapping, err := w.dm.RESTMapping(schema.GroupKind{Group: gv.Group, Kind: ref.Kind}, gv.Version)
+		if err != nil {
+			return errors.Wrapf(err, "cannot find the kind of %s %s", ref.APIVersion, ref.Kind)
+		}
+		gvk := mapping.GroupVersionKind
+		if w.watched[gvk] {
+			continue
+		}
+		u := &unstructured.Unstructured{}
+		u.SetGroupVersionKind(gvk)
+		if err := w.controller.Watch(&source.Kind{Type: u}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(findApplication),
+		}, predicate.Funcs{
+			CreateFunc:  func(e event.CreateEvent) bool { return hasApplication(e.Meta.GetLabels()) },
+			UpdateFunc:  func(e event.UpdateEvent) bool { return hasApplication(e.MetaNew.GetLabels()) },
+			DeleteFunc:  func(e event.DeleteEvent) bool { return hasApplication(e.Meta.GetLabels()) },
+			GenericFunc: func(e event.GenericEvent) bool { return hasApplication(e.Meta.GetLabels()) },
+		}); err != nil {
+			return errors.Wrapf(err, "cannot watch %s", gvk)
+		}
+		w.watched[gvk] = true
+	}
+	return nil
+}
+
+func hasApplication(labels map[string]string) bool {
+	return len(labels[oam.LabelAppName]) != 0
+}
+
+// findApplication finds the application that generates the resource by its labels
+func findApplication(obj handler.MapObject) []reconcile.Request {
+	appName := obj.Meta.GetLabels()[oam.LabelAppName]
+	if len(appName) == 0 {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: obj.Meta.GetNamespace(), Name: appName}}}
+}
+
+// watchAppResources watches the workloads and traits tracked in the status of the application
+// and the ones in the status of its latest appConfig
+func (r *Reconciler) watchAppResources(ctx context.Context, app *v1alpha2.Application) error {
+	if r.healthWatcher == nil {
+		return fmt.Errorf("the health watcher is not set up")
+	}
+	var refs []runtimev1alpha1.TypedReference
+	for _, res := range app.Status.ResourceTracker {
+		if len(res.Component) != 0 {
+			refs = append(refs, res.Reference)
+		}
+	}
+	if app.Status.LatestRevision != nil {
+		var ac v1alpha2.ApplicationConfiguration
+		if err := r.Get(ctx, types.NamespacedName{Namespace: app.Namespace, Name: app.Status.LatestRevision.Name},
+			&ac); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+		for _, w := range ac.Status.Workloads {
+			refs = append(refs, w.Reference)
+			for _, tr := range w.Traits {
+				refs = append(refs, tr.Reference)
+			}
+		}
+	}
+	return r.healthWatcher.watchResources(refs)
+}
+
+// dependencyStatus reads the unsatisfied dependencies of the components from the latest appConfig of the application
+func (r *Reconciler) dependencyStatus(ctx context.Context, app *v1alpha2.Application) (v1alpha2.DependencyStatus, error) {
+	if app.Status.LatestRevision == nil {
+		return v1alpha2.DependencyStatus{}, nil
+	}
+	var ac v1alpha2.ApplicationConfiguration
+	if err := r.Get(ctx, types.NamespacedName{Namespace: app.Namespace, Name: app.Status.LatestRevision.Name},
+		&ac); err != nil {
+		return v1alpha2.DependencyStatus{}, client.IgnoreNotFound(err)
+	}
+	return ac.Status.Dependency, nil
+}
+
+// healthReconciler checks the health of an application when its workloads or traits change
+type healthReconciler struct {
+	r *Reconciler
+
+	mu sync.Mutex
+	// appfiles caches the appfiles parsed from the applications, a change of the workloads or traits
+	// doesn't parse the application again until the application or its latest revision changes
+	appfiles map[types.NamespacedName]*parsedAppfile
+}
+
+// parsedAppfile is the appfile parsed from a generation of the application
+type parsedAppfile struct {
+	generation     int64
+	revision       string
+	appfile        *appfile.Appfile
+	renderErrs     []*appfile.RenderError
+	appSpecChanged bool
+}
+
+// Reconcile checks the health of the application and records it in the status
+func (hr *healthReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	applog := hr.r.Log.WithValues("application", req.NamespacedName, "health", true)
+	app := new(v1alpha2.Application)
+	if err := hr.r.Get(ctx, req.NamespacedName, app); err != nil {
+		if kerrors.IsNotFound(err) {
+			hr.forgetAppfile(req.NamespacedName)
+		}
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	// the application reconciler checks the health by itself until the application is applied
+	if app.DeletionTimestamp != nil || (app.Status.Phase != v1alpha2.ApplicationHealthChecking &&
+		app.Status.Phase != v1alpha2.ApplicationRunning) {
+		return ctrl.Result{}, nil
+	}
+	if err := hr.r.watchAppResources(ctx, app); err != nil {
+		applog.Error(err, "cannot watch the resources of the application")
+	}
+	handler := &appHandler{r: hr.r, app: app, logger: applog}
+	parsed, err := hr.parseAppfile(ctx, handler)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if parsed.appSpecChanged {
+		// the application reconciler is about to render the new spec
+		return ctrl.Result{}, nil
+	}
+	renderErrs := append([]*appfile.RenderError{}, parsed.renderErrs...)
+	renderErrs = append(renderErrs, lastRenderErrors(app)...)
+	appCompStatus, healthy := handler.statusAggregate(parsed.appfile, renderErrs)
+
+	status := app.Status.DeepCopy()
+	status.Services = appCompStatus
+	dependency, err := hr.r.dependencyStatus(ctx, app)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	status.Dependency = dependency
+	if healthy {
+		status.SetConditions(readyCondition("HealthCheck"))
+		status.Phase = v1alpha2.ApplicationRunning
+	} else {
+		status.SetConditions(errorCondition("HealthCheck", errors.New("not healthy")))
+		status.Phase = v1alpha2.ApplicationHealthChecking
+	}
+	applog.Info("checked application health", "healthy", healthy)
+	return ctrl.Result{}, retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		if err := hr.r.Get(ctx, req.NamespacedName, app); err != nil {
+			return err
+		}
+		if app.Status.Phase != v1alpha2.ApplicationHealthChecking && app.Status.Phase != v1alpha2.ApplicationRunning {
+			// the application is being rendered again
+			return nil
+		}
+		app.Status.Services = status.Services
+		app.Status.Conditions = status.Conditions
+		app.Status.Phase = status.Phase
+		app.Status.Dependency = status.Dependency
+		err := hr.r.Status().Update(ctx, app)
+		if kerrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	})
+}
+
+// parseAppfile returns the appfile of the application, it's parsed again only if the application or its latest
+// revision changes
+func (hr *healthReconciler) parseAppfile(ctx context.Context, handler *appHandler) (*parsedAppfile, error) {
+	app := handler.app
+	key := types.NamespacedName{Namespace: app.Namespace, Name: app.Name}
+	var revision string
+	if app.Status.LatestRevision != nil {
+		revision = app.Status.LatestRevision.Name
+	}
+	hr.mu.Lock()
+	parsed, exist := hr.appfiles[key]
+	hr.mu.Unlock()
+	if exist && parsed.generation == app.Generation && parsed.revision == revision {
+		return parsed, nil
+	}
+	appParser := appfile.NewApplicationParser(hr.r.Client, hr.r.dm).WithAPIReader(hr.r.apiReader)
+	if err := handler.prepareAppRevision(ctx, appParser); err != nil {
+		return nil, err
+	}
+	parsed = &parsedAppfile{generation: app.Generation, revision: revision, appSpecChanged: handler.appSpecChanged}
+	if !parsed.appSpecChanged {
+		parsed.appfile, parsed.renderErrs = appParser.GeneratePartialAppFile(app.Name, app)
+		parsed.appfile.RevisionName, parsed.appfile.Revision = handler.renderRevision()
+	}
+	hr.mu.Lock()
+	defer hr.mu.Unlock()
+	if hr.appfiles == nil {
+		hr.appfiles = make(map[types.NamespacedName]*parsedAppfile)
+	}
+	hr.appfiles[key] = parsed
+	return parsed, nil
+}
+
+func (hr *healthReconciler) forgetAppfile(key types.NamespacedName) {
+	hr.mu.Lock()
+	defer hr.mu.Unlock()
+	delete(hr.appfiles, key)
+}
+
+// lastRenderErrors finds the components that failed to render in the last reconcile of the application
+func lastRenderErrors(app *v1alpha2.Application) []*appfile.RenderError {
+	var renderErrs []*appfile.RenderError
+	for _, svc := range app.Status.Services {
+		cond := svc.GetCondition(v1alpha2.TypeRendered)
+		if cond.Status != corev1.ConditionFalse {
+			continue
+		}
+		renderErr := &appfile.RenderError{Component: svc.Name, Err: errors.New(cond.Message)}
+		for _, tr := range svc.Traits {
+			if tr.GetCondition(v1alpha2.TypeRendered).Status == corev1.ConditionFalse {
+				renderErr.Trait = tr.Type
+				break
+			}
+		}
+		renderErrs = append(renderErrs, renderErr)
+	}
+	return renderErrs
+}
+
+// appChangedPredicate filters out the updates of the application status for the application reconciler,
+// the health of the application is checked by the health controller
+var appChangedPredicate = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		oldMeta, newMeta := e.MetaOld, e.MetaNew
+		return oldMeta.GetGeneration() != newMeta.GetGeneration() ||
+			!reflect.DeepEqual(oldMeta.GetLabels(), newMeta.GetLabels()) ||
+			!reflect.DeepEqual(oldMeta.GetAnnotations(), newMeta.GetAnnotations()) ||
+			!reflect.DeepEqual(oldMeta.GetFinalizers(), newMeta.GetFinalizers()) ||
+			!reflect.DeepEqual(oldMeta.GetDeletionTimestamp(), newMeta.GetDeletionTimestamp())
+	},
+}
+
+// setupHealthController adds a controller that checks the health of the applications when their resources change,
+// the kinds of the resources are watched as the applications are applied
+func (r *Reconciler) setupHealthController(mgr ctrl.Manager) error {
+	c, err := controller.New("application-health", mgr, controller.Options{
+		Reconciler: &healthReconciler{r: r},
+	})
+	if err != nil {
+		return err
+	}
+	// the workloads and traits are listed in the status of the appConfig when they are created
+	if err := c.Watch(&source.Kind{Type: &v1alpha2.ApplicationConfiguration{}}, &handler.EnqueueRequestForOwner{
+		OwnerType:    &v1alpha2.Application{},
+		IsController: true,
+	}); err != nil {
+		return err
+	}
+	r.healthWatcher = newHealthWatcher(c, r.dm)
+	return nil
+}
@@ -0,0 +1,208 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/mock"
+)
+
+// watchRecorder is a controller that records the kinds it watches
+type watchRecorder struct {
+	reconcile.Reconciler
+	kinds []schema.GroupVersionKind
+}
+
+func (w *watchRecorder) Watch(src source.Source, _ handler.EventHandler, _ ...predicate.Predicate) error {
+	w.kinds = append(w.kinds, src.(*source.Kind).Type.GetObjectKind().GroupVersionKind())
+	return nil
+}
+
+func (w *watchRecorder) Start(<-chan struct{}) error {
+	return nil
+}
+
+func TestHealthWatcher(t *testing.T) {
+	dm := mock.NewMockDiscoveryMapper()
+	dm.MockRESTMapping = func(gk schema.GroupKind, versions ...string) (*meta.RESTMapping, error) {
+		if gk.Kind == "Unknown" {
+			return nil, errors.New("no matches for kind Unknown")
+		}
+		return &meta.RESTMapping{GroupVersionKind: gk.WithVersion(versions[0])}, nil
+	}
+	c := &watchRecorder{}
+	w := newHealthWatcher(c, dm)
+	assert.NoError(t, w.watchResources([]runtimev1alpha1.TypedReference{
+		{APIVersion: "apps/v1", Kind: "Deployment", Name: "frontend"},
+		{APIVersion: "apps/v1", Kind: "Deployment", Name: "backend"},
+		{APIVersion: "v1", Kind: "Service", Name: "backend"},
+		{APIVersion: v1alpha2.SchemeGroupVersion.String(), Kind: v1alpha2.ComponentKind, Name: "backend"},
+	}))
+	// each kind is watched once
+	assert.NoError(t, w.watchResources([]runtimev1alpha1.TypedReference{
+		{APIVersion: "apps/v1", Kind: "Deployment", Name: "frontend"},
+	}))
+	assert.Equal(t, []schema.GroupVersionKind{
+		{Group: "apps", Version: "v1", Kind: "Deployment"},
+		{Version: "v1", Kind: "Service"},
+	}, c.kinds)
+	assert.Error(t, w.watchResources([]runtimev1alpha1.TypedReference{{APIVersion: "v1", Kind: "Unknown"}}))
+
+	u := &unstructured.Unstructured{}
+	u.SetNamespace("default")
+	u.SetLabels(map[string]string{oam.LabelAppName: "myapp"})
+	assert.Equal(t, []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: "default", Name: "myapp"}}},
+		findApplication(handler.MapObject{Meta: u, Object: u}))
+	assert.Empty(t, findApplication(handler.MapObject{Meta: &unstructured.Unstructured{}}))
+}
+
+func TestAppChangedPredicate(t *testing.T) {
+	app := &v1alpha2.Application{ObjectMeta: metav1.ObjectMeta{Name: "myapp", Generation: 1}}
+	statusUpdated := app.DeepCopy()
+	statusUpdated.Status.Phase = v1alpha2.ApplicationRunning
+	assert.False(t, appChangedPredicate.Update(event.UpdateEvent{
+		MetaOld: app, ObjectOld: app, MetaNew: statusUpdated, ObjectNew: statusUpdated}))
+
+	specUpdated := app.DeepCopy()
+	specUpdated.Generation = 2
+	assert.True(t, appChangedPredicate.Update(event.UpdateEvent{
+		MetaOld: app, ObjectOld: app, MetaNew: specUpdated, ObjectNew: specUpdated}))
+
+	annotated := app.DeepCopy()
+	annotated.SetAnnotations(map[string]string{oam.AnnotationRolloutApprovedBatch: "1"})
+	assert.True(t, appChangedPredicate.Update(event.UpdateEvent{
+		MetaOld: app, ObjectOld: app, MetaNew: annotated, ObjectNew: annotated}))
+}
+
+func TestHealthReconcile(t *testing.T) {
+	ctx := context.Background()
+	wd := &v1alpha2.WorkloadDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker"},
+		Spec:       v1alpha2.WorkloadDefinitionSpec{Template: `output: {apiVersion: "v1", kind: "ConfigMap"}`},
+	}
+	app := &v1alpha2.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "myapp", Namespace: "default"},
+		Spec: v1alpha2.ApplicationSpec{Components: []v1alpha2.ApplicationComponent{
+			{Name: "frontend", WorkloadType: "worker", Settings: runtime.RawExtension{Raw: []byte(`{}`)}},
+			{Name: "backend", WorkloadType: "worker", Settings: runtime.RawExtension{Raw: []byte(`{}`)}},
+		}},
+		Status: v1alpha2.AppStatus{
+			Phase: v1alpha2.ApplicationHealthChecking,
+			Services: []v1alpha2.ApplicationComponentStatus{
+				{Name: "frontend"},
+				{Name: "backend", ConditionedStatus: runtimev1alpha1.ConditionedStatus{
+					Conditions: []runtimev1alpha1.Condition{renderErrorCondition(v1alpha2.ReasonRenderError,
+						errors.New("bad template"))},
+				}},
+			},
+		},
+	}
+	h := newTrackerTestHandler(t, wd, app)
+	hr := &healthReconciler{r: h.r}
+	hr.r.Log = ctrl.Log.WithName("test")
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "myapp"}}
+
+	// the backend still fails to render
+	_, err := hr.Reconcile(req)
+	assert.NoError(t, err)
+	var checked v1alpha2.Application
+	assert.NoError(t, hr.r.Get(ctx, req.NamespacedName, &checked))
+	assert.Equal(t, v1alpha2.ApplicationHealthChecking, checked.Status.Phase)
+	assert.Len(t, checked.Status.Services, 2)
+	assert.True(t, checked.Status.Services[0].Healthy)
+	assert.Equal(t, corev1.ConditionTrue, checked.Status.Services[0].GetCondition(v1alpha2.TypeHealthy).Status)
+	assert.False(t, checked.Status.Services[1].Healthy)
+	assert.Equal(t, "bad template", checked.Status.Services[1].GetCondition(v1alpha2.TypeRendered).Message)
+	parsed := hr.appfiles[req.NamespacedName]
+	assert.NotNil(t, parsed)
+
+	// the application is rendered again without the backend
+	checked.Generation++
+	checked.Spec.Components = checked.Spec.Components[:1]
+	checked.Status.Services = checked.Status.Services[:1]
+	assert.NoError(t, hr.r.Update(ctx, &checked))
+	_, err = hr.Reconcile(req)
+	assert.NoError(t, err)
+	var healthy v1alpha2.Application
+	assert.NoError(t, hr.r.Get(ctx, req.NamespacedName, &healthy))
+	assert.Equal(t, v1alpha2.ApplicationRunning, healthy.Status.Phase)
+	assert.Equal(t, corev1.ConditionTrue, healthy.Status.GetCondition("HealthCheck").Status)
+	assert.NotSame(t, parsed, hr.appfiles[req.NamespacedName])
+	assert.Len(t, hr.appfiles[req.NamespacedName].appfile.Workloads, 1)
+
+	// a change of the resources doesn't parse the same application again
+	parsed = hr.appfiles[req.NamespacedName]
+	_, err = hr.Reconcile(req)
+	assert.NoError(t, err)
+	assert.Same(t, parsed, hr.appfiles[req.NamespacedName])
+	assert.NoError(t, hr.r.Get(ctx, req.NamespacedName, &healthy))
+
+	// the health is not checked while the application is rendered
+	healthy.Status.Phase = v1alpha2.ApplicationRendering
+	healthy.Status.Services = nil
+	assert.NoError(t, hr.r.Update(ctx, &healthy))
+	_, err = hr.Reconcile(req)
+	assert.NoError(t, err)
+	var rendering v1alpha2.Application
+	assert.NoError(t, hr.r.Get(ctx, req.NamespacedName, &rendering))
+	assert.Empty(t, rendering.Status.Services)
+
+	// it's ok if the application is gone
+	_, err = hr.Reconcile(ctrl.Request{NamespacedName: client.ObjectKey{Namespace: "default", Name: "gone"}})
+	assert.NoError(t, err)
+	assert.NoError(t, hr.r.Delete(ctx, &rendering))
+	_, err = hr.Reconcile(req)
+	assert.NoError(t, err)
+	assert.NotContains(t, hr.appfiles, req.NamespacedName)
+}
+
+func TestDependencyStatus(t *testing.T) {
+	ctx := context.Background()
+	ac := newTrackerTestAppConfig("myapp-v2")
+	ac.Status.Dependency = v1alpha2.DependencyStatus{Unsatisfied: []v1alpha2.UnstaifiedDependency{{
+		Reason: "status.secretName not found in object",
+		From: v1alpha2.DependencyFromObject{
+			TypedReference: runtimev1alpha1.TypedReference{APIVersion: "v1", Kind: "ConfigMap", Name: "db"},
+			FieldPath:      "status.secretName",
+		},
+		To: v1alpha2.DependencyToObject{
+			TypedReference: runtimev1alpha1.TypedReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "web"},
+			FieldPaths:     []string{"spec.template.spec.containers[0].env[0].value"},
+		},
+	}}}
+	h := newTrackerTestHandler(t, ac)
+	dependency, err := h.r.dependencyStatus(ctx, h.app)
+	assert.NoError(t, err)
+	assert.Equal(t, ac.Status.Dependency, dependency)
+
+	// the appConfig of the latest revision is not created yet
+	h.app.Status.LatestRevision.Name = "myapp-v3"
+	dependency, err = h.r.dependencyStatus(ctx, h.app)
+	assert.NoError(t, err)
+	assert.Empty(t, dependency.Unsatisfied)
+
+	h.app.Status.LatestRevision = nil
+	dependency, err = h.r.dependencyStatus(ctx, h.app)
+	assert.NoError(t, err)
+	assert.Empty(t, dependency.Unsatisfied)
+}
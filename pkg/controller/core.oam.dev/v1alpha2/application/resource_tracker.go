@@ -0,0 +1,161 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	ktypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+)
+
+// appFinalizer makes sure that the resources generated from the application are deleted with it
+const appFinalizer = "finalizers.application.oam.dev"
+
+// registerFinalizer adds the finalizer to the application, it returns true if the finalizer is newly added
+func registerFinalizer(app *v1alpha2.Application) bool {
+	if meta.FinalizerExists(&app.ObjectMeta, appFinalizer) {
+		return false
+	}
+	meta.AddFinalizer(&app.ObjectMeta, appFinalizer)
+	return true
+}
+
+// resourceTracker records the resources generated from an application, each resource is recorded once
+type resourceTracker struct {
+	resources []v1alpha2.TrackedResource
+	index     map[string]int
+}
+
+func newResourceTracker(resources []v1alpha2.TrackedResource) *resourceTracker {
+	t := &resourceTracker{index: make(map[string]int)}
+	for _, res := range resources {
+		t.track(res.Component, res.Reference)
+	}
+	return t
+}
+
+// track records the resource, the latest component that generates the resource wins
+func (t *resourceTracker) track(component string, ref runtimev1alpha1.TypedReference) {
+	if len(ref.Name) == 0 || len(ref.Kind) == 0 {
+		return
+	}
+	key := strings.Join([]string{ref.APIVersion, ref.Kind, ref.Name}, "/")
+	res := v1alpha2.TrackedResource{Component: component, Reference: ref}
+	if i, exist := t.index[key]; exist {
+		t.resources[i] = res
+		return
+	}
+	t.index[key] = len(t.resources)
+	t.resources = append(t.resources, res)
+}
+
+// trackResources records the resources generated from the application in its status
+// and garbage collects the resources of the components that are removed from the application
+func (h *appHandler) trackResources(ctx context.Context, comps []*v1alpha2.Component, prevRevision string) error {
+	tracker := newResourceTracker(h.app.Status.ResourceTracker)
+	// the components recorded before we track the resources
+	for _, comp := range h.app.Status.Components {
+		tracker.track(comp.Name, runtimev1alpha1.TypedReference{
+			APIVersion: v1alpha2.SchemeGroupVersion.String(),
+			Kind:       v1alpha2.ComponentKind,
+			Name:       comp.Name,
+		})
+	}
+	// the components that fail to render are still in the application, their resources are kept
+	appComponents := make(map[string]bool, len(h.app.Spec.Components))
+	for _, comp := range h.app.Spec.Components {
+		appComponents[comp.Name] = true
+	}
+	for _, comp := range comps {
+		tracker.track(comp.Name, runtimev1alpha1.TypedReference{
+			APIVersion: v1alpha2.SchemeGroupVersion.String(),
+			Kind:       v1alpha2.ComponentKind,
+			Name:       comp.Name,
+		})
+	}
+	// the workloads and traits are created by the appConfig controller, we find them in the appConfig status
+	// the appConfig and the application revision share the revision name
+	for _, revision := range []string{h.app.Status.LatestRevision.Name, prevRevision} {
+		if len(revision) == 0 {
+			continue
+		}
+		var ac v1alpha2.ApplicationConfiguration
+		if err := h.r.Get(ctx, ktypes.NamespacedName{Namespace: h.app.Namespace, Name: revision}, &ac); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+		tracker.track("", runtimev1alpha1.TypedReference{
+			APIVersion: v1alpha2.SchemeGroupVersion.String(),
+			Kind:       v1alpha2.ApplicationConfigurationKind,
+			Name:       ac.Name,
+			UID:        ac.UID,
+		})
+		tracker.track("", runtimev1alpha1.TypedReference{
+			APIVersion: v1alpha2.SchemeGroupVersion.String(),
+			Kind:       v1alpha2.ApplicationRevisionKind,
+			Name:       ac.Name,
+		})
+		for _, w := range ac.Status.Workloads {
+			tracker.track(w.ComponentName, w.Reference)
+			for _, tr := range w.Traits {
+				tracker.track(w.ComponentName, tr.Reference)
+			}
+		}
+	}
+
+	// garbage collect the resources of the removed components
+	var tracked []v1alpha2.TrackedResource
+	var gcErr error
+	for _, res := range tracker.resources {
+		if len(res.Component) == 0 || appComponents[res.Component] {
+			tracked = append(tracked, res)
+			continue
+		}
+		if err := h.deleteTrackedResource(ctx, res); err != nil {
+			// keep it so that we can try again
+			tracked = append(tracked, res)
+			gcErr = err
+			continue
+		}
+		h.logger.Info("garbage collected the resource of a removed component", "component", res.Component,
+			"kind", res.Reference.Kind, "name", res.Reference.Name)
+	}
+	h.app.Status.ResourceTracker = tracked
+	return gcErr
+}
+
+// finalizeResources deletes all the resources generated from the application
+func (h *appHandler) finalizeResources(ctx context.Context) error {
+	var remaining []v1alpha2.TrackedResource
+	var finalizeErr error
+	for _, res := range h.app.Status.ResourceTracker {
+		if err := h.deleteTrackedResource(ctx, res); err != nil {
+			remaining = append(remaining, res)
+			finalizeErr = err
+		}
+	}
+	h.app.Status.ResourceTracker = remaining
+	return finalizeErr
+}
+
+// deleteTrackedResource deletes a resource in the namespace of the application, it's ok if it's gone already
+func (h *appHandler) deleteTrackedResource(ctx context.Context, res v1alpha2.TrackedResource) error {
+	u := &unstructured.Unstructured{}
+	u.SetAPIVersion(res.Reference.APIVersion)
+	u.SetKind(res.Reference.Kind)
+	u.SetNamespace(h.app.Namespace)
+	u.SetName(res.Reference.Name)
+	if err := h.r.Delete(ctx, u); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("cannot delete the %s %s: %w", res.Reference.Kind, res.Reference.Name, err)
+	}
+	return nil
+}
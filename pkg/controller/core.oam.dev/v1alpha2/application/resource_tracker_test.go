@@ -0,0 +1,110 @@
+package application
+
+import (
+	"context"
+	"testing"
+
+	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+)
+
+func newTrackerTestHandler(t *testing.T, objs ...runtime.Object) *appHandler {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, v1alpha2.SchemeBuilder.AddToScheme(scheme))
+	app := &v1alpha2.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "myapp", Namespace: "default"},
+		Status: v1alpha2.AppStatus{
+			LatestRevision: &v1alpha2.Revision{Name: "myapp-v2", Revision: 2},
+		},
+	}
+	return &appHandler{
+		r:      &Reconciler{Client: fake.NewFakeClientWithScheme(scheme, objs...)},
+		app:    app,
+		logger: ctrl.Log.WithName("test"),
+	}
+}
+
+func newTrackerTestAppConfig(name string, workloads ...v1alpha2.WorkloadStatus) *v1alpha2.ApplicationConfiguration {
+	return &v1alpha2.ApplicationConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Status:     v1alpha2.ApplicationConfigurationStatus{Workloads: workloads},
+	}
+}
+
+func newTrackerTestWorkload(component string, traits ...string) v1alpha2.WorkloadStatus {
+	w := v1alpha2.WorkloadStatus{
+		ComponentName: component,
+		Reference:     runtimev1alpha1.TypedReference{APIVersion: "apps/v1", Kind: "Deployment", Name: component},
+	}
+	for _, tr := range traits {
+		w.Traits = append(w.Traits, v1alpha2.WorkloadTrait{
+			Reference: runtimev1alpha1.TypedReference{APIVersion: "v1", Kind: "Service", Name: tr},
+		})
+	}
+	return w
+}
+
+func TestTrackResources(t *testing.T) {
+	ctx := context.Background()
+	objs := []runtime.Object{
+		&v1alpha2.Component{ObjectMeta: metav1.ObjectMeta{Name: "frontend", Namespace: "default"}},
+		&v1alpha2.Component{ObjectMeta: metav1.ObjectMeta{Name: "backend", Namespace: "default"}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "frontend", Namespace: "default"}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "backend", Namespace: "default"}},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "backend-svc", Namespace: "default"}},
+		newTrackerTestAppConfig("myapp-v1", newTrackerTestWorkload("frontend"),
+			newTrackerTestWorkload("backend", "backend-svc")),
+		newTrackerTestAppConfig("myapp-v2", newTrackerTestWorkload("frontend")),
+	}
+	h := newTrackerTestHandler(t, objs...)
+	comps := []*v1alpha2.Component{{ObjectMeta: metav1.ObjectMeta{Name: "frontend", Namespace: "default"}}}
+	// the backend is removed from the application in the latest revision
+	h.app.Spec.Components = []v1alpha2.ApplicationComponent{{Name: "frontend"}}
+	h.app.Status.Components = []runtimev1alpha1.TypedReference{{Name: "frontend"}, {Name: "backend"}}
+	assert.NoError(t, h.trackResources(ctx, comps, "myapp-v1"))
+
+	for _, obj := range []runtime.Object{&v1alpha2.Component{}, &appsv1.Deployment{}} {
+		err := h.r.Get(ctx, client.ObjectKey{Namespace: "default", Name: "backend"}, obj)
+		assert.True(t, kerrors.IsNotFound(err))
+		assert.NoError(t, h.r.Get(ctx, client.ObjectKey{Namespace: "default", Name: "frontend"}, obj))
+	}
+	err := h.r.Get(ctx, client.ObjectKey{Namespace: "default", Name: "backend-svc"}, &corev1.Service{})
+	assert.True(t, kerrors.IsNotFound(err))
+
+	var tracked []string
+	for _, res := range h.app.Status.ResourceTracker {
+		assert.NotEqual(t, "backend", res.Component)
+		tracked = append(tracked, res.Reference.Kind+"/"+res.Reference.Name)
+	}
+	assert.ElementsMatch(t, []string{"Component/frontend", "Deployment/frontend",
+		"ApplicationConfiguration/myapp-v1", "ApplicationConfiguration/myapp-v2",
+		"ApplicationRevision/myapp-v1", "ApplicationRevision/myapp-v2"}, tracked)
+
+	// everything goes away with the application
+	assert.NoError(t, h.finalizeResources(ctx))
+	assert.Empty(t, h.app.Status.ResourceTracker)
+	err = h.r.Get(ctx, client.ObjectKey{Namespace: "default", Name: "frontend"}, &appsv1.Deployment{})
+	assert.True(t, kerrors.IsNotFound(err))
+	err = h.r.Get(ctx, client.ObjectKey{Namespace: "default", Name: "myapp-v2"},
+		&v1alpha2.ApplicationConfiguration{})
+	assert.True(t, kerrors.IsNotFound(err))
+}
+
+func TestRegisterFinalizer(t *testing.T) {
+	app := &v1alpha2.Application{}
+	assert.True(t, registerFinalizer(app))
+	assert.Equal(t, []string{appFinalizer}, app.Finalizers)
+	assert.False(t, registerFinalizer(app))
+}
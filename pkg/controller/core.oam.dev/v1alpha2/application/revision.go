@@ -0,0 +1,152 @@
+package application
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/mitchellh/hashstructure/v2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ktypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/pkg/appfile"
+	"github.com/oam-dev/kubevela/pkg/controller/utils"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	oamutil "github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+// computeAppSpecHash computes the hash value of the application spec
+func computeAppSpecHash(app *v1alpha2.Application) (string, error) {
+	specHash, err := hashstructure.Hash(app.Spec, hashstructure.FormatV2, nil)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatUint(specHash, 16), nil
+}
+
+// prepareAppRevision finds the latest application revision and the ones generated from the same application spec.
+// The parser renders the application with the definitions recorded in the latest one of them so that an upgrade of
+// the definitions doesn't change a running application, and an application that rolls back to the spec of an old
+// revision is rendered the same way as that revision.
+func (h *appHandler) prepareAppRevision(ctx context.Context, parser *appfile.Parser) error {
+	specHash, err := computeAppSpecHash(h.app)
+	if err != nil {
+		return err
+	}
+	h.parser = parser
+	h.appSpecHash = specHash
+	var appRevisions v1alpha2.ApplicationRevisionList
+	if err := h.r.List(ctx, &appRevisions, client.InNamespace(h.app.Namespace), client.MatchingLabels{
+		oam.LabelAppName: h.app.Name,
+	}); err != nil {
+		return err
+	}
+	var latest, matched *v1alpha2.ApplicationRevision
+	latestRevision, matchedRevision := -1, -1
+	for i, appRevision := range appRevisions.Items {
+		revision, err := utils.ExtractRevision(appRevision.Name)
+		if err != nil {
+			continue
+		}
+		if revision > latestRevision {
+			latest, latestRevision = &appRevisions.Items[i], revision
+		}
+		if appRevision.GetLabels()[oam.LabelAppSpecHash] == specHash && revision > matchedRevision {
+			matched, matchedRevision = &appRevisions.Items[i], revision
+		}
+	}
+	if latest != nil {
+		h.latestAppRevision = int64(latestRevision)
+		h.appSpecChanged = latest.GetLabels()[oam.LabelAppSpecHash] != specHash
+	}
+	if matched == nil {
+		return nil
+	}
+	h.logger.Info("render the application with the definitions of the application revision",
+		"revision", matched.Name)
+	parser.WithDefinitions(matched.Spec.WorkloadDefinitions, matched.Spec.TraitDefinitions)
+	return nil
+}
+
+// nextRevision returns the number of the next revision, it's after both the latest appConfig and the latest
+// application revision
+func (h *appHandler) nextRevision() int64 {
+	next := h.latestAppRevision
+	if latest := h.app.Status.LatestRevision; latest != nil && latest.Revision > next {
+		next = latest.Revision
+	}
+	return next + 1
+}
+
+// renderRevision returns the application revision the application is rendered to, it's the next revision
+// if the application spec changes, the templates read it from the context
+func (h *appHandler) renderRevision() (string, int64) {
+	latest := h.app.Status.LatestRevision
+	if latest == nil || h.appSpecChanged {
+		next := h.nextRevision()
+		return utils.ConstructRevisionName(h.app.Name, next), next
+	}
+	return latest.Name, latest.Revision
+}
+
+// ensureAppRevision creates the application revision of the latest appConfig if it doesn't exist.
+// It returns true if the revision is created.
+func (h *appHandler) ensureAppRevision(ctx context.Context, appConfig *v1alpha2.ApplicationConfiguration,
+	comps []*v1alpha2.Component) (bool, error) {
+	var appRevision v1alpha2.ApplicationRevision
+	err := h.r.Get(ctx, ktypes.NamespacedName{Namespace: h.app.Namespace, Name: appConfig.Name}, &appRevision)
+	if err == nil || !apierrors.IsNotFound(err) {
+		return false, err
+	}
+	h.logger.Info("the application revision of the latest appConfig is missing", "application name",
+		h.app.GetName(), "revision", appConfig.Name)
+	return true, h.createAppRevision(ctx, appConfig.Name, appConfig, comps)
+}
+
+// createAppRevision records an immutable snapshot of the application spec, the definitions it is rendered with
+// and the appConfig and components rendered from it. The revision has the same name as the appConfig unless the
+// application spec renders the same appConfig as the previous revision, it records the existing appConfig then.
+func (h *appHandler) createAppRevision(ctx context.Context, revisionName string,
+	appConfig *v1alpha2.ApplicationConfiguration, comps []*v1alpha2.Component) error {
+	appRevision := &v1alpha2.ApplicationRevision{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            revisionName,
+			Namespace:       h.app.Namespace,
+			OwnerReferences: appConfig.GetOwnerReferences(),
+			Labels: map[string]string{
+				oam.LabelAppName:     h.app.Name,
+				oam.LabelAppSpecHash: h.appSpecHash,
+			},
+		},
+		Spec: v1alpha2.ApplicationRevisionSpec{
+			Application: v1alpha2.Application{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: v1alpha2.SchemeGroupVersion.String(),
+					Kind:       v1alpha2.ApplicationKind,
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        h.app.Name,
+					Namespace:   h.app.Namespace,
+					Labels:      h.app.GetLabels(),
+					Annotations: h.app.GetAnnotations(),
+				},
+				Spec: *h.app.Spec.DeepCopy(),
+			},
+			ApplicationConfiguration: oamutil.Object2RawExtension(appConfig),
+		},
+	}
+	if h.parser != nil {
+		appRevision.Spec.WorkloadDefinitions, appRevision.Spec.TraitDefinitions = h.parser.Definitions()
+	}
+	for _, comp := range comps {
+		appRevision.Spec.Components = append(appRevision.Spec.Components, oamutil.Object2RawExtension(comp))
+	}
+	if err := h.r.Create(ctx, appRevision); err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+	h.logger.Info("created a new application revision", "application name", h.app.GetName(),
+		"revision", appRevision.Name)
+	return nil
+}
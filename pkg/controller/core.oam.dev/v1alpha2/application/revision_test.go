@@ -0,0 +1,145 @@
+package application
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/pkg/appfile"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	oamutil "github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+// renderWithRevision prepares the application revision and returns the workload template the application uses
+func renderWithRevision(t *testing.T, h *appHandler) string {
+	parser := appfile.NewApplicationParser(h.r.Client, nil)
+	assert.NoError(t, h.prepareAppRevision(context.Background(), parser))
+	af, err := parser.GenerateAppFile(h.app.Name, h.app)
+	assert.NoError(t, err)
+	return af.Workloads[0].Template
+}
+
+func newRevisionTestAppConfig(name string) *v1alpha2.ApplicationConfiguration {
+	return &v1alpha2.ApplicationConfiguration{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+}
+
+func TestAppRevision(t *testing.T) {
+	ctx := context.Background()
+	h := newTrackerTestHandler(t, &v1alpha2.WorkloadDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker"},
+		Spec:       v1alpha2.WorkloadDefinitionSpec{Template: "output: v1"},
+	})
+	h.app.Status.LatestRevision = nil
+	h.app.Spec.Components = []v1alpha2.ApplicationComponent{{Name: "frontend", WorkloadType: "worker",
+		Settings: runtime.RawExtension{Raw: []byte(`{"image":"nginx:1"}`)}}}
+	oldSpec := h.app.Spec.DeepCopy()
+
+	// the first revision records the definition it is rendered with
+	assert.Equal(t, "output: v1", renderWithRevision(t, h))
+	assert.False(t, h.appSpecChanged)
+	revisionName, revision := h.renderRevision()
+	assert.Equal(t, "myapp-v1", revisionName)
+	assert.Equal(t, int64(1), revision)
+	comps := []*v1alpha2.Component{{ObjectMeta: metav1.ObjectMeta{Name: "frontend", Namespace: "default"}}}
+	assert.NoError(t, h.createAppRevision(ctx, "myapp-v1", newRevisionTestAppConfig("myapp-v1"), comps))
+	var appRevision v1alpha2.ApplicationRevision
+	assert.NoError(t, h.r.Get(ctx, client.ObjectKey{Namespace: "default", Name: "myapp-v1"}, &appRevision))
+	assert.Equal(t, h.appSpecHash, appRevision.GetLabels()[oam.LabelAppSpecHash])
+	assert.Equal(t, "myapp", appRevision.GetLabels()[oam.LabelAppName])
+	assert.Equal(t, *oldSpec, appRevision.Spec.Application.Spec)
+	assert.Equal(t, "output: v1", appRevision.Spec.WorkloadDefinitions["worker"].Spec.Template)
+	assert.Len(t, appRevision.Spec.Components, 1)
+	h.app.Status.LatestRevision = &v1alpha2.Revision{Name: "myapp-v1", Revision: 1}
+
+	// upgrading the definition doesn't change the running application
+	var wd v1alpha2.WorkloadDefinition
+	assert.NoError(t, h.r.Get(ctx, client.ObjectKey{Name: "worker"}, &wd))
+	wd.Spec.Template = "output: v2"
+	assert.NoError(t, h.r.Update(ctx, &wd))
+	assert.Equal(t, "output: v1", renderWithRevision(t, h))
+	assert.False(t, h.appSpecChanged)
+	revisionName, revision = h.renderRevision()
+	assert.Equal(t, "myapp-v1", revisionName)
+	assert.Equal(t, int64(1), revision)
+
+	// a new application spec picks up the new definition
+	h.app.Spec.Components[0].Settings = runtime.RawExtension{Raw: []byte(`{"image":"nginx:2"}`)}
+	assert.Equal(t, "output: v2", renderWithRevision(t, h))
+	assert.True(t, h.appSpecChanged)
+	revisionName, revision = h.renderRevision()
+	assert.Equal(t, "myapp-v2", revisionName)
+	assert.Equal(t, int64(2), revision)
+	assert.NoError(t, h.createAppRevision(ctx, "myapp-v2", newRevisionTestAppConfig("myapp-v2"), comps))
+	h.app.Status.LatestRevision = &v1alpha2.Revision{Name: "myapp-v2", Revision: 2}
+
+	// rolling back to the old spec renders it the same way as before
+	h.app.Spec = *oldSpec
+	assert.Equal(t, "output: v1", renderWithRevision(t, h))
+	assert.True(t, h.appSpecChanged)
+}
+
+func TestCreateOrUpdateAppConfigRevisions(t *testing.T) {
+	ctx := context.Background()
+	h := newTrackerTestHandler(t)
+	h.app.Status.LatestRevision = nil
+	h.app.Spec.Components = []v1alpha2.ApplicationComponent{{Name: "frontend", WorkloadType: "worker"}}
+	assert.NoError(t, h.r.Create(ctx, h.app))
+	newAppConfig := func(compName string) *v1alpha2.ApplicationConfiguration {
+		ac := newRevisionTestAppConfig("myapp")
+		ac.Spec.Components = []v1alpha2.ApplicationConfigurationComponent{{ComponentName: compName}}
+		return ac
+	}
+	apply := func(ac *v1alpha2.ApplicationConfiguration) {
+		assert.NoError(t, h.prepareAppRevision(ctx, appfile.NewApplicationParser(h.r.Client, nil)))
+		assert.NoError(t, h.createOrUpdateAppConfig(ctx, ac, nil))
+	}
+	getAppRevision := func(name string) (*v1alpha2.ApplicationRevision, error) {
+		appRevision := &v1alpha2.ApplicationRevision{}
+		return appRevision, h.r.Get(ctx, client.ObjectKey{Namespace: "default", Name: name}, appRevision)
+	}
+	getAppConfig := func(name string) error {
+		return h.r.Get(ctx, client.ObjectKey{Namespace: "default", Name: name}, &v1alpha2.ApplicationConfiguration{})
+	}
+
+	apply(newAppConfig("frontend"))
+	assert.Equal(t, "myapp-v1", h.app.Status.LatestRevision.Name)
+	assert.NoError(t, getAppConfig("myapp-v1"))
+	appRevision, err := getAppRevision("myapp-v1")
+	assert.NoError(t, err)
+
+	// the missing revision of the latest appConfig is created again
+	assert.NoError(t, h.r.Delete(ctx, appRevision))
+	apply(newAppConfig("frontend"))
+	_, err = getAppRevision("myapp-v1")
+	assert.NoError(t, err)
+	assert.True(t, apierrors.IsNotFound(getAppConfig("myapp-v2")))
+
+	// a new application spec that renders the same appConfig only gets a new application revision
+	h.app.Spec.Components[0].Settings = runtime.RawExtension{Raw: []byte(`{"image":"nginx:2"}`)}
+	apply(newAppConfig("frontend"))
+	assert.True(t, h.appSpecChanged)
+	assert.Equal(t, "myapp-v1", h.app.Status.LatestRevision.Name)
+	assert.True(t, apierrors.IsNotFound(getAppConfig("myapp-v2")))
+	appRevision, err = getAppRevision("myapp-v2")
+	assert.NoError(t, err)
+	assert.Equal(t, h.appSpecHash, appRevision.GetLabels()[oam.LabelAppSpecHash])
+	ac, err := oamutil.RawExtension2Map(&appRevision.Spec.ApplicationConfiguration)
+	assert.NoError(t, err)
+	assert.Equal(t, "myapp-v1", ac["metadata"].(map[string]interface{})["name"])
+
+	// the next appConfig comes after the application revision
+	apply(newAppConfig("frontend"))
+	assert.False(t, h.appSpecChanged)
+	assert.True(t, apierrors.IsNotFound(getAppConfig("myapp-v3")))
+	apply(newAppConfig("backend"))
+	assert.Equal(t, "myapp-v3", h.app.Status.LatestRevision.Name)
+	assert.NoError(t, getAppConfig("myapp-v3"))
+	_, err = getAppRevision("myapp-v3")
+	assert.NoError(t, err)
+}
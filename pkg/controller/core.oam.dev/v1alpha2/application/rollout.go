@@ -0,0 +1,171 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	ktypes "k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+	"github.com/oam-dev/kubevela/pkg/controller/common"
+	"github.com/oam-dev/kubevela/pkg/controller/common/rollout"
+	"github.com/oam-dev/kubevela/pkg/controller/utils"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+// getRolloutSourceRevision returns the appConfig revision that the next rollout upgrades from
+// it is the target of the last rollout if it succeeded, otherwise the source of the last rollout is still the
+// stable one. An application that has never rolled out upgrades from the revision it was running before.
+func getRolloutSourceRevision(status *v1alpha2.AppStatus, prevRevision string) string {
+	if status.RolloutTargetRevision == "" {
+		return prevRevision
+	}
+	if status.RollingState == v1alpha1.RolloutSucceedState {
+		return status.RolloutTargetRevision
+	}
+	return status.RolloutSourceRevision
+}
+
+// getRollingComponents returns the names of the components that have a new revision in the appConfig
+func getRollingComponents(ac *v1alpha2.ApplicationConfiguration) []string {
+	var componentList []string
+	for _, revisionName := range strings.Split(ac.GetAnnotations()[oam.AnnotationRollingComponent],
+		common.RollingComponentsSep) {
+		if len(revisionName) != 0 {
+			componentList = append(componentList, utils.ExtractComponentName(revisionName))
+		}
+	}
+	return componentList
+}
+
+// reconcileRollout drives the rollout plan of the application from the old appConfig revision to the latest one
+// a new revision waits for the current rollout to finish before it is rolled out.
+// It returns true if the rollout is still in progress.
+func (h *appHandler) reconcileRollout(ctx context.Context, prevRevision string) (ctrl.Result, bool, error) {
+	app := h.app
+	latestRevision := app.Status.LatestRevision.Name
+	desiredRevision := latestRevision
+	if undoRevision, exist := app.GetAnnotations()[oam.AnnotationRolloutUndoRevision]; exist {
+		if prevRevision != latestRevision {
+			// the application spec changes after the undo
+			if err := h.updateAnnotations(ctx, func(annotations map[string]string) {
+				delete(annotations, oam.AnnotationRolloutUndoRevision)
+			}); err != nil {
+				return ctrl.Result{}, false, err
+			}
+		} else {
+			desiredRevision = undoRevision
+		}
+	}
+	if app.Status.RolloutTargetRevision != desiredRevision && !rollout.IsRolloutRunning(app.Status.RollingState) {
+		if err := h.startRollout(ctx, desiredRevision, prevRevision, desiredRevision != latestRevision); err != nil {
+			return ctrl.Result{}, false, err
+		}
+	}
+	switch app.Status.RollingState {
+	case "", v1alpha1.RolloutSucceedState, v1alpha1.RolloutRolledBackState:
+		// nothing to roll out
+		return ctrl.Result{}, false, nil
+	default:
+		// a failed rollout still needs to follow its rollback policy
+	}
+
+	var targetAppConfig v1alpha2.ApplicationConfiguration
+	if err := h.r.Get(ctx, ktypes.NamespacedName{Namespace: app.Namespace, Name: app.Status.RolloutTargetRevision},
+		&targetAppConfig); err != nil {
+		return ctrl.Result{}, false, fmt.Errorf("cannot locate the target appConfig %s: %w",
+			app.Status.RolloutTargetRevision, err)
+	}
+	var sourceAppConfig *v1alpha2.ApplicationConfiguration
+	if app.Status.RolloutSourceRevision != "" {
+		sourceAppConfig = &v1alpha2.ApplicationConfiguration{}
+		if err := h.r.Get(ctx, ktypes.NamespacedName{Namespace: app.Namespace,
+			Name: app.Status.RolloutSourceRevision}, sourceAppConfig); err != nil {
+			return ctrl.Result{}, false, fmt.Errorf("cannot locate the source appConfig %s: %w",
+				app.Status.RolloutSourceRevision, err)
+		}
+	}
+	componentList := getRollingComponents(&targetAppConfig)
+	if len(componentList) == 0 && sourceAppConfig != nil {
+		// we are rolling back to an old revision
+		componentList = getRollingComponents(sourceAppConfig)
+	}
+	targetWorkload, sourceWorkload, err := rollout.ExtractWorkloads(ctx, h.r, componentList, &targetAppConfig,
+		sourceAppConfig)
+	if err != nil {
+		return ctrl.Result{}, false, fmt.Errorf("cannot fetch the workloads to upgrade: %w", err)
+	}
+
+	// the rollout controller consumes the approve and abort annotations on the application
+	annotations := app.DeepCopy().GetAnnotations()
+	prevState := app.Status.RollingState
+	rolloutPlanController := rollout.NewRolloutPlanController(h.r, app, h.r.record, app.Spec.RolloutPlan,
+		&app.Status.RolloutStatus, targetWorkload, sourceWorkload)
+	result, rolloutStatus := rolloutPlanController.Reconcile(ctx)
+	// make sure that the new status is copied back
+	app.Status.RolloutStatus = *rolloutStatus
+	rollout.RecordRolloutHistory(prevState, &app.Status.RolloutStatus, app.Status.RolloutSourceRevision,
+		app.Status.RolloutTargetRevision)
+	if !reflect.DeepEqual(annotations, app.GetAnnotations()) {
+		if err := h.updateAnnotations(ctx, nil); err != nil {
+			return ctrl.Result{}, false, err
+		}
+	}
+	return result, rollout.IsRolloutRunning(app.Status.RollingState), nil
+}
+
+// updateAnnotations persists the annotations of the application after the mutation
+// we update a copy so that the status we have is not overridden
+func (h *appHandler) updateAnnotations(ctx context.Context, mutate func(annotations map[string]string)) error {
+	app := h.app
+	if mutate != nil {
+		annotations := app.GetAnnotations()
+		mutate(annotations)
+		app.SetAnnotations(annotations)
+	}
+	appCopy := app.DeepCopy()
+	if err := h.r.Update(ctx, appCopy); err != nil {
+		return err
+	}
+	app.SetResourceVersion(appCopy.GetResourceVersion())
+	return nil
+}
+
+// startRollout points the rollout to the desired revision and restarts the rollout state machine
+// we don't need a rollout if no component of the latest revision changes, the workloads stay the same
+func (h *appHandler) startRollout(ctx context.Context, desiredRevision, prevRevision string, undo bool) error {
+	app := h.app
+	var desiredAppConfig v1alpha2.ApplicationConfiguration
+	if err := h.r.Get(ctx, ktypes.NamespacedName{Namespace: app.Namespace, Name: desiredRevision},
+		&desiredAppConfig); err != nil {
+		return fmt.Errorf("cannot locate the appConfig %s: %w", desiredRevision, err)
+	}
+	componentList := getRollingComponents(&desiredAppConfig)
+	if len(componentList) == 0 && !undo {
+		h.logger.Info("no component is changed, skip the rollout", "latest revision", desiredRevision)
+		return nil
+	}
+	if len(componentList) > 1 {
+		return fmt.Errorf("the rollout plan can only upgrade one component at a time, changed components: %v",
+			componentList)
+	}
+	sourceRevision := getRolloutSourceRevision(&app.Status, prevRevision)
+	if sourceRevision == desiredRevision {
+		// the application is deployed for the first time
+		sourceRevision = ""
+	}
+	app.Status.RolloutSourceRevision = sourceRevision
+	app.Status.RolloutTargetRevision = desiredRevision
+	if app.Status.RollingState == "" {
+		app.Status.RollingState = v1alpha1.VerifyingState
+	} else {
+		app.Status.StateTransition(v1alpha1.WorkloadModifiedEvent)
+	}
+	h.logger.Info("start to roll out the application", "source revision", app.Status.RolloutSourceRevision,
+		"target revision", desiredRevision, "undo", undo)
+	return nil
+}
@@ -0,0 +1,43 @@
+package application
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+func TestGetRolloutSourceRevision(t *testing.T) {
+	// the first rollout upgrades from the revision that runs before
+	status := &v1alpha2.AppStatus{}
+	assert.Equal(t, "app-v1", getRolloutSourceRevision(status, "app-v1"))
+
+	// the target of a successful rollout becomes the source
+	status = &v1alpha2.AppStatus{
+		RolloutStatus:         v1alpha1.RolloutStatus{RollingState: v1alpha1.RolloutSucceedState},
+		RolloutSourceRevision: "app-v1",
+		RolloutTargetRevision: "app-v2",
+	}
+	assert.Equal(t, "app-v2", getRolloutSourceRevision(status, "app-v2"))
+
+	// the source of a failed rollout is still the stable one
+	status.RollingState = v1alpha1.RolloutRolledBackState
+	assert.Equal(t, "app-v1", getRolloutSourceRevision(status, "app-v2"))
+	status.RollingState = v1alpha1.RolloutFailedState
+	assert.Equal(t, "app-v1", getRolloutSourceRevision(status, "app-v2"))
+}
+
+func TestGetRollingComponents(t *testing.T) {
+	ac := &v1alpha2.ApplicationConfiguration{}
+	assert.Nil(t, getRollingComponents(ac))
+	ac.SetAnnotations(map[string]string{oam.AnnotationRollingComponent: ""})
+	assert.Nil(t, getRollingComponents(ac))
+	ac.ObjectMeta = metav1.ObjectMeta{Annotations: map[string]string{
+		oam.AnnotationRollingComponent: "frontend-v2,backend-v3",
+	}}
+	assert.Equal(t, []string{"frontend", "backend"}, getRollingComponents(ac))
+}
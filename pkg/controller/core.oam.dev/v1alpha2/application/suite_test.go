@@ -22,6 +22,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/crossplane/crossplane-runtime/pkg/event"
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
 	"github.com/go-logr/logr"
 	. "github.com/onsi/ginkgo"
@@ -109,6 +110,7 @@ var _ = BeforeSuite(func(done Done) {
 		Log:    ctrl.Log.WithName("Application-Test"),
 		Scheme: testScheme,
 		dm:     dm,
+		record: event.NewNopRecorder(),
 	}
 	// setup the controller manager since we need the component handler to run in the background
 	ctlManager, err = ctrl.NewManager(cfg, ctrl.Options{
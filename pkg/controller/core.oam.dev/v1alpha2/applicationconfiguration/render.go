@@ -171,7 +171,8 @@ func (r *components) renderComponent(ctx context.Context, acc v1alpha2.Applicati
 	// pass through labels and annotation from app-config to workload
 	util.PassLabelAndAnnotation(ac, w)
 	// don't pass the following annotation as those are for appConfig only
-	util.RemoveAnnotations(w, []string{oam.AnnotationNewAppConfig, oam.AnnotationRollingComponent})
+	util.RemoveAnnotations(w, []string{oam.AnnotationNewAppConfig, oam.AnnotationRollingComponent,
+		oam.AnnotationInPlaceUpgrade})
 	ref := metav1.NewControllerRef(ac, v1alpha2.ApplicationConfigurationGroupVersionKind)
 	w.SetOwnerReferences([]metav1.OwnerReference{*ref})
 	w.SetNamespace(ac.GetNamespace())
@@ -190,7 +191,8 @@ func (r *components) renderComponent(ctx context.Context, acc v1alpha2.Applicati
 
 		// pass through labels and annotation from app-config to trait
 		util.PassLabelAndAnnotation(ac, t)
-		util.RemoveAnnotations(t, []string{oam.AnnotationNewAppConfig, oam.AnnotationRollingComponent})
+		util.RemoveAnnotations(t, []string{oam.AnnotationNewAppConfig, oam.AnnotationRollingComponent,
+			oam.AnnotationInPlaceUpgrade})
 		traits = append(traits, &Trait{Object: *t, Definition: *traitDef})
 		traitDefs = append(traitDefs, *traitDef)
 	}
@@ -209,7 +211,13 @@ func (r *components) renderComponent(ctx context.Context, acc v1alpha2.Applicati
 		if err != nil {
 			return nil, err
 		}
-		SetAppWorkloadInstanceName(acc.ComponentName, w, revision)
+		inPlaceUpgrade := IsInPlaceUpgrade(ac)
+		SetAppWorkloadInstanceName(acc.ComponentName, w, revision, inPlaceUpgrade)
+		if inPlaceUpgrade {
+			if err := AdoptRevisionedStatefulSet(ctx, r.client, ac.GetNamespace(), ac.GetName(), w); err != nil {
+				return nil, err
+			}
+		}
 		if isCompRolling {
 			// we have a special logic to emit the workload disabled so that the rollout process can take over
 			// this is the only place the appConfig controller is involved in the rollout process
@@ -1,16 +1,23 @@
 package applicationconfiguration
 
 import (
+	"context"
 	"fmt"
 	"reflect"
+	"strconv"
 
 	"github.com/crossplane/crossplane-runtime/pkg/fieldpath"
 	"github.com/openkruise/kruise-api/apps/v1alpha1"
+	"github.com/pkg/errors"
 	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
 	"github.com/oam-dev/kubevela/pkg/controller/utils"
+	"github.com/oam-dev/kubevela/pkg/oam"
 )
 
 const (
@@ -18,25 +25,26 @@ const (
 	cloneSetDisablePath            = "spec.updateStrategy.paused"
 	advancedStatefulSetDisablePath = "spec.updateStrategy.rollingUpdate.paused"
 	deploymentDisablePath          = "spec.paused"
+	// the statefulSet is paused by a partition that covers all the pods
+	statefulSetReplicasPath  = "spec.replicas"
+	statefulSetStrategyPath  = "spec.updateStrategy.type"
+	statefulSetPartitionPath = "spec.updateStrategy.rollingUpdate.partition"
 )
 
 // SetAppWorkloadInstanceName sets the name of the workload instance depends on the component revision
-// and the workload kind
-func SetAppWorkloadInstanceName(componentName string, w *unstructured.Unstructured, revision int) {
+// and the workload kind, the apps/v1 statefulSet only reuses the component name when the appConfig is upgraded in place
+func SetAppWorkloadInstanceName(componentName string, w *unstructured.Unstructured, revision int, inPlaceUpgrade bool) {
 	// TODO: we can get the workloadDefinition name from w.GetLabels()["oam.WorkloadTypeLabel"]
 	// and use a special field like "use-inplace-upgrade" in the definition to allow configurable behavior
 
 	// we hard code the behavior depends on the workload group/kind for now. The only in-place upgradable resources
 	// we support is cloneset/statefulset for now. We can easily add more later.
-	if w.GroupVersionKind().Group == v1alpha1.GroupVersion.Group {
-		if w.GetKind() == reflect.TypeOf(v1alpha1.CloneSet{}).Name() ||
-			w.GetKind() == reflect.TypeOf(v1alpha1.StatefulSet{}).Name() {
-			// we use the component name alone for those resources that do support in-place upgrade
-			klog.InfoS("we reuse the component name for resources that support in-place upgrade",
-				"kind", w.GetKind(), "instance name", componentName)
-			w.SetName(componentName)
-			return
-		}
+	if isInPlaceUpgradable(w, inPlaceUpgrade) {
+		// we use the component name alone for those resources that do support in-place upgrade
+		klog.InfoS("we reuse the component name for resources that support in-place upgrade",
+			"kind", w.GetKind(), "instance name", componentName)
+		w.SetName(componentName)
+		return
 	}
 	// we assume that the rest of the resources do not support in-place upgrade
 	instanceName := utils.ConstructRevisionName(componentName, int64(revision))
@@ -45,6 +53,72 @@ func SetAppWorkloadInstanceName(componentName string, w *unstructured.Unstructur
 	w.SetName(instanceName)
 }
 
+// isInPlaceUpgradable returns whether the workload upgrades its pods in place, they are the kruise cloneset and
+// advanced statefulset, and the apps/v1 statefulset that is rolled out in place
+func isInPlaceUpgradable(w *unstructured.Unstructured, inPlaceUpgrade bool) bool {
+	switch w.GroupVersionKind().Group {
+	case v1alpha1.GroupVersion.Group:
+		return w.GetKind() == reflect.TypeOf(v1alpha1.CloneSet{}).Name() ||
+			w.GetKind() == reflect.TypeOf(v1alpha1.StatefulSet{}).Name()
+	case appsv1.GroupName:
+		// the apps/v1 statefulSets used to be named after the component revisions, renaming them outside of
+		// a rollout would replace the statefulSets and their volumes
+		return inPlaceUpgrade && isStatefulSet(w)
+	}
+	return false
+}
+
+func isStatefulSet(w *unstructured.Unstructured) bool {
+	return w.GroupVersionKind().Group == appsv1.GroupName && w.GetKind() == reflect.TypeOf(appsv1.StatefulSet{}).Name()
+}
+
+// IsInPlaceUpgrade returns whether the workloads of the appConfig are upgraded in place by a rollout
+func IsInPlaceUpgrade(ac metav1.Object) bool {
+	return ac.GetAnnotations()[oam.AnnotationInPlaceUpgrade] == strconv.FormatBool(true)
+}
+
+// AdoptRevisionedStatefulSet migrates an apps/v1 statefulSet that is named after its component to the statefulSet
+// of the same application that was named after a component revision before the application is rolled out in place.
+// The workload keeps the name of the latest such statefulSet so that its pods keep their volumes, it's a no-op
+// if the statefulSet named after the component exists or the workload is not an apps/v1 statefulSet.
+func AdoptRevisionedStatefulSet(ctx context.Context, c client.Reader, namespace, appConfigName string,
+	w *unstructured.Unstructured) error {
+	if !isStatefulSet(w) {
+		return nil
+	}
+	componentName := w.GetName()
+	var statefulSets appsv1.StatefulSetList
+	if err := c.List(ctx, &statefulSets, client.InNamespace(namespace),
+		client.MatchingLabels{oam.LabelAppComponent: componentName}); err != nil {
+		return errors.Wrapf(err, "cannot list the statefulSets of component %s", componentName)
+	}
+	appName := utils.ExtractComponentName(appConfigName)
+	adopted, adoptedRevision := "", -1
+	for i := range statefulSets.Items {
+		sts := &statefulSets.Items[i]
+		owner := metav1.GetControllerOf(sts)
+		if owner == nil || owner.Kind != v1alpha2.ApplicationConfigurationKind ||
+			utils.ExtractComponentName(owner.Name) != appName {
+			continue
+		}
+		if sts.Name == componentName {
+			return nil
+		}
+		if utils.ExtractComponentName(sts.Name) != componentName {
+			continue
+		}
+		if revision, err := utils.ExtractRevision(sts.Name); err == nil && revision > adoptedRevision {
+			adopted, adoptedRevision = sts.Name, revision
+		}
+	}
+	if adopted != "" {
+		klog.InfoS("we keep the name of the statefulSet that is named after a component revision",
+			"component", componentName, "instance name", adopted)
+		w.SetName(adopted)
+	}
+	return nil
+}
+
 // prepWorkloadInstanceForRollout prepare the workload before it is emit to the k8s. The current approach is to mark it
 // as disabled so that it's spec won't take effect immediately. The rollout controller can take over the resources
 // and enable it on its own since appConfig controller here won't override their change
@@ -74,15 +148,39 @@ func prepWorkloadInstanceForRollout(workload *unstructured.Unstructured) error {
 				"kind", workload.GetKind(), "instance name", workload.GetName())
 			return nil
 		}
-	} else if workload.GroupVersionKind().Group == appsv1.GroupName &&
-		workload.GetKind() == reflect.TypeOf(appsv1.Deployment{}).Name() {
-		err := pv.SetBool(deploymentDisablePath, true)
-		if err != nil {
-			return err
+	} else if workload.GroupVersionKind().Group == appsv1.GroupName {
+		switch workload.GetKind() {
+		case reflect.TypeOf(appsv1.Deployment{}).Name():
+			err := pv.SetBool(deploymentDisablePath, true)
+			if err != nil {
+				return err
+			}
+			klog.InfoS("we render a deployment workload paused on the first time",
+				"kind", workload.GetKind(), "instance name", workload.GetName())
+			return nil
+		case reflect.TypeOf(appsv1.StatefulSet{}).Name():
+			// the statefulSet has no pause, the partition that equals the replicas keeps all the pods
+			// in the current revision until the rollout controller moves it
+			// the replicas is 1 by default
+			replicas := int64(1)
+			if v, err := pv.GetValue(statefulSetReplicasPath); err == nil {
+				switch r := v.(type) {
+				case int64:
+					replicas = r
+				case float64:
+					replicas = int64(r)
+				}
+			}
+			if err := pv.SetString(statefulSetStrategyPath, string(appsv1.RollingUpdateStatefulSetStrategyType)); err != nil {
+				return err
+			}
+			if err := pv.SetValue(statefulSetPartitionPath, replicas); err != nil {
+				return err
+			}
+			klog.InfoS("we render a statefulset workload paused by the partition on the first time",
+				"kind", workload.GetKind(), "instance name", workload.GetName(), "partition", replicas)
+			return nil
 		}
-		klog.InfoS("we render a deployment workload paused on the first time",
-			"kind", workload.GetKind(), "instance name", workload.GetName())
-		return nil
 	}
 	klog.InfoS("we encountered an unknown resource, we don't know how to prepare it",
 		"GVK", workload.GroupVersionKind().String(), "instance name", workload.GetName())
@@ -1,15 +1,23 @@
 package applicationconfiguration
 
 import (
+	"context"
 	"strings"
 	"testing"
 
 	kruise "github.com/openkruise/kruise-api/apps/v1alpha1"
 	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/pkg/oam"
 	"github.com/oam-dev/kubevela/pkg/oam/util"
 )
 
@@ -18,6 +26,7 @@ func TestSetAppWorkloadInstanceName(t *testing.T) {
 		compName string
 		w        *unstructured.Unstructured
 		revision int
+		inPlace  bool
 		expName  string
 		reason   string
 	}{
@@ -54,6 +63,27 @@ func TestSetAppWorkloadInstanceName(t *testing.T) {
 			expName: "mysql",
 			reason:  "workloadName set in the template is ignored",
 		},
+		"apps statefulset": {
+			compName: "mysql",
+			revision: 3,
+			w: &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "StatefulSet",
+			}},
+			expName: "mysql-v3",
+			reason:  "the apps/v1 statefulset keeps the revision name if it's not rolled out in place",
+		},
+		"apps statefulset upgraded in place": {
+			compName: "mysql",
+			revision: 3,
+			inPlace:  true,
+			w: &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "StatefulSet",
+			}},
+			expName: "mysql",
+			reason:  "the apps/v1 statefulset is upgraded in place by the partition",
+		},
 		"one resources same name case": {
 			compName: "mysql",
 			revision: 2,
@@ -67,12 +97,67 @@ func TestSetAppWorkloadInstanceName(t *testing.T) {
 	}
 	for name, ti := range tests {
 		t.Run(name, func(t *testing.T) {
-			SetAppWorkloadInstanceName(ti.compName, ti.w, ti.revision)
+			SetAppWorkloadInstanceName(ti.compName, ti.w, ti.revision, ti.inPlace)
 			assert.Equal(t, ti.expName, ti.w.GetName(), ti.reason)
 		})
 	}
 }
 
+func TestAdoptRevisionedStatefulSet(t *testing.T) {
+	statefulSet := func(name, owner string) *appsv1.StatefulSet {
+		return &appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+				Labels:    map[string]string{oam.LabelAppComponent: "mysql"},
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: v1alpha2.SchemeGroupVersion.String(),
+					Kind:       v1alpha2.ApplicationConfigurationKind,
+					Name:       owner,
+					Controller: pointer.BoolPtr(true),
+				}},
+			},
+		}
+	}
+	tests := map[string]struct {
+		existing []runtime.Object
+		expName  string
+		reason   string
+	}{
+		"no statefulset": {
+			expName: "mysql",
+			reason:  "a new statefulset is named after the component",
+		},
+		"revisioned statefulsets": {
+			existing: []runtime.Object{statefulSet("mysql-v2", "myapp-v2"), statefulSet("mysql-v10", "myapp-v3")},
+			expName:  "mysql-v10",
+			reason:   "the latest statefulset named after a component revision is adopted",
+		},
+		"statefulset named after the component": {
+			existing: []runtime.Object{statefulSet("mysql-v2", "myapp-v2"), statefulSet("mysql", "myapp-v3")},
+			expName:  "mysql",
+			reason:   "the statefulset that is already upgraded in place is kept",
+		},
+		"statefulset of another application": {
+			existing: []runtime.Object{statefulSet("mysql-v2", "otherapp-v2")},
+			expName:  "mysql",
+			reason:   "the statefulsets of other applications are not adopted",
+		},
+	}
+	for name, ti := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := fake.NewFakeClientWithScheme(clientgoscheme.Scheme, ti.existing...)
+			w := &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "StatefulSet",
+			}}
+			SetAppWorkloadInstanceName("mysql", w, 4, true)
+			assert.NoError(t, AdoptRevisionedStatefulSet(context.Background(), c, "default", "myapp-v4", w))
+			assert.Equal(t, ti.expName, w.GetName(), ti.reason)
+		})
+	}
+}
+
 func TestPrepWorkloadInstanceForRollout(t *testing.T) {
 	workload := kruise.CloneSet{
 		TypeMeta: metav1.TypeMeta{
@@ -110,8 +195,19 @@ func TestPrepWorkloadInstanceForRollout(t *testing.T) {
 	assert.True(t, exist)
 	assert.True(t, err == nil)
 	assert.True(t, value)
-	// Test other
+	// Test apps statefulset
 	workload.Kind = "StatefulSet"
 	w, _ = util.Object2Unstructured(workload)
+	assert.NoError(t, unstructured.SetNestedField(w.Object, int64(3), "spec", "replicas"))
+	assert.True(t, prepWorkloadInstanceForRollout(w) == nil)
+	strategy, _, _ := unstructured.NestedString(w.Object, "spec", "updateStrategy", "type")
+	assert.Equal(t, "RollingUpdate", strategy)
+	partition, exist, err := unstructured.NestedFieldNoCopy(w.Object, "spec", "updateStrategy", "rollingUpdate", "partition")
+	assert.True(t, exist)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), partition)
+	// Test other
+	workload.Kind = "DaemonSet"
+	w, _ = util.Object2Unstructured(workload)
 	assert.True(t, strings.Contains(prepWorkloadInstanceForRollout(w).Error(), "we do not know how to prepare"))
 }
@@ -17,6 +17,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	oamv1alpha2 "github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
 	"github.com/oam-dev/kubevela/pkg/controller/common/rollout"
 	controller "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev"
 	"github.com/oam-dev/kubevela/pkg/oam/discoverymapper"
@@ -66,13 +67,13 @@ func (r *Reconciler) Reconcile(req ctrl.Request) (res reconcile.Result, retErr e
 	}
 	klog.InfoS("Start to reconcile ", "application deployment", klog.KObj(&appDeploy))
 
-	// TODO: check if the target/source has changed
 	r.handleFinalizer(&appDeploy)
+	startRollout(&appDeploy)
 
-	// Get the target application
+	// Get the target application that we are rolling out
 	var targetApp oamv1alpha2.ApplicationConfiguration
 	var sourceApp *oamv1alpha2.ApplicationConfiguration
-	targetAppName := appDeploy.Spec.TargetApplicationName
+	targetAppName := appDeploy.Status.LastTargetApplicationName
 	if err := r.Get(ctx, ktypes.NamespacedName{Namespace: req.Namespace, Name: targetAppName},
 		&targetApp); err != nil {
 		klog.ErrorS(err, "cannot locate target application", "target application",
@@ -81,16 +82,20 @@ func (r *Reconciler) Reconcile(req ctrl.Request) (res reconcile.Result, retErr e
 	}
 
 	// Get the source application
-	sourceAppName := appDeploy.Spec.SourceApplicationName
+	sourceAppName := appDeploy.Status.LastSourceApplicationName
 	if sourceAppName == "" {
 		klog.Info("source app fields not filled, we assume it is deployed for the first time")
-	} else if err := r.Get(ctx, ktypes.NamespacedName{Namespace: req.Namespace, Name: sourceAppName}, sourceApp); err != nil {
-		klog.ErrorS(err, "cannot locate source application", "source application", klog.KRef(req.Namespace,
-			sourceAppName))
-		return ctrl.Result{}, err
+	} else {
+		sourceApp = &oamv1alpha2.ApplicationConfiguration{}
+		if err := r.Get(ctx, ktypes.NamespacedName{Namespace: req.Namespace, Name: sourceAppName}, sourceApp); err != nil {
+			klog.ErrorS(err, "cannot locate source application", "source application", klog.KRef(req.Namespace,
+				sourceAppName))
+			return ctrl.Result{}, err
+		}
 	}
 
-	targetWorkload, sourceWorkload, err := r.extractWorkloads(ctx, appDeploy.Spec.ComponentList, &targetApp, sourceApp)
+	targetWorkload, sourceWorkload, err := rollout.ExtractWorkloads(ctx, r, appDeploy.Spec.ComponentList, &targetApp,
+		sourceApp)
 	if err != nil {
 		klog.ErrorS(err, "cannot fetch the workloads to upgrade", "target application",
 			klog.KRef(req.Namespace, targetAppName), "source application", klog.KRef(req.Namespace, sourceAppName),
@@ -104,13 +109,42 @@ func (r *Reconciler) Reconcile(req ctrl.Request) (res reconcile.Result, retErr e
 	}
 
 	// reconcile the rollout part of the spec given the target and source workload
+	prevState := appDeploy.Status.RollingState
 	rolloutPlanController := rollout.NewRolloutPlanController(r, &appDeploy, r.record,
 		&appDeploy.Spec.RolloutPlan, &appDeploy.Status.RolloutStatus, targetWorkload, sourceWorkload)
 	result, rolloutStatus := rolloutPlanController.Reconcile(ctx)
 	// make sure that the new status is copied back
-	appDeploy.Status.RolloutStatus = *rolloutStatus
+	rolloutStatus.DeepCopyInto(&appDeploy.Status.RolloutStatus)
+	rollout.RecordRolloutHistory(prevState, &appDeploy.Status.RolloutStatus, appDeploy.Status.LastSourceApplicationName,
+		appDeploy.Status.LastTargetApplicationName)
+	// the rollout controller may consume the annotations, update a copy so that the status is not overridden
+	status := appDeploy.Status.DeepCopy()
+	if err := r.Update(ctx, &appDeploy); err != nil {
+		return ctrl.Result{}, err
+	}
 	// update the appDeploy status
-	return result, r.Update(ctx, &appDeploy)
+	appDeploy.Status = *status
+	return result, r.Status().Update(ctx, &appDeploy)
+}
+
+// startRollout starts the rollout for the first time or restarts it when the source or target changes
+// a new target waits for the current rollout to finish
+func startRollout(appDeploy *oamv1alpha2.ApplicationDeployment) {
+	status := &appDeploy.Status
+	if status.RollingState != "" && (rollout.IsRolloutRunning(status.RollingState) ||
+		(status.LastTargetApplicationName == appDeploy.Spec.TargetApplicationName &&
+			status.LastSourceApplicationName == appDeploy.Spec.SourceApplicationName)) {
+		return
+	}
+	klog.InfoS("start to roll out", "application deployment", klog.KObj(appDeploy),
+		"source", appDeploy.Spec.SourceApplicationName, "target", appDeploy.Spec.TargetApplicationName)
+	if status.RollingState == "" {
+		status.RollingState = v1alpha1.VerifyingState
+	} else {
+		status.StateTransition(v1alpha1.WorkloadModifiedEvent)
+	}
+	status.LastTargetApplicationName = appDeploy.Spec.TargetApplicationName
+	status.LastSourceApplicationName = appDeploy.Spec.SourceApplicationName
 }
 
 func (r *Reconciler) handleFinalizer(appDeploy *oamv1alpha2.ApplicationDeployment) {
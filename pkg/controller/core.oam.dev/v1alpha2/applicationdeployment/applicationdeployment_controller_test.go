@@ -0,0 +1,41 @@
+package applicationdeployment
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	oamv1alpha2 "github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+)
+
+func TestStartRollout(t *testing.T) {
+	appDeploy := &oamv1alpha2.ApplicationDeployment{
+		Spec: oamv1alpha2.ApplicationDeploymentSpec{
+			SourceApplicationName: "app-v1",
+			TargetApplicationName: "app-v2",
+		},
+	}
+	startRollout(appDeploy)
+	assert.Equal(t, v1alpha1.VerifyingState, appDeploy.Status.RollingState)
+	assert.Equal(t, "app-v2", appDeploy.Status.LastTargetApplicationName)
+
+	// a new target waits for the running rollout
+	appDeploy.Status.RollingState = v1alpha1.RollingInBatchesState
+	appDeploy.Spec.SourceApplicationName = "app-v2"
+	appDeploy.Spec.TargetApplicationName = "app-v3"
+	startRollout(appDeploy)
+	assert.Equal(t, v1alpha1.RollingInBatchesState, appDeploy.Status.RollingState)
+	assert.Equal(t, "app-v2", appDeploy.Status.LastTargetApplicationName)
+
+	appDeploy.Status.RollingState = v1alpha1.RolloutSucceedState
+	startRollout(appDeploy)
+	assert.Equal(t, v1alpha1.VerifyingState, appDeploy.Status.RollingState)
+	assert.Equal(t, "app-v2", appDeploy.Status.LastSourceApplicationName)
+	assert.Equal(t, "app-v3", appDeploy.Status.LastTargetApplicationName)
+
+	// nothing changes
+	appDeploy.Status.RollingState = v1alpha1.RolloutSucceedState
+	startRollout(appDeploy)
+	assert.Equal(t, v1alpha1.RolloutSucceedState, appDeploy.Status.RollingState)
+}
@@ -0,0 +1,177 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package definition
+
+import (
+	"context"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/types"
+	core "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+	"github.com/oam-dev/kubevela/pkg/utils/common"
+)
+
+// Reconcile error strings.
+const (
+	errGenerateSchema = "cannot generate the OpenAPI schema of the parameter"
+)
+
+// definitionObject is a WorkloadDefinition or a TraitDefinition
+type definitionObject interface {
+	metav1.Object
+	runtime.Object
+}
+
+// Reconciler generates the OpenAPI v3 schemas of the parameters of the WorkloadDefinitions and TraitDefinitions,
+// the schema of a definition is stored in a ConfigMap owned by the definition
+type Reconciler struct {
+	client.Client
+	record event.Recorder
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+}
+
+// WorkloadDefinitionReconciler reconciles the schemas of the WorkloadDefinitions
+type WorkloadDefinitionReconciler struct {
+	*Reconciler
+}
+
+// +kubebuilder:rbac:groups=core.oam.dev,resources=workloaddefinitions,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+
+// Reconcile generates the schema of a WorkloadDefinition
+func (r *WorkloadDefinitionReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	wd := new(v1alpha2.WorkloadDefinition)
+	if err := r.Get(ctx, req.NamespacedName, wd); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	tmpl, err := util.NewTemplate(wd.Spec.Template, nil, wd.Spec.Extension)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, r.reconcileSchema(ctx, wd, v1alpha2.WorkloadDefinitionGroupVersionKind,
+		types.TypeWorkload, tmpl.TemplateStr)
+}
+
+// TraitDefinitionReconciler reconciles the schemas of the TraitDefinitions
+type TraitDefinitionReconciler struct {
+	*Reconciler
+}
+
+// +kubebuilder:rbac:groups=core.oam.dev,resources=traitdefinitions,verbs=get;list;watch
+
+// Reconcile generates the schema of a TraitDefinition
+func (r *TraitDefinitionReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	td := new(v1alpha2.TraitDefinition)
+	if err := r.Get(ctx, req.NamespacedName, td); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	tmpl, err := util.NewTemplate(td.Spec.Template, nil, td.Spec.Extension)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, r.reconcileSchema(ctx, td, v1alpha2.TraitDefinitionGroupVersionKind,
+		types.TypeTrait, tmpl.TemplateStr)
+}
+
+// reconcileSchema stores the schema of the parameter of the template in the ConfigMap of the definition,
+// the ConfigMap is removed if the definition has no CUE template or no parameter. The ConfigMap is kept if the
+// schema can't be generated, the error requeues the definition.
+func (r *Reconciler) reconcileSchema(ctx context.Context, def definitionObject, gvk schema.GroupVersionKind,
+	kind types.CapType, template string) error {
+	logger := r.Log.WithValues(string(kind)+"Definition", def.GetName())
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+		Name:      util.GenDefinitionSchemaName(kind, def.GetName()),
+		Namespace: def.GetNamespace(),
+	}}
+	if cm.Namespace == "" {
+		cm.Namespace = types.DefaultKubeVelaNS
+	}
+
+	var parameterSchema []byte
+	if template != "" && def.GetAnnotations()["type"] != string(types.TerraformCategory) {
+		var err error
+		if parameterSchema, err = common.GenOpenAPIFromTemplate(template); err != nil {
+			logger.Error(err, errGenerateSchema)
+			r.record.Event(def, event.Warning(errGenerateSchema, err))
+			return errors.Wrap(err, errGenerateSchema)
+		}
+	}
+	if parameterSchema == nil {
+		if err := r.Delete(ctx, cm); err != nil && !kerrors.IsNotFound(err) {
+			return errors.Wrapf(err, "cannot delete the schema of %s", def.GetName())
+		}
+		return nil
+	}
+
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, cm, func() error {
+		typeLabel := oam.WorkloadTypeLabel
+		if kind == types.TypeTrait {
+			typeLabel = oam.TraitTypeLabel
+		}
+		util.AddLabels(cm, map[string]string{typeLabel: def.GetName()})
+		cm.SetOwnerReferences([]metav1.OwnerReference{*metav1.NewControllerRef(def, gvk)})
+		cm.Data = map[string]string{types.OpenapiV3JSONSchema: string(parameterSchema)}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "cannot apply the schema of %s", def.GetName())
+	}
+	logger.Info("Generated the schema of the definition", "configMap", cm.Name)
+	return nil
+}
+
+// SetupWithManager setups the reconcilers of the WorkloadDefinitions and TraitDefinitions with the manager
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha2.WorkloadDefinition{}).
+		Owns(&corev1.ConfigMap{}).
+		Complete(&WorkloadDefinitionReconciler{Reconciler: r}); err != nil {
+		return err
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha2.TraitDefinition{}).
+		Owns(&corev1.ConfigMap{}).
+		Complete(&TraitDefinitionReconciler{Reconciler: r})
+}
+
+// Setup adds the controllers that generate the schemas of the definitions to the manager
+func Setup(mgr ctrl.Manager, _ core.Args, _ logging.Logger) error {
+	reconciler := Reconciler{
+		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName("Definition"),
+		record: event.NewAPIRecorder(mgr.GetEventRecorderFor("Definition")),
+		Scheme: mgr.GetScheme(),
+	}
+	return reconciler.SetupWithManager(mgr)
+}
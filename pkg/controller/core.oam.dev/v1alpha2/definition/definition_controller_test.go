@@ -0,0 +1,131 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package definition
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	oamtypes "github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+func TestReconcileSchema(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, v1alpha2.SchemeBuilder.AddToScheme(scheme))
+
+	wd := &v1alpha2.WorkloadDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "vela-system"},
+		Spec: v1alpha2.WorkloadDefinitionSpec{
+			Reference: v1alpha2.DefinitionReference{Name: "deployments.apps"},
+			Template: `
+output: {
+	apiVersion: "apps/v1"
+	kind:       "Deployment"
+	spec: template: spec: containers: [{name: context.name, image: parameter.image}]
+}
+parameter: {
+	// +usage=Which image would you like to use for your service
+	// +short=i
+	image: string
+	cmd?: [...string]
+}
+`,
+		},
+	}
+	td := &v1alpha2.TraitDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "scaler", Namespace: "vela-system"},
+		Spec: v1alpha2.TraitDefinitionSpec{
+			Template: "patch: spec: replicas: parameter.replicas\nparameter: replicas: *1 | int\n",
+		},
+	}
+	cli := fake.NewFakeClientWithScheme(scheme, wd, td)
+	r := &Reconciler{Client: cli, record: event.NewNopRecorder(), Log: ctrl.Log.WithName("Definition"), Scheme: scheme}
+
+	_, err := (&WorkloadDefinitionReconciler{Reconciler: r}).Reconcile(ctrl.Request{
+		NamespacedName: types.NamespacedName{Name: "worker", Namespace: "vela-system"}})
+	assert.NoError(t, err)
+	cm := &corev1.ConfigMap{}
+	assert.NoError(t, cli.Get(ctx, client.ObjectKey{Name: "schema-workload-worker", Namespace: "vela-system"}, cm))
+	assert.Equal(t, "worker", cm.Labels[oam.WorkloadTypeLabel])
+	assert.Equal(t, "worker", cm.OwnerReferences[0].Name)
+	assert.Equal(t, v1alpha2.WorkloadDefinitionKind, cm.OwnerReferences[0].Kind)
+	var schema map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(cm.Data[oamtypes.OpenapiV3JSONSchema]), &schema))
+	assert.Equal(t, map[string]interface{}{
+		"type":     "object",
+		"required": []interface{}{"image"},
+		"properties": map[string]interface{}{
+			"image": map[string]interface{}{
+				"type":        "string",
+				"title":       "image",
+				"description": "Which image would you like to use for your service",
+			},
+			"cmd": map[string]interface{}{
+				"type":  "array",
+				"title": "cmd",
+				"items": map[string]interface{}{"type": "string"},
+			},
+		},
+	}, schema)
+
+	_, err = (&TraitDefinitionReconciler{Reconciler: r}).Reconcile(ctrl.Request{
+		NamespacedName: types.NamespacedName{Name: "scaler", Namespace: "vela-system"}})
+	assert.NoError(t, err)
+	assert.NoError(t, cli.Get(ctx, client.ObjectKey{Name: "schema-trait-scaler", Namespace: "vela-system"}, cm))
+	assert.Equal(t, "scaler", cm.Labels[oam.TraitTypeLabel])
+	assert.JSONEq(t, `{"type":"object","required":["replicas"],"properties":{"replicas":{"type":"integer","title":"replicas","default":1}}}`,
+		cm.Data[oamtypes.OpenapiV3JSONSchema])
+
+	// the schema is kept if the template is broken
+	td.Spec.Template = "patch: spec: replicas: parameter.replicas\nparameter: replicas: *1 | int\nparameter: {\n"
+	assert.NoError(t, cli.Update(ctx, td))
+	_, err = (&TraitDefinitionReconciler{Reconciler: r}).Reconcile(ctrl.Request{
+		NamespacedName: types.NamespacedName{Name: "scaler", Namespace: "vela-system"}})
+	assert.Error(t, err)
+	assert.NoError(t, cli.Get(ctx, client.ObjectKey{Name: "schema-trait-scaler", Namespace: "vela-system"}, cm))
+	assert.Contains(t, cm.Data[oamtypes.OpenapiV3JSONSchema], "replicas")
+
+	// the schema is removed with the parameter
+	td.Spec.Template = "patch: spec: replicas: 1\n"
+	assert.NoError(t, cli.Update(ctx, td))
+	_, err = (&TraitDefinitionReconciler{Reconciler: r}).Reconcile(ctrl.Request{
+		NamespacedName: types.NamespacedName{Name: "scaler", Namespace: "vela-system"}})
+	assert.NoError(t, err)
+	err = cli.Get(ctx, client.ObjectKey{Name: "schema-trait-scaler", Namespace: "vela-system"}, cm)
+	assert.True(t, kerrors.IsNotFound(err))
+
+	// the definition that is deleted is ignored
+	_, err = (&WorkloadDefinitionReconciler{Reconciler: r}).Reconcile(ctrl.Request{
+		NamespacedName: types.NamespacedName{Name: "unknown", Namespace: "vela-system"}})
+	assert.NoError(t, err)
+}
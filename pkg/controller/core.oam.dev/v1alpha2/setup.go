@@ -29,6 +29,7 @@ import (
 	"github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2/core/scopes/healthscope"
 	"github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2/core/traits/manualscalertrait"
 	"github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2/core/workloads/containerizedworkload"
+	"github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2/definition"
 )
 
 // Setup workload controllers.
@@ -36,7 +37,7 @@ func Setup(mgr ctrl.Manager, args controller.Args, l logging.Logger) error {
 	for _, setup := range []func(ctrl.Manager, controller.Args, logging.Logger) error{
 		applicationconfiguration.Setup,
 		containerizedworkload.Setup, manualscalertrait.Setup, healthscope.Setup,
-		application.Setup, applicationdeployment.Setup,
+		application.Setup, applicationdeployment.Setup, definition.Setup,
 	} {
 		if err := setup(mgr, args, l); err != nil {
 			return err
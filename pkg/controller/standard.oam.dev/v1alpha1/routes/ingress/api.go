@@ -2,9 +2,12 @@ package ingress
 
 import (
 	"fmt"
+	"strconv"
 
 	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
 	"k8s.io/api/networking/v1beta1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	standardv1alpha1 "github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
@@ -23,12 +26,47 @@ const (
 	StatusSynced = "Synced"
 )
 
+// CanaryIngressSuffix is the name suffix of the ingress that sends part of the traffic to the canary backend
+const CanaryIngressSuffix = "-canary"
+
 // RouteIngress is an interface of route ingress implementation
 type RouteIngress interface {
 	Construct(routeTrait *standardv1alpha1.Route) []*v1beta1.Ingress
 	CheckStatus(routeTrait *standardv1alpha1.Route) (string, []runtimev1alpha1.Condition)
 }
 
+// TrafficSplitter is implemented by the route ingress that can't split the traffic with ingresses,
+// the rules with a canary backend are served by the resources it constructs instead of ingresses
+type TrafficSplitter interface {
+	ConstructTrafficSplit(routeTrait *standardv1alpha1.Route) []*unstructured.Unstructured
+	TrafficSplitKind() schema.GroupVersionKind
+}
+
+// RuleIngressName returns the name of the ingress that serves the rule
+func RuleIngressName(routeTrait *standardv1alpha1.Route, idx int) string {
+	name := routeTrait.Spec.Rules[idx].Name
+	if name == "" {
+		name = strconv.Itoa(idx)
+	}
+	return routeTrait.Name + "-" + name
+}
+
+// TrafficSplitName returns the name of the resource that splits the traffic of the route
+func TrafficSplitName(routeTrait *standardv1alpha1.Route) string {
+	return routeTrait.Name
+}
+
+// GetCanaryWeight returns the weight of the canary backends in the route, it's nil if there is no canary backend
+func GetCanaryWeight(routeTrait *standardv1alpha1.Route) *int32 {
+	for _, rule := range routeTrait.Spec.Rules {
+		if rule.CanaryBackend != nil {
+			weight := rule.CanaryBackend.Weight
+			return &weight
+		}
+	}
+	return nil
+}
+
 // GetRouteIngress will get real implementation from type, we could support more in the future.
 func GetRouteIngress(provider string, client client.Client) (RouteIngress, error) {
 	var routeIngress RouteIngress
@@ -15,17 +15,27 @@ import (
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/api/networking/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/utils/pointer"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// httpProxyGVK is the kind of the contour HTTPProxy that supports weighted services
+var httpProxyGVK = schema.GroupVersionKind{Group: "projectcontour.io", Version: "v1", Kind: "HTTPProxy"}
+
+// httpProxyStatusValid is the status of a HTTPProxy that is accepted by contour
+const httpProxyStatusValid = "valid"
+
 // Contour is Contour ingress implementation
 type Contour struct {
 	Client client.Client
 }
 
 var _ RouteIngress = &Contour{}
+var _ TrafficSplitter = &Contour{}
 
 // CheckStatus will check status of the ingress
 func (n *Contour) CheckStatus(routeTrait *standardv1alpha1.Route) (string, []runtimev1alpha1.Condition) {
@@ -54,6 +64,12 @@ func (n *Contour) CheckStatus(routeTrait *standardv1alpha1.Route) (string, []run
 				Message: condition.Message}}
 		}
 	}
+	// the traffic of a rule is not split until the ports of its backends are resolved
+	if _, err := n.resolveSplitPorts(routeTrait); err != nil {
+		return StatusSynced, []runtimev1alpha1.Condition{{Type: runtimev1alpha1.TypeSynced,
+			Status: v1.ConditionFalse, LastTransitionTime: metav1.Now(), Reason: runtimev1alpha1.ReasonUnavailable,
+			Message: err.Error()}}
+	}
 	// check ingress
 	ingresses := n.Construct(routeTrait)
 	for _, in := range ingresses {
@@ -97,27 +113,45 @@ func (n *Contour) CheckStatus(routeTrait *standardv1alpha1.Route) (string, []run
 				Message: fmt.Sprintf("IP/Hostname of %s ingress is generating", in.Name)}}
 		}
 	}
+	// check HTTPProxy
+	for _, proxy := range n.ConstructTrafficSplit(routeTrait) {
+		if err := n.Client.Get(ctx, types.NamespacedName{Namespace: proxy.GetNamespace(), Name: proxy.GetName()},
+			proxy); err != nil {
+			return StatusSynced, []runtimev1alpha1.Condition{{Type: runtimev1alpha1.TypeSynced,
+				Status: v1.ConditionFalse, LastTransitionTime: metav1.Now(), Reason: runtimev1alpha1.ReasonUnavailable,
+				Message: err.Error()}}
+		}
+		currentStatus, _, _ := unstructured.NestedString(proxy.Object, "status", "currentStatus")
+		if currentStatus != httpProxyStatusValid {
+			description, _, _ := unstructured.NestedString(proxy.Object, "status", "description")
+			return StatusSynced, []runtimev1alpha1.Condition{{Type: runtimev1alpha1.TypeSynced,
+				Status: v1.ConditionFalse, LastTransitionTime: metav1.Now(), Reason: runtimev1alpha1.ReasonCreating,
+				Message: fmt.Sprintf("HTTPProxy %s is not valid: %s", proxy.GetName(), description)}}
+		}
+	}
 	return StatusReady, []runtimev1alpha1.Condition{{Type: runtimev1alpha1.TypeReady, Status: v1.ConditionTrue,
 		Reason: runtimev1alpha1.ReasonAvailable, LastTransitionTime: metav1.Now()}}
 }
 
 // Construct will construct ingress from route
-func (*Contour) Construct(routeTrait *standardv1alpha1.Route) []*v1beta1.Ingress {
+func (n *Contour) Construct(routeTrait *standardv1alpha1.Route) []*v1beta1.Ingress {
 
 	// Don't create ingress if no host set, this is used for local K8s cluster demo and the route trait will create K8s service only.
 	if routeTrait.Spec.Host == "" || strings.Contains(routeTrait.Spec.Host, "localhost") || strings.Contains(routeTrait.Spec.Host, "127.0.0.1") {
 		return nil
 	}
+	splitPorts, _ := n.resolveSplitPorts(routeTrait)
 	var ingresses []*v1beta1.Ingress
 	for idx, rule := range routeTrait.Spec.Rules {
-		name := rule.Name
-		if name == "" {
-			name = strconv.Itoa(idx)
-		}
+		ingressName := RuleIngressName(routeTrait, idx)
 		backend := rule.Backend
 		if backend == nil || backend.BackendService == nil {
 			continue
 		}
+		// contour splits the traffic with a HTTPProxy instead of an ingress
+		if _, split := splitPorts[idx]; split {
+			continue
+		}
 
 		var annotations = make(map[string]string)
 
@@ -148,7 +182,7 @@ func (*Contour) Construct(routeTrait *standardv1alpha1.Route) []*v1beta1.Ingress
 				APIVersion: v1beta1.SchemeGroupVersion.String(),
 			},
 			ObjectMeta: metav1.ObjectMeta{
-				Name:        routeTrait.Name + "-" + name,
+				Name:        ingressName,
 				Namespace:   routeTrait.Namespace,
 				Annotations: annotations,
 				Labels:      routeTrait.GetLabels(),
@@ -168,7 +202,7 @@ func (*Contour) Construct(routeTrait *standardv1alpha1.Route) []*v1beta1.Ingress
 			ingress.Spec.TLS = []v1beta1.IngressTLS{
 				{
 					Hosts:      []string{routeTrait.Spec.Host},
-					SecretName: routeTrait.Name + "-" + name + "-cert",
+					SecretName: ingressName + "-cert",
 				},
 			}
 		}
@@ -201,3 +235,148 @@ func (*Contour) Construct(routeTrait *standardv1alpha1.Route) []*v1beta1.Ingress
 	}
 	return ingresses
 }
+
+// TrafficSplitKind returns the kind of the HTTPProxy
+func (*Contour) TrafficSplitKind() schema.GroupVersionKind {
+	return httpProxyGVK
+}
+
+// ConstructTrafficSplit constructs the weighted HTTPProxy for the rules with a canary backend, contour only takes
+// one root HTTPProxy of a host so all the rules are routed by the same HTTPProxy named after the route
+func (n *Contour) ConstructTrafficSplit(routeTrait *standardv1alpha1.Route) []*unstructured.Unstructured {
+	if routeTrait.Spec.Host == "" || strings.Contains(routeTrait.Spec.Host, "localhost") || strings.Contains(routeTrait.Spec.Host, "127.0.0.1") {
+		return nil
+	}
+	splitPorts, _ := n.resolveSplitPorts(routeTrait)
+	var routes []interface{}
+	certRule := -1
+	for idx, rule := range routeTrait.Spec.Rules {
+		backend := rule.Backend
+		if backend == nil || backend.BackendService == nil {
+			continue
+		}
+		ports, split := splitPorts[idx]
+		if !split {
+			// prefer the certificate of a rule that is still served by its ingress
+			if _, certSplit := splitPorts[certRule]; certRule < 0 || certSplit {
+				certRule = idx
+			}
+			continue
+		}
+		if certRule < 0 {
+			certRule = idx
+		}
+		path := rule.Path
+		if path == "" {
+			path = "/"
+		}
+		canary := rule.CanaryBackend
+		services := []interface{}{
+			map[string]interface{}{
+				"name":   backend.BackendService.ServiceName,
+				"port":   ports[0],
+				"weight": int64(100 - canary.Weight),
+			},
+			map[string]interface{}{
+				"name":   canary.BackendService.ServiceName,
+				"port":   ports[1],
+				"weight": int64(canary.Weight),
+			},
+		}
+		route := map[string]interface{}{
+			"conditions": []interface{}{map[string]interface{}{"prefix": path}},
+			"services":   services,
+		}
+		if backend.ReadTimeout != 0 {
+			route["timeoutPolicy"] = map[string]interface{}{
+				"response": strconv.Itoa(backend.ReadTimeout) + "s",
+			}
+		}
+		routes = append(routes, route)
+	}
+	if len(routes) == 0 {
+		return nil
+	}
+	virtualHost := map[string]interface{}{
+		"fqdn": routeTrait.Spec.Host,
+	}
+	if routeTrait.Spec.TLS != nil {
+		// the certificate is the one issued for the ingress of a rule of the same host
+		virtualHost["tls"] = map[string]interface{}{
+			"secretName": RuleIngressName(routeTrait, certRule) + "-cert",
+		}
+	}
+
+	proxy := &unstructured.Unstructured{}
+	proxy.SetGroupVersionKind(httpProxyGVK)
+	proxy.SetName(TrafficSplitName(routeTrait))
+	proxy.SetNamespace(routeTrait.Namespace)
+	proxy.SetLabels(routeTrait.GetLabels())
+	proxy.SetOwnerReferences([]metav1.OwnerReference{
+		{
+			APIVersion:         routeTrait.GetObjectKind().GroupVersionKind().GroupVersion().String(),
+			Kind:               routeTrait.GetObjectKind().GroupVersionKind().Kind,
+			UID:                routeTrait.GetUID(),
+			Name:               routeTrait.GetName(),
+			Controller:         pointer.BoolPtr(true),
+			BlockOwnerDeletion: pointer.BoolPtr(true),
+		},
+	})
+	proxy.Object["spec"] = map[string]interface{}{
+		"virtualhost": virtualHost,
+		"routes":      routes,
+	}
+	return []*unstructured.Unstructured{proxy}
+}
+
+// resolveSplitPorts returns the port numbers of the backend and the canary backend of the rules that split the
+// traffic by their index. HTTPProxy only takes the port number, the named ports are resolved by the services.
+// The rules whose ports can't be resolved are left out and keep their ingresses, the error reports them.
+func (n *Contour) resolveSplitPorts(routeTrait *standardv1alpha1.Route) (map[int][]int64, error) {
+	splitPorts := make(map[int][]int64)
+	var errs []string
+	for idx, rule := range routeTrait.Spec.Rules {
+		if rule.Backend == nil || rule.Backend.BackendService == nil || rule.CanaryBackend == nil {
+			continue
+		}
+		port, err := n.resolvePort(routeTrait.Namespace, rule.Backend.BackendService)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		canaryPort, err := n.resolvePort(routeTrait.Namespace, &rule.CanaryBackend.BackendService)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		splitPorts[idx] = []int64{port, canaryPort}
+	}
+	if len(errs) != 0 {
+		return splitPorts, fmt.Errorf("cannot split the traffic: %s", strings.Join(errs, "; "))
+	}
+	return splitPorts, nil
+}
+
+// resolvePort returns the port number of the backend service
+func (n *Contour) resolvePort(namespace string, ref *standardv1alpha1.BackendServiceRef) (int64, error) {
+	if ref.Port.Type == intstr.Int {
+		if ref.Port.IntVal <= 0 {
+			return 0, fmt.Errorf("the port of the service %s is not set", ref.ServiceName)
+		}
+		return int64(ref.Port.IntVal), nil
+	}
+	if n.Client == nil {
+		return 0, fmt.Errorf("cannot resolve the port %s of the service %s", ref.Port.StrVal, ref.ServiceName)
+	}
+	var svc v1.Service
+	if err := n.Client.Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: ref.ServiceName},
+		&svc); err != nil {
+		return 0, fmt.Errorf("cannot resolve the port %s of the service %s: %w", ref.Port.StrVal, ref.ServiceName, err)
+	}
+	for _, port := range svc.Spec.Ports {
+		if port.Name == ref.Port.StrVal {
+			return int64(port.Port), nil
+		}
+	}
+	return 0, fmt.Errorf("the service %s has no port named %s", ref.ServiceName, ref.Port.StrVal)
+}
@@ -9,8 +9,11 @@ import (
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/api/networking/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	standardv1alpha1 "github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
 )
@@ -107,3 +110,75 @@ func TestContourConstruct(t *testing.T) {
 		}
 	}
 }
+
+func TestContourConstructTrafficSplit(t *testing.T) {
+	routeTrait := &standardv1alpha1.Route{
+		ObjectMeta: metav1.ObjectMeta{Name: "trait-test", Namespace: "default"},
+		Spec: standardv1alpha1.RouteSpec{
+			Host: "test.abc",
+			TLS:  &standardv1alpha1.TLS{IssuerName: "test-issuer", Type: "Issuer"},
+			Rules: []standardv1alpha1.Rule{
+				{
+					Backend: &standardv1alpha1.Backend{BackendService: &standardv1alpha1.BackendServiceRef{ServiceName: "stable", Port: intstr.FromInt(80)}},
+					CanaryBackend: &standardv1alpha1.CanaryBackend{
+						BackendService: standardv1alpha1.BackendServiceRef{ServiceName: "canary", Port: intstr.FromInt(8080)},
+						Weight:         30,
+					},
+				},
+				{
+					Path:    "/api",
+					Backend: &standardv1alpha1.Backend{BackendService: &standardv1alpha1.BackendServiceRef{ServiceName: "stable", Port: intstr.FromString("http")}},
+					CanaryBackend: &standardv1alpha1.CanaryBackend{
+						BackendService: standardv1alpha1.BackendServiceRef{ServiceName: "canary", Port: intstr.FromInt(8080)},
+						Weight:         30,
+					},
+				},
+			},
+		},
+	}
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	contour := &Contour{Client: fake.NewFakeClientWithScheme(scheme)}
+	// the rule whose named port can't be resolved keeps its ingress
+	ingresses := contour.Construct(routeTrait)
+	assert.Len(t, ingresses, 1)
+	assert.Equal(t, "trait-test-1", ingresses[0].Name)
+	noTLS := routeTrait.DeepCopy()
+	noTLS.Spec.TLS = nil
+	status, conditions := contour.CheckStatus(noTLS)
+	assert.Equal(t, StatusSynced, status)
+	assert.Contains(t, conditions[0].Message, "the port http of the service stable")
+
+	contour = &Contour{Client: fake.NewFakeClientWithScheme(scheme, &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "stable", Namespace: "default"},
+		Spec:       v1.ServiceSpec{Ports: []v1.ServicePort{{Name: "http", Port: 8000}}},
+	})}
+	// all the rules with a canary backend are served by one HTTPProxy of the host
+	assert.Empty(t, contour.Construct(routeTrait))
+	got := contour.ConstructTrafficSplit(routeTrait)
+	assert.Len(t, got, 1)
+	assert.Equal(t, "HTTPProxy", got[0].GetKind())
+	assert.Equal(t, "trait-test", got[0].GetName())
+	assert.Equal(t, map[string]interface{}{
+		"virtualhost": map[string]interface{}{
+			"fqdn": "test.abc",
+			"tls":  map[string]interface{}{"secretName": "trait-test-0-cert"},
+		},
+		"routes": []interface{}{
+			map[string]interface{}{
+				"conditions": []interface{}{map[string]interface{}{"prefix": "/"}},
+				"services": []interface{}{
+					map[string]interface{}{"name": "stable", "port": int64(80), "weight": int64(70)},
+					map[string]interface{}{"name": "canary", "port": int64(8080), "weight": int64(30)},
+				},
+			},
+			map[string]interface{}{
+				"conditions": []interface{}{map[string]interface{}{"prefix": "/api"}},
+				"services": []interface{}{
+					map[string]interface{}{"name": "stable", "port": int64(8000), "weight": int64(70)},
+					map[string]interface{}{"name": "canary", "port": int64(8080), "weight": int64(30)},
+				},
+			},
+		},
+	}, got[0].Object["spec"])
+}
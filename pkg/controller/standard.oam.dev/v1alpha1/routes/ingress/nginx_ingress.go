@@ -20,6 +20,8 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+const nginxCanaryAnnotation = "nginx.ingress.kubernetes.io/canary"
+
 // Nginx is nginx ingress implementation
 type Nginx struct {
 	Client client.Client
@@ -58,8 +60,8 @@ func (n *Nginx) CheckStatus(routeTrait *standardv1alpha1.Route) (string, []runti
 	ingresses := n.Construct(routeTrait)
 	for _, in := range ingresses {
 
-		// Check Certificate
-		if routeTrait.Spec.TLS != nil {
+		// Check Certificate, the canary ingress shares the certificate with the main ingress
+		if routeTrait.Spec.TLS != nil && !isNginxCanary(in) {
 			var cert certmanager.Certificate
 			// check cert
 			err := n.Client.Get(ctx, types.NamespacedName{Namespace: routeTrait.Namespace, Name: in.Name + "-cert"}, &cert)
@@ -110,10 +112,7 @@ func (*Nginx) Construct(routeTrait *standardv1alpha1.Route) []*v1beta1.Ingress {
 	}
 	var ingresses []*v1beta1.Ingress
 	for idx, rule := range routeTrait.Spec.Rules {
-		name := rule.Name
-		if name == "" {
-			name = strconv.Itoa(idx)
-		}
+		ingressName := RuleIngressName(routeTrait, idx)
 		backend := rule.Backend
 		if backend == nil || backend.BackendService == nil {
 			continue
@@ -161,7 +160,7 @@ func (*Nginx) Construct(routeTrait *standardv1alpha1.Route) []*v1beta1.Ingress {
 				APIVersion: v1beta1.SchemeGroupVersion.String(),
 			},
 			ObjectMeta: metav1.ObjectMeta{
-				Name:        routeTrait.Name + "-" + name,
+				Name:        ingressName,
 				Namespace:   routeTrait.Namespace,
 				Annotations: annotations,
 				Labels:      routeTrait.GetLabels(),
@@ -181,7 +180,7 @@ func (*Nginx) Construct(routeTrait *standardv1alpha1.Route) []*v1beta1.Ingress {
 			ingress.Spec.TLS = []v1beta1.IngressTLS{
 				{
 					Hosts:      []string{routeTrait.Spec.Host},
-					SecretName: routeTrait.Name + "-" + name + "-cert",
+					SecretName: ingressName + "-cert",
 				},
 			}
 		}
@@ -211,6 +210,34 @@ func (*Nginx) Construct(routeTrait *standardv1alpha1.Route) []*v1beta1.Ingress {
 			},
 		}
 		ingresses = append(ingresses, ingress)
+		if rule.CanaryBackend != nil {
+			ingresses = append(ingresses, constructNginxCanary(ingress, rule.CanaryBackend))
+		}
 	}
 	return ingresses
 }
+
+// constructNginxCanary constructs the canary ingress of the ingress, nginx sends the weight of
+// the traffic to the host and path of the ingress to the canary backend
+func constructNginxCanary(ingress *v1beta1.Ingress, canary *standardv1alpha1.CanaryBackend) *v1beta1.Ingress {
+	canaryIngress := ingress.DeepCopy()
+	canaryIngress.Name = ingress.Name + CanaryIngressSuffix
+	// the certificate and the default backend are managed by the main ingress
+	canaryIngress.Annotations = map[string]string{
+		"kubernetes.io/ingress.class":               ingress.Annotations["kubernetes.io/ingress.class"],
+		nginxCanaryAnnotation:                       "true",
+		"nginx.ingress.kubernetes.io/canary-weight": strconv.Itoa(int(canary.Weight)),
+	}
+	canaryIngress.Spec.TLS = nil
+	canaryIngress.Spec.Backend = nil
+	canaryIngress.Spec.Rules[0].HTTP.Paths[0].Backend = v1beta1.IngressBackend{
+		ServiceName: canary.BackendService.ServiceName,
+		ServicePort: canary.BackendService.Port,
+	}
+	return canaryIngress
+}
+
+// isNginxCanary checks if the ingress is a canary ingress
+func isNginxCanary(ingress *v1beta1.Ingress) bool {
+	return ingress.Annotations[nginxCanaryAnnotation] == "true"
+}
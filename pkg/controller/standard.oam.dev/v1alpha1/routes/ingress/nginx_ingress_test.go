@@ -108,3 +108,40 @@ func TestConstruct(t *testing.T) {
 		}
 	}
 }
+
+func TestConstructCanary(t *testing.T) {
+	routeTrait := &standardv1alpha1.Route{
+		ObjectMeta: metav1.ObjectMeta{Name: "trait-test", Namespace: "default"},
+		Spec: standardv1alpha1.RouteSpec{
+			Host: "test.abc",
+			TLS:  &standardv1alpha1.TLS{IssuerName: "test-issuer", Type: "Issuer"},
+			Rules: []standardv1alpha1.Rule{
+				{
+					Name:    "myrule1",
+					Backend: &standardv1alpha1.Backend{BackendService: &standardv1alpha1.BackendServiceRef{ServiceName: "stable", Port: intstr.FromInt(80)}},
+					CanaryBackend: &standardv1alpha1.CanaryBackend{
+						BackendService: standardv1alpha1.BackendServiceRef{ServiceName: "canary", Port: intstr.FromInt(80)},
+						Weight:         20,
+					},
+				},
+			},
+			IngressClass: "nginx",
+		},
+	}
+	nginx := &Nginx{}
+	got := nginx.Construct(routeTrait)
+	assert.Len(t, got, 2)
+	assert.Equal(t, "stable", got[0].Spec.Rules[0].HTTP.Paths[0].Backend.ServiceName)
+	canary := got[1]
+	assert.Equal(t, "trait-test-myrule1-canary", canary.Name)
+	assert.Equal(t, map[string]string{
+		"kubernetes.io/ingress.class":               "nginx",
+		"nginx.ingress.kubernetes.io/canary":        "true",
+		"nginx.ingress.kubernetes.io/canary-weight": "20",
+	}, canary.Annotations)
+	assert.Nil(t, canary.Spec.TLS)
+	assert.Equal(t, "test.abc", canary.Spec.Rules[0].Host)
+	assert.Equal(t, v1beta1.IngressBackend{ServiceName: "canary", ServicePort: intstr.FromInt(80)},
+		canary.Spec.Rules[0].HTTP.Paths[0].Backend)
+	assert.Equal(t, int32(20), *GetCanaryWeight(routeTrait))
+}
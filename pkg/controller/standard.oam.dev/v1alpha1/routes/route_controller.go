@@ -35,9 +35,11 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/api/networking/v1beta1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/util/retry"
@@ -51,6 +53,7 @@ import (
 
 const (
 	errApplyNginxIngress = "failed to apply the ingress"
+	errApplyTrafficSplit = "failed to apply the traffic split"
 )
 
 var requeueNotReady = 10 * time.Second
@@ -130,6 +133,25 @@ func (r *Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		r.record.Event(eventObj, event.Normal("nginx ingress patched",
 			fmt.Sprintf("successfully server side patched a route trait `%s`", routeTrait.Name)))
 	}
+	// the rules with a canary backend are served by the traffic split resources if the ingress can't split traffic
+	var trafficSplits []*unstructured.Unstructured
+	if splitter, ok := routeIngress.(ingress.TrafficSplitter); ok {
+		trafficSplits = splitter.ConstructTrafficSplit(&routeTrait)
+		for _, split := range trafficSplits {
+			if err := r.Patch(ctx, split, client.Apply, applyOpts...); err != nil {
+				mLog.Error(err, "Failed to apply the traffic split", "kind", split.GetKind())
+				r.record.Event(eventObj, event.Warning(errApplyTrafficSplit, err))
+				return oamutil.ReconcileWaitResult,
+					oamutil.PatchCondition(ctx, r, &routeTrait,
+						runtimev1alpha1.ReconcileError(errors.Wrap(err, errApplyTrafficSplit)))
+			}
+		}
+	}
+	if err := r.cleanupReplacedResources(ctx, &routeTrait, routeIngress, ingresses, trafficSplits); err != nil {
+		mLog.Error(err, "Failed to clean up the replaced ingress resources")
+		return oamutil.ReconcileWaitResult,
+			oamutil.PatchCondition(ctx, r, &routeTrait, runtimev1alpha1.ReconcileError(err))
+	}
 	// TODO(wonderflow): GC mechanism for no used ingress, service, issuer
 
 	var ingressCreated []runtimev1alpha1.TypedReference
@@ -149,6 +171,8 @@ func (r *Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	if routeTrait.Status.Status != ingress.StatusReady {
 		return ctrl.Result{RequeueAfter: requeueNotReady}, r.UpdateStatus(ctx, &routeTrait)
 	}
+	// the canary weight is only reported after it takes effect
+	routeTrait.Status.CanaryWeight = ingress.GetCanaryWeight(&routeTrait)
 	err = r.UpdateStatus(ctx, &routeTrait)
 	if err != nil {
 		return oamutil.ReconcileWaitResult, err
@@ -320,6 +344,62 @@ func (r *Reconciler) fillBackendByCheckChildResource(mLog logr.Logger,
 	}
 }
 
+// cleanupReplacedResources deletes the ingress resources of the route rules that are no longer constructed,
+// they are left behind when the canary backend of a rule is added or removed
+func (r *Reconciler) cleanupReplacedResources(ctx context.Context, routeTrait *standardv1alpha1.Route,
+	routeIngress ingress.RouteIngress, ingresses []*v1beta1.Ingress, trafficSplits []*unstructured.Unstructured) error {
+	ingressGVK := v1beta1.SchemeGroupVersion.WithKind(reflect.TypeOf(v1beta1.Ingress{}).Name())
+	constructed := make(map[schema.GroupVersionKind]map[string]bool)
+	constructed[ingressGVK] = make(map[string]bool)
+	for _, in := range ingresses {
+		constructed[ingressGVK][in.Name] = true
+	}
+	candidates := make(map[schema.GroupVersionKind][]string)
+	splitter, canSplit := routeIngress.(ingress.TrafficSplitter)
+	if canSplit {
+		constructed[splitter.TrafficSplitKind()] = make(map[string]bool)
+		for _, split := range trafficSplits {
+			constructed[splitter.TrafficSplitKind()][split.GetName()] = true
+		}
+	}
+	if canSplit {
+		candidates[splitter.TrafficSplitKind()] = append(candidates[splitter.TrafficSplitKind()],
+			ingress.TrafficSplitName(routeTrait))
+	}
+	for idx := range routeTrait.Spec.Rules {
+		name := ingress.RuleIngressName(routeTrait, idx)
+		candidates[ingressGVK] = append(candidates[ingressGVK], name, name+ingress.CanaryIngressSuffix)
+		if canSplit {
+			// the traffic used to be split by the resources of each rule
+			candidates[splitter.TrafficSplitKind()] = append(candidates[splitter.TrafficSplitKind()], name)
+		}
+	}
+	for gvk, names := range candidates {
+		for _, name := range names {
+			if constructed[gvk][name] {
+				continue
+			}
+			obj := &unstructured.Unstructured{}
+			obj.SetGroupVersionKind(gvk)
+			if err := r.Get(ctx, types.NamespacedName{Namespace: routeTrait.Namespace, Name: name}, obj); err != nil {
+				if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+					continue
+				}
+				return err
+			}
+			// only delete what the route owns
+			if !metav1.IsControlledBy(obj, routeTrait) {
+				continue
+			}
+			if err := r.Delete(ctx, obj); err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+			r.Log.Info("deleted the replaced ingress resource", "kind", gvk.Kind, "name", name)
+		}
+	}
+	return nil
+}
+
 // SetupWithManager setup with manager
 func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 	r.record = event.NewAPIRecorder(mgr.GetEventRecorderFor("Route")).
@@ -5,6 +5,17 @@ const BaseTemplate = `
 
 context: {
   name: string
+  appName: string
+  namespace: string
+  appRevision?: string
+  appRevisionNum?: int
+  appLabels: [string]: string
+  appAnnotations: [string]: string
+  scopes: [string]: string
+  components: [string]: {
+    type: string
+    output?: {...}
+  }
   config?: [...{
     name: string
     value: string
@@ -0,0 +1,201 @@
+package definition
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"cuelang.org/go/cue"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/util/cache"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/oam-dev/kubevela/pkg/dsl/model"
+	"github.com/oam-dev/kubevela/pkg/dsl/process"
+)
+
+const (
+	// the size and the ttl of the cache of the compiled templates
+	templateCacheSize = 1024
+	templateCacheTTL  = 30 * time.Minute
+	// maxTemplateUses is the number of evaluations after which a template is compiled again. Every evaluation adds
+	// its inputs to the runtime of the template, so the runtime is dropped before it grows too large.
+	maxTemplateUses = 500
+
+	// the size and the ttl of the cache of the last renders of the templates with processing tasks
+	processedCacheSize = 1024
+	processedCacheTTL  = 30 * time.Minute
+
+	workloadKind = "workload"
+	traitKind    = "trait"
+
+	stageRender = "render"
+	stageHealth = "health"
+	stageStatus = "status"
+)
+
+// templateCache keeps the compiled templates by the names and the generations of their definitions and the hashes
+// of the templates, so the renders and the health and status checks of all the applications share them.
+var templateCache = cache.NewLRUExpireCache(templateCacheSize)
+
+// processedCache keeps the last renders of the templates with processing tasks, the health and status checks read
+// them so that the tasks are not run again.
+var processedCache = cache.NewLRUExpireCache(processedCacheSize)
+
+var templateEvaluationSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "kubevela_template_evaluation_seconds",
+	Help:    "The time to evaluate the CUE templates of the definitions.",
+	Buckets: []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5},
+}, []string{"kind", "definition", "stage", "cached"})
+
+func init() {
+	metrics.Registry.MustRegister(templateEvaluationSeconds)
+}
+
+// evaluation records the time to evaluate a template
+type evaluation struct {
+	kind   string
+	name   string
+	stage  string
+	start  time.Time
+	cached bool
+}
+
+func startEvaluation(kind, name, stage string) *evaluation {
+	return &evaluation{kind: kind, name: name, stage: stage, start: time.Now()}
+}
+
+func (e *evaluation) done() {
+	cached := "false"
+	if e.cached {
+		cached = "true"
+	}
+	templateEvaluationSeconds.WithLabelValues(e.kind, e.name, e.stage, cached).Observe(time.Since(e.start).Seconds())
+}
+
+// evaluationKey computes the hash of the inputs of an evaluation
+func evaluationKey(inputs ...string) string {
+	h := sha256.New()
+	for _, in := range inputs {
+		// a zero byte separates an input from the next one
+		_, _ = h.Write([]byte(in))
+		_, _ = h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// templateKey identifies a compiled template
+type templateKey struct {
+	kind       string
+	name       string
+	stage      string
+	generation int64
+	hash       string
+}
+
+// compiledTemplate is a template compiled in its own runtime. The values of a runtime can't be evaluated at the
+// same time, so an evaluation holds the lock of the template until it gets its results.
+type compiledTemplate struct {
+	sync.Mutex
+	template string
+	runtime  *cue.Runtime
+	inst     *cue.Instance
+	uses     int
+}
+
+// compile compiles the template with an open context, the context of an evaluation is filled into it
+func (t *compiledTemplate) compile() error {
+	t.runtime = &cue.Runtime{}
+	inst, err := t.runtime.Compile("-", t.template+"\ncontext: _\n")
+	if err != nil {
+		return err
+	}
+	t.inst = inst
+	t.uses = 0
+	return nil
+}
+
+// fill returns the instance of the template filled with the inputs, the template must be locked
+func (t *compiledTemplate) fill(inputs string) (*cue.Instance, error) {
+	if t.uses >= maxTemplateUses {
+		if err := t.compile(); err != nil {
+			return nil, err
+		}
+	}
+	t.uses++
+	in, err := t.runtime.Compile("-", inputs)
+	if err != nil {
+		return nil, err
+	}
+	inst, err := t.inst.Fill(in.Value())
+	if err != nil {
+		return nil, err
+	}
+	return inst, inst.Value().Err()
+}
+
+// getCompiledTemplate returns the locked compiled template of the evaluation, the caller unlocks it once it's done
+func getCompiledTemplate(eval *evaluation, generation int64, template string) (*compiledTemplate, error) {
+	key := templateKey{
+		kind:       eval.kind,
+		name:       eval.name,
+		stage:      eval.stage,
+		generation: generation,
+		hash:       evaluationKey(template),
+	}
+	if v, ok := templateCache.Get(key); ok {
+		eval.cached = true
+		t := v.(*compiledTemplate)
+		t.Lock()
+		return t, nil
+	}
+	t := &compiledTemplate{template: template}
+	if err := t.compile(); err != nil {
+		return nil, err
+	}
+	t.Lock()
+	templateCache.Add(key, t, templateCacheTTL)
+	return t, nil
+}
+
+// rendered is the result of the render of a template
+type rendered struct {
+	base        model.Instance
+	patch       model.Instance
+	auxiliaries []process.Auxiliary
+}
+
+// copy returns a copy of the result, the base of a workload is unified with the patches of its traits
+// so that the cached one must not be used directly
+func (r *rendered) copy() *rendered {
+	c := &rendered{}
+	if r.base != nil {
+		c.base = model.Copy(r.base)
+	}
+	if r.patch != nil {
+		c.patch = model.Copy(r.patch)
+	}
+	for _, aux := range r.auxiliaries {
+		aux.Ins = model.Copy(aux.Ins)
+		c.auxiliaries = append(c.auxiliaries, aux)
+	}
+	return c
+}
+
+// getProcessed returns the last render of a template with processing tasks, only the health and status checks read
+// it as the results of the tasks may change
+func getProcessed(ctx process.Context, key string) (*rendered, bool) {
+	if !ctx.IsHealthCheck() {
+		return nil, false
+	}
+	v, ok := processedCache.Get(key)
+	if !ok {
+		return nil, false
+	}
+	return v.(*rendered).copy(), true
+}
+
+func putProcessed(key string, r *rendered) {
+	processedCache.Add(key, r.copy(), processedCacheTTL)
+}
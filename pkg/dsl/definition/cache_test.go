@@ -0,0 +1,119 @@
+package definition
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/oam-dev/kubevela/pkg/dsl/process"
+)
+
+func TestTemplateCache(t *testing.T) {
+	workloadTemplate := `
+output: {
+	apiVersion: "apps/v1"
+	kind:       "Deployment"
+	spec: template: spec: containers: [{name: context.name, image: parameter.image}]
+}
+parameter: image: string
+`
+	traitTemplate := `
+patch: {
+	// +patchKey=name
+	spec: template: spec: containers: [{name: "sidecar", image: parameter.image}]
+}
+parameter: image: string
+`
+	render := func(name, image string, generation int64) *unstructured.Unstructured {
+		ctx := process.NewContext(name, "myapp")
+		wt := NewWorkloadAbstractEngine(name).Definition("cached-worker", generation).
+			Params(map[string]interface{}{"image": image})
+		assert.NoError(t, wt.Complete(ctx, workloadTemplate))
+		td := NewTraitAbstractEngine("cached-sidecar").Params(map[string]interface{}{"image": "agent"})
+		assert.NoError(t, td.Complete(ctx, traitTemplate))
+		base, _ := ctx.Output()
+		u, err := base.Unstructured()
+		assert.NoError(t, err)
+		return u
+	}
+	cached := func(kind, name, stage string) uint64 {
+		m := &dto.Metric{}
+		observer := templateEvaluationSeconds.WithLabelValues(kind, name, stage, "true")
+		assert.NoError(t, observer.(prometheus.Metric).Write(m))
+		return m.GetHistogram().GetSampleCount()
+	}
+	image := func(u *unstructured.Unstructured, i int) string {
+		containers, _, _ := unstructured.NestedSlice(u.Object, "spec", "template", "spec", "containers")
+		assert.Len(t, containers, 2)
+		return containers[i].(map[string]interface{})["image"].(string)
+	}
+
+	first := render("frontend", "nginx", 1)
+	assert.Equal(t, uint64(0), cached(workloadKind, "cached-worker", stageRender))
+	// the components of the same definition share the compiled template
+	second := render("backend", "redis", 1)
+	assert.Equal(t, uint64(1), cached(workloadKind, "cached-worker", stageRender))
+	assert.Equal(t, uint64(1), cached(traitKind, "cached-sidecar", stageRender))
+	assert.Equal(t, "nginx", image(first, 0))
+	assert.Equal(t, "redis", image(second, 0))
+	assert.Equal(t, "agent", image(second, 1))
+	// a new generation of the definition compiles the template again
+	render("backend", "redis", 2)
+	assert.Equal(t, uint64(1), cached(workloadKind, "cached-worker", stageRender))
+
+	// the processing tasks run in every render, the health and status checks reuse the last render of them
+	processingTemplate := `
+processing: tasks: hash: {type: "encode", method: "base64", data: parameter.image}
+output: {
+	apiVersion: "v1"
+	kind:       "ConfigMap"
+	data: image: processing.tasks.hash.result
+}
+parameter: image: string
+`
+	for _, img := range []string{"nginx", "redis"} {
+		ctx := process.NewContext("processed", "myapp")
+		wt := NewWorkloadAbstractEngine("processed-worker").Params(map[string]interface{}{"image": img})
+		assert.NoError(t, wt.Complete(ctx, processingTemplate))
+		base, _ := ctx.Output()
+		u, err := base.Unstructured()
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"image": map[string]string{"nginx": "bmdpbng=", "redis": "cmVkaXM="}[img]},
+			u.Object["data"])
+	}
+	assert.Equal(t, uint64(1), cached(workloadKind, "processed-worker", stageRender))
+	ctx := process.NewContext("processed", "myapp")
+	ctx.SetHealthCheck(true)
+	wt := NewWorkloadAbstractEngine("processed-worker").Params(map[string]interface{}{"image": "redis"})
+	assert.NoError(t, wt.Complete(ctx, processingTemplate))
+	assert.Equal(t, uint64(2), cached(workloadKind, "processed-worker", stageRender))
+
+	// the health template is compiled once and evaluated with the live workloads
+	healthTemplate := `isHealth: context.output.status.readyReplicas == context.output.status.replicas`
+	for i, ready := range []int{1, 2} {
+		eval := startEvaluation(workloadKind, "cached-worker", stageHealth)
+		templateContext := map[string]interface{}{
+			"output": map[string]interface{}{"status": map[string]interface{}{"readyReplicas": ready, "replicas": 2}},
+		}
+		healthy, err := checkHealth(eval, 1, templateContext, healthTemplate)
+		assert.NoError(t, err)
+		assert.Equal(t, ready == 2, healthy)
+		assert.Equal(t, i == 1, eval.cached)
+	}
+}
+
+func TestCompiledTemplateRecompiled(t *testing.T) {
+	tmpl := &compiledTemplate{template: `message: "ready \(context.replicas)"`}
+	assert.NoError(t, tmpl.compile())
+	for i := 0; i <= maxTemplateUses+1; i++ {
+		inst, err := tmpl.fill(`context: replicas: 3`)
+		assert.NoError(t, err)
+		msg, err := inst.Lookup(CustomMessage).String()
+		assert.NoError(t, err)
+		assert.Equal(t, "ready 3", msg)
+	}
+	assert.Equal(t, 2, tmpl.uses)
+}
@@ -0,0 +1,338 @@
+package definition
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/ast"
+	"cuelang.org/go/cue/build"
+	"cuelang.org/go/cue/format"
+	"cuelang.org/go/cue/parser"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/dsl/model"
+	"github.com/oam-dev/kubevela/pkg/dsl/process"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+// TemplateFieldName is the name of the field that holds the template in a definition file
+const TemplateFieldName = "template"
+
+// File is a WorkloadDefinition or a TraitDefinition written in a CUE file. The file has a field named by the
+// definition that describes it, and a `template` field that holds the CUE template, like
+//
+//	"my-worker": {
+//		type:        "workload"
+//		description: "My worker"
+//		attributes: definitionRef: name: "deployments.apps"
+//	}
+//	template: {
+//		output: {...}
+//		parameter: {...}
+//	}
+//
+// The attributes are the fields of the spec of the definition except the template.
+type File struct {
+	Name        string
+	Type        types.CapType
+	Description string
+	Attributes  map[string]interface{}
+	// Template is the CUE template with its imports
+	Template string
+}
+
+type fileHeader struct {
+	Type        types.CapType          `json:"type"`
+	Description string                 `json:"description,omitempty"`
+	Attributes  map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// ParseFile parses a definition file
+func ParseFile(src []byte) (*File, error) {
+	f, err := parser.ParseFile("-", src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		imports  []ast.Decl
+		template *ast.StructLit
+		header   *ast.Field
+	)
+	for _, decl := range f.Decls {
+		switch x := decl.(type) {
+		case *ast.ImportDecl:
+			imports = append(imports, x)
+		case *ast.Field:
+			label, _, err := ast.LabelName(x.Label)
+			if err != nil {
+				return nil, err
+			}
+			if label == TemplateFieldName {
+				lit, ok := x.Value.(*ast.StructLit)
+				if !ok {
+					return nil, errors.New("the template must be a struct")
+				}
+				template = lit
+				continue
+			}
+			if header != nil {
+				return nil, errors.Errorf("only one definition is allowed in a file, found %s", label)
+			}
+			header = x
+		}
+	}
+	if header == nil {
+		return nil, errors.New("the definition is not found")
+	}
+	if template == nil {
+		return nil, errors.New("the template is not found")
+	}
+
+	name, _, err := ast.LabelName(header.Label)
+	if err != nil {
+		return nil, err
+	}
+	file := &File{Name: name}
+	if err := decodeHeader(header.Value, file); err != nil {
+		return nil, errors.WithMessagef(err, "invalid definition %s", name)
+	}
+	tmpl, err := format.Node(&ast.File{Decls: append(imports, template.Elts...)})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "invalid template of %s", name)
+	}
+	file.Template = string(tmpl)
+	return file, nil
+}
+
+func decodeHeader(value ast.Expr, file *File) error {
+	src, err := format.Node(value)
+	if err != nil {
+		return err
+	}
+	var r cue.Runtime
+	inst, err := r.Compile("-", fmt.Sprintf("header: %s", src))
+	if err != nil {
+		return err
+	}
+	b, err := inst.Lookup("header").MarshalJSON()
+	if err != nil {
+		return err
+	}
+	var h fileHeader
+	if err := json.Unmarshal(b, &h); err != nil {
+		return err
+	}
+	if h.Type != types.TypeWorkload && h.Type != types.TypeTrait {
+		return errors.Errorf("unknown type %q, it must be %s or %s", h.Type, types.TypeWorkload, types.TypeTrait)
+	}
+	file.Type, file.Description, file.Attributes = h.Type, h.Description, h.Attributes
+	return nil
+}
+
+// Format prints the definition file in the canonical CUE format
+func (f *File) Format() ([]byte, error) {
+	tf, err := parser.ParseFile("-", f.Template, parser.ParseComments)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "invalid template of %s", f.Name)
+	}
+	var imports, decls []ast.Decl
+	for _, decl := range tf.Decls {
+		if _, ok := decl.(*ast.ImportDecl); ok {
+			imports = append(imports, decl)
+			continue
+		}
+		decls = append(decls, decl)
+	}
+	header, err := json.Marshal(fileHeader{Type: f.Type, Description: f.Description, Attributes: f.Attributes})
+	if err != nil {
+		return nil, err
+	}
+	var r cue.Runtime
+	inst, err := r.Compile("-", fmt.Sprintf("header: %s", header))
+	if err != nil {
+		return nil, err
+	}
+	headerExpr, ok := inst.Lookup("header").Syntax().(ast.Expr)
+	if !ok {
+		return nil, errors.Errorf("invalid definition %s", f.Name)
+	}
+	var label ast.Label = ast.NewString(f.Name)
+	if ast.IsValidIdent(f.Name) {
+		label = ast.NewIdent(f.Name)
+	}
+	file := &ast.File{Decls: append(imports,
+		&ast.Field{Label: label, Value: headerExpr},
+		&ast.Field{Label: ast.NewIdent(TemplateFieldName), Value: &ast.StructLit{Elts: decls}},
+	)}
+	return format.Node(file, format.Simplify())
+}
+
+// Object converts the definition file into a WorkloadDefinition or a TraitDefinition in the namespace
+func (f *File) Object(namespace string) (runtime.Object, error) {
+	spec := map[string]interface{}{}
+	for k, v := range f.Attributes {
+		spec[k] = v
+	}
+	spec[TemplateFieldName] = f.Template
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	obj.SetName(f.Name)
+	obj.SetNamespace(namespace)
+	if f.Description != "" {
+		obj.SetAnnotations(map[string]string{types.AnnDescription: f.Description})
+	}
+
+	var def runtime.Object
+	switch f.Type {
+	case types.TypeWorkload:
+		obj.SetGroupVersionKind(v1alpha2.WorkloadDefinitionGroupVersionKind)
+		def = &v1alpha2.WorkloadDefinition{}
+	case types.TypeTrait:
+		obj.SetGroupVersionKind(v1alpha2.TraitDefinitionGroupVersionKind)
+		def = &v1alpha2.TraitDefinition{}
+	default:
+		return nil, errors.Errorf("unknown type %q of definition %s", f.Type, f.Name)
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, def); err != nil {
+		return nil, errors.WithMessagef(err, "invalid attributes of definition %s", f.Name)
+	}
+	return def, nil
+}
+
+// NewFile converts a WorkloadDefinition or a TraitDefinition into a definition file
+func NewFile(def runtime.Object) (*File, error) {
+	var (
+		file      = &File{}
+		spec      interface{}
+		template  string
+		extension *runtime.RawExtension
+	)
+	switch d := def.(type) {
+	case *v1alpha2.WorkloadDefinition:
+		file.Name, file.Type, file.Description = d.Name, types.TypeWorkload, d.Annotations[types.AnnDescription]
+		spec, template, extension = d.Spec, d.Spec.Template, d.Spec.Extension
+	case *v1alpha2.TraitDefinition:
+		file.Name, file.Type, file.Description = d.Name, types.TypeTrait, d.Annotations[types.AnnDescription]
+		spec, template, extension = d.Spec, d.Spec.Template, d.Spec.Extension
+	default:
+		return nil, errors.Errorf("unsupported definition %T", def)
+	}
+	tmpl, err := util.NewTemplate(template, nil, extension)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "invalid extension of definition %s", file.Name)
+	}
+	if tmpl.TemplateStr == "" {
+		return nil, errors.Errorf("definition %s has no CUE template", file.Name)
+	}
+	file.Template = tmpl.TemplateStr
+
+	if file.Attributes, err = util.Object2Map(spec); err != nil {
+		return nil, err
+	}
+	delete(file.Attributes, TemplateFieldName)
+	// the template in the extension is moved to the template of the file
+	if ext, ok := file.Attributes["extension"].(map[string]interface{}); ok {
+		delete(ext, TemplateFieldName)
+	}
+	pruneEmpty(file.Attributes)
+	if len(file.Attributes) == 0 {
+		file.Attributes = nil
+	}
+	return file, nil
+}
+
+// Vet checks the template of the definition by rendering it without the parameters, the values that depend on the
+// parameters are left incomplete. The template of a trait patches an empty workload.
+func (f *File) Vet() error {
+	ctx := process.NewContext(f.Name, f.Name)
+	bi := build.NewContext().NewInstance("", nil)
+	if err := bi.AddFile("-", f.Template); err != nil {
+		return err
+	}
+	if err := bi.AddFile("context", ctx.BaseContextFile()); err != nil {
+		return err
+	}
+	inst := cue.Build([]*build.Instance{bi})[0]
+	if inst.Err != nil {
+		return inst.Err
+	}
+	if err := inst.Value().Validate(); err != nil {
+		return err
+	}
+	if f.Type == types.TypeWorkload && !inst.Lookup(OutputFieldName).Exists() {
+		return errors.Errorf("the %s of workload %s is not found", OutputFieldName, f.Name)
+	}
+
+	if f.Type == types.TypeTrait {
+		base, err := NewBaseObject(map[string]interface{}{})
+		if err != nil {
+			return err
+		}
+		ctx.SetBase(base)
+	}
+	return f.engine(nil).Complete(ctx, f.Template)
+}
+
+// Render renders the template of the definition with the parameters in the context, the template of a trait
+// patches the base of the context and adds its outputs to the context
+func (f *File) Render(ctx process.Context, params map[string]interface{}) error {
+	if f.Type == types.TypeTrait {
+		if base, _ := ctx.Output(); base == nil {
+			return errors.Errorf("trait %s needs a workload to render", f.Name)
+		}
+	}
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	return f.engine(params).Complete(ctx, f.Template)
+}
+
+func (f *File) engine(params map[string]interface{}) AbstractEngine {
+	var engine AbstractEngine
+	if f.Type == types.TypeTrait {
+		engine = NewTraitAbstractEngine(f.Name)
+	} else {
+		engine = NewWorkloadAbstractEngine(f.Name)
+	}
+	if params != nil {
+		engine = engine.Params(params)
+	}
+	return engine
+}
+
+// NewBaseObject creates the base of a context from an object, it's the workload a trait renders with
+func NewBaseObject(obj map[string]interface{}) (model.Instance, error) {
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	var r cue.Runtime
+	inst, err := r.Compile("-", string(b))
+	if err != nil {
+		return nil, err
+	}
+	return model.NewBase(inst.Value())
+}
+
+// pruneEmpty removes the empty strings and structs from the attributes, they are the fields that are not set
+func pruneEmpty(attrs map[string]interface{}) {
+	for k, v := range attrs {
+		switch x := v.(type) {
+		case string:
+			if x == "" {
+				delete(attrs, k)
+			}
+		case map[string]interface{}:
+			pruneEmpty(x)
+			if len(x) == 0 {
+				delete(attrs, k)
+			}
+		case nil:
+			delete(attrs, k)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package definition
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/dsl/process"
+)
+
+const workerFile = `import "strings"
+
+"my-worker": {
+	type:        "workload"
+	description: "My worker"
+	attributes: definitionRef: name: "deployments.apps"
+}
+template: {
+	output: {
+		apiVersion: "apps/v1"
+		kind:       "Deployment"
+		metadata: name: strings.ToLower(context.name)
+		spec: replicas: parameter.replicas
+	}
+	parameter: {
+		// +usage=Number of the replicas
+		replicas: *1 | int
+	}
+}
+`
+
+const workerTemplate = `import "strings"
+
+output: {
+	apiVersion: "apps/v1"
+	kind:       "Deployment"
+	metadata: name: strings.ToLower(context.name)
+	spec: replicas: parameter.replicas
+}
+parameter: {
+	// +usage=Number of the replicas
+	replicas: *1 | int
+}
+`
+
+const labelsFile = `labels: type: "trait"
+template: {
+	patch: metadata: labels: parameter
+	parameter: [string]: string
+}
+`
+
+func TestParseFile(t *testing.T) {
+	f, err := ParseFile([]byte(workerFile))
+	assert.NoError(t, err)
+	assert.Equal(t, &File{
+		Name:        "my-worker",
+		Type:        types.TypeWorkload,
+		Description: "My worker",
+		Attributes:  map[string]interface{}{"definitionRef": map[string]interface{}{"name": "deployments.apps"}},
+		Template:    workerTemplate,
+	}, f)
+	formatted, err := f.Format()
+	assert.NoError(t, err)
+	assert.Equal(t, workerFile, string(formatted))
+
+	obj, err := f.Object("vela-system")
+	assert.NoError(t, err)
+	wd := &v1alpha2.WorkloadDefinition{
+		TypeMeta: metav1.TypeMeta{Kind: v1alpha2.WorkloadDefinitionKind, APIVersion: v1alpha2.SchemeGroupVersion.String()},
+		ObjectMeta: metav1.ObjectMeta{Name: "my-worker", Namespace: "vela-system",
+			Annotations: map[string]string{types.AnnDescription: "My worker"}},
+		Spec: v1alpha2.WorkloadDefinitionSpec{
+			Reference: v1alpha2.DefinitionReference{Name: "deployments.apps"},
+			Template:  workerTemplate,
+		},
+	}
+	assert.Equal(t, wd, obj)
+	newFile, err := NewFile(wd)
+	assert.NoError(t, err)
+	assert.Equal(t, f, newFile)
+
+	// the template in the extension is moved to the template of the file
+	td := &v1alpha2.TraitDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "labels"},
+		Spec: v1alpha2.TraitDefinitionSpec{
+			Extension: &runtime.RawExtension{Raw: []byte(`{"template":"patch: metadata: labels: parameter\nparameter: [string]: string\n"}`)},
+		},
+	}
+	f, err = NewFile(td)
+	assert.NoError(t, err)
+	formatted, err = f.Format()
+	assert.NoError(t, err)
+	assert.Equal(t, labelsFile, string(formatted))
+
+	for name, src := range map[string]string{
+		"no template":     `worker: type: "workload"`,
+		"no definition":   `template: output: {}`,
+		"two definitions": "a: type: \"trait\"\nb: type: \"trait\"\ntemplate: {}",
+		"unknown type":    "a: type: \"scope\"\ntemplate: {}",
+		"invalid cue":     "a: {",
+	} {
+		_, err := ParseFile([]byte(src))
+		assert.Error(t, err, name)
+	}
+}
+
+func TestVetAndRenderFile(t *testing.T) {
+	worker, err := ParseFile([]byte(workerFile))
+	assert.NoError(t, err)
+	assert.NoError(t, worker.Vet())
+	labels, err := ParseFile([]byte(labelsFile))
+	assert.NoError(t, err)
+	assert.NoError(t, labels.Vet())
+
+	broken := &File{Name: "broken", Type: types.TypeWorkload, Template: "output: {\n\treplicas: 1\n\treplicas: 2\n}\n"}
+	assert.Error(t, broken.Vet())
+	noOutput := &File{Name: "no-output", Type: types.TypeWorkload, Template: "parameter: {}\n"}
+	assert.Error(t, noOutput.Vet())
+
+	ctx := process.NewContext("MyWeb", "myapp")
+	assert.Error(t, labels.Render(ctx, map[string]interface{}{"app": "web"}))
+	assert.NoError(t, worker.Render(ctx, map[string]interface{}{"replicas": 3}))
+	assert.NoError(t, labels.Render(ctx, map[string]interface{}{"app": "web"}))
+	base, _ := ctx.Output()
+	workload, err := base.Unstructured()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]interface{}{
+			"name":   "myweb",
+			"labels": map[string]interface{}{"app": "web"},
+		},
+		"spec": map[string]interface{}{"replicas": int64(3)},
+	}, workload.Object)
+}
@@ -0,0 +1,190 @@
+package definition
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/ast"
+	"cuelang.org/go/cue/build"
+	cueerrors "cuelang.org/go/cue/errors"
+	"cuelang.org/go/cue/token"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// ParameterFieldName is the name of the struct that declares the parameters of a definition
+const ParameterFieldName = "parameter"
+
+// placeholderContextFile is the context the parameter is compiled with. The context is not known before the render,
+// the placeholder makes the defaults of the parameters that refer to it concrete, the parts of it that depend on the
+// other components are left open.
+const placeholderContextFile = `context: {
+	name:           "placeholder"
+	appName:        "placeholder"
+	namespace:      "default"
+	appRevision:    "placeholder-v1"
+	appRevisionNum: 1
+	appLabels: [string]:      string
+	appAnnotations: [string]: string
+	scopes: [string]:         string
+	components: [string]: {...}
+	output: _
+	outputs: [string]: _
+	config: [...]
+}`
+
+// ValidateParameters checks the parameters against the `parameter` of the template. It returns the errors of the
+// unknown parameters, the parameters of the wrong types or values and the missing required parameters, the fields
+// of the errors are under the path. A template without the `parameter` accepts any parameters.
+func ValidateParameters(template string, params map[string]interface{}, path *field.Path) (field.ErrorList, error) {
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	inst, err := compileParameter(template, "")
+	if err != nil {
+		return nil, errors.WithMessage(err, "invalid cue template")
+	}
+	schema := inst.Lookup(ParameterFieldName)
+	if !schema.Exists() {
+		return nil, nil
+	}
+
+	errs := unknownParameters(schema, params, path)
+	paramFile, err := parameterFile(params)
+	if err != nil {
+		return nil, err
+	}
+	filled, err := compileParameter(template, paramFile)
+	if err != nil {
+		return nil, err
+	}
+	err = filled.Lookup(ParameterFieldName).Validate(cue.Concrete(true))
+	for _, e := range cueerrors.Errors(err) {
+		keys := e.Path()
+		if len(keys) != 0 && keys[0] == ParameterFieldName {
+			keys = keys[1:]
+		}
+		fieldPath, value, exist := lookupParameter(params, keys, path)
+		if !exist && hasDefault(schema.Lookup(keys...)) {
+			// the default refers to the parts of the context that are only known in the render
+			continue
+		}
+		if !exist {
+			errs = append(errs, field.Required(fieldPath, ""))
+			continue
+		}
+		format, args := e.Msg()
+		errs = append(errs, field.Invalid(fieldPath, value, fmt.Sprintf(format, args...)))
+	}
+	return errs, nil
+}
+
+func compileParameter(template, paramFile string) (*cue.Instance, error) {
+	bi := build.NewContext().NewInstance("", nil)
+	if err := bi.AddFile("-", template); err != nil {
+		return nil, err
+	}
+	if paramFile != "" {
+		if err := bi.AddFile(ParameterFieldName, paramFile); err != nil {
+			return nil, err
+		}
+	}
+	if err := bi.AddFile("-", placeholderContextFile); err != nil {
+		return nil, err
+	}
+	inst := cue.Build([]*build.Instance{bi})[0]
+	return inst, inst.Err
+}
+
+// hasDefault checks if the value is declared with a default like `*1 | int`
+func hasDefault(v cue.Value) bool {
+	if !v.Exists() || v.Source() == nil {
+		return false
+	}
+	var found bool
+	ast.Walk(v.Source(), func(n ast.Node) bool {
+		if u, ok := n.(*ast.UnaryExpr); ok && u.Op == token.MUL {
+			found = true
+		}
+		return !found
+	}, nil)
+	return found
+}
+
+// unknownParameters finds the parameters that are not declared in the schema, the structs with a pattern
+// constraint or an ellipsis accept any fields
+func unknownParameters(schema cue.Value, value interface{}, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if schema.IncompleteKind() != cue.StructKind {
+			return nil
+		}
+		declared := map[string]cue.Value{}
+		var names []string
+		iter, err := schema.Fields(cue.Optional(true))
+		if err != nil {
+			return nil
+		}
+		for iter.Next() {
+			declared[iter.Label()] = iter.Value()
+			names = append(names, iter.Label())
+		}
+		sort.Strings(names)
+		template := schema.Template()
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if fieldSchema, ok := declared[k]; ok {
+				errs = append(errs, unknownParameters(fieldSchema, v[k], path.Child(k))...)
+				continue
+			}
+			if template != nil {
+				errs = append(errs, unknownParameters(template(k), v[k], path.Child(k))...)
+				continue
+			}
+			errs = append(errs, field.NotSupported(path.Child(k), k, names))
+		}
+	case []interface{}:
+		elem, ok := schema.Elem()
+		if !ok {
+			return nil
+		}
+		for i, e := range v {
+			errs = append(errs, unknownParameters(elem, e, path.Index(i))...)
+		}
+	}
+	return errs
+}
+
+// lookupParameter finds the value of the parameter by the keys of the cue path, and the field path of it
+func lookupParameter(params map[string]interface{}, keys []string, path *field.Path) (*field.Path, interface{}, bool) {
+	var value interface{} = params
+	exist := true
+	for _, key := range keys {
+		if l, ok := value.([]interface{}); ok {
+			if i, err := strconv.Atoi(key); err == nil {
+				path = path.Index(i)
+				if i >= 0 && i < len(l) {
+					value = l[i]
+				} else {
+					value, exist = nil, false
+				}
+				continue
+			}
+		}
+		path = path.Child(key)
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			value, exist = nil, false
+			continue
+		}
+		value, exist = m[key]
+	}
+	return path, value, exist
+}
@@ -0,0 +1,116 @@
+package definition
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func TestValidateParameters(t *testing.T) {
+	template := `
+output: {
+	metadata: name: context.name
+	spec: replicas: parameter.replicas
+}
+parameter: {
+	image:    string
+	replicas: *1 | int
+	port:     int & >0
+	cmd?: [...string]
+	env?: [...{
+		name:   string
+		value?: string
+	}]
+	labels?: [string]: string
+	resources?: {
+		cpu?: string
+	}
+}
+`
+	path := field.NewPath("spec", "components").Index(0).Child("settings")
+	testCases := map[string]struct {
+		template string
+		params   map[string]interface{}
+		expErrs  field.ErrorList
+	}{
+		"valid parameters": {
+			template: template,
+			params: map[string]interface{}{
+				"image":  "nginx",
+				"port":   float64(80),
+				"cmd":    []interface{}{"run"},
+				"env":    []interface{}{map[string]interface{}{"name": "k", "value": "v"}},
+				"labels": map[string]interface{}{"app": "nginx"},
+			},
+		},
+		"unknown parameters": {
+			template: template,
+			params: map[string]interface{}{
+				"image":     "nginx",
+				"port":      float64(80),
+				"imagee":    "nginx",
+				"env":       []interface{}{map[string]interface{}{"name": "k", "val": "v"}},
+				"resources": map[string]interface{}{"memory": "1Gi"},
+			},
+			expErrs: field.ErrorList{
+				field.NotSupported(path.Child("env").Index(0).Child("val"), "val", []string{"name", "value"}),
+				field.NotSupported(path.Child("imagee"), "imagee",
+					[]string{"cmd", "env", "image", "labels", "port", "replicas", "resources"}),
+				field.NotSupported(path.Child("resources", "memory"), "memory", []string{"cpu"}),
+			},
+		},
+		"wrong types and missing parameters": {
+			template: template,
+			params: map[string]interface{}{
+				"replicas": "3",
+				"port":     float64(-1),
+				"labels":   map[string]interface{}{"app": float64(1)},
+			},
+			expErrs: field.ErrorList{
+				field.Invalid(path.Child("replicas"), "3",
+					`conflicting values (*1 | int) and "3" (mismatched types int and string)`),
+				field.Required(path.Child("image"), ""),
+				field.Invalid(path.Child("port"), float64(-1), "invalid value -1 (out of bound int & >0)"),
+				field.Invalid(path.Child("labels", "app"), float64(1),
+					"conflicting values 1 and string (mismatched types int and string)"),
+			},
+		},
+		"defaults referring to the context": {
+			template: `
+output: metadata: name: parameter.name
+parameter: {
+	image: string
+	name:  *context.name | string
+	port:  *context.output.spec.port | int
+	labels: app: *context.appName | string
+}
+`,
+			params: map[string]interface{}{"image": "nginx"},
+		},
+		"missing parameter with the defaults referring to the context": {
+			template: `
+parameter: {
+	image: string
+	name:  *context.name | string
+}
+`,
+			params:  map[string]interface{}{"name": "frontend"},
+			expErrs: field.ErrorList{field.Required(path.Child("image"), "")},
+		},
+		"template without parameter": {
+			template: `output: {}`,
+			params:   map[string]interface{}{"any": "value"},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			errs, err := ValidateParameters(tc.template, tc.params, path)
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, tc.expErrs, errs)
+		})
+	}
+
+	_, err := ValidateParameters(`parameter: {`, nil, path)
+	assert.Error(t, err)
+}
@@ -5,8 +5,6 @@ import (
 	"encoding/json"
 	"fmt"
 
-	"cuelang.org/go/cue"
-	"cuelang.org/go/cue/build"
 	"github.com/pkg/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -40,6 +38,9 @@ const (
 // AbstractEngine defines Definition's Render interface
 type AbstractEngine interface {
 	Params(params interface{}) AbstractEngine
+	// Definition sets the name and the generation of the definition the templates come from, the compiled templates
+	// are cached by them, the name of the engine is used if it's not set
+	Definition(name string, generation int64) AbstractEngine
 	Complete(ctx process.Context, abstractTemplate string) error
 	HealthCheck(ctx process.Context, cli client.Client, ns string, healthPolicyTemplate string) (bool, error)
 	Status(ctx process.Context, cli client.Client, ns string, customStatusTemplate string) (string, error)
@@ -48,6 +49,17 @@ type AbstractEngine interface {
 type def struct {
 	name   string
 	params interface{}
+
+	definition string
+	generation int64
+}
+
+// definitionName returns the name of the definition the templates come from
+func (d *def) definitionName() string {
+	if d.definition != "" {
+		return d.definition
+	}
+	return d.name
 }
 
 type workloadDef struct {
@@ -70,42 +82,54 @@ func (wd *workloadDef) Params(params interface{}) AbstractEngine {
 	return wd
 }
 
+// Definition sets the name and the generation of the workload definition
+func (wd *workloadDef) Definition(name string, generation int64) AbstractEngine {
+	wd.definition = name
+	wd.generation = generation
+	return wd
+}
+
 // Complete do workload definition's rendering
 func (wd *workloadDef) Complete(ctx process.Context, abstractTemplate string) error {
-	bi := build.NewContext().NewInstance("", nil)
-	if err := bi.AddFile("-", abstractTemplate); err != nil {
-		return errors.WithMessagef(err, "invalid cue template of workload %s", wd.name)
+	eval := startEvaluation(workloadKind, wd.definitionName(), stageRender)
+	defer eval.done()
+	paramFile, err := parameterFile(wd.params)
+	if err != nil {
+		return errors.WithMessagef(err, "marshal parameter of workload %s", wd.name)
 	}
-	if wd.params != nil {
-		bt, err := json.Marshal(wd.params)
-		if err != nil {
-			return errors.WithMessagef(err, "marshal parameter of workload %s", wd.name)
-		}
-		if err := bi.AddFile("parameter", fmt.Sprintf("parameter: %s", string(bt))); err != nil {
-			return errors.WithMessagef(err, "invalid parameter of workload %s", wd.name)
-		}
+	contextFile := ctx.BaseContextFile()
+	key := evaluationKey(workloadKind, wd.definitionName(), abstractTemplate, paramFile, contextFile)
+	if r, ok := getProcessed(ctx, key); ok {
+		eval.cached = true
+		ctx.SetBase(r.base)
+		ctx.AppendAuxiliaries(r.auxiliaries...)
+		return nil
 	}
 
-	if err := bi.AddFile("-", ctx.BaseContextFile()); err != nil {
-		return err
+	t, err := getCompiledTemplate(eval, wd.generation, abstractTemplate)
+	if err != nil {
+		return errors.WithMessagef(err, "invalid cue template of workload %s", wd.name)
 	}
-	instances := cue.Build([]*build.Instance{bi})
-	for _, inst := range instances {
-		if err := inst.Value().Err(); err != nil {
-			return errors.WithMessagef(err, "invalid cue template of workload %s after merge parameter and context", wd.name)
-		}
-		output := inst.Lookup(OutputFieldName)
-		base, err := model.NewBase(output)
-		if err != nil {
-			return errors.WithMessagef(err, "invalid output of workload %s", wd.name)
+	defer t.Unlock()
+	inst, err := t.fill(paramFile + "\n" + contextFile)
+	if err != nil {
+		return errors.WithMessagef(err, "invalid cue template of workload %s after merge parameter and context", wd.name)
+	}
+	r := &rendered{}
+	processing := inst.Lookup(task.ProcessingFieldName)
+	if processing.Exists() {
+		if inst, err = task.Process(ctx, inst); err != nil {
+			return errors.WithMessagef(err, "invalid process of workload %s", wd.name)
 		}
-		ctx.SetBase(base)
+	}
+	output := inst.Lookup(OutputFieldName)
+	r.base, err = model.NewBase(output)
+	if err != nil {
+		return errors.WithMessagef(err, "invalid output of workload %s", wd.name)
+	}
 
-		// we will support outputs for workload composition, and it will become trait in AppConfig.
-		outputs := inst.Lookup(OutputsFieldName)
-		if !outputs.Exists() {
-			continue
-		}
+	// we will support outputs for workload composition, and it will become trait in AppConfig.
+	if outputs := inst.Lookup(OutputsFieldName); outputs.Exists() {
 		st, err := outputs.Struct()
 		if err != nil {
 			return errors.WithMessagef(err, "invalid outputs of workload %s", wd.name)
@@ -119,12 +143,29 @@ func (wd *workloadDef) Complete(ctx process.Context, abstractTemplate string) er
 			if err != nil {
 				return errors.WithMessagef(err, "invalid outputs(%s) of workload %s", fieldInfo.Name, wd.name)
 			}
-			ctx.AppendAuxiliaries(process.Auxiliary{Ins: other, Type: AuxiliaryWorkload, Name: fieldInfo.Name})
+			r.auxiliaries = append(r.auxiliaries, process.Auxiliary{Ins: other, Type: AuxiliaryWorkload, Name: fieldInfo.Name})
 		}
 	}
+	if processing.Exists() {
+		putProcessed(key, r)
+	}
+	ctx.SetBase(r.base)
+	ctx.AppendAuxiliaries(r.auxiliaries...)
 	return nil
 }
 
+// parameterFile returns the cue file of the parameters, it's empty if there's no parameter
+func parameterFile(params interface{}) (string, error) {
+	if params == nil {
+		return "", nil
+	}
+	bt, err := json.Marshal(params)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("parameter: %s", string(bt)), nil
+}
+
 func (wd *workloadDef) getTemplateContext(ctx process.Context, cli client.Reader, ns string) (map[string]interface{}, error) {
 
 	var commonLabels = map[string]string{}
@@ -184,22 +225,28 @@ func (wd *workloadDef) HealthCheck(ctx process.Context, cli client.Client, ns st
 	if healthPolicyTemplate == "" {
 		return true, nil
 	}
+	eval := startEvaluation(workloadKind, wd.definitionName(), stageHealth)
+	defer eval.done()
 	templateContext, err := wd.getTemplateContext(ctx, cli, ns)
 	if err != nil {
 		return false, errors.WithMessage(err, "get template context")
 	}
-	return checkHealth(templateContext, healthPolicyTemplate)
+	return checkHealth(eval, wd.generation, templateContext, healthPolicyTemplate)
 }
 
-func checkHealth(templateContext map[string]interface{}, healthPolicyTemplate string) (bool, error) {
+// checkHealth evaluates the compiled health template with the template context
+func checkHealth(eval *evaluation, generation int64, templateContext map[string]interface{},
+	healthPolicyTemplate string) (bool, error) {
 	bt, err := json.Marshal(templateContext)
 	if err != nil {
 		return false, errors.WithMessage(err, "json marshal template context")
 	}
-
-	var buff = "context: " + string(bt) + "\n" + healthPolicyTemplate
-	var r cue.Runtime
-	inst, err := r.Compile("-", buff)
+	t, err := getCompiledTemplate(eval, generation, healthPolicyTemplate)
+	if err != nil {
+		return false, errors.WithMessage(err, "compile health template")
+	}
+	defer t.Unlock()
+	inst, err := t.fill("context: " + string(bt))
 	if err != nil {
 		return false, errors.WithMessage(err, "compile health template")
 	}
@@ -215,21 +262,28 @@ func (wd *workloadDef) Status(ctx process.Context, cli client.Client, ns string,
 	if customStatusTemplate == "" {
 		return "", nil
 	}
+	eval := startEvaluation(workloadKind, wd.definitionName(), stageStatus)
+	defer eval.done()
 	templateContext, err := wd.getTemplateContext(ctx, cli, ns)
 	if err != nil {
 		return "", errors.WithMessage(err, "get template context")
 	}
-	return getStatusMessage(templateContext, customStatusTemplate)
+	return getStatusMessage(eval, wd.generation, templateContext, customStatusTemplate)
 }
 
-func getStatusMessage(templateContext map[string]interface{}, customStatusTemplate string) (string, error) {
+// getStatusMessage evaluates the compiled customStatus template with the template context
+func getStatusMessage(eval *evaluation, generation int64, templateContext map[string]interface{},
+	customStatusTemplate string) (string, error) {
 	bt, err := json.Marshal(templateContext)
 	if err != nil {
 		return "", errors.WithMessage(err, "json marshal template context")
 	}
-	var buff = "context: " + string(bt) + "\n" + customStatusTemplate
-	var r cue.Runtime
-	inst, err := r.Compile("-", buff)
+	t, err := getCompiledTemplate(eval, generation, customStatusTemplate)
+	if err != nil {
+		return "", errors.WithMessage(err, "compile customStatus template")
+	}
+	defer t.Unlock()
+	inst, err := t.fill("context: " + string(bt))
 	if err != nil {
 		return "", errors.WithMessage(err, "compile customStatus template")
 	}
@@ -259,69 +313,86 @@ func (td *traitDef) Params(params interface{}) AbstractEngine {
 	return td
 }
 
+// Definition sets the name and the generation of the trait definition
+func (td *traitDef) Definition(name string, generation int64) AbstractEngine {
+	td.definition = name
+	td.generation = generation
+	return td
+}
+
 // Complete do trait definition's rendering
 func (td *traitDef) Complete(ctx process.Context, abstractTemplate string) error {
-	bi := build.NewContext().NewInstance("", nil)
-	if err := bi.AddFile("-", abstractTemplate); err != nil {
+	eval := startEvaluation(traitKind, td.definitionName(), stageRender)
+	defer eval.done()
+	paramFile, err := parameterFile(td.params)
+	if err != nil {
+		return errors.WithMessagef(err, "marshal parameter of trait %s", td.name)
+	}
+	contextFile := ctx.BaseContextFile()
+	key := evaluationKey(traitKind, td.definitionName(), abstractTemplate, paramFile, contextFile)
+	if r, ok := getProcessed(ctx, key); ok {
+		eval.cached = true
+		return td.apply(ctx, r)
+	}
+
+	t, err := getCompiledTemplate(eval, td.generation, abstractTemplate)
+	if err != nil {
 		return errors.WithMessagef(err, "invalid template of trait %s", td.name)
 	}
-	if td.params != nil {
-		bt, err := json.Marshal(td.params)
-		if err != nil {
-			return errors.WithMessagef(err, "marshal parameter of trait %s", td.name)
-		}
-		if err := bi.AddFile("parameter", fmt.Sprintf("parameter: %s", string(bt))); err != nil {
-			return errors.WithMessagef(err, "invalid parameter of trait %s", td.name)
+	defer t.Unlock()
+	inst, err := t.fill(paramFile + "\n" + contextFile)
+	if err != nil {
+		return errors.WithMessagef(err, "invalid template of trait %s after merge with parameter and context", td.name)
+	}
+	r := &rendered{}
+	processing := inst.Lookup(task.ProcessingFieldName)
+	if processing.Exists() {
+		if inst, err = task.Process(ctx, inst); err != nil {
+			return errors.WithMessagef(err, "invalid process of trait %s", td.name)
 		}
 	}
 
-	if err := bi.AddFile("context", ctx.BaseContextFile()); err != nil {
-		return errors.WithMessagef(err, "invalid context of trait %s", td.name)
-	}
-	instances := cue.Build([]*build.Instance{bi})
-	for _, inst := range instances {
-		if err := inst.Value().Err(); err != nil {
-			return errors.WithMessagef(err, "invalid template of trait %s after merge with parameter and context", td.name)
+	outputs := inst.Lookup(OutputsFieldName)
+	if outputs.Exists() {
+		st, err := outputs.Struct()
+		if err != nil {
+			return errors.WithMessagef(err, "invalid outputs of trait %s", td.name)
 		}
-		processing := inst.Lookup("processing")
-		var err error
-		if processing.Exists() {
-			if inst, err = task.Process(inst); err != nil {
-				return errors.WithMessagef(err, "invalid process of trait %s", td.name)
+		for i := 0; i < st.Len(); i++ {
+			fieldInfo := st.Field(i)
+			if fieldInfo.IsDefinition || fieldInfo.IsHidden || fieldInfo.IsOptional {
+				continue
 			}
-		}
-
-		outputs := inst.Lookup(OutputsFieldName)
-		if outputs.Exists() {
-			st, err := outputs.Struct()
+			other, err := model.NewOther(fieldInfo.Value)
 			if err != nil {
-				return errors.WithMessagef(err, "invalid outputs of trait %s", td.name)
-			}
-			for i := 0; i < st.Len(); i++ {
-				fieldInfo := st.Field(i)
-				if fieldInfo.IsDefinition || fieldInfo.IsHidden || fieldInfo.IsOptional {
-					continue
-				}
-				other, err := model.NewOther(fieldInfo.Value)
-				if err != nil {
-					return errors.WithMessagef(err, "invalid outputs(resource=%s) of trait %s", fieldInfo.Name, td.name)
-				}
-				ctx.AppendAuxiliaries(process.Auxiliary{Ins: other, Type: td.name, Name: fieldInfo.Name})
+				return errors.WithMessagef(err, "invalid outputs(resource=%s) of trait %s", fieldInfo.Name, td.name)
 			}
+			r.auxiliaries = append(r.auxiliaries, process.Auxiliary{Ins: other, Type: td.name, Name: fieldInfo.Name})
 		}
+	}
 
-		patcher := inst.Lookup(PatchFieldName)
-		if patcher.Exists() {
-			base, _ := ctx.Output()
-			p, err := model.NewOther(patcher)
-			if err != nil {
-				return errors.WithMessagef(err, "invalid patch of trait %s", td.name)
-			}
-			if err := base.Unify(p); err != nil {
-				return errors.WithMessagef(err, "invalid patch trait %s into workload", td.name)
-			}
+	patcher := inst.Lookup(PatchFieldName)
+	if patcher.Exists() {
+		if r.patch, err = model.NewOther(patcher); err != nil {
+			return errors.WithMessagef(err, "invalid patch of trait %s", td.name)
 		}
 	}
+	if processing.Exists() {
+		putProcessed(key, r)
+	}
+	return td.apply(ctx, r)
+}
+
+// apply adds the outputs of the trait to the context and patches the workload
+func (td *traitDef) apply(ctx process.Context, r *rendered) error {
+	ctx.AppendAuxiliaries(r.auxiliaries...)
+	if r.patch == nil {
+		return nil
+	}
+	base, _ := ctx.Output()
+	if err := base.Unify(r.patch); err != nil {
+		return errors.WithMessagef(err, "invalid patch trait %s into workload", td.name)
+	}
 	return nil
 }
 
@@ -366,11 +437,13 @@ func (td *traitDef) Status(ctx process.Context, cli client.Client, ns string, cu
 	if customStatusTemplate == "" {
 		return "", nil
 	}
+	eval := startEvaluation(traitKind, td.definitionName(), stageStatus)
+	defer eval.done()
 	templateContext, err := td.getTemplateContext(ctx, cli, ns)
 	if err != nil {
 		return "", errors.WithMessage(err, "get template context")
 	}
-	return getStatusMessage(templateContext, customStatusTemplate)
+	return getStatusMessage(eval, td.generation, templateContext, customStatusTemplate)
 }
 
 // HealthCheck address health check for trait
@@ -378,11 +451,13 @@ func (td *traitDef) HealthCheck(ctx process.Context, cli client.Client, ns strin
 	if healthPolicyTemplate == "" {
 		return true, nil
 	}
+	eval := startEvaluation(traitKind, td.definitionName(), stageHealth)
+	defer eval.done()
 	templateContext, err := td.getTemplateContext(ctx, cli, ns)
 	if err != nil {
 		return false, errors.WithMessage(err, "get template context")
 	}
-	return checkHealth(templateContext, healthPolicyTemplate)
+	return checkHealth(eval, td.generation, templateContext, healthPolicyTemplate)
 }
 
 func getResourceFromObj(obj *unstructured.Unstructured, client client.Reader, namespace string, labels map[string]string, outputsResource string) (map[string]interface{}, error) {
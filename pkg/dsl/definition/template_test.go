@@ -20,6 +20,32 @@ func TestWorkloadTemplateComplete(t *testing.T) {
 	}{
 		{
 			workloadTemplate: `
+processing: tasks: hash: {
+	type: "encode"
+	method: "sha256"
+	data: parameter.type
+}
+output:{
+	apiVersion: "apps/v1"
+    kind: "Deployment"
+	metadata: name: context.name
+	metadata: annotations: "config-hash": processing.tasks.hash.result
+    spec: replicas: parameter.replicas
+}
+parameter: {
+	replicas: *1 | int
+	type: string
+}
+`,
+			params: map[string]interface{}{
+				"replicas": 2,
+				"type":     "ClusterIP",
+			},
+			expectObj: &unstructured.Unstructured{Object: map[string]interface{}{"apiVersion": "apps/v1", "kind": "Deployment", "metadata": map[string]interface{}{"name": "test",
+				"annotations": map[string]interface{}{"config-hash": "d6e79bcb9ebe2da8c86ef1939289fc28e7c5eccd72d5dde3ec3f618e06c13218"}}, "spec": map[string]interface{}{"replicas": int64(2)}}},
+		},
+		{
+			workloadTemplate: `
 output:{
 	apiVersion: "apps/v1"
     kind: "Deployment"
@@ -158,6 +184,49 @@ parameter: {
 				},
 			},
 		},
+		"strategy patch trait": {
+			traitTemplate: `
+patch: {
+      // +patchStrategy=jsonMerge
+      spec: {
+            replicas: parameter.replicas
+            template: spec: containers: [{name: "main", image: parameter.image}]
+      }
+}
+
+parameter: {
+	replicas: int
+	image: string
+}`,
+			params: map[string]interface{}{
+				"replicas": 5,
+				"image":    "website:0.2",
+			},
+			expWorkload: &unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"apiVersion": "apps/v1",
+					"kind":       "Deployment",
+					"spec": map[string]interface{}{
+						"replicas": int64(5),
+						"selector": map[string]interface{}{
+							"matchLabels": map[string]interface{}{
+								"app.oam.dev/component": "test"}},
+						"template": map[string]interface{}{
+							"metadata": map[string]interface{}{
+								"labels": map[string]interface{}{"app.oam.dev/component": "test"},
+							},
+							"spec": map[string]interface{}{
+								"containers": []interface{}{map[string]interface{}{"image": "website:0.2", "name": "main"}}}}}},
+			},
+			expAssObjs: map[string]runtime.Object{
+				"AuxiliaryWorkloadgameconfig": &unstructured.Unstructured{
+					Object: map[string]interface{}{
+						"apiVersion": "v1",
+						"kind":       "ConfigMap",
+						"metadata":   map[string]interface{}{"name": "testgame-config"}, "data": map[string]interface{}{"enemies": "enemies-data", "lives": "lives-data"}},
+				},
+			},
+		},
 		"output trait": {
 			traitTemplate: `
 outputs: service: {
@@ -475,7 +544,7 @@ func TestCheckHealth(t *testing.T) {
 		},
 	}
 	for message, ca := range cases {
-		healthy, err := checkHealth(ca.tpContext, ca.healthTemp)
+		healthy, err := checkHealth(startEvaluation(workloadKind, message, stageHealth), 0, ca.tpContext, ca.healthTemp)
 		assert.NoError(t, err, message)
 		assert.Equal(t, ca.exp, healthy, message)
 	}
@@ -546,7 +615,8 @@ if len(context.outputs.ingress.status.loadBalancer.ingress) == 0 {
 		},
 	}
 	for message, ca := range cases {
-		gotMessage, err := getStatusMessage(ca.tpContext, ca.statusTemp)
+		gotMessage, err := getStatusMessage(startEvaluation(workloadKind, message, stageStatus), 0, ca.tpContext,
+			ca.statusTemp)
 		assert.NoError(t, err, message)
 		assert.Equal(t, ca.expMessage, gotMessage, message)
 	}
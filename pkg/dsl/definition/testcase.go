@@ -0,0 +1,469 @@
+package definition
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/build"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/yaml"
+
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/dsl/process"
+)
+
+const (
+	// TestFileSuffix is the suffix of the files of the test cases of the definitions
+	TestFileSuffix = "_test.yaml"
+	// DefinitionFileExt is the extension of the definition files
+	DefinitionFileExt = ".cue"
+
+	defaultTestComponent = "my-component"
+	defaultTestApp       = "my-app"
+)
+
+// TestFile is the test cases of a definition file, it's written in yaml like
+//
+//	definition: my-scaler.cue
+//	cases:
+//	- name: scale the deployment
+//	  parameter:
+//	    replicas: 3
+//	  workload:
+//	    apiVersion: apps/v1
+//	    kind: Deployment
+//	  expected:
+//	    patch:
+//	      spec:
+//	        replicas: 3
+//	    output:
+//	      apiVersion: apps/v1
+//	      kind: Deployment
+//	      spec:
+//	        replicas: 3
+type TestFile struct {
+	// Definition is the definition file relative to the test file, it's the file of the same name without the
+	// `_test.yaml` suffix and with the `.cue` extension by default
+	Definition string     `json:"definition,omitempty"`
+	Cases      []TestCase `json:"cases"`
+}
+
+// TestCase renders the template of the definition with the parameter in the context and checks the results
+type TestCase struct {
+	Name      string                 `json:"name"`
+	Parameter map[string]interface{} `json:"parameter,omitempty"`
+	Context   TestContext            `json:"context,omitempty"`
+	// Workload is the workload a trait patches, it's the `context.output` of the trait
+	Workload map[string]interface{} `json:"workload,omitempty"`
+	Expected TestExpectation        `json:"expected"`
+}
+
+// TestContext is the context the template is rendered in
+type TestContext struct {
+	// Name is the name of the component, it's `my-component` by default
+	Name string `json:"name,omitempty"`
+	// AppName is the name of the application, it's `my-app` by default
+	AppName        string            `json:"appName,omitempty"`
+	Namespace      string            `json:"namespace,omitempty"`
+	AppRevision    string            `json:"appRevision,omitempty"`
+	AppRevisionNum int64             `json:"appRevisionNum,omitempty"`
+	AppLabels      map[string]string `json:"appLabels,omitempty"`
+	AppAnnotations map[string]string `json:"appAnnotations,omitempty"`
+	Scopes         map[string]string `json:"scopes,omitempty"`
+}
+
+// TestExpectation is the expected results of a test case, the results that are not set are not checked
+type TestExpectation struct {
+	// Output is the workload rendered from a workload definition, or the workload patched by a trait
+	Output map[string]interface{} `json:"output,omitempty"`
+	// Outputs are the other resources rendered by their names
+	Outputs map[string]interface{} `json:"outputs,omitempty"`
+	// Patch is the patch of a trait before it's applied to the workload
+	Patch map[string]interface{} `json:"patch,omitempty"`
+	// Error is a part of the message of the error the render fails with
+	Error string `json:"error,omitempty"`
+}
+
+// TestResult is the result of a test case
+type TestResult struct {
+	// File is the test file of the case
+	File string
+	Case string
+	// Err is the error that fails the test case before the results are compared
+	Err   error
+	Diffs []TestDiff
+}
+
+// Passed returns whether the test case passed
+func (r TestResult) Passed() bool {
+	return r.Err == nil && len(r.Diffs) == 0
+}
+
+// String prints the result of the test case with its errors and differences
+func (r TestResult) String() string {
+	if r.Passed() {
+		return fmt.Sprintf("--- PASS: %s/%s", r.File, r.Case)
+	}
+	lines := []string{fmt.Sprintf("--- FAIL: %s/%s", r.File, r.Case)}
+	if r.Err != nil {
+		lines = append(lines, "    "+r.Err.Error())
+	}
+	for _, d := range r.Diffs {
+		lines = append(lines, "    "+d.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// TestDiff is a difference between the expected and the actual results, the value is nil if the field is absent
+type TestDiff struct {
+	Path     string
+	Expected interface{}
+	Actual   interface{}
+}
+
+// String prints the difference
+func (d TestDiff) String() string {
+	return fmt.Sprintf("%s: expected %s, got %s", d.Path, diffValue(d.Expected), diffValue(d.Actual))
+}
+
+func diffValue(v interface{}) string {
+	if v == nil {
+		return "<none>"
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// FindTestFiles finds the test files in the paths, the directories are searched recursively
+func FindTestFiles(paths ...string) ([]string, error) {
+	var files []string
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, p)
+			continue
+		}
+		err = filepath.Walk(p, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() && strings.HasSuffix(info.Name(), TestFileSuffix) {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// LoadTestFile reads the test file and the definition file it tests
+func LoadTestFile(path string) (*TestFile, *File, error) {
+	content, err := ioutil.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, nil, err
+	}
+	tf := &TestFile{}
+	if err := yaml.UnmarshalStrict(content, tf); err != nil {
+		return nil, nil, errors.WithMessagef(err, "invalid test file %s", path)
+	}
+	defPath := strings.TrimSuffix(path, TestFileSuffix) + DefinitionFileExt
+	if tf.Definition != "" {
+		defPath = filepath.Join(filepath.Dir(path), tf.Definition)
+	}
+	src, err := ioutil.ReadFile(filepath.Clean(defPath))
+	if err != nil {
+		return nil, nil, errors.WithMessagef(err, "read the definition of test file %s", path)
+	}
+	f, err := ParseFile(src)
+	if err != nil {
+		return nil, nil, errors.WithMessagef(err, "parse definition file %s", defPath)
+	}
+	return tf, f, nil
+}
+
+// RunTestFile runs the test cases in the test file
+func RunTestFile(path string) ([]TestResult, error) {
+	tf, f, err := LoadTestFile(path)
+	if err != nil {
+		return nil, err
+	}
+	results := tf.Run(f)
+	for i := range results {
+		results[i].File = path
+	}
+	return results, nil
+}
+
+// Run runs the test cases against the definition file
+func (tf *TestFile) Run(f *File) []TestResult {
+	results := make([]TestResult, 0, len(tf.Cases))
+	for i, c := range tf.Cases {
+		name := c.Name
+		if name == "" {
+			name = fmt.Sprintf("case-%d", i)
+		}
+		result := TestResult{Case: name}
+		result.Diffs, result.Err = c.Run(f)
+		results = append(results, result)
+	}
+	return results
+}
+
+// Run renders the template of the definition file and compares the results with the expected ones
+func (c TestCase) Run(f *File) ([]TestDiff, error) {
+	if c.Expected.Patch != nil && f.Type != types.TypeTrait {
+		return nil, errors.Errorf("only a trait has the patch, %s is a %s", f.Name, f.Type)
+	}
+	r, err := c.render(f)
+	if c.Expected.Error != "" {
+		if err == nil {
+			return nil, errors.Errorf("expected error %q, got nil", c.Expected.Error)
+		}
+		if !strings.Contains(err.Error(), c.Expected.Error) {
+			return nil, errors.Errorf("expected error %q, got %q", c.Expected.Error, err.Error())
+		}
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var diffs []TestDiff
+	if c.Expected.Patch != nil {
+		diffs = append(diffs, compare(field.NewPath(PatchFieldName), c.Expected.Patch, r.patch)...)
+	}
+	if c.Expected.Output != nil {
+		diffs = append(diffs, compare(field.NewPath(OutputFieldName), c.Expected.Output, r.output)...)
+	}
+	if c.Expected.Outputs != nil {
+		diffs = append(diffs, compare(field.NewPath(OutputsFieldName), c.Expected.Outputs, r.outputs)...)
+	}
+	return diffs, nil
+}
+
+// testResults are the rendered results of a test case
+type testResults struct {
+	patch   interface{}
+	output  interface{}
+	outputs map[string]interface{}
+}
+
+// render renders the template in the context of the test case, the results must be complete
+func (c TestCase) render(f *File) (*testResults, error) {
+	ctx := c.Context.newContext()
+	r := &testResults{outputs: map[string]interface{}{}}
+	if f.Type == types.TypeTrait {
+		workload := c.Workload
+		if workload == nil {
+			workload = map[string]interface{}{}
+		}
+		base, err := NewBaseObject(workload)
+		if err != nil {
+			return nil, errors.WithMessage(err, "invalid workload")
+		}
+		ctx.SetBase(base)
+		// the patch is evaluated with the workload before it's patched
+		if r.patch, err = renderPatch(f, ctx, c.Parameter); err != nil {
+			return nil, err
+		}
+	}
+	if err := f.Render(ctx, c.Parameter); err != nil {
+		return nil, err
+	}
+
+	base, auxiliaries := ctx.Output()
+	if base != nil {
+		obj, err := base.Unstructured()
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid %s", OutputFieldName)
+		}
+		r.output = obj.Object
+	}
+	for _, aux := range auxiliaries {
+		obj, err := aux.Ins.Unstructured()
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid %s %s", OutputsFieldName, aux.Name)
+		}
+		r.outputs[aux.Name] = obj.Object
+	}
+	return r, nil
+}
+
+func (c TestContext) newContext() process.Context {
+	name, appName := c.Name, c.AppName
+	if name == "" {
+		name = defaultTestComponent
+	}
+	if appName == "" {
+		appName = defaultTestApp
+	}
+	ctx := process.NewContext(name, appName)
+	ctx.SetClient(nil, c.Namespace)
+	if c.AppRevision != "" {
+		ctx.SetAppRevision(c.AppRevision, c.AppRevisionNum)
+	}
+	ctx.SetAppMeta(c.AppLabels, c.AppAnnotations)
+	ctx.SetScopes(c.Scopes)
+	return ctx
+}
+
+// renderPatch evaluates the patch of a trait in the context, before it's applied to the workload of the context
+func renderPatch(f *File, ctx process.Context, params map[string]interface{}) (interface{}, error) {
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	paramFile, err := parameterFile(params)
+	if err != nil {
+		return nil, err
+	}
+	bi := build.NewContext().NewInstance("", nil)
+	if err := bi.AddFile("-", f.Template); err != nil {
+		return nil, errors.WithMessagef(err, "invalid template of trait %s", f.Name)
+	}
+	if err := bi.AddFile(ParameterFieldName, paramFile); err != nil {
+		return nil, errors.WithMessagef(err, "invalid parameter of trait %s", f.Name)
+	}
+	if err := bi.AddFile("context", ctx.BaseContextFile()); err != nil {
+		return nil, errors.WithMessagef(err, "invalid context of trait %s", f.Name)
+	}
+	inst := cue.Build([]*build.Instance{bi})[0]
+	if inst.Err != nil {
+		return nil, errors.WithMessagef(inst.Err, "invalid template of trait %s", f.Name)
+	}
+	patcher := inst.Lookup(PatchFieldName)
+	if !patcher.Exists() {
+		return nil, nil
+	}
+	b, err := patcher.MarshalJSON()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "invalid patch of trait %s", f.Name)
+	}
+	var patch interface{}
+	if err := json.Unmarshal(b, &patch); err != nil {
+		return nil, err
+	}
+	return patch, nil
+}
+
+// compare finds the differences between the expected and the actual values, the values are compared in their
+// json forms so that the numbers of different types are equal
+func compare(path *field.Path, expected, actual interface{}) []TestDiff {
+	e, err := normalize(expected)
+	if err != nil {
+		return []TestDiff{{Path: path.String(), Expected: expected, Actual: actual}}
+	}
+	a, err := normalize(actual)
+	if err != nil {
+		return []TestDiff{{Path: path.String(), Expected: expected, Actual: actual}}
+	}
+	return diff(path, e, a)
+}
+
+func normalize(v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var n interface{}
+	err = json.Unmarshal(b, &n)
+	return n, err
+}
+
+func diff(path *field.Path, expected, actual interface{}) []TestDiff {
+	switch e := expected.(type) {
+	case map[string]interface{}:
+		a, ok := actual.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(e)+len(a))
+		for k := range e {
+			keys = append(keys, k)
+		}
+		for k := range a {
+			if _, ok := e[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		var diffs []TestDiff
+		for _, k := range keys {
+			diffs = append(diffs, diff(path.Child(k), e[k], a[k])...)
+		}
+		return diffs
+	case []interface{}:
+		a, ok := actual.([]interface{})
+		if !ok {
+			break
+		}
+		var diffs []TestDiff
+		for i := 0; i < len(e) || i < len(a); i++ {
+			var ev, av interface{}
+			if i < len(e) {
+				ev = e[i]
+			}
+			if i < len(a) {
+				av = a[i]
+			}
+			diffs = append(diffs, diff(path.Index(i), ev, av)...)
+		}
+		return diffs
+	}
+	if reflect.DeepEqual(expected, actual) {
+		return nil
+	}
+	return []TestDiff{{Path: path.String(), Expected: expected, Actual: actual}}
+}
+
+// TestReporter reports the failed test cases, *testing.T is a TestReporter
+type TestReporter interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// RunTests runs the test files in the paths and reports the failed test cases, it runs the test cases of the
+// definitions with go test, like
+//
+//	func TestDefinitions(t *testing.T) {
+//		definition.RunTests(t, "./definitions")
+//	}
+func RunTests(t TestReporter, paths ...string) {
+	t.Helper()
+	files, err := FindTestFiles(paths...)
+	if err != nil {
+		t.Errorf("find test files: %v", err)
+		return
+	}
+	for _, file := range files {
+		results, err := RunTestFile(file)
+		if err != nil {
+			t.Errorf("%v", err)
+			continue
+		}
+		for _, r := range results {
+			if !r.Passed() {
+				t.Errorf("%s", r)
+			}
+		}
+	}
+}
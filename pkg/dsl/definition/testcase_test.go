@@ -0,0 +1,96 @@
+package definition
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeReporter struct {
+	errs []string
+}
+
+func (r *fakeReporter) Helper() {}
+
+func (r *fakeReporter) Errorf(format string, args ...interface{}) {
+	r.errs = append(r.errs, fmt.Sprintf(format, args...))
+}
+
+func TestRunTestFiles(t *testing.T) {
+	files, err := FindTestFiles("testdata")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"testdata/sidecar_test.yaml", "testdata/worker_test.yaml"}, files)
+	for _, file := range files {
+		results, err := RunTestFile(file)
+		assert.NoError(t, err)
+		for _, r := range results {
+			assert.True(t, r.Passed(), r.String())
+		}
+	}
+	RunTests(t, "testdata")
+
+	_, err = RunTestFile("testdata/not-found_test.yaml")
+	assert.Error(t, err)
+}
+
+func TestRunTestCases(t *testing.T) {
+	_, sidecar, err := LoadTestFile("testdata/sidecar_test.yaml")
+	assert.NoError(t, err)
+	workload := map[string]interface{}{
+		"kind": "Deployment",
+		"spec": map[string]interface{}{"template": map[string]interface{}{"spec": map[string]interface{}{
+			"containers": []interface{}{map[string]interface{}{"name": "web", "image": "nginx"}},
+		}}},
+	}
+	tf := &TestFile{Cases: []TestCase{{
+		Parameter: map[string]interface{}{"name": "log", "image": "fluentd"},
+		Workload:  workload,
+		Expected: TestExpectation{
+			Output: map[string]interface{}{
+				"kind": "Deployment",
+				"spec": map[string]interface{}{"template": map[string]interface{}{"spec": map[string]interface{}{
+					"containers": []interface{}{map[string]interface{}{"name": "web", "image": "nginx"}},
+					"replicas":   1,
+				}}},
+			},
+		},
+	}, {
+		Name:      "missing image",
+		Parameter: map[string]interface{}{"name": "log"},
+		Workload:  workload,
+		Expected:  TestExpectation{Error: "conflict"},
+	}, {
+		Name:     "missing parameters",
+		Expected: TestExpectation{Patch: map[string]interface{}{}},
+	}}}
+	results := tf.Run(sidecar)
+	assert.Equal(t, 3, len(results))
+
+	assert.Equal(t, "case-0", results[0].Case)
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, []TestDiff{
+		{Path: "output.spec.template.spec.containers[1]", Actual: map[string]interface{}{"name": "log", "image": "fluentd"}},
+		{Path: "output.spec.template.spec.replicas", Expected: float64(1)},
+	}, results[0].Diffs)
+	assert.Equal(t, `--- FAIL: /case-0
+    output.spec.template.spec.containers[1]: expected <none>, got {"image":"fluentd","name":"log"}
+    output.spec.template.spec.replicas: expected 1, got <none>`, results[0].String())
+
+	assert.False(t, results[1].Passed())
+	assert.Contains(t, results[1].Err.Error(), `expected error "conflict"`)
+
+	assert.Contains(t, results[2].Err.Error(), "invalid patch of trait sidecar")
+
+	_, worker, err := LoadTestFile("testdata/worker_test.yaml")
+	assert.NoError(t, err)
+	results = (&TestFile{Cases: []TestCase{{
+		Name:     "patch of a workload",
+		Expected: TestExpectation{Patch: map[string]interface{}{}},
+	}}}).Run(worker)
+	assert.EqualError(t, results[0].Err, "only a trait has the patch, worker is a workload")
+
+	r := &fakeReporter{}
+	RunTests(r, "testdata/not-found")
+	assert.Equal(t, 1, len(r.errs))
+}
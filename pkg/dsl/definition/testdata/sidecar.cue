@@ -0,0 +1,17 @@
+sidecar: {
+	type:        "trait"
+	description: "Add a sidecar container to the workload"
+}
+template: {
+	patch: spec: template: spec: {
+		// +patchKey=name
+		containers: [{
+			name:  parameter.name
+			image: parameter.image
+		}]
+	}
+	parameter: {
+		name:  string
+		image: string
+	}
+}
@@ -0,0 +1,31 @@
+worker: {
+	type:        "workload"
+	description: "Long-running scalable backend worker"
+	attributes: definitionRef: name: "deployments.apps"
+}
+template: {
+	output: {
+		apiVersion: "apps/v1"
+		kind:       "Deployment"
+		metadata: name: context.name
+		spec: {
+			selector: matchLabels: "app.oam.dev/component": context.name
+			template: {
+				metadata: labels: "app.oam.dev/component": context.name
+				spec: containers: [{
+					name:  context.name
+					image: parameter.image
+				}]
+			}
+		}
+	}
+	outputs: config: {
+		apiVersion: "v1"
+		kind:       "ConfigMap"
+		metadata: name: "\(context.appName)-\(context.name)"
+		data: image: parameter.image
+	}
+	parameter: {
+		image: string
+	}
+}
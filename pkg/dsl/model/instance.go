@@ -73,6 +73,16 @@ func (inst *instance) Unify(other Instance) error {
 	return nil
 }
 
+// Copy returns a copy of the instance, unifying the copy doesn't change the original one
+func Copy(inst Instance) Instance {
+	i, ok := inst.(*instance)
+	if !ok {
+		return inst
+	}
+	c := *i
+	return &c
+}
+
 // NewBase create a base instance
 func NewBase(v cue.Value) (Instance, error) {
 	vs, err := openPrint(v)
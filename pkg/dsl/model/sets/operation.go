@@ -108,7 +108,7 @@ func StrategyUnify(base, patch string) (string, error) {
 		return "", errors.WithMessage(err, "invalid patch cue file")
 	}
 
-	return strategyUnify(baseFile, patchFile, listMergeByKey(baseFile))
+	return strategyUnify(baseFile, patchFile, listMergeByKey(baseFile), patchByStrategy(baseFile))
 }
 
 func strategyUnify(baseFile *ast.File, patchFile *ast.File, patchOpts ...interceptor) (string, error) {
@@ -0,0 +1,391 @@
+package sets
+
+import (
+	"strconv"
+	"strings"
+
+	"cuelang.org/go/cue/ast"
+	"cuelang.org/go/cue/literal"
+	"cuelang.org/go/cue/token"
+	"github.com/pkg/errors"
+)
+
+const (
+	// TagPatchStrategy specify the strategy to patch the field
+	TagPatchStrategy = "patchStrategy"
+
+	// StrategyReplace replaces the field of the base with the one of the patch
+	StrategyReplace = "replace"
+	// StrategyRetainKeys removes the fields of the base struct that are not in the patch, the others are merged
+	StrategyRetainKeys = "retainKeys"
+	// StrategyJSONMerge merges the patch into the base like a json merge patch (RFC 7386), the values of the patch
+	// take the place of the ones of the base, the structs are merged and the null fields are removed
+	StrategyJSONMerge = "jsonMerge"
+	// StrategyJSONPatch applies the json patch (RFC 6902) operations in the list of the patch to the field of the base
+	StrategyJSONPatch = "jsonPatch"
+	// StrategyDelete removes the field from the base
+	StrategyDelete = "delete"
+)
+
+// patchByStrategy rewrites the base and the patch by the patch strategies of the fields in the patch,
+// so that the unify of them gives the patched result
+func patchByStrategy(baseNode ast.Node) interceptor {
+	return func(pnode ast.Node) (ast.Node, error) {
+		var (
+			err error
+			// the fields that are applied to the base and must be removed from the patch
+			applied []*ast.Field
+			parents []ast.Node
+		)
+		walker := newWalker(func(node ast.Node, ctx walkCtx) {
+			field, ok := node.(*ast.Field)
+			if !ok || err != nil {
+				return
+			}
+			strategy, ok := findCommentTag(field.Comments())[TagPatchStrategy]
+			if !ok {
+				return
+			}
+			label := labelStr(field.Label)
+			patchParent, lerr := lookUp(pnode, ctx.Pos()...)
+			if lerr != nil {
+				return
+			}
+			baseParent, lerr := lookUp(baseNode, ctx.Pos()...)
+			if lerr != nil {
+				baseParent = nil
+			}
+			patchValue := lookField(field, label)
+
+			switch strategy {
+			case StrategyReplace:
+				removeFields(baseParent, byLabel(label))
+			case StrategyRetainKeys:
+				var baseValue ast.Node
+				if f := findField(baseParent, label); f != nil {
+					baseValue = lookField(f, label)
+				}
+				if !isStruct(patchValue) || (baseValue != nil && !isStruct(baseValue)) {
+					err = errors.Errorf("the value of field %s must be a struct to retain keys", label)
+					return
+				}
+				keys := map[string]bool{}
+				for _, f := range structFields(patchValue) {
+					keys[labelStr(f.Label)] = true
+				}
+				removeFields(baseValue, func(decl ast.Decl) bool {
+					f, ok := decl.(*ast.Field)
+					return ok && !keys[labelStr(f.Label)]
+				})
+			case StrategyJSONMerge:
+				if isNull(patchValue) {
+					removeFields(baseParent, byLabel(label))
+					applied, parents = append(applied, field), append(parents, patchParent)
+					return
+				}
+				mergeJSON(baseParent, field)
+			case StrategyJSONPatch:
+				if baseParent == nil || findField(baseParent, label) == nil {
+					err = errors.Errorf("field %s to apply the json patch is not found", label)
+					return
+				}
+				if err = applyJSONPatch(baseParent, field); err != nil {
+					err = errors.WithMessagef(err, "apply the json patch of field %s", label)
+					return
+				}
+				applied, parents = append(applied, field), append(parents, patchParent)
+			case StrategyDelete:
+				removeFields(baseParent, byLabel(label))
+				applied, parents = append(applied, field), append(parents, patchParent)
+			default:
+				err = errors.Errorf("unknown patch strategy %s of field %s", strategy, label)
+			}
+		})
+		walker.walk(pnode)
+		if err != nil {
+			return nil, err
+		}
+		for i, field := range applied {
+			f := field
+			removeFields(parents[i], func(decl ast.Decl) bool {
+				return decl == f
+			})
+		}
+		return pnode, nil
+	}
+}
+
+// mergeJSON merges the patch field into the field of the same label in the base struct, the structs are merged
+// field by field, the other values of the patch take the place of the ones of the base and the null fields are removed
+func mergeJSON(baseParent ast.Node, patch *ast.Field) {
+	label := labelStr(patch.Label)
+	base := findField(baseParent, label)
+	if base == nil {
+		return
+	}
+	baseValue, patchValue := lookField(base, label), lookField(patch, label)
+	if !isStruct(baseValue) || !isStruct(patchValue) {
+		removeFields(baseParent, byLabel(label))
+		return
+	}
+	for _, pf := range structFields(patchValue) {
+		pl := labelStr(pf.Label)
+		if isNull(lookField(pf, pl)) {
+			removeFields(baseValue, byLabel(pl))
+			f := pf
+			removeFields(patchValue, func(decl ast.Decl) bool {
+				return decl == f
+			})
+			continue
+		}
+		mergeJSON(baseValue, pf)
+	}
+}
+
+// applyJSONPatch applies the json patch operations in the patch field to the field of the same label in the parent
+func applyJSONPatch(parent ast.Node, patch *ast.Field) error {
+	label, _, err := ast.LabelName(patch.Label)
+	if err != nil {
+		return err
+	}
+	ops, ok := lookField(patch, labelStr(patch.Label)).(*ast.ListLit)
+	if !ok {
+		return errors.New("the value must be a list of operations")
+	}
+	for i, op := range listElts(ops) {
+		if err := applyJSONPatchOperation(parent, label, op); err != nil {
+			return errors.WithMessagef(err, "operation %d", i)
+		}
+	}
+	return nil
+}
+
+func applyJSONPatchOperation(root ast.Node, label string, op ast.Node) error {
+	opName, err := stringField(op, "op")
+	if err != nil {
+		return err
+	}
+	path, err := stringField(op, "path")
+	if err != nil {
+		return err
+	}
+	keys, err := parsePointer(path)
+	if err != nil {
+		return err
+	}
+	keys = append([]string{label}, keys...)
+	parent := root
+	for _, key := range keys[:len(keys)-1] {
+		if parent = child(parent, key); parent == nil {
+			return errors.Errorf("path %s is not found", path)
+		}
+	}
+	key := keys[len(keys)-1]
+
+	switch opName {
+	case "add", "replace":
+		value, err := lookUp(op, "value")
+		if err != nil {
+			return errors.New("the value of the operation is not found")
+		}
+		return setChild(parent, key, value.(ast.Expr), opName == "add")
+	case "remove":
+		return removeChild(parent, key)
+	default:
+		return errors.Errorf("unsupported operation %s", opName)
+	}
+}
+
+// parsePointer parses the json pointer (RFC 6901) into the keys
+func parsePointer(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(path, "/") {
+		return nil, errors.Errorf("invalid path %s, it must start with /", path)
+	}
+	keys := strings.Split(path[1:], "/")
+	for i, key := range keys {
+		keys[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(key)
+	}
+	return keys, nil
+}
+
+// child finds the value of the key in the struct or the list
+func child(node ast.Node, key string) ast.Node {
+	switch x := node.(type) {
+	case *ast.ListLit:
+		index, err := strconv.Atoi(key)
+		elts := listElts(x)
+		if err != nil || index < 0 || index >= len(elts) {
+			return nil
+		}
+		return elts[index]
+	default:
+		f := findFieldByName(node, key)
+		if f == nil {
+			return nil
+		}
+		return lookField(f, labelStr(f.Label))
+	}
+}
+
+// setChild sets the value of the key in the struct or the list, the value is inserted into the list if it's added
+func setChild(node ast.Node, key string, value ast.Expr, add bool) error {
+	if l, ok := node.(*ast.ListLit); ok {
+		elts := listElts(l)
+		index := len(elts)
+		if key != "-" || !add {
+			var err error
+			if index, err = strconv.Atoi(key); err != nil || index < 0 || index > len(elts) || (!add && index == len(elts)) {
+				return errors.Errorf("invalid index %s of the list", key)
+			}
+		}
+		if add {
+			l.Elts = append(l.Elts[:index], append([]ast.Expr{value}, l.Elts[index:]...)...)
+		} else {
+			l.Elts[index] = value
+		}
+		return nil
+	}
+	if !isStruct(node) {
+		return errors.Errorf("cannot set key %s of a value that is not a struct or a list", key)
+	}
+	if f := findFieldByName(node, key); f != nil {
+		f.Value = value
+		return nil
+	}
+	if !add {
+		return errors.Errorf("key %s to replace is not found", key)
+	}
+	var label ast.Label = ast.NewString(key)
+	if ast.IsValidIdent(key) {
+		label = ast.NewIdent(key)
+	}
+	field := &ast.Field{Label: label, Value: value}
+	switch x := node.(type) {
+	case *ast.File:
+		x.Decls = append(x.Decls, field)
+	case *ast.StructLit:
+		x.Elts = append(x.Elts, field)
+	}
+	return nil
+}
+
+// removeChild removes the key from the struct or the list
+func removeChild(node ast.Node, key string) error {
+	if l, ok := node.(*ast.ListLit); ok {
+		index, err := strconv.Atoi(key)
+		if err != nil || index < 0 || index >= len(listElts(l)) {
+			return errors.Errorf("invalid index %s of the list", key)
+		}
+		l.Elts = append(l.Elts[:index], l.Elts[index+1:]...)
+		return nil
+	}
+	f := findFieldByName(node, key)
+	if f == nil {
+		return errors.Errorf("key %s to remove is not found", key)
+	}
+	removeFields(node, func(decl ast.Decl) bool {
+		return decl == f
+	})
+	return nil
+}
+
+func stringField(node ast.Node, key string) (string, error) {
+	v, err := lookUp(node, key)
+	if err != nil {
+		return "", errors.Errorf("the %s of the operation is not found", key)
+	}
+	lit, ok := v.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", errors.Errorf("the %s of the operation must be a string", key)
+	}
+	return literal.Unquote(lit.Value)
+}
+
+// listElts returns the elements of the list without the ellipsis
+func listElts(l *ast.ListLit) []ast.Expr {
+	var elts []ast.Expr
+	for _, elt := range l.Elts {
+		if _, ok := elt.(*ast.Ellipsis); !ok {
+			elts = append(elts, elt)
+		}
+	}
+	return elts
+}
+
+func byLabel(label string) func(decl ast.Decl) bool {
+	return func(decl ast.Decl) bool {
+		f, ok := decl.(*ast.Field)
+		return ok && labelStr(f.Label) == label
+	}
+}
+
+// removeFields removes the fields that match from the struct or the file
+func removeFields(node ast.Node, match func(decl ast.Decl) bool) {
+	filter := func(decls []ast.Decl) []ast.Decl {
+		var kept []ast.Decl
+		for _, decl := range decls {
+			if !match(decl) {
+				kept = append(kept, decl)
+			}
+		}
+		return kept
+	}
+	switch x := node.(type) {
+	case *ast.File:
+		x.Decls = filter(x.Decls)
+	case *ast.StructLit:
+		x.Elts = filter(x.Elts)
+	}
+}
+
+func structFields(node ast.Node) []*ast.Field {
+	var decls []ast.Decl
+	switch x := node.(type) {
+	case *ast.File:
+		decls = x.Decls
+	case *ast.StructLit:
+		decls = x.Elts
+	}
+	var fields []*ast.Field
+	for _, decl := range decls {
+		if f, ok := decl.(*ast.Field); ok {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+func findField(node ast.Node, label string) *ast.Field {
+	for _, f := range structFields(node) {
+		if labelStr(f.Label) == label {
+			return f
+		}
+	}
+	return nil
+}
+
+// findFieldByName finds the field by the unquoted label
+func findFieldByName(node ast.Node, name string) *ast.Field {
+	for _, f := range structFields(node) {
+		if n, _, err := ast.LabelName(f.Label); err == nil && n == name {
+			return f
+		}
+	}
+	return nil
+}
+
+func isStruct(node ast.Node) bool {
+	switch node.(type) {
+	case *ast.File, *ast.StructLit:
+		return true
+	}
+	return false
+}
+
+func isNull(node ast.Node) bool {
+	lit, ok := node.(*ast.BasicLit)
+	return ok && lit.Kind == token.NULL
+}
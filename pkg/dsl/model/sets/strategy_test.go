@@ -0,0 +1,169 @@
+package sets
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestPatchStrategy(t *testing.T) {
+	testCase := []struct {
+		base   string
+		patch  string
+		result string
+		err    string
+	}{
+		{
+			base: `spec: {replicas: 2, containers: [{name: "x1"}, {name: "x2"}, ...]}`,
+			patch: `
+spec: {
+	replicas: 2
+	// +patchStrategy=replace
+	containers: [{name: "x3"}]
+}`,
+			result: `spec: {
+	replicas: 2
+	// +patchStrategy=replace
+	containers: [{
+		name: "x3"
+	}]
+}
+`,
+		},
+
+		{
+			base: `metadata: labels: {app: "x", env: "dev"}`,
+			patch: `
+metadata: {
+	// +patchStrategy=retainKeys
+	labels: {app: "x", team: "t"}
+}`,
+			result: `metadata: {
+	// +patchStrategy=retainKeys
+	labels: {
+		app:  "x"
+		team: "t"
+	}
+}
+`,
+		},
+
+		{
+			base: `metadata: labels: {app: "x", env: "dev"}`,
+			patch: `
+metadata: {
+	// +patchStrategy=retainKeys
+	labels: {app: "y"}
+}`,
+			result: "_|_\n",
+			err:    `result check err: metadata.labels.app: conflicting values "x" and "y"`,
+		},
+
+		{
+			base: `spec: {replicas: 2, selector: {app: "x", env: "dev"}, hostNetwork: true}`,
+			patch: `
+// +patchStrategy=jsonMerge
+spec: {replicas: 3, selector: {env: null, team: "t"}, hostNetwork: null}`,
+			result: `// +patchStrategy=jsonMerge
+spec: {
+	selector: {
+		app:  "x"
+		team: "t"
+	}
+	replicas: 3
+}
+`,
+		},
+
+		{
+			base: `spec: {replicas: 2, hostNetwork: true}`,
+			patch: `
+spec: {
+	// +patchStrategy=delete
+	hostNetwork: null
+}`,
+			result: `spec: {
+	replicas: 2
+}
+`,
+		},
+
+		{
+			base: `spec: {replicas: 2, hostNetwork: true, containers: [{name: "x1", env: [{name: "A"}, ...]}, ...], "app.oam.dev/x": "1"}`,
+			patch: `
+// +patchStrategy=jsonPatch
+spec: [
+	{op: "replace", path: "/replicas", value: 3},
+	{op: "remove", path: "/hostNetwork"},
+	{op: "add", path: "/containers/0/env/-", value: {name: "B"}},
+	{op: "add", path: "/containers/0/env/0", value: {name: "C"}},
+	{op: "add", path: "/app.oam.dev~1x", value: "2"},
+	{op: "add", path: "/paused", value: true},
+]`,
+			result: `spec: {
+	replicas: 3
+	containers: [{
+		name: "x1"
+		env: [{
+			name: "C"
+		}, {
+			name: "A"
+		}, {
+			name: "B"
+		}, ...]
+	}, ...]
+	"app.oam.dev/x": "2"
+	paused:          true
+}
+`,
+		},
+
+		{
+			base: `spec: {replicas: 2}`,
+			patch: `
+// +patchStrategy=jsonPatch
+spec: [{op: "remove", path: "/paused"}]`,
+			err: "process patchOption: apply the json patch of field spec: operation 0: key paused to remove is not found",
+		},
+
+		{
+			base: `spec: {replicas: 2}`,
+			patch: `
+// +patchStrategy=jsonPatch
+spec: [{op: "move", from: "/replicas", path: "/paused"}]`,
+			err: "process patchOption: apply the json patch of field spec: operation 0: unsupported operation move",
+		},
+
+		{
+			base: `spec: {replicas: 2}`,
+			patch: `
+// +patchStrategy=unknown
+spec: replicas: 3`,
+			err: "process patchOption: unknown patch strategy unknown of field spec",
+		},
+	}
+
+	for i, tcase := range testCase {
+		v, err := StrategyUnify(tcase.base, tcase.patch)
+		assert.Equal(t, tcase.result, v, fmt.Sprintf("testPatchStrategy for case(no:%d)", i))
+		var errMsg string
+		if err != nil {
+			errMsg = err.Error()
+		}
+		assert.Equal(t, tcase.err, errMsg, fmt.Sprintf("testPatchStrategy for case(no:%d)", i))
+	}
+}
+
+func TestParsePointer(t *testing.T) {
+	keys, err := parsePointer("/a~1b/c~0d/0")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"a/b", "c~d", "0"}, keys)
+
+	keys, err = parsePointer("")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 0, len(keys))
+
+	_, err = parsePointer("a/b")
+	assert.NotEqual(t, nil, err)
+}
@@ -43,8 +43,12 @@ func (nwk *nodewalker) walk(node ast.Node) {
 			origin := nwk.pos
 			oriTags := nwk.tags
 			nwk.pos = append(nwk.pos, labelStr(n.Label))
-			tags := findCommentTag(n.Comments())
-			for tk, tv := range tags {
+			// the tags of the field only apply to its value
+			nwk.tags = map[string]string{}
+			for tk, tv := range oriTags {
+				nwk.tags[tk] = tv
+			}
+			for tk, tv := range findCommentTag(n.Comments()) {
 				nwk.tags[tk] = tv
 			}
 
@@ -3,9 +3,13 @@ package process
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 	"unicode"
 
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
 	"github.com/oam-dev/kubevela/pkg/dsl/model"
 )
 
@@ -20,8 +24,52 @@ const (
 	ContextName = "name"
 	// ContextAppName is the appName of context
 	ContextAppName = "appName"
+	// ContextNamespace is the namespace the application is deployed to
+	ContextNamespace = "namespace"
+	// ContextAppRevision is the name of the application revision the component is rendered to
+	ContextAppRevision = "appRevision"
+	// ContextAppRevisionNum is the number of the application revision the component is rendered to
+	ContextAppRevisionNum = "appRevisionNum"
+	// ContextAppLabels is the labels of the application
+	ContextAppLabels = "appLabels"
+	// ContextAppAnnotations is the annotations of the application
+	ContextAppAnnotations = "appAnnotations"
+	// ContextScopes is the scopes of the component, the keys are the types of the scopes and the values are their names
+	ContextScopes = "scopes"
+	// ContextComponents is the components of the application
+	ContextComponents = "components"
+	// ContextComponentType is the workload type of a component in context.components
+	ContextComponentType = "type"
 )
 
+// ContextField describes a field of the context the templates read
+type ContextField struct {
+	Name        string
+	Type        string
+	Description string
+}
+
+// ContextFields are the fields of the context the templates read
+var ContextFields = []ContextField{
+	{Name: ContextName, Type: "string", Description: "the name of the component"},
+	{Name: ContextAppName, Type: "string", Description: "the name of the application"},
+	{Name: ContextNamespace, Type: "string", Description: "the namespace the application is deployed to"},
+	{Name: ContextAppRevision, Type: "string", Description: "the name of the application revision the component is rendered to, " +
+		"it's not set out of the controller"},
+	{Name: ContextAppRevisionNum, Type: "int", Description: "the number of the application revision the component is rendered to, " +
+		"it's not set out of the controller"},
+	{Name: ContextAppLabels, Type: "{[string]: string}", Description: "the labels of the application"},
+	{Name: ContextAppAnnotations, Type: "{[string]: string}", Description: "the annotations of the application"},
+	{Name: ContextScopes, Type: "{[string]: string}", Description: "the scopes of the component, the keys are the types of the scopes " +
+		"and the values are their names"},
+	{Name: ContextComponents, Type: "{[string]: {type: string, output?: {...}}}", Description: "the other components of the " +
+		"application by their names, the output is the workload of a component before this one"},
+	{Name: OutputFieldName, Type: "{...}", Description: "the workload rendered from the component, the traits read it"},
+	{Name: OutputsFieldName, Type: "{[string]: {...}}", Description: "the resources in the outputs of the workload and the traits " +
+		"rendered before"},
+	{Name: ConfigFieldName, Type: "[...{name: string, value: string}]", Description: "the config of the component"},
+}
+
 // Context defines Rendering Context Interface
 type Context interface {
 	SetBase(base model.Instance)
@@ -30,6 +78,25 @@ type Context interface {
 	Output() (model.Instance, []Auxiliary)
 	BaseContextFile() string
 	BaseContextLabels() map[string]string
+	// SetClient sets the client and the namespace the processing tasks in the templates use to read the cluster
+	SetClient(cli client.Reader, namespace string)
+	Client() client.Reader
+	Namespace() string
+	// SetDeadline sets the time the processing tasks in the templates must be done by, there's no deadline if it's zero
+	SetDeadline(deadline time.Time)
+	Deadline() time.Time
+	// SetAppRevision sets the application revision the component is rendered to
+	SetAppRevision(name string, revision int64)
+	// SetAppMeta sets the labels and the annotations of the application
+	SetAppMeta(labels, annotations map[string]string)
+	// SetScopes sets the scopes of the component, the keys are the types of the scopes and the values are their names
+	SetScopes(scopes map[string]string)
+	// AppendComponents adds the components of the application
+	AppendComponents(components ...Component)
+	// SetHealthCheck marks the context as the one of the health and status checks, the templates that are rendered
+	// before reuse the results of their processing tasks instead of running them again
+	SetHealthCheck(healthCheck bool)
+	IsHealthCheck() bool
 }
 
 // Auxiliary are objects rendered by definition template.
@@ -44,6 +111,15 @@ type Auxiliary struct {
 	Name string
 }
 
+// Component is a component of the application in the context
+type Component struct {
+	Name string
+	// Type is the workload type of the component
+	Type string
+	// Output is the workload rendered from the component, it's nil if the component is not rendered yet
+	Output model.Instance
+}
+
 type templateContext struct {
 	// name is the component name of Application
 	name string
@@ -53,7 +129,18 @@ type templateContext struct {
 	base        model.Instance
 	auxiliaries []Auxiliary
 
-	// TODO(wonderflow): add a revision number here, and it should be a suffix combined with appName to be the name of AppConfig
+	// client and namespace are used by the processing tasks
+	client    client.Reader
+	namespace string
+	deadline  time.Time
+
+	appRevision    string
+	appRevisionNum int64
+	appLabels      map[string]string
+	appAnnotations map[string]string
+	scopes         map[string]string
+	components     []Component
+	healthCheck    bool
 }
 
 // NewContext create render templateContext
@@ -81,11 +168,87 @@ func (ctx *templateContext) AppendAuxiliaries(auxiliaries ...Auxiliary) {
 	ctx.auxiliaries = append(ctx.auxiliaries, auxiliaries...)
 }
 
+// SetClient sets the client and the namespace of the processing tasks
+func (ctx *templateContext) SetClient(cli client.Reader, namespace string) {
+	ctx.client = cli
+	ctx.namespace = namespace
+}
+
+// Client returns the client of the processing tasks
+func (ctx *templateContext) Client() client.Reader {
+	return ctx.client
+}
+
+// Namespace returns the namespace of the processing tasks
+func (ctx *templateContext) Namespace() string {
+	return ctx.namespace
+}
+
+// SetDeadline sets the time the processing tasks must be done by
+func (ctx *templateContext) SetDeadline(deadline time.Time) {
+	ctx.deadline = deadline
+}
+
+// Deadline returns the time the processing tasks must be done by
+func (ctx *templateContext) Deadline() time.Time {
+	return ctx.deadline
+}
+
+// SetAppRevision sets the application revision the component is rendered to
+func (ctx *templateContext) SetAppRevision(name string, revision int64) {
+	ctx.appRevision = name
+	ctx.appRevisionNum = revision
+}
+
+// SetAppMeta sets the labels and the annotations of the application
+func (ctx *templateContext) SetAppMeta(labels, annotations map[string]string) {
+	ctx.appLabels = labels
+	ctx.appAnnotations = annotations
+}
+
+// SetScopes sets the scopes of the component
+func (ctx *templateContext) SetScopes(scopes map[string]string) {
+	ctx.scopes = scopes
+}
+
+// AppendComponents adds the components of the application
+func (ctx *templateContext) AppendComponents(components ...Component) {
+	ctx.components = append(ctx.components, components...)
+}
+
+// SetHealthCheck marks the context as the one of the health and status checks
+func (ctx *templateContext) SetHealthCheck(healthCheck bool) {
+	ctx.healthCheck = healthCheck
+}
+
+// IsHealthCheck returns true if the context is the one of the health and status checks
+func (ctx *templateContext) IsHealthCheck() bool {
+	return ctx.healthCheck
+}
+
 // BaseContextFile return cue format string of templateContext
 func (ctx *templateContext) BaseContextFile() string {
 	var buff string
-	buff += fmt.Sprintf(ContextName+": \"%s\"\n", ctx.name)
-	buff += fmt.Sprintf(ContextAppName+": \"%s\"\n", ctx.appName)
+	buff += fmt.Sprintf(ContextName+": %s\n", strconv.Quote(ctx.name))
+	buff += fmt.Sprintf(ContextAppName+": %s\n", strconv.Quote(ctx.appName))
+	buff += fmt.Sprintf(ContextNamespace+": %s\n", strconv.Quote(ctx.namespace))
+	if ctx.appRevision != "" {
+		buff += fmt.Sprintf(ContextAppRevision+": %s\n", strconv.Quote(ctx.appRevision))
+		buff += fmt.Sprintf(ContextAppRevisionNum+": %d\n", ctx.appRevisionNum)
+	}
+	buff += ContextAppLabels + ": " + mapMarshal(ctx.appLabels) + "\n"
+	buff += ContextAppAnnotations + ": " + mapMarshal(ctx.appAnnotations) + "\n"
+	buff += ContextScopes + ": " + mapMarshal(ctx.scopes) + "\n"
+
+	var compLines []string
+	for _, comp := range ctx.components {
+		line := fmt.Sprintf("%s: %s: %s", strconv.Quote(comp.Name), ContextComponentType, strconv.Quote(comp.Type))
+		if comp.Output != nil {
+			line += fmt.Sprintf("\n%s: %s: %s", strconv.Quote(comp.Name), OutputFieldName, structMarshal(comp.Output.String()))
+		}
+		compLines = append(compLines, line)
+	}
+	buff += fmt.Sprintf(ContextComponents+": {%s}\n", strings.Join(compLines, "\n"))
 
 	if ctx.base != nil {
 		buff += fmt.Sprintf(OutputFieldName+": %s\n", structMarshal(ctx.base.String()))
@@ -124,6 +287,14 @@ func (ctx *templateContext) Output() (model.Instance, []Auxiliary) {
 	return ctx.base, ctx.auxiliaries
 }
 
+func mapMarshal(m map[string]string) string {
+	if len(m) == 0 {
+		return "{}"
+	}
+	bt, _ := json.Marshal(m)
+	return string(bt)
+}
+
 func structMarshal(v string) string {
 	skip := false
 	v = strings.TrimFunc(v, func(r rune) bool {
@@ -51,6 +51,11 @@ image: "myserver"
 	ctx := NewContext("mycomp", "myapp")
 	ctx.SetBase(base)
 	ctx.AppendAuxiliaries(svcAux)
+	ctx.SetClient(nil, "prod")
+	ctx.SetAppRevision("myapp-v2", 2)
+	ctx.SetAppMeta(map[string]string{"team": "web"}, nil)
+	ctx.SetScopes(map[string]string{"healthscopes.core.oam.dev": "myscope"})
+	ctx.AppendComponents(Component{Name: "db", Type: "worker", Output: svcIns}, Component{Name: "cache", Type: "worker"})
 
 	ctxInst, err := r.Compile("-", ctx.BaseContextFile())
 	if err != nil {
@@ -73,4 +78,31 @@ image: "myserver"
 	outputsJs, err := ctxInst.Lookup("context", OutputsFieldName, "service").MarshalJSON()
 	assert.Equal(t, nil, err)
 	assert.Equal(t, "{\"apiVersion\":\"v1\",\"kind\":\"ConfigMap\"}", string(outputsJs))
+
+	namespace, err := ctxInst.Lookup("context", ContextNamespace).String()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "prod", namespace)
+
+	revision, err := ctxInst.Lookup("context", ContextAppRevision).String()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "myapp-v2", revision)
+	revisionNum, err := ctxInst.Lookup("context", ContextAppRevisionNum).Int64()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(2), revisionNum)
+
+	labelsJs, err := ctxInst.Lookup("context", ContextAppLabels).MarshalJSON()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, `{"team":"web"}`, string(labelsJs))
+	annotationsJs, err := ctxInst.Lookup("context", ContextAppAnnotations).MarshalJSON()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, `{}`, string(annotationsJs))
+
+	scope, err := ctxInst.Lookup("context", ContextScopes, "healthscopes.core.oam.dev").String()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "myscope", scope)
+
+	componentsJs, err := ctxInst.Lookup("context", ContextComponents).MarshalJSON()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, `{"db":{"type":"worker","output":{"apiVersion":"v1","kind":"ConfigMap"}},"cache":{"type":"worker"}}`,
+		string(componentsJs))
 }
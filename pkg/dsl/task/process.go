@@ -1,36 +1,140 @@
 package task
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"cuelang.org/go/cue"
 
 	"github.com/oam-dev/kubevela/pkg/builtin"
 	"github.com/oam-dev/kubevela/pkg/builtin/registry"
+	"github.com/oam-dev/kubevela/pkg/dsl/process"
 )
 
-// Process processing the http task
-func Process(inst *cue.Instance) (*cue.Instance, error) {
-	taskVal := inst.Lookup("processing", "http")
-	if !taskVal.Exists() {
-		return inst, errors.New("there is no http in processing")
+const (
+	// ProcessingFieldName is the name of the struct contains the processing tasks
+	ProcessingFieldName = "processing"
+	// TasksFieldName is the name of the struct contains the named processing tasks
+	TasksFieldName = "tasks"
+	// TaskTypeFieldName is the name of the field that tells which runner runs the task
+	TaskTypeFieldName = "type"
+	// TaskResultFieldName is the name of the field the result of the task is filled into
+	TaskResultFieldName = "result"
+)
+
+// Timeout is the max time the processing tasks of all the templates of an application take in a render, the tasks
+// still running when it's exceeded fail so that the reconcile isn't blocked, the application is rendered again later
+var Timeout = 30 * time.Second
+
+// Process runs the processing tasks in the template and fills their results into the template.
+// The `processing.http` task fills the json body it gets into `processing.output`.
+// The tasks in `processing.tasks` run with the runners of their types, a task runs once the tasks it depends on
+// are done and its parameters are complete, its result is filled into its `result` field.
+func Process(ctx process.Context, inst *cue.Instance) (*cue.Instance, error) {
+	taskCtx, cancel := context.WithCancel(context.Background())
+	if deadline := ctx.Deadline(); !deadline.IsZero() {
+		taskCtx, cancel = context.WithDeadline(taskCtx, deadline)
+	}
+	defer cancel()
+	if taskVal := inst.Lookup(ProcessingFieldName, "http"); taskVal.Exists() {
+		resp, err := exec(taskCtx, ctx, taskVal)
+		if err != nil {
+			return nil, fmt.Errorf("fail to exec http task, %w", err)
+		}
+		if inst, err = inst.Fill(resp, ProcessingFieldName, "output"); err != nil {
+			return nil, fmt.Errorf("fail to fill output from http, %w", err)
+		}
+	}
+	return runTasks(taskCtx, ctx, inst)
+}
+
+// runTasks runs the tasks whose parameters are complete until all of them are done
+func runTasks(taskCtx context.Context, ctx process.Context, inst *cue.Instance) (*cue.Instance, error) {
+	done := map[string]bool{}
+	for {
+		tasks := inst.Lookup(ProcessingFieldName, TasksFieldName)
+		if !tasks.Exists() {
+			return inst, nil
+		}
+		iter, err := tasks.Fields()
+		if err != nil {
+			return nil, fmt.Errorf("invalid processing tasks, %w", err)
+		}
+		var ran bool
+		var pending []string
+		var pendingErr error
+		for iter.Next() {
+			name := iter.Label()
+			if done[name] {
+				continue
+			}
+			if err := complete(iter.Value()); err != nil {
+				pending = append(pending, name)
+				pendingErr = err
+				continue
+			}
+			result, err := run(taskCtx, ctx, iter.Value())
+			if err != nil {
+				return nil, fmt.Errorf("fail to run processing task %s, %w", name, err)
+			}
+			if inst, err = inst.Fill(result, ProcessingFieldName, TasksFieldName, name, TaskResultFieldName); err != nil {
+				return nil, fmt.Errorf("fail to fill the result of processing task %s, %w", name, err)
+			}
+			done[name] = true
+			ran = true
+			// the tasks that depend on this one may be complete now
+			break
+		}
+		if ran {
+			continue
+		}
+		if len(pending) != 0 {
+			return nil, fmt.Errorf("processing tasks %s cannot run as their parameters are not complete, %w",
+				strings.Join(pending, ", "), pendingErr)
+		}
+		return inst, nil
 	}
-	resp, err := exec(taskVal)
+}
+
+// complete checks if all the parameters of the task are concrete
+func complete(v cue.Value) error {
+	iter, err := v.Fields()
 	if err != nil {
-		return nil, fmt.Errorf("fail to exec http task, %w", err)
+		return err
 	}
+	for iter.Next() {
+		if iter.Label() == TaskResultFieldName {
+			continue
+		}
+		if err := iter.Value().Validate(cue.Concrete(true)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	appInst, err := inst.Fill(resp, "processing", "output")
+func run(taskCtx context.Context, ctx process.Context, v cue.Value) (interface{}, error) {
+	taskType, err := v.Lookup(TaskTypeFieldName).String()
 	if err != nil {
-		return nil, fmt.Errorf("fail to fill output from http, %w", err)
+		return nil, fmt.Errorf("invalid type of the task, %w", err)
+	}
+	return builtin.RunTaskByKey(taskType, cue.Value{}, newMeta(taskCtx, ctx, v))
+}
+
+func newMeta(taskCtx context.Context, ctx process.Context, v cue.Value) *registry.Meta {
+	return &registry.Meta{
+		Context:   taskCtx,
+		Obj:       v,
+		Client:    ctx.Client(),
+		Namespace: ctx.Namespace(),
 	}
-	return appInst, nil
 }
 
-func exec(v cue.Value) (map[string]interface{}, error) {
-	got, err := builtin.RunTaskByKey("http", cue.Value{}, &registry.Meta{Obj: v})
+func exec(taskCtx context.Context, ctx process.Context, v cue.Value) (map[string]interface{}, error) {
+	got, err := builtin.RunTaskByKey("http", cue.Value{}, newMeta(taskCtx, ctx, v))
 	if err != nil {
 		return nil, err
 	}
@@ -7,10 +7,17 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"cuelang.org/go/cue"
 	cueJson "cuelang.org/go/pkg/encoding/json"
 	"github.com/bmizerany/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	velahttp "github.com/oam-dev/kubevela/pkg/builtin/http"
+	"github.com/oam-dev/kubevela/pkg/dsl/process"
 )
 
 const TaskTemplate = `
@@ -45,6 +52,12 @@ output: {
 func TestProcess(t *testing.T) {
 	s := NewMock()
 	defer s.Close()
+	opts := velahttp.DefaultOptions
+	opts.AllowedURLs = []string{"http://127.0.0.1:8090"}
+	if err := velahttp.SetOptions(opts); err != nil {
+		t.Fatal(err)
+	}
+	defer velahttp.SetOptions(velahttp.DefaultOptions)
 
 	r := cue.Runtime{}
 	taskTemplate, err := r.Compile("", TaskTemplate)
@@ -55,13 +68,77 @@ func TestProcess(t *testing.T) {
 		"serviceURL": "http://127.0.0.1:8090/api/v1/token?val=test-token",
 	}, "parameter")
 
-	inst, err := Process(taskTemplate)
+	inst, err := Process(process.NewContext("test", "test"), taskTemplate)
 	if err != nil {
 		t.Fatal(err)
 	}
 	output := inst.Lookup("output")
 	data, _ := cueJson.Marshal(output)
 	assert.Equal(t, "{\"data\":\"test-token\"}", data)
+
+	// the tasks fail once the deadline of the render is exceeded
+	ctx := process.NewContext("test", "test")
+	ctx.SetDeadline(time.Now().Add(-time.Second))
+	_, err = Process(ctx, taskTemplate)
+	assert.NotEqual(t, nil, err)
+}
+
+const TasksTemplate = `
+parameter: {
+  configName: string
+}
+
+processing: tasks: {
+  // the encoded task depends on the config task
+  encoded: {
+    type: "encode"
+    method: "base64"
+    data: processing.tasks.config.result.data.password
+  }
+  config: {
+    type: "secret"
+    name: parameter.configName
+  }
+}
+
+output: {
+  data: password: processing.tasks.encoded.result
+}
+`
+
+func TestProcessTasks(t *testing.T) {
+	cli := fake.NewFakeClient(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+		Data:       map[string][]byte{"password": []byte("secret")},
+	})
+	ctx := process.NewContext("test", "test")
+	ctx.SetClient(cli, "default")
+
+	r := cue.Runtime{}
+	tasksTemplate, err := r.Compile("", TasksTemplate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inst, err := tasksTemplate.Fill(map[string]interface{}{"configName": "db"}, "parameter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	inst, err = Process(ctx, inst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := cueJson.Marshal(inst.Lookup("output"))
+	assert.Equal(t, `{"data":{"password":"c2VjcmV0"}}`, data)
+
+	// the task never runs without its parameters
+	_, err = Process(ctx, tasksTemplate)
+	assert.NotEqual(t, nil, err)
+
+	// the secret can't be read from another namespace
+	ctx.SetClient(cli, "vela-system")
+	inst, _ = tasksTemplate.Fill(map[string]interface{}{"configName": "db"}, "parameter")
+	_, err = Process(ctx, inst)
+	assert.NotEqual(t, nil, err)
 }
 
 func NewMock() *httptest.Server {
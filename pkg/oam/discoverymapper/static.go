@@ -0,0 +1,59 @@
+package discoverymapper
+
+import (
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var _ DiscoveryMapper = &StaticDiscoveryMapper{}
+
+// StaticDiscoveryMapper is a K8s resource mapper for the fixed kinds, it works without a cluster
+type StaticDiscoveryMapper struct {
+	mapper meta.RESTMapper
+}
+
+// NewStatic creates a StaticDiscoveryMapper that knows the kinds of the scheme and the kinds of the CRDs,
+// the resources of the kinds in the scheme are guessed from the kinds like `Deployment` to `deployments`
+func NewStatic(scheme *runtime.Scheme, crds ...apiextensionsv1.CustomResourceDefinition) *StaticDiscoveryMapper {
+	mapper := meta.NewDefaultRESTMapper(scheme.PrioritizedVersionsAllGroups())
+	for gvk := range scheme.AllKnownTypes() {
+		if gvk.Version == runtime.APIVersionInternal {
+			continue
+		}
+		mapper.Add(gvk, meta.RESTScopeNamespace)
+	}
+	for _, crd := range crds {
+		scope := meta.RESTScopeNamespace
+		if crd.Spec.Scope == apiextensionsv1.ClusterScoped {
+			scope = meta.RESTScopeRoot
+		}
+		for _, v := range crd.Spec.Versions {
+			gv := schema.GroupVersion{Group: crd.Spec.Group, Version: v.Name}
+			mapper.AddSpecific(gv.WithKind(crd.Spec.Names.Kind), gv.WithResource(crd.Spec.Names.Plural),
+				gv.WithResource(crd.Spec.Names.Singular), scope)
+		}
+	}
+	return &StaticDiscoveryMapper{mapper: mapper}
+}
+
+// GetMapper returns the fixed restmapper
+func (d *StaticDiscoveryMapper) GetMapper() (meta.RESTMapper, error) {
+	return d.mapper, nil
+}
+
+// Refresh returns the fixed restmapper as the kinds never change
+func (d *StaticDiscoveryMapper) Refresh() (meta.RESTMapper, error) {
+	return d.mapper, nil
+}
+
+// RESTMapping will mapping resource information from GVK
+func (d *StaticDiscoveryMapper) RESTMapping(gk schema.GroupKind, version ...string) (*meta.RESTMapping, error) {
+	return d.mapper.RESTMapping(gk, version...)
+}
+
+// KindsFor will get kinds from GroupVersionResource
+func (d *StaticDiscoveryMapper) KindsFor(input schema.GroupVersionResource) ([]schema.GroupVersionKind, error) {
+	return d.mapper.KindsFor(input)
+}
@@ -29,6 +29,11 @@ const (
 	LabelOAMResourceType = "app.oam.dev/resourceType"
 	// LabelAppConfigHash records the Hash value of the application configuration
 	LabelAppConfigHash = "app.oam.dev/appConfig-hash"
+	// LabelAppSpecHash records the Hash value of the application spec that an application revision is generated from
+	LabelAppSpecHash = "app.oam.dev/app-spec-hash"
+
+	// LabelRolloutSelectedPod marks the pods that the current rollout batch selects by name
+	LabelRolloutSelectedPod = "app.oam.dev/rollout-selected-pod"
 
 	// WorkloadTypeLabel indicates the type of the workloadDefinition
 	WorkloadTypeLabel = "workload.oam.dev/type"
@@ -66,4 +71,20 @@ const (
 	// this is to enable any concerned controllers to handle the first component apply logic differently
 	// the value of the annotation is a list of revision name of all the new component
 	AnnotationRollingComponent = "app.oam.dev/new-components"
+
+	// AnnotationInPlaceUpgrade indicates that the workloads of the application configuration are upgraded in place
+	// by a rollout, the apps/v1 statefulSets are named after their components instead of the component revisions
+	AnnotationInPlaceUpgrade = "app.oam.dev/inplace-upgrade"
+
+	// AnnotationRolloutApprovedBatch approves the rollout batch that is waiting for a manual approval
+	// the value is the number of the batch, the rollout controller removes it once the batch is approved
+	AnnotationRolloutApprovedBatch = "app.oam.dev/rollout-approved-batch"
+
+	// AnnotationRolloutAbort indicates that the operator wants to abort the ongoing rollout
+	// the rollout controller fails the rollout and removes it
+	AnnotationRolloutAbort = "app.oam.dev/rollout-abort"
+
+	// AnnotationRolloutUndoRevision pins the application to an old appConfig revision that the operator rolls back to
+	// the application controller removes it once the application spec changes
+	AnnotationRolloutUndoRevision = "app.oam.dev/rollout-undo-revision"
 )
@@ -21,6 +21,8 @@ type Template struct {
 	Health             string
 	CustomStatus       string
 	CapabilityCategory types.CapabilityCategory
+	// Generation is the generation of the definition the template is loaded from
+	Generation int64
 }
 
 // GetScopeGVK Get ScopeDefinition
@@ -36,6 +38,11 @@ func GetScopeGVK(cli client.Client, dm discoverymapper.DiscoveryMapper,
 	return GetGVKFromDefinition(dm, sd.Spec.Reference)
 }
 
+// GenDefinitionSchemaName generates the name of the ConfigMap that stores the OpenAPI v3 schema of a definition
+func GenDefinitionSchemaName(kd types.CapType, name string) string {
+	return fmt.Sprintf("%s-%s-%s", types.SchemaConfigMapPrefix, kd, name)
+}
+
 // LoadTemplate Get template according to key
 func LoadTemplate(cli client.Reader, key string, kd types.CapType) (*Template, error) {
 	switch kd {
@@ -56,6 +63,7 @@ func LoadTemplate(cli client.Reader, key string, kd types.CapType) (*Template, e
 			return nil, errors.New("no template found in definition")
 		}
 		tmpl.CapabilityCategory = capabilityCategory
+		tmpl.Generation = wd.Generation
 		return tmpl, nil
 
 	case types.TypeTrait:
@@ -75,6 +83,7 @@ func LoadTemplate(cli client.Reader, key string, kd types.CapType) (*Template, e
 			return nil, errors.New("no template found in definition")
 		}
 		tmpl.CapabilityCategory = capabilityCategory
+		tmpl.Generation = td.Generation
 		return tmpl, nil
 	case types.TypeScope:
 		// TODO: add scope template support
@@ -12,11 +12,13 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 
 	"cuelang.org/go/cue"
 	"cuelang.org/go/cue/load"
 	"cuelang.org/go/encoding/openapi"
 	"github.com/AlecAivazis/survey/v2"
+	"github.com/getkin/kin-openapi/openapi3"
 	certmanager "github.com/wonderflow/cert-manager-api/pkg/apis/certmanager/v1"
 	k8sruntime "k8s.io/apimachinery/pkg/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
@@ -28,6 +30,15 @@ import (
 	mycue "github.com/oam-dev/kubevela/pkg/cue"
 )
 
+const (
+	// UsageTag is usage comment annotation
+	UsageTag = "+usage="
+	// ShortTag is the short alias annotation
+	ShortTag = "+short"
+
+	parameterFieldName = "parameter"
+)
+
 var (
 	// Scheme defines the default KubeVela schema
 	Scheme = k8sruntime.NewScheme()
@@ -115,6 +126,62 @@ func GenOpenAPI(inst *cue.Instance) ([]byte, error) {
 	return out.Bytes(), nil
 }
 
+// GenOpenAPIFromTemplate generates the OpenAPI v3 schema of the `parameter` of a definition template, the comments
+// of the parameters are cleaned up to be their descriptions. It returns nil if the template has no parameter.
+func GenOpenAPIFromTemplate(template string) ([]byte, error) {
+	var r cue.Runtime
+	inst, err := r.Compile("-", template+mycue.BaseTemplate)
+	if err != nil {
+		return nil, err
+	}
+	if !inst.Lookup(parameterFieldName).Exists() {
+		return nil, nil
+	}
+	// only the definitions are generated, the parameter is copied as one
+	inst, err = r.Compile("-", template+mycue.BaseTemplate+fmt.Sprintf("\n#%s: %s\n", parameterFieldName, parameterFieldName))
+	if err != nil {
+		return nil, err
+	}
+	b, err := openapi.Gen(inst, &openapi.Config{ExpandReferences: true})
+	if err != nil {
+		return nil, err
+	}
+	swagger, err := openapi3.NewSwaggerLoader().LoadSwaggerFromData(b)
+	if err != nil {
+		return nil, err
+	}
+	schema := swagger.Components.Schemas[parameterFieldName].Value
+	FixOpenAPISchema("", schema)
+	return schema.MarshalJSON()
+}
+
+// FixOpenAPISchema fixes tainted `description` filed, missing of title `field`.
+func FixOpenAPISchema(name string, schema *openapi3.Schema) {
+	t := schema.Type
+	switch t {
+	case "object":
+		for k, v := range schema.Properties {
+			s := v.Value
+			FixOpenAPISchema(k, s)
+		}
+	case "array":
+		FixOpenAPISchema("", schema.Items.Value)
+	}
+	if name != "" {
+		schema.Title = name
+	}
+
+	description := schema.Description
+	if strings.Contains(description, UsageTag) {
+		description = strings.Split(description, UsageTag)[1]
+	}
+	if strings.Contains(description, ShortTag) {
+		description = strings.Split(description, ShortTag)[0]
+		description = strings.TrimSpace(description)
+	}
+	schema.Description = description
+}
+
 // GenOpenAPIFromFile generates OpenAPI json schema from cue file
 func GenOpenAPIFromFile(filePath string, fileName string) ([]byte, error) {
 	filename := filepath.FromSlash(fileName)
@@ -42,6 +42,84 @@ func ValidateCreate(rollout *v1alpha1.RolloutPlan, rootPath *field.Path) field.E
 	// validate the webhooks
 	allErrs = append(allErrs, validateWebhook(rollout, rootPath)...)
 
+	// validate the progress deadlines
+	if rollout.ProgressDeadlineSeconds != nil && *rollout.ProgressDeadlineSeconds <= 0 {
+		allErrs = append(allErrs, field.Invalid(rootPath.Child("progressDeadlineSeconds"),
+			*rollout.ProgressDeadlineSeconds, "the progress deadline has to be positive"))
+	}
+	for i, rb := range rollout.RolloutBatches {
+		if rb.ProgressDeadlineSeconds != nil && *rb.ProgressDeadlineSeconds <= 0 {
+			allErrs = append(allErrs, field.Invalid(rootPath.Child("rolloutBatches").Index(i).
+				Child("progressDeadlineSeconds"), *rb.ProgressDeadlineSeconds,
+				"the progress deadline has to be positive"))
+		}
+	}
+
+	// validate the pod lists
+	allErrs = append(allErrs, validatePodList(rollout, rootPath)...)
+
+	// validate the canary metrics
+	allErrs = append(allErrs, validateCanaryMetrics(rollout.CanaryMetric, rootPath.Child("canaryMetric"))...)
+	for i, rb := range rollout.RolloutBatches {
+		allErrs = append(allErrs, validateCanaryMetrics(rb.CanaryMetric,
+			rootPath.Child("rolloutBatches").Index(i).Child("canaryMetric"))...)
+	}
+
+	// validate the traffic routing
+	if rollout.TrafficRouting != nil {
+		trafficPath := rootPath.Child("trafficRouting")
+		if len(rollout.TrafficRouting.RouteName) == 0 {
+			allErrs = append(allErrs, field.Required(trafficPath.Child("routeName"),
+				"the traffic routing needs the name of the route"))
+		}
+		if len(rollout.TrafficRouting.CanaryService.ServiceName) == 0 {
+			allErrs = append(allErrs, field.Required(trafficPath.Child("canaryService", "serviceName"),
+				"the traffic routing needs the canary service"))
+		}
+	}
+
+	return allErrs
+}
+
+// validatePodList makes sure that a batch either selects the pods by name or by number and no pod is selected twice
+func validatePodList(rollout *v1alpha1.RolloutPlan, rootPath *field.Path) (allErrs field.ErrorList) {
+	batchesPath := rootPath.Child("rolloutBatches")
+	selected := make(map[string]bool)
+	for i, rb := range rollout.RolloutBatches {
+		if len(rb.PodList) == 0 {
+			continue
+		}
+		if rb.Replicas != (intstr.IntOrString{}) {
+			allErrs = append(allErrs, field.Forbidden(batchesPath.Index(i).Child("podList"),
+				"the pod list and the replicas of a batch are mutually exclusive"))
+		}
+		for j, podName := range rb.PodList {
+			if selected[podName] {
+				allErrs = append(allErrs, field.Duplicate(batchesPath.Index(i).Child("podList").Index(j), podName))
+			}
+			selected[podName] = true
+		}
+	}
+	return allErrs
+}
+
+// validateCanaryMetrics makes sure that the metrics can be queried, the metric templates are not supported yet
+func validateCanaryMetrics(metrics []v1alpha1.CanaryMetric, metricsPath *field.Path) (allErrs field.ErrorList) {
+	for i, metric := range metrics {
+		if metric.TemplateRef != nil {
+			allErrs = append(allErrs, field.Forbidden(metricsPath.Index(i).Child("templateRef"),
+				"the metric templates are not supported, set the query and the metrics provider instead"))
+			continue
+		}
+		if len(metric.Query) == 0 {
+			allErrs = append(allErrs, field.Required(metricsPath.Index(i).Child("query"),
+				"the canary metric needs a query"))
+		}
+		if metric.MetricsProvider == nil {
+			allErrs = append(allErrs, field.Required(metricsPath.Index(i).Child("metricsProvider"),
+				"the canary metric needs a metrics provider"))
+		}
+	}
 	return allErrs
 }
 
@@ -54,6 +132,7 @@ func validateWebhook(rollout *v1alpha1.RolloutPlan, rootPath *field.Path) (allEr
 				allErrs = append(allErrs, field.Invalid(webhookPath.Index(i),
 					rw.Type, "the rollout webhook type can only be initialize or finalize webhook"))
 			}
+			allErrs = append(allErrs, validateWebhookOptions(rw, webhookPath.Index(i))...)
 			// TODO: check the URL/name uniqueness?
 		}
 	}
@@ -68,6 +147,8 @@ func validateWebhook(rollout *v1alpha1.RolloutPlan, rootPath *field.Path) (allEr
 					allErrs = append(allErrs, field.Invalid(rolloutBatchPath.Child("batchRolloutWebhooks").Index(j),
 						brw.Type, "the batch webhook type can only be pre or post batch webhook"))
 				}
+				allErrs = append(allErrs, validateWebhookOptions(brw,
+					rolloutBatchPath.Child("batchRolloutWebhooks").Index(j))...)
 				// TODO: check the URL/name uniqueness?
 			}
 		}
@@ -75,6 +156,26 @@ func validateWebhook(rollout *v1alpha1.RolloutPlan, rootPath *field.Path) (allEr
 	return allErrs
 }
 
+func validateWebhookOptions(rw v1alpha1.RolloutWebhook, webhookPath *field.Path) (allErrs field.ErrorList) {
+	if rw.TimeoutSeconds != nil && *rw.TimeoutSeconds <= 0 {
+		allErrs = append(allErrs, field.Invalid(webhookPath.Child("timeoutSeconds"), *rw.TimeoutSeconds,
+			"the webhook timeout has to be positive"))
+	}
+	if rw.MaxRetries != nil && *rw.MaxRetries < 0 {
+		allErrs = append(allErrs, field.Invalid(webhookPath.Child("maxRetries"), *rw.MaxRetries,
+			"the webhook max retries can not be negative"))
+	}
+	if rw.RetryBackoffSeconds != nil && *rw.RetryBackoffSeconds < 0 {
+		allErrs = append(allErrs, field.Invalid(webhookPath.Child("retryBackoffSeconds"), *rw.RetryBackoffSeconds,
+			"the webhook retry backoff can not be negative"))
+	}
+	if rw.SecretRef != nil && (len(rw.SecretRef.Name) == 0 || len(rw.SecretRef.Key) == 0) {
+		allErrs = append(allErrs, field.Required(webhookPath.Child("secretRef"),
+			"the webhook secret reference needs both the name and the key"))
+	}
+	return allErrs
+}
+
 // ValidateUpdate validate if one can change the rollout plan from the previous psec
 func ValidateUpdate(new *v1alpha1.RolloutPlan, prev *v1alpha1.RolloutPlan, rootPath *field.Path) field.ErrorList {
 	// makes sure the new rollout alone is valid
@@ -0,0 +1,37 @@
+package rollout
+
+import (
+	"testing"
+
+	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+)
+
+func TestValidateCanaryMetrics(t *testing.T) {
+	rollout := &v1alpha1.RolloutPlan{
+		CanaryMetric: []v1alpha1.CanaryMetric{{
+			Name:            "success-rate",
+			Query:           "success_rate",
+			MetricsProvider: &v1alpha1.MetricsProvider{Address: "http://prometheus:9090"},
+		}},
+		RolloutBatches: []v1alpha1.RolloutBatch{{
+			Replicas: intstr.FromInt(1),
+			CanaryMetric: []v1alpha1.CanaryMetric{{
+				Name:        "latency",
+				TemplateRef: &runtimev1alpha1.TypedReference{Kind: "MetricTemplate", Name: "latency"},
+			}, {
+				Name: "error-rate",
+			}},
+		}},
+	}
+	errs := ValidateCreate(rollout, field.NewPath("spec"))
+	assert.Len(t, errs, 3)
+	assert.Equal(t, "spec.rolloutBatches[0].canaryMetric[0].templateRef", errs[0].Field)
+	assert.Equal(t, field.ErrorTypeForbidden, errs[0].Type)
+	assert.Equal(t, "spec.rolloutBatches[0].canaryMetric[1].query", errs[1].Field)
+	assert.Equal(t, "spec.rolloutBatches[0].canaryMetric[1].metricsProvider", errs[2].Field)
+}
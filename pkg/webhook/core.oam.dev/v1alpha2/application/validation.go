@@ -4,8 +4,11 @@ import (
 	"k8s.io/apimachinery/pkg/util/validation/field"
 
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/types"
 	"github.com/oam-dev/kubevela/pkg/appfile"
+	"github.com/oam-dev/kubevela/pkg/dsl/definition"
 	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/webhook/common/rollout"
 )
 
 // ValidateCreate validates the Application on creation
@@ -13,12 +16,55 @@ func (h *ValidatingHandler) ValidateCreate(app *v1alpha2.Application) field.Erro
 	var componentErrs field.ErrorList
 	// try to generate an app file
 	appParser := appfile.NewApplicationParser(h.Client, h.dm)
-	if _, err := appParser.GenerateAppFile(app.Name, app); err != nil {
+	af, err := appParser.GenerateAppFile(app.Name, app)
+	if err != nil {
 		componentErrs = append(componentErrs, field.Invalid(field.NewPath("spec"), app, err.Error()))
+	} else {
+		componentErrs = append(componentErrs, validateParameters(af)...)
+	}
+	// the rollout plan is driven by the application controller
+	if app.Spec.RolloutPlan != nil {
+		componentErrs = append(componentErrs, rollout.ValidateCreate(app.Spec.RolloutPlan,
+			field.NewPath("spec", "rolloutPlan"))...)
 	}
 	return componentErrs
 }
 
+// validateParameters checks the settings of the components and the properties of their traits
+// against the parameters of their definitions
+func validateParameters(af *appfile.Appfile) field.ErrorList {
+	var errs field.ErrorList
+	compsPath := field.NewPath("spec", "components")
+	for i, wl := range af.Workloads {
+		settings := map[string]interface{}{}
+		for k, v := range wl.Params {
+			// the config is a built-in setting of the appfile
+			if k != appfile.AppfileBuiltinConfig {
+				settings[k] = v
+			}
+		}
+		errs = append(errs, validateDefinitionParameters(wl.CapabilityCategory, wl.Template, settings,
+			compsPath.Index(i).Child("settings"))...)
+		for j, tr := range wl.Traits {
+			errs = append(errs, validateDefinitionParameters(tr.CapabilityCategory, tr.Template, tr.Params,
+				compsPath.Index(i).Child("traits").Index(j).Child("properties"))...)
+		}
+	}
+	return errs
+}
+
+func validateDefinitionParameters(category types.CapabilityCategory, template string,
+	params map[string]interface{}, path *field.Path) field.ErrorList {
+	if template == "" || category == types.TerraformCategory {
+		return nil
+	}
+	errs, err := definition.ValidateParameters(template, params, path)
+	if err != nil {
+		return field.ErrorList{field.InternalError(path, err)}
+	}
+	return errs
+}
+
 // ValidateUpdate validates the Application on update
 func (h *ValidatingHandler) ValidateUpdate(newApp, oldApp *v1alpha2.Application) field.ErrorList {
 	// check if the newApp is valid
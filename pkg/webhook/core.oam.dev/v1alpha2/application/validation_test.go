@@ -0,0 +1,57 @@
+package application
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/appfile"
+)
+
+func TestValidateParameters(t *testing.T) {
+	af := &appfile.Appfile{
+		Name: "myapp",
+		Workloads: []*appfile.Workload{{
+			Name: "myweb",
+			Type: "worker",
+			Template: `
+output: {
+	apiVersion: "apps/v1"
+	kind:       "Deployment"
+	spec: template: spec: containers: [{name: context.name, image: parameter.image}]
+}
+parameter: image: string
+`,
+			Params: map[string]interface{}{"image": "busybox", "config": "myconfig"},
+			Traits: []*appfile.Trait{{
+				Name:     "ingress",
+				Template: "patch: {}\nparameter: {domain: string, port: *80 | int}\n",
+				Params:   map[string]interface{}{"domain": "example.com"},
+			}, {
+				Name:     "scaler",
+				Template: "patch: spec: replicas: parameter.replicas\nparameter: replicas: int\n",
+				Params:   map[string]interface{}{"replicas": "10", "replica": float64(10)},
+			}},
+		}, {
+			Name:               "mydb",
+			Type:               "rds",
+			CapabilityCategory: types.TerraformCategory,
+			Template:           `resource "alicloud_db_instance" "default" {}`,
+			Params:             map[string]interface{}{"name": "db"},
+		}, {
+			Name:     "mytask",
+			Type:     "task",
+			Template: "output: {}\nparameter: {image: string, count: *1 | int}\n",
+			Params:   map[string]interface{}{"count": float64(2)},
+		}},
+	}
+	propertiesPath := field.NewPath("spec", "components").Index(0).Child("traits").Index(1).Child("properties")
+	assert.Equal(t, field.ErrorList{
+		field.NotSupported(propertiesPath.Child("replica"), "replica", []string{"replicas"}),
+		field.Invalid(propertiesPath.Child("replicas"), "10",
+			`conflicting values int and "10" (mismatched types int and string)`),
+		field.Required(field.NewPath("spec", "components").Index(2).Child("settings", "image"), ""),
+	}, validateParameters(af))
+}
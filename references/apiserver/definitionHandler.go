@@ -7,7 +7,7 @@ import (
 	"github.com/oam-dev/kubevela/references/common"
 )
 
-// GetDefinition gets OpenAPI schema from Cue section of a WorkloadDefinition/TraitDefinition
+// GetDefinition gets OpenAPI schema of the parameter of a WorkloadDefinition/TraitDefinition from its schema ConfigMap
 // @tags definitions
 // @ID GetDefinition
 // @Summary gets OpenAPI schema from Cue section of a WorkloadDefinition/TraitDefinition
@@ -17,7 +17,7 @@ import (
 // @Router /definitions/{definitionName} [get]
 func (s *APIServer) GetDefinition(c *gin.Context) {
 	definitionName := c.Param("name")
-	parameter, err := common.GetDefinition(definitionName)
+	parameter, err := common.GetDefinition(c.Request.Context(), s.KubeClient, definitionName)
 	if err != nil {
 		util.HandleError(c, util.StatusInternalServerError, err)
 		return
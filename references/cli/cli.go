@@ -64,6 +64,7 @@ func NewCommand() *cobra.Command {
 		NewListCommand(commandArgs, ioStream),
 		NewDeleteCommand(commandArgs, ioStream),
 		NewAppStatusCommand(commandArgs, ioStream),
+		NewRolloutCommand(commandArgs, ioStream),
 		NewExecCommand(commandArgs, ioStream),
 		NewPortForwardCommand(commandArgs, ioStream),
 		NewLogsCommand(commandArgs, ioStream),
@@ -72,6 +73,7 @@ func NewCommand() *cobra.Command {
 
 		// Capabilities
 		CapabilityCommandGroup(commandArgs, ioStream),
+		NewDefinitionCommandGroup(commandArgs, ioStream),
 		NewTemplateCommand(ioStream),
 		NewTraitsCommand(commandArgs, ioStream),
 		NewWorkloadsCommand(commandArgs, ioStream),
@@ -0,0 +1,531 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/dsl/definition"
+	"github.com/oam-dev/kubevela/pkg/dsl/process"
+	cmdutil "github.com/oam-dev/kubevela/pkg/utils/util"
+)
+
+const workloadScaffold = `output: {
+	apiVersion: "apps/v1"
+	kind:       "Deployment"
+	spec: {
+		selector: matchLabels: "app.oam.dev/component": context.name
+		template: {
+			metadata: labels: "app.oam.dev/component": context.name
+			spec: containers: [{
+				name:  context.name
+				image: parameter.image
+			}]
+		}
+	}
+}
+parameter: {
+	// +usage=Which image would you like to use for your service
+	image: string
+}
+`
+
+const traitScaffold = `patch: spec: replicas: parameter.replicas
+parameter: {
+	// +usage=Specify the number of workload
+	replicas: *1 | int
+}
+`
+
+// NewDefinitionCommandGroup creates `def` command and its nested children command
+func NewDefinitionCommandGroup(c types.Args, ioStreams cmdutil.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "def",
+		Short: "Author, check and apply the definitions written in CUE files",
+		Long: "Author, check and apply the WorkloadDefinitions and TraitDefinitions written in CUE files, " +
+			"a file describes the definition and holds its template.",
+		Annotations: map[string]string{
+			types.TagCommandType: types.TypeCap,
+		},
+	}
+	cmd.AddCommand(
+		NewDefinitionInitCommand(ioStreams),
+		NewDefinitionVetCommand(ioStreams),
+		NewDefinitionRenderCommand(ioStreams),
+		NewDefinitionTestCommand(ioStreams),
+		NewDefinitionApplyCommand(c, ioStreams),
+		NewDefinitionGetCommand(c, ioStreams),
+		NewDefinitionEditCommand(c, ioStreams),
+	)
+	return cmd
+}
+
+// NewDefinitionInitCommand creates `def init` command
+func NewDefinitionInitCommand(ioStreams cmdutil.IOStreams) *cobra.Command {
+	var capType, description, definitionRef, output string
+	cmd := &cobra.Command{
+		Use:     "init <name>",
+		Short:   "Scaffold a definition file",
+		Long:    "Scaffold a definition file with a sample template of the workload or trait.",
+		Example: "vela def init my-worker --type workload --definition-ref deployments.apps -o my-worker.cue",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f := &definition.File{Name: args[0], Type: types.CapType(capType), Description: description}
+			switch f.Type {
+			case types.TypeWorkload:
+				f.Template = workloadScaffold
+			case types.TypeTrait:
+				f.Template = traitScaffold
+			default:
+				return errors.Errorf("unknown type %s, it must be %s or %s", capType, types.TypeWorkload, types.TypeTrait)
+			}
+			if definitionRef != "" {
+				f.Attributes = map[string]interface{}{"definitionRef": map[string]interface{}{"name": definitionRef}}
+			}
+			content, err := f.Format()
+			if err != nil {
+				return err
+			}
+			if output == "" {
+				ioStreams.Infonln(string(content))
+				return nil
+			}
+			if err := ioutil.WriteFile(output, content, 0600); err != nil {
+				return err
+			}
+			ioStreams.Infof("Definition %s is created in %s\n", f.Name, output)
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&capType, "type", "t", string(types.TypeWorkload), "type of the definition, workload or trait")
+	cmd.Flags().StringVar(&description, "desc", "", "description of the definition")
+	cmd.Flags().StringVar(&definitionRef, "definition-ref", "", "name of the CRD that the definition refers to, like deployments.apps")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "the file to write, the definition is printed if it's not set")
+	cmd.SetOut(ioStreams.Out)
+	return cmd
+}
+
+// NewDefinitionVetCommand creates `def vet` command
+func NewDefinitionVetCommand(ioStreams cmdutil.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "vet <file>...",
+		Short: "Check the definition files",
+		Long: "Check the definition files, the templates are rendered without the parameters to find the syntax " +
+			"errors and the conflicting values.",
+		Example: "vela def vet my-worker.cue my-scaler.cue",
+		Args:    cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			for _, file := range args {
+				f, err := readDefinitionFile(file)
+				if err != nil {
+					return err
+				}
+				if err := f.Vet(); err != nil {
+					return errors.WithMessagef(err, "invalid definition file %s", file)
+				}
+				ioStreams.Infof("Validation %s succeeded\n", file)
+			}
+			return nil
+		},
+	}
+	cmd.SetOut(ioStreams.Out)
+	return cmd
+}
+
+// NewDefinitionRenderCommand creates `def render` command
+func NewDefinitionRenderCommand(ioStreams cmdutil.IOStreams) *cobra.Command {
+	var paramFile, workloadFile, compName, appName string
+	cmd := &cobra.Command{
+		Use:   "render <file>",
+		Short: "Render the template of a definition file with the sample parameters",
+		Long: "Render the template of a definition file with the sample parameters in the context of a component, " +
+			"a trait patches the sample workload. The rendered objects are printed in yaml.",
+		Example: "vela def render my-worker.cue -p params.yaml\nvela def render my-scaler.cue -p params.yaml -w deployment.yaml",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f, err := readDefinitionFile(args[0])
+			if err != nil {
+				return err
+			}
+			params := map[string]interface{}{}
+			if paramFile != "" {
+				if err := readYAMLFile(paramFile, &params); err != nil {
+					return errors.WithMessagef(err, "read parameters %s", paramFile)
+				}
+			}
+			ctx := process.NewContext(compName, appName)
+			if f.Type == types.TypeTrait {
+				workload := map[string]interface{}{}
+				if workloadFile != "" {
+					if err := readYAMLFile(workloadFile, &workload); err != nil {
+						return errors.WithMessagef(err, "read workload %s", workloadFile)
+					}
+				}
+				base, err := definition.NewBaseObject(workload)
+				if err != nil {
+					return err
+				}
+				ctx.SetBase(base)
+			}
+			if err := f.Render(ctx, params); err != nil {
+				return err
+			}
+			result, err := renderedObjects(ctx)
+			if err != nil {
+				return err
+			}
+			ioStreams.Infonln(result)
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&paramFile, "parameters", "p", "", "the yaml or json file of the parameters")
+	cmd.Flags().StringVarP(&workloadFile, "workload", "w", "", "the yaml or json file of the workload that a trait patches")
+	cmd.Flags().StringVar(&compName, "component", "my-component", "name of the component in the context")
+	cmd.Flags().StringVar(&appName, "app", "my-app", "name of the application in the context")
+	cmd.SetOut(ioStreams.Out)
+	return cmd
+}
+
+// NewDefinitionTestCommand creates `def test` command
+func NewDefinitionTestCommand(ioStreams cmdutil.IOStreams) *cobra.Command {
+	var verbose bool
+	cmd := &cobra.Command{
+		Use:   "test [path]...",
+		Short: "Run the test cases of the definition files",
+		Long: "Run the test cases in the " + definition.TestFileSuffix + " files, each file tests the definition file " +
+			"next to it. The template is rendered with the parameters, the context and the workload of a case, and the " +
+			"patch, output and outputs are compared with the expected ones. The directories are searched recursively, " +
+			"it's the current directory by default.",
+		Example: "vela def test\nvela def test ./definitions my-scaler_test.yaml",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				args = []string{"."}
+			}
+			files, err := definition.FindTestFiles(args...)
+			if err != nil {
+				return err
+			}
+			var passed, failed int
+			for _, file := range files {
+				results, err := definition.RunTestFile(file)
+				if err != nil {
+					return err
+				}
+				for _, r := range results {
+					if r.Passed() {
+						passed++
+						if verbose {
+							ioStreams.Info(r.String())
+						}
+						continue
+					}
+					failed++
+					ioStreams.Info(r.String())
+				}
+			}
+			ioStreams.Infof("%d passed, %d failed\n", passed, failed)
+			if failed > 0 {
+				return errors.Errorf("%d test cases failed", failed)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "print the passed test cases too")
+	cmd.SetOut(ioStreams.Out)
+	return cmd
+}
+
+// renderedObjects prints the workload and the other objects rendered in the context in yaml
+func renderedObjects(ctx process.Context) (string, error) {
+	base, auxiliaries := ctx.Output()
+	var objs []string
+	if base != nil {
+		obj, err := base.Unstructured()
+		if err != nil {
+			return "", errors.WithMessage(err, "render the workload")
+		}
+		b, err := yaml.Marshal(obj.Object)
+		if err != nil {
+			return "", err
+		}
+		objs = append(objs, string(b))
+	}
+	for _, aux := range auxiliaries {
+		obj, err := aux.Ins.Unstructured()
+		if err != nil {
+			return "", errors.WithMessagef(err, "render the output %s", aux.Name)
+		}
+		b, err := yaml.Marshal(obj.Object)
+		if err != nil {
+			return "", err
+		}
+		objs = append(objs, string(b))
+	}
+	return strings.Join(objs, "---\n"), nil
+}
+
+// NewDefinitionApplyCommand creates `def apply` command
+func NewDefinitionApplyCommand(c types.Args, ioStreams cmdutil.IOStreams) *cobra.Command {
+	var namespace string
+	var dryRun bool
+	cmd := &cobra.Command{
+		Use:     "apply <file>",
+		Short:   "Apply the definition file to the cluster",
+		Long:    "Convert the definition file into a WorkloadDefinition or a TraitDefinition and create or update it in the cluster.",
+		Example: "vela def apply my-worker.cue\nvela def apply my-worker.cue --dry-run",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f, err := readDefinitionFile(args[0])
+			if err != nil {
+				return err
+			}
+			if err := f.Vet(); err != nil {
+				return errors.WithMessagef(err, "invalid definition file %s", args[0])
+			}
+			obj, err := f.Object(namespace)
+			if err != nil {
+				return err
+			}
+			if dryRun {
+				b, err := yaml.Marshal(obj)
+				if err != nil {
+					return err
+				}
+				ioStreams.Infonln(string(b))
+				return nil
+			}
+			if err := c.SetConfig(); err != nil {
+				return err
+			}
+			newClient, err := client.New(c.Config, client.Options{Scheme: c.Schema})
+			if err != nil {
+				return err
+			}
+			if err := applyDefinition(context.Background(), newClient, obj); err != nil {
+				return err
+			}
+			ioStreams.Infof("%s definition %s is applied in namespace %s\n", f.Type, f.Name, namespace)
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&namespace, "namespace", "n", types.DefaultKubeVelaNS, "namespace of the definition")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "print the definition object instead of applying it")
+	cmd.SetOut(ioStreams.Out)
+	return cmd
+}
+
+// NewDefinitionGetCommand creates `def get` command
+func NewDefinitionGetCommand(c types.Args, ioStreams cmdutil.IOStreams) *cobra.Command {
+	var namespace, capType string
+	cmd := &cobra.Command{
+		Use:     "get <name>",
+		Short:   "Print a definition in the cluster as a definition file",
+		Long:    "Print a WorkloadDefinition or a TraitDefinition in the cluster as a definition file.",
+		Example: "vela def get webservice\nvela def get scaler --type trait > scaler.cue",
+		Args:    cobra.ExactArgs(1),
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return c.SetConfig()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			newClient, err := client.New(c.Config, client.Options{Scheme: c.Schema})
+			if err != nil {
+				return err
+			}
+			def, err := getDefinition(context.Background(), newClient, args[0], types.CapType(capType), namespace)
+			if err != nil {
+				return err
+			}
+			f, err := definition.NewFile(def)
+			if err != nil {
+				return err
+			}
+			content, err := f.Format()
+			if err != nil {
+				return err
+			}
+			ioStreams.Infonln(string(content))
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&namespace, "namespace", "n", types.DefaultKubeVelaNS, "namespace of the definition")
+	cmd.Flags().StringVarP(&capType, "type", "t", "", "type of the definition, workload or trait, both are searched if it's not set")
+	cmd.SetOut(ioStreams.Out)
+	return cmd
+}
+
+// NewDefinitionEditCommand creates `def edit` command
+func NewDefinitionEditCommand(c types.Args, ioStreams cmdutil.IOStreams) *cobra.Command {
+	var namespace, capType string
+	cmd := &cobra.Command{
+		Use:   "edit <name>",
+		Short: "Edit a definition in the cluster as a definition file",
+		Long: "Edit a WorkloadDefinition or a TraitDefinition in the cluster as a definition file with the editor " +
+			"in the EDITOR environment variable, the definition is updated after the editor exits.",
+		Example: "EDITOR=vim vela def edit webservice",
+		Args:    cobra.ExactArgs(1),
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return c.SetConfig()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			newClient, err := client.New(c.Config, client.Options{Scheme: c.Schema})
+			if err != nil {
+				return err
+			}
+			def, err := getDefinition(ctx, newClient, args[0], types.CapType(capType), namespace)
+			if err != nil {
+				return err
+			}
+			f, err := definition.NewFile(def)
+			if err != nil {
+				return err
+			}
+			content, err := f.Format()
+			if err != nil {
+				return err
+			}
+			edited, err := editInEditor(f.Name, content, ioStreams)
+			if err != nil {
+				return err
+			}
+			if string(edited) == string(content) {
+				ioStreams.Info("Edit cancelled, no changes made.")
+				return nil
+			}
+			f, err = definition.ParseFile(edited)
+			if err != nil {
+				return err
+			}
+			if err := f.Vet(); err != nil {
+				return errors.WithMessage(err, "invalid definition")
+			}
+			obj, err := f.Object(namespace)
+			if err != nil {
+				return err
+			}
+			if err := applyDefinition(ctx, newClient, obj); err != nil {
+				return err
+			}
+			ioStreams.Infof("%s definition %s is updated in namespace %s\n", f.Type, f.Name, namespace)
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&namespace, "namespace", "n", types.DefaultKubeVelaNS, "namespace of the definition")
+	cmd.Flags().StringVarP(&capType, "type", "t", "", "type of the definition, workload or trait, both are searched if it's not set")
+	cmd.SetOut(ioStreams.Out)
+	return cmd
+}
+
+// editInEditor opens the content in the editor and returns the edited content
+func editInEditor(name string, content []byte, ioStreams cmdutil.IOStreams) ([]byte, error) {
+	editor := os.Getenv("EDITOR")
+	if editor == "" {
+		editor = "vi"
+	}
+	tmp, err := ioutil.TempFile("", fmt.Sprintf("vela-def-%s-*.cue", name))
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck
+	if _, err := tmp.Write(content); err != nil {
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+	editorArgs := append(strings.Fields(editor), tmp.Name())
+	// #nosec
+	editCmd := exec.Command(editorArgs[0], editorArgs[1:]...)
+	editCmd.Stdin, editCmd.Stdout, editCmd.Stderr = os.Stdin, ioStreams.Out, ioStreams.ErrOut
+	if err := editCmd.Run(); err != nil {
+		return nil, errors.WithMessagef(err, "run editor %s", editor)
+	}
+	return ioutil.ReadFile(filepath.Clean(tmp.Name()))
+}
+
+func readDefinitionFile(file string) (*definition.File, error) {
+	content, err := ioutil.ReadFile(filepath.Clean(file))
+	if err != nil {
+		return nil, err
+	}
+	f, err := definition.ParseFile(content)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "parse definition file %s", file)
+	}
+	return f, nil
+}
+
+func readYAMLFile(file string, obj interface{}) error {
+	content, err := ioutil.ReadFile(filepath.Clean(file))
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(content, obj)
+}
+
+// getDefinition gets the definition of the type in the namespace, both the WorkloadDefinition and
+// the TraitDefinition of the name are searched if the type is not set
+func getDefinition(ctx context.Context, cli client.Reader, name string, capType types.CapType,
+	namespace string) (runtime.Object, error) {
+	key := client.ObjectKey{Name: name, Namespace: namespace}
+	if capType == "" || capType == types.TypeWorkload {
+		wd := &v1alpha2.WorkloadDefinition{}
+		err := cli.Get(ctx, key, wd)
+		if err == nil {
+			return wd, nil
+		}
+		if !kerrors.IsNotFound(err) || capType == types.TypeWorkload {
+			return nil, err
+		}
+	}
+	if capType == "" || capType == types.TypeTrait {
+		td := &v1alpha2.TraitDefinition{}
+		if err := cli.Get(ctx, key, td); err != nil {
+			return nil, err
+		}
+		return td, nil
+	}
+	return nil, errors.Errorf("unknown type %s, it must be %s or %s", capType, types.TypeWorkload, types.TypeTrait)
+}
+
+// applyDefinition creates the definition or updates the one in the cluster
+func applyDefinition(ctx context.Context, cli client.Client, def runtime.Object) error {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(def)
+	if err != nil {
+		return err
+	}
+	obj := &unstructured.Unstructured{Object: content}
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(obj.GroupVersionKind())
+	err = cli.Get(ctx, client.ObjectKey{Name: obj.GetName(), Namespace: obj.GetNamespace()}, existing)
+	if kerrors.IsNotFound(err) {
+		return cli.Create(ctx, obj)
+	}
+	if err != nil {
+		return err
+	}
+	existing.Object["spec"] = obj.Object["spec"]
+	if description, ok := obj.GetAnnotations()[types.AnnDescription]; ok {
+		annotations := existing.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[types.AnnDescription] = description
+		existing.SetAnnotations(annotations)
+	}
+	return cli.Update(ctx, existing)
+}
@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/dsl/definition"
+	cmdutil "github.com/oam-dev/kubevela/pkg/utils/util"
+)
+
+func TestDefinitionCommands(t *testing.T) {
+	dir, err := ioutil.TempDir("", "def")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	buffer := &bytes.Buffer{}
+	ioStreams := cmdutil.IOStreams{In: os.Stdin, Out: buffer, ErrOut: buffer}
+	run := func(args ...string) error {
+		buffer.Reset()
+		cmd := NewDefinitionCommandGroup(types.Args{}, ioStreams)
+		cmd.SetArgs(args)
+		cmd.SetOut(buffer)
+		cmd.SetErr(buffer)
+		return cmd.Execute()
+	}
+
+	workerFile := filepath.Join(dir, "worker.cue")
+	scalerFile := filepath.Join(dir, "scaler.cue")
+	assert.NoError(t, run("init", "worker", "--desc", "My worker", "--definition-ref", "deployments.apps", "-o", workerFile))
+	assert.NoError(t, run("init", "scaler", "-t", "trait", "-o", scalerFile))
+	assert.Error(t, run("init", "mynet", "-t", "scope"))
+
+	assert.NoError(t, run("vet", workerFile, scalerFile))
+	assert.Contains(t, buffer.String(), "Validation "+scalerFile+" succeeded")
+	brokenFile := filepath.Join(dir, "broken.cue")
+	assert.NoError(t, ioutil.WriteFile(brokenFile, []byte("broken: type: \"workload\"\ntemplate: output: {a: 1, a: 2}\n"), 0600))
+	assert.Error(t, run("vet", brokenFile))
+
+	paramFile := filepath.Join(dir, "params.yaml")
+	assert.NoError(t, ioutil.WriteFile(paramFile, []byte("image: nginx\n"), 0600))
+	assert.NoError(t, run("render", workerFile, "-p", paramFile, "--component", "web"))
+	assert.Contains(t, buffer.String(), "image: nginx")
+	assert.Contains(t, buffer.String(), "app.oam.dev/component: web")
+	workloadFile := filepath.Join(dir, "deployment.yaml")
+	assert.NoError(t, ioutil.WriteFile(workloadFile, []byte(buffer.String()), 0600))
+	assert.NoError(t, ioutil.WriteFile(paramFile, []byte("replicas: 3\n"), 0600))
+	assert.NoError(t, run("render", scalerFile, "-p", paramFile, "-w", workloadFile))
+	assert.Contains(t, buffer.String(), "replicas: 3")
+	assert.Contains(t, buffer.String(), "image: nginx")
+
+	testFile := filepath.Join(dir, "scaler"+definition.TestFileSuffix)
+	assert.NoError(t, ioutil.WriteFile(testFile, []byte(`cases:
+- name: default replicas
+  workload:
+    kind: Deployment
+  expected:
+    output:
+      kind: Deployment
+      spec:
+        replicas: 1
+- name: scale to 3
+  parameter:
+    replicas: 3
+  workload:
+    kind: Deployment
+  expected:
+    patch:
+      spec:
+        replicas: 2
+`), 0600))
+	assert.Error(t, run("test", dir))
+	assert.Contains(t, buffer.String(), "--- FAIL: "+testFile+"/scale to 3\n    patch.spec.replicas: expected 2, got 3")
+	assert.Contains(t, buffer.String(), "1 passed, 1 failed")
+	content, err := ioutil.ReadFile(testFile)
+	assert.NoError(t, err)
+	assert.NoError(t, ioutil.WriteFile(testFile, bytes.Replace(content, []byte("replicas: 2"), []byte("replicas: 3"), 1), 0600))
+	assert.NoError(t, run("test", testFile, "-v"))
+	assert.Contains(t, buffer.String(), "--- PASS: "+testFile+"/scale to 3")
+	assert.Contains(t, buffer.String(), "2 passed, 0 failed")
+
+	assert.NoError(t, run("apply", workerFile, "--dry-run"))
+	assert.Contains(t, buffer.String(), "kind: WorkloadDefinition")
+	assert.Contains(t, buffer.String(), "name: deployments.apps")
+}
+
+func TestApplyAndGetDefinition(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, v1alpha2.SchemeBuilder.AddToScheme(scheme))
+	cli := fake.NewFakeClientWithScheme(scheme)
+
+	f := &definition.File{Name: "scaler", Type: types.TypeTrait, Description: "Scale the workload",
+		Template: "patch: spec: replicas: parameter.replicas\nparameter: replicas: *1 | int\n"}
+	obj, err := f.Object(types.DefaultKubeVelaNS)
+	assert.NoError(t, err)
+	assert.NoError(t, applyDefinition(ctx, cli, obj))
+
+	_, err = getDefinition(ctx, cli, "scaler", types.TypeWorkload, types.DefaultKubeVelaNS)
+	assert.True(t, kerrors.IsNotFound(err))
+	def, err := getDefinition(ctx, cli, "scaler", "", types.DefaultKubeVelaNS)
+	assert.NoError(t, err)
+	got, err := definition.NewFile(def)
+	assert.NoError(t, err)
+	assert.Equal(t, f, got)
+
+	// the definition is updated
+	f.Template = "patch: spec: replicas: parameter.replicas\nparameter: replicas: *2 | int\n"
+	obj, err = f.Object(types.DefaultKubeVelaNS)
+	assert.NoError(t, err)
+	assert.NoError(t, applyDefinition(ctx, cli, obj))
+	def, err = getDefinition(ctx, cli, "scaler", types.TypeTrait, types.DefaultKubeVelaNS)
+	assert.NoError(t, err)
+	assert.Equal(t, f.Template, def.(*v1alpha2.TraitDefinition).Spec.Template)
+}
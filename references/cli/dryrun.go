@@ -14,12 +14,15 @@ import (
 	"github.com/oam-dev/kubevela/apis/types"
 	"github.com/oam-dev/kubevela/pkg/appfile"
 	"github.com/oam-dev/kubevela/pkg/oam/discoverymapper"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
 	cmdutil "github.com/oam-dev/kubevela/pkg/utils/util"
 )
 
 type dryRunOptions struct {
 	cmdutil.IOStreams
 	applicationFile string
+	definitionPath  string
+	outputFormat    string
 }
 
 // NewDryRunCommand creates `dry-run` command
@@ -29,18 +32,22 @@ func NewDryRunCommand(c types.Args, ioStreams cmdutil.IOStreams) *cobra.Command
 		Use:                   "dry-run",
 		DisableFlagsInUseLine: true,
 		Short:                 "Dry Run an application, and output the conversion result to stdout",
-		Long:                  "Dry Run an application, and output the conversion result to stdout",
-		Example:               "vela dry-run",
+		Long: "Dry Run an application, and output the conversion result to stdout. The application is rendered " +
+			"with the definitions in the cluster, or with the definitions in the local files without a cluster if " +
+			"--definitions is set.",
+		Example: "vela dry-run\nvela dry-run -f app.yaml --definitions ./defs/ -o json",
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(o.definitionPath) != 0 {
+				// the local definitions don't need a cluster
+				return nil
+			}
 			return c.SetConfig()
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			newClient, err := client.New(c.Config, client.Options{Scheme: c.Schema})
-			if err != nil {
-				return err
+			if o.outputFormat != "yaml" && o.outputFormat != "json" {
+				return errors.Errorf("unknown output format %s, it must be yaml or json", o.outputFormat)
 			}
-
-			dm, err := discoverymapper.New(c.Config)
+			parser, err := o.newParser(c)
 			if err != nil {
 				return err
 			}
@@ -50,8 +57,6 @@ func NewDryRunCommand(c types.Args, ioStreams cmdutil.IOStreams) *cobra.Command
 				return errors.WithMessagef(err, "read application file: %s", o.applicationFile)
 			}
 
-			parser := appfile.NewApplicationParser(newClient, dm)
-
 			appFile, err := parser.GenerateAppFile(app.Name, app)
 			if err != nil {
 				return errors.WithMessage(err, "generate appFile")
@@ -62,14 +67,18 @@ func NewDryRunCommand(c types.Args, ioStreams cmdutil.IOStreams) *cobra.Command
 				return errors.WithMessage(err, "generate OAM objects")
 			}
 
-			var outs = []interface{}{ac}
-			for index := range comps {
-				outs = append(outs, comps[index])
+			outs, err := dryRunObjects(ac, comps)
+			if err != nil {
+				return err
+			}
+			var result []byte
+			if o.outputFormat == "json" {
+				result, err = json.MarshalIndent(outs, "", "  ")
+			} else {
+				result, err = yaml.Marshal(outs)
 			}
-
-			result, err := yaml.Marshal(outs)
 			if err != nil {
-				return errors.WithMessage(err, "marshal result object in yaml format")
+				return errors.WithMessagef(err, "marshal result object in %s format", o.outputFormat)
 			}
 			o.Info(string(result))
 			return nil
@@ -77,10 +86,59 @@ func NewDryRunCommand(c types.Args, ioStreams cmdutil.IOStreams) *cobra.Command
 	}
 
 	cmd.Flags().StringVarP(&o.applicationFile, "file", "f", "./app.yaml", "application file name")
+	cmd.Flags().StringVarP(&o.definitionPath, "definitions", "d", "",
+		"render with the definitions in the yaml or json files of the path instead of the ones in the cluster, "+
+			"it's a file or a directory")
+	cmd.Flags().StringVarP(&o.outputFormat, "output", "o", "yaml", "output format, yaml or json")
 	cmd.SetOut(ioStreams.Out)
 	return cmd
 }
 
+// newParser creates the parser that reads the definitions from the local files or the cluster
+func (o *dryRunOptions) newParser(c types.Args) (*appfile.Parser, error) {
+	if len(o.definitionPath) != 0 {
+		objs, err := appfile.ReadDefinitionFiles(o.definitionPath)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "read definitions: %s", o.definitionPath)
+		}
+		return appfile.NewLocalApplicationParser(objs...), nil
+	}
+	newClient, err := client.New(c.Config, client.Options{Scheme: c.Schema})
+	if err != nil {
+		return nil, err
+	}
+	dm, err := discoverymapper.New(c.Config)
+	if err != nil {
+		return nil, err
+	}
+	return appfile.NewApplicationParser(newClient, dm), nil
+}
+
+// dryRunObjects lists the appConfig, the components, and then the workloads and traits they create
+func dryRunObjects(ac *corev1alpha2.ApplicationConfiguration, comps []*corev1alpha2.Component) ([]interface{}, error) {
+	var outs = []interface{}{ac}
+	for index := range comps {
+		outs = append(outs, comps[index])
+	}
+	for _, comp := range comps {
+		workload, err := util.RawExtension2Unstructured(&comp.Spec.Workload)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid workload of component %s", comp.Name)
+		}
+		outs = append(outs, workload)
+	}
+	for _, acComp := range ac.Spec.Components {
+		for i := range acComp.Traits {
+			trait, err := util.RawExtension2Unstructured(&acComp.Traits[i].Trait)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "invalid trait of component %s", acComp.ComponentName)
+			}
+			outs = append(outs, trait)
+		}
+	}
+	return outs, nil
+}
+
 func readApplicationFromFile(filename string) (*corev1alpha2.Application, error) {
 
 	fileContent, err := ioutil.ReadFile(filepath.Clean(filename))
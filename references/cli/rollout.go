@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/types"
+	cmdutil "github.com/oam-dev/kubevela/pkg/utils/util"
+	"github.com/oam-dev/kubevela/references/common"
+)
+
+// NewRolloutCommand creates `rollout` command and its nested children commands
+func NewRolloutCommand(c types.Args, ioStreams cmdutil.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "rollout",
+		DisableFlagsInUseLine: true,
+		Short:                 "Manage the rollout of an application",
+		Long:                  "Approve, pause, resume, abort or undo the rollout of an application",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return c.SetConfig()
+		},
+		Annotations: map[string]string{
+			types.TagCommandType: types.TypeApp,
+		},
+	}
+	cmd.SetOut(ioStreams.Out)
+	var toRevision int64
+	undoCmd := newRolloutSubCommand(c, ioStreams, "undo", "Roll back the application to a previous revision",
+		func(o *common.RolloutOptions) (string, error) {
+			return o.UndoRollout(toRevision)
+		})
+	undoCmd.Flags().Int64Var(&toRevision, "to-revision", 0,
+		"the rollout in the history to go back to, default to undo the last rollout")
+	cmd.AddCommand(
+		newRolloutSubCommand(c, ioStreams, "approve", "Approve the rollout batch that is waiting for approval",
+			(*common.RolloutOptions).ApproveRolloutBatch),
+		newRolloutSubCommand(c, ioStreams, "pause", "Pause the rollout of an application",
+			(*common.RolloutOptions).PauseRollout),
+		newRolloutSubCommand(c, ioStreams, "resume", "Resume the paused rollout of an application",
+			(*common.RolloutOptions).ResumeRollout),
+		newRolloutSubCommand(c, ioStreams, "abort", "Abort the rollout of an application",
+			(*common.RolloutOptions).AbortRollout),
+		newRolloutHistoryCommand(c, ioStreams),
+		undoCmd,
+	)
+	return cmd
+}
+
+func newRolloutHistoryCommand(c types.Args, ioStreams cmdutil.IOStreams) *cobra.Command {
+	return newRolloutSubCommand(c, ioStreams, "history", "Show the rollout history of an application",
+		func(o *common.RolloutOptions) (string, error) {
+			history, err := o.GetRolloutHistory()
+			if err != nil {
+				return "", err
+			}
+			table := newUITable()
+			table.AddRow("REVISION", "SOURCE", "TARGET", "STATE", "FAILED-BATCH", "STARTED", "FINISHED")
+			for _, record := range history {
+				failedBatch, started := "", ""
+				if record.FailedBatch != nil {
+					failedBatch = strconv.Itoa(int(*record.FailedBatch))
+				}
+				if record.StartTime != nil {
+					started = record.StartTime.Format(time.RFC3339)
+				}
+				table.AddRow(record.Revision, record.SourceRevision, record.TargetRevision, record.FinalState,
+					failedBatch, started, record.EndTime.Format(time.RFC3339))
+			}
+			return table.String(), nil
+		})
+}
+
+func newRolloutSubCommand(c types.Args, ioStreams cmdutil.IOStreams, action, short string,
+	run func(o *common.RolloutOptions) (string, error)) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   action + " APP_NAME",
+		DisableFlagsInUseLine: true,
+		Short:                 short,
+		Long:                  short,
+		Example:               "vela rollout " + action + " frontend",
+		Annotations: map[string]string{
+			types.TagCommandType: types.TypeApp,
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return errors.New("must specify name for the app")
+			}
+			newClient, err := client.New(c.Config, client.Options{Scheme: c.Schema})
+			if err != nil {
+				return err
+			}
+			o := &common.RolloutOptions{AppName: args[0], Client: newClient}
+			o.Env, err = GetEnv(cmd)
+			if err != nil {
+				return err
+			}
+			info, err := run(o)
+			if err != nil {
+				return err
+			}
+			ioStreams.Info(info)
+			return nil
+		},
+	}
+	return cmd
+}
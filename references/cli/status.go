@@ -7,9 +7,11 @@ import (
 	"strings"
 	"time"
 
+	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
 	"github.com/fatih/color"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
@@ -161,9 +163,19 @@ func loopCheckStatus(ctx context.Context, c client.Client, ioStreams cmdutil.IOS
 			return err
 		}
 		// workload Must found
-		ioStreams.Infof("    Traits:\n")
 		workloadStatus, _ := getWorkloadStatusFromApp(remoteApp, compName)
+		for _, cond := range failedConditions(workloadStatus.ConditionedStatus) {
+			ioStreams.Infof("    %s%s: %s\n", emojiFail, red.Sprint(cond.Reason), cond.Message)
+		}
+		ioStreams.Infof("    Traits:\n")
 		for _, tr := range workloadStatus.Traits {
+			if conds := failedConditions(tr.ConditionedStatus); len(conds) != 0 {
+				for _, cond := range conds {
+					ioStreams.Infof("      - %s%s: %s: %s\n", emojiFail, white.Sprint(tr.Type), red.Sprint(cond.Reason),
+						cond.Message)
+				}
+				continue
+			}
 			if tr.Message != "" {
 				if tr.Healthy {
 					ioStreams.Infof("      - %s%s: %s", emojiSucceed, white.Sprint(tr.Type), tr.Message)
@@ -188,9 +200,29 @@ func loopCheckStatus(ctx context.Context, c client.Client, ioStreams cmdutil.IOS
 		ioStreams.Infof("    Last Deployment:\n")
 		ioStreams.Infof("      Created at: %v\n", remoteApp.CreationTimestamp)
 	}
+	printUnsatisfiedDependencies(ioStreams, remoteApp.Status.Dependency)
 	return nil
 }
 
+// printUnsatisfiedDependencies prints the inputs of the components that are still waiting for the outputs
+func printUnsatisfiedDependencies(ioStreams cmdutil.IOStreams, dependency v1alpha2.DependencyStatus) {
+	if len(dependency.Unsatisfied) == 0 {
+		return
+	}
+	ioStreams.Info("")
+	ioStreams.Infof("Unsatisfied Dependencies:\n\n")
+	for _, dep := range dependency.Unsatisfied {
+		ioStreams.Infof("  - %s%s/%s is waiting for %s/%s", emojiFail, dep.To.Kind, dep.To.Name, dep.From.Kind, dep.From.Name)
+		if len(dep.From.FieldPath) != 0 {
+			ioStreams.Infof(" %s", dep.From.FieldPath)
+		}
+		ioStreams.Info("")
+		if len(dep.Reason) != 0 {
+			ioStreams.Infof("    Reason: %s\n", dep.Reason)
+		}
+	}
+}
+
 func healthCheckLoop(ctx context.Context, c client.Client, compName, appName string, env *types.EnvMeta) (HealthStatus, string, error) {
 	// Health Check Loop For Workload
 	var healthInfo string
@@ -354,6 +386,17 @@ func getWorkloadStatusFromApp(app *v1alpha2.Application, compName string) (v1alp
 	return wlStatus, foundWlStatus
 }
 
+// failedConditions returns the conditions of a component or a trait that tell why it fails to render or to check health
+func failedConditions(status runtimev1alpha1.ConditionedStatus) []runtimev1alpha1.Condition {
+	var conds []runtimev1alpha1.Condition
+	for _, cond := range status.Conditions {
+		if cond.Status == corev1.ConditionFalse && cond.Reason != v1alpha2.ReasonUnhealthy {
+			conds = append(conds, cond)
+		}
+	}
+	return conds
+}
+
 func getHealthStatusColor(s HealthStatus) *color.Color {
 	var c *color.Color
 	switch s {
@@ -4,7 +4,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/oam-dev/kubevela/apis/types"
-	mycue "github.com/oam-dev/kubevela/pkg/cue"
+	"github.com/oam-dev/kubevela/pkg/dsl/process"
 	cmdutil "github.com/oam-dev/kubevela/pkg/utils/util"
 )
 
@@ -30,13 +30,18 @@ func NewTemplateContextCommand(ioStream cmdutil.IOStreams) *cobra.Command {
 		Use:                   "context",
 		DisableFlagsInUseLine: true,
 		Short:                 "Show context parameters",
-		Long:                  "Show context parameter",
+		Long:                  "Show the fields of the context that the templates read, e.g. context.appRevision",
 		Example:               `vela template context`,
 		Annotations: map[string]string{
 			types.TagCommandType: types.TypeSystem,
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			ioStream.Info(mycue.BaseTemplate)
+			table := newUITable()
+			table.AddRow("FIELD", "TYPE", "DESCRIPTION")
+			for _, field := range process.ContextFields {
+				table.AddRow("context."+field.Name, field.Type, field.Description)
+			}
+			ioStream.Info(table.String())
 			return nil
 		},
 	}
@@ -2,6 +2,7 @@ package common
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -10,8 +11,13 @@ import (
 	"strings"
 
 	"github.com/getkin/kin-openapi/openapi3"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/oam-dev/kubevela/apis/types"
 	mycue "github.com/oam-dev/kubevela/pkg/cue"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
 	"github.com/oam-dev/kubevela/pkg/utils/common"
 	"github.com/oam-dev/kubevela/pkg/utils/system"
 )
@@ -20,9 +26,9 @@ const (
 	// OpenAPISchemaDir is the folder name under ~/.vela/capabilities
 	OpenAPISchemaDir = "openapi"
 	// UsageTag is usage comment annotation
-	UsageTag = "+usage="
+	UsageTag = common.UsageTag
 	// ShortTag is the short alias annotation
-	ShortTag = "+short"
+	ShortTag = common.ShortTag
 )
 
 // OpenAPISchema is the struct for OpenAPI Schema generated by Cue OpenAPI
@@ -41,8 +47,34 @@ type Schemas struct {
 	Parameter map[string]interface{} `json:"parameter"`
 }
 
-// GetDefinition is the main function for GetDefinition API
-func GetDefinition(name string) ([]byte, error) {
+// GetDefinition returns the OpenAPI v3 schema of the parameter of a workload type or trait. The schema is read from
+// the ConfigMap the controller generates for the definition, it's generated from the local capability file only if
+// there's no cluster.
+func GetDefinition(ctx context.Context, c client.Reader, name string) ([]byte, error) {
+	if c == nil {
+		return getDefinitionFromLocal(name)
+	}
+	for _, kind := range []types.CapType{types.TypeWorkload, types.TypeTrait} {
+		var cm corev1.ConfigMap
+		err := c.Get(ctx, client.ObjectKey{Namespace: types.DefaultKubeVelaNS,
+			Name: util.GenDefinitionSchemaName(kind, name)}, &cm)
+		if kerrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		parameter, ok := cm.Data[types.OpenapiV3JSONSchema]
+		if !ok {
+			return nil, fmt.Errorf("the ConfigMap %s has no schema of %s", cm.Name, name)
+		}
+		return []byte(parameter), nil
+	}
+	return nil, fmt.Errorf("the schema of %s is not found, it's not a workload type or trait with a parameter", name)
+}
+
+// getDefinitionFromLocal generates the schema of the parameter from the local capability file
+func getDefinitionFromLocal(name string) ([]byte, error) {
 	openAPISchema, err := generateOpenAPISchemaFromCapabilityParameter(name)
 	if err != nil {
 		return nil, err
@@ -53,7 +85,7 @@ func GetDefinition(name string) ([]byte, error) {
 	}
 	schemaRef := swagger.Components.Schemas["parameter"]
 	schema := schemaRef.Value
-	fixOpenAPISchema("", schema)
+	common.FixOpenAPISchema("", schema)
 
 	parameter, err := schema.MarshalJSON()
 	if err != nil {
@@ -149,33 +181,6 @@ func appendCueReference(cueFile string) error {
 	return nil
 }
 
-// fixOpenAPISchema fixes tainted `description` filed, missing of title `field`.
-func fixOpenAPISchema(name string, schema *openapi3.Schema) {
-	t := schema.Type
-	switch t {
-	case "object":
-		for k, v := range schema.Properties {
-			s := v.Value
-			fixOpenAPISchema(k, s)
-		}
-	case "array":
-		fixOpenAPISchema("", schema.Items.Value)
-	}
-	if name != "" {
-		schema.Title = name
-	}
-
-	description := schema.Description
-	if strings.Contains(description, UsageTag) {
-		description = strings.Split(description, UsageTag)[1]
-	}
-	if strings.Contains(description, ShortTag) {
-		description = strings.Split(description, ShortTag)[0]
-		description = strings.TrimSpace(description)
-	}
-	schema.Description = description
-}
-
 // getParameterItemName gets the name of a parameter item
 func getParameterItemName(previousLine string) (string, error) {
 	// parse property name, like from `"cmd": {\n`
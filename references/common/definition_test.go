@@ -1,6 +1,7 @@
 package common
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -13,7 +14,13 @@ import (
 	"github.com/crossplane/crossplane-runtime/pkg/test"
 	"github.com/google/go-cmp/cmp"
 	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/utils/common"
 	"github.com/oam-dev/kubevela/pkg/utils/system"
 )
 
@@ -177,7 +184,7 @@ func TestFixOpenAPISchema(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			swagger, _ := openapi3.NewSwaggerLoader().LoadSwaggerFromFile(filepath.Join(TestDir, tc.inputFile))
 			schema := swagger.Components.Schemas["parameter"].Value
-			fixOpenAPISchema("", schema)
+			common.FixOpenAPISchema("", schema)
 			fixedSchema, _ := schema.MarshalJSON()
 			expectedSchema, _ := ioutil.ReadFile(filepath.Join(TestDir, tc.fixedFile))
 			assert.Equal(t, fixedSchema, expectedSchema)
@@ -231,3 +238,25 @@ func TestGetParameterFromOpenAPISchema(t *testing.T) {
 		})
 	}
 }
+
+func TestGetDefinition(t *testing.T) {
+	ctx := context.Background()
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "schema-trait-scaler", Namespace: types.DefaultKubeVelaNS},
+		Data:       map[string]string{types.OpenapiV3JSONSchema: `{"type":"object"}`},
+	}
+	broken := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "schema-workload-worker", Namespace: types.DefaultKubeVelaNS},
+	}
+	cli := fake.NewFakeClientWithScheme(clientgoscheme.Scheme, cm, broken)
+
+	parameter, err := GetDefinition(ctx, cli, "scaler")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"type":"object"}`, string(parameter))
+
+	_, err = GetDefinition(ctx, cli, "worker")
+	assert.EqualError(t, err, "the ConfigMap schema-workload-worker has no schema of worker")
+
+	_, err = GetDefinition(ctx, cli, "unknown")
+	assert.Error(t, err)
+}
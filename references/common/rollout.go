@@ -0,0 +1,218 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha2 "github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+// RolloutOptions is the options for the commands that drive a rollout
+type RolloutOptions struct {
+	AppName string
+	Client  client.Client
+	Env     *types.EnvMeta
+}
+
+// rolloutOwner is the object that carries the rollout plan of the app
+type rolloutOwner struct {
+	object oam.Object
+	plan   *v1alpha1.RolloutPlan
+	status v1alpha1.RolloutStatus
+}
+
+// getRolloutOwner gets the application deployment that rolls out the app, or the application itself
+// if it has a rollout plan
+func (o *RolloutOptions) getRolloutOwner(ctx context.Context) (*rolloutOwner, error) {
+	key := client.ObjectKey{Name: o.AppName, Namespace: o.Env.Namespace}
+	var appDeploy corev1alpha2.ApplicationDeployment
+	err := o.Client.Get(ctx, key, &appDeploy)
+	if err == nil {
+		return &rolloutOwner{object: &appDeploy, plan: &appDeploy.Spec.RolloutPlan,
+			status: appDeploy.Status.RolloutStatus}, nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("get the rollout of app %s err: %w", o.AppName, err)
+	}
+	var app corev1alpha2.Application
+	if err := o.Client.Get(ctx, key, &app); err != nil {
+		return nil, fmt.Errorf("get the rollout of app %s err: %w", o.AppName, err)
+	}
+	if app.Spec.RolloutPlan == nil {
+		return nil, fmt.Errorf("app %s does not have a rollout plan", o.AppName)
+	}
+	return &rolloutOwner{object: &app, plan: app.Spec.RolloutPlan, status: app.Status.RolloutStatus}, nil
+}
+
+// isRolloutInProgress checks if the rollout is moving forward to the target
+func isRolloutInProgress(status v1alpha1.RolloutStatus) bool {
+	switch status.RollingState {
+	case v1alpha1.VerifyingState, v1alpha1.InitializingState, v1alpha1.RollingInBatchesState,
+		v1alpha1.FinalisingState:
+		return true
+	default:
+		return false
+	}
+}
+
+// ApproveRolloutBatch approves the batch of the rollout that is waiting for approval
+func (o *RolloutOptions) ApproveRolloutBatch() (string, error) {
+	ctx := context.Background()
+	owner, err := o.getRolloutOwner(ctx)
+	if err != nil {
+		return "", err
+	}
+	status := owner.status
+	if status.RollingState != v1alpha1.RollingInBatchesState ||
+		status.BatchRollingState != v1alpha1.BatchWaitingApprovalState {
+		return "", fmt.Errorf("the rollout of app %s is not waiting for approval, rolling state = %s, batch state = %s",
+			o.AppName, status.RollingState, status.BatchRollingState)
+	}
+	meta := owner.object.GetAnnotations()
+	if meta == nil {
+		meta = make(map[string]string)
+	}
+	meta[oam.AnnotationRolloutApprovedBatch] = strconv.Itoa(int(status.CurrentBatch))
+	owner.object.SetAnnotations(meta)
+	if err := o.Client.Update(ctx, owner.object); err != nil {
+		return "", fmt.Errorf("approve the rollout of app %s err: %w", o.AppName, err)
+	}
+	return fmt.Sprintf("batch %d of app \"%s\" approved", status.CurrentBatch, o.AppName), nil
+}
+
+// PauseRollout pauses the rollout of the app
+func (o *RolloutOptions) PauseRollout() (string, error) {
+	return o.setRolloutPaused(true)
+}
+
+// ResumeRollout resumes the paused rollout of the app
+func (o *RolloutOptions) ResumeRollout() (string, error) {
+	return o.setRolloutPaused(false)
+}
+
+func (o *RolloutOptions) setRolloutPaused(paused bool) (string, error) {
+	ctx := context.Background()
+	owner, err := o.getRolloutOwner(ctx)
+	if err != nil {
+		return "", err
+	}
+	action := "resumed"
+	if paused {
+		action = "paused"
+	}
+	if owner.plan.Paused == paused {
+		return fmt.Sprintf("the rollout of app \"%s\" is already %s", o.AppName, action), nil
+	}
+	owner.plan.Paused = paused
+	if err := o.Client.Update(ctx, owner.object); err != nil {
+		return "", fmt.Errorf("update the rollout of app %s err: %w", o.AppName, err)
+	}
+	return fmt.Sprintf("the rollout of app \"%s\" %s", o.AppName, action), nil
+}
+
+// AbortRollout aborts the rollout of the app, the rollout fails and follows its rollback policy
+func (o *RolloutOptions) AbortRollout() (string, error) {
+	ctx := context.Background()
+	owner, err := o.getRolloutOwner(ctx)
+	if err != nil {
+		return "", err
+	}
+	status := owner.status
+	if !isRolloutInProgress(status) {
+		return "", fmt.Errorf("the rollout of app %s can not be aborted in state %s", o.AppName, status.RollingState)
+	}
+	meta := owner.object.GetAnnotations()
+	if meta == nil {
+		meta = make(map[string]string)
+	}
+	meta[oam.AnnotationRolloutAbort] = "true"
+	owner.object.SetAnnotations(meta)
+	if err := o.Client.Update(ctx, owner.object); err != nil {
+		return "", fmt.Errorf("abort the rollout of app %s err: %w", o.AppName, err)
+	}
+	return fmt.Sprintf("the rollout of app \"%s\" aborted", o.AppName), nil
+}
+
+// GetRolloutHistory returns the finished rollouts of the app, the latest one is the last
+func (o *RolloutOptions) GetRolloutHistory() ([]v1alpha1.RolloutRecord, error) {
+	owner, err := o.getRolloutOwner(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return owner.status.RolloutHistory, nil
+}
+
+// UndoRollout starts a reverse rollout back to the target revision of the rollout record with toRevision
+// it undoes the last rollout if toRevision is 0
+func (o *RolloutOptions) UndoRollout(toRevision int64) (string, error) {
+	ctx := context.Background()
+	owner, err := o.getRolloutOwner(ctx)
+	if err != nil {
+		return "", err
+	}
+	status := owner.status
+	if isRolloutInProgress(status) || status.RollingState == v1alpha1.RollingBackState {
+		return "", fmt.Errorf("the rollout of app %s can not be undone in state %s", o.AppName, status.RollingState)
+	}
+	history := status.RolloutHistory
+	if len(history) == 0 {
+		return "", fmt.Errorf("app %s does not have any rollout history", o.AppName)
+	}
+	// the target of the last rollout is running only if it succeeded
+	last := history[len(history)-1]
+	currentRevision := last.SourceRevision
+	if last.FinalState == v1alpha1.RolloutSucceedState {
+		currentRevision = last.TargetRevision
+	}
+	var undoRevision string
+	if toRevision == 0 {
+		if last.FinalState != v1alpha1.RolloutSucceedState {
+			return "", fmt.Errorf("the last rollout of app %s did not succeed, nothing to undo", o.AppName)
+		}
+		undoRevision = last.SourceRevision
+	} else {
+		for _, record := range history {
+			if record.Revision != toRevision {
+				continue
+			}
+			if record.FinalState != v1alpha1.RolloutSucceedState {
+				return "", fmt.Errorf("the rollout %d of app %s did not succeed", toRevision, o.AppName)
+			}
+			undoRevision = record.TargetRevision
+		}
+		if undoRevision == "" {
+			return "", fmt.Errorf("the rollout %d of app %s is not found in the history", toRevision, o.AppName)
+		}
+	}
+	if undoRevision == "" {
+		return "", fmt.Errorf("the first deployment of app %s can not be undone", o.AppName)
+	}
+	if undoRevision == currentRevision {
+		return "", fmt.Errorf("app %s is already running the revision %s", o.AppName, undoRevision)
+	}
+
+	switch obj := owner.object.(type) {
+	case *corev1alpha2.ApplicationDeployment:
+		obj.Spec.SourceApplicationName = currentRevision
+		obj.Spec.TargetApplicationName = undoRevision
+	default:
+		// the application controller rolls out the revision and keeps it until the app changes
+		meta := obj.GetAnnotations()
+		if meta == nil {
+			meta = make(map[string]string)
+		}
+		meta[oam.AnnotationRolloutUndoRevision] = undoRevision
+		obj.SetAnnotations(meta)
+	}
+	if err := o.Client.Update(ctx, owner.object); err != nil {
+		return "", fmt.Errorf("undo the rollout of app %s err: %w", o.AppName, err)
+	}
+	return fmt.Sprintf("app \"%s\" starts to roll back from %s to %s", o.AppName, currentRevision, undoRevision), nil
+}
@@ -0,0 +1,190 @@
+package common
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	corev1alpha2 "github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+func newRolloutTestOptions(t *testing.T, status v1alpha1.RolloutStatus) *RolloutOptions {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, corev1alpha2.SchemeBuilder.AddToScheme(scheme))
+	appDeploy := &corev1alpha2.ApplicationDeployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "frontend", Namespace: "default"},
+		Status:     corev1alpha2.ApplicationDeploymentStatus{RolloutStatus: status},
+	}
+	return &RolloutOptions{
+		AppName: "frontend",
+		Client:  fake.NewFakeClientWithScheme(scheme, appDeploy),
+		Env:     &types.EnvMeta{Name: "default", Namespace: "default"},
+	}
+}
+
+func getTestAppDeployment(t *testing.T, o *RolloutOptions) *corev1alpha2.ApplicationDeployment {
+	var appDeploy corev1alpha2.ApplicationDeployment
+	assert.NoError(t, o.Client.Get(context.Background(), client.ObjectKey{Name: "frontend", Namespace: "default"}, &appDeploy))
+	return &appDeploy
+}
+
+func TestApproveRolloutBatch(t *testing.T) {
+	o := newRolloutTestOptions(t, v1alpha1.RolloutStatus{
+		RollingState:      v1alpha1.RollingInBatchesState,
+		BatchRollingState: v1alpha1.BatchWaitingApprovalState,
+		CurrentBatch:      2,
+	})
+	_, err := o.ApproveRolloutBatch()
+	assert.NoError(t, err)
+	assert.Equal(t, "2", getTestAppDeployment(t, o).GetAnnotations()[oam.AnnotationRolloutApprovedBatch])
+
+	o = newRolloutTestOptions(t, v1alpha1.RolloutStatus{
+		RollingState:      v1alpha1.RollingInBatchesState,
+		BatchRollingState: v1alpha1.BatchInRollingState,
+	})
+	_, err = o.ApproveRolloutBatch()
+	assert.Error(t, err)
+}
+
+func TestPauseAndResumeRollout(t *testing.T) {
+	o := newRolloutTestOptions(t, v1alpha1.RolloutStatus{RollingState: v1alpha1.RollingInBatchesState})
+	_, err := o.PauseRollout()
+	assert.NoError(t, err)
+	assert.True(t, getTestAppDeployment(t, o).Spec.RolloutPlan.Paused)
+	_, err = o.ResumeRollout()
+	assert.NoError(t, err)
+	assert.False(t, getTestAppDeployment(t, o).Spec.RolloutPlan.Paused)
+}
+
+func TestAbortRollout(t *testing.T) {
+	o := newRolloutTestOptions(t, v1alpha1.RolloutStatus{RollingState: v1alpha1.RollingInBatchesState})
+	_, err := o.AbortRollout()
+	assert.NoError(t, err)
+	assert.Equal(t, "true", getTestAppDeployment(t, o).GetAnnotations()[oam.AnnotationRolloutAbort])
+
+	o = newRolloutTestOptions(t, v1alpha1.RolloutStatus{RollingState: v1alpha1.RolloutSucceedState})
+	_, err = o.AbortRollout()
+	assert.Error(t, err)
+}
+
+func TestRolloutOnApplication(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, corev1alpha2.SchemeBuilder.AddToScheme(scheme))
+	app := &corev1alpha2.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "frontend", Namespace: "default"},
+		Spec:       corev1alpha2.ApplicationSpec{RolloutPlan: &v1alpha1.RolloutPlan{}},
+		Status: corev1alpha2.AppStatus{RolloutStatus: v1alpha1.RolloutStatus{
+			RollingState: v1alpha1.RollingInBatchesState,
+		}},
+	}
+	o := &RolloutOptions{
+		AppName: "frontend",
+		Client:  fake.NewFakeClientWithScheme(scheme, app),
+		Env:     &types.EnvMeta{Name: "default", Namespace: "default"},
+	}
+	_, err := o.PauseRollout()
+	assert.NoError(t, err)
+	_, err = o.AbortRollout()
+	assert.NoError(t, err)
+	var got corev1alpha2.Application
+	assert.NoError(t, o.Client.Get(context.Background(), client.ObjectKey{Name: "frontend", Namespace: "default"}, &got))
+	assert.True(t, got.Spec.RolloutPlan.Paused)
+	assert.Equal(t, "true", got.GetAnnotations()[oam.AnnotationRolloutAbort])
+
+	// the application without a rollout plan can't be rolled out
+	got.Spec.RolloutPlan = nil
+	assert.NoError(t, o.Client.Update(context.Background(), &got))
+	_, err = o.ResumeRollout()
+	assert.Error(t, err)
+}
+
+func TestUndoRollout(t *testing.T) {
+	history := []v1alpha1.RolloutRecord{
+		{Revision: 1, TargetRevision: "frontend-v1", FinalState: v1alpha1.RolloutSucceedState},
+		{Revision: 2, SourceRevision: "frontend-v1", TargetRevision: "frontend-v2",
+			FinalState: v1alpha1.RolloutSucceedState},
+		{Revision: 3, SourceRevision: "frontend-v2", TargetRevision: "frontend-v3",
+			FinalState: v1alpha1.RolloutSucceedState},
+	}
+	o := newRolloutTestOptions(t, v1alpha1.RolloutStatus{
+		RollingState:   v1alpha1.RolloutSucceedState,
+		RolloutHistory: history,
+	})
+	records, err := o.GetRolloutHistory()
+	assert.NoError(t, err)
+	assert.Equal(t, history, records)
+
+	// undo the last rollout
+	_, err = o.UndoRollout(0)
+	assert.NoError(t, err)
+	appDeploy := getTestAppDeployment(t, o)
+	assert.Equal(t, "frontend-v3", appDeploy.Spec.SourceApplicationName)
+	assert.Equal(t, "frontend-v2", appDeploy.Spec.TargetApplicationName)
+
+	// go back to a revision in the history
+	_, err = o.UndoRollout(1)
+	assert.NoError(t, err)
+	appDeploy = getTestAppDeployment(t, o)
+	assert.Equal(t, "frontend-v3", appDeploy.Spec.SourceApplicationName)
+	assert.Equal(t, "frontend-v1", appDeploy.Spec.TargetApplicationName)
+
+	// the revision is already running
+	_, err = o.UndoRollout(3)
+	assert.Error(t, err)
+	_, err = o.UndoRollout(4)
+	assert.Error(t, err)
+
+	// a failed rollout can't be undone
+	history[2].FinalState = v1alpha1.RolloutRolledBackState
+	o = newRolloutTestOptions(t, v1alpha1.RolloutStatus{
+		RollingState:   v1alpha1.RolloutRolledBackState,
+		RolloutHistory: history,
+	})
+	_, err = o.UndoRollout(0)
+	assert.Error(t, err)
+	_, err = o.UndoRollout(1)
+	assert.NoError(t, err)
+	appDeploy = getTestAppDeployment(t, o)
+	assert.Equal(t, "frontend-v2", appDeploy.Spec.SourceApplicationName)
+	assert.Equal(t, "frontend-v1", appDeploy.Spec.TargetApplicationName)
+
+	// no undo while rolling out
+	o = newRolloutTestOptions(t, v1alpha1.RolloutStatus{
+		RollingState:   v1alpha1.RollingInBatchesState,
+		RolloutHistory: history,
+	})
+	_, err = o.UndoRollout(1)
+	assert.Error(t, err)
+}
+
+func TestUndoApplicationRollout(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, corev1alpha2.SchemeBuilder.AddToScheme(scheme))
+	app := &corev1alpha2.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "frontend", Namespace: "default"},
+		Spec:       corev1alpha2.ApplicationSpec{RolloutPlan: &v1alpha1.RolloutPlan{}},
+		Status: corev1alpha2.AppStatus{RolloutStatus: v1alpha1.RolloutStatus{
+			RollingState: v1alpha1.RolloutSucceedState,
+			RolloutHistory: []v1alpha1.RolloutRecord{{Revision: 1, SourceRevision: "frontend-v1",
+				TargetRevision: "frontend-v2", FinalState: v1alpha1.RolloutSucceedState}},
+		}},
+	}
+	o := &RolloutOptions{
+		AppName: "frontend",
+		Client:  fake.NewFakeClientWithScheme(scheme, app),
+		Env:     &types.EnvMeta{Name: "default", Namespace: "default"},
+	}
+	_, err := o.UndoRollout(0)
+	assert.NoError(t, err)
+	var got corev1alpha2.Application
+	assert.NoError(t, o.Client.Get(context.Background(), client.ObjectKey{Name: "frontend", Namespace: "default"}, &got))
+	assert.Equal(t, "frontend-v1", got.GetAnnotations()[oam.AnnotationRolloutUndoRevision])
+}